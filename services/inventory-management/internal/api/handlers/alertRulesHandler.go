@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"inventory-management/internal/model"
+	"inventory-management/internal/utils"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CreateAlertRule godoc
+// @Summary Create a low-stock alert rule
+// @Description Decide who is notified when stock runs low: the users, roles and departments to notify, the products,
+// @Description categories and warehouses the rule covers (all stock when none are given), immediate or daily digest
+// @Description mode and a cooldown per stock item. Requires manager permission
+// @Tags alert-rules
+// @Accept json
+// @Produce json
+// @Param body body model.AlertRule true "Alert rule"
+// @Success 201 {object} model.AlertRule
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Router /alert-rules [post]
+func CreateAlertRule(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		managerID, ok := requireManager(c)
+		if !ok {
+			return
+		}
+
+		rule := model.AlertRule{IsActive: true}
+		if err := c.ShouldBindJSON(&rule); err != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid request data"})
+			return
+		}
+		if err := utils.ValidateAlertRule(&rule); err != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: err.Error()})
+			return
+		}
+
+		rule.ID = 0
+		rule.CreatedBy = managerID
+		rule.AccountID = accountID.(uint)
+		if err := db.Create(&rule).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to create alert rule"})
+			return
+		}
+
+		c.JSON(http.StatusCreated, rule)
+	}
+}
+
+// GetAlertRules godoc
+// @Summary Get low-stock alert rules
+// @Description Retrieve the account's low-stock alert rules
+// @Tags alert-rules
+// @Produce json
+// @Success 200 {object} model.AlertRulesResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /alert-rules [get]
+func GetAlertRules(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		var rules []model.AlertRule
+		if err := db.Where("account_id = ?", accountID).Order("id").Find(&rules).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to retrieve alert rules"})
+			return
+		}
+
+		c.JSON(http.StatusOK, model.AlertRulesResponse{Message: "Alert rules retrieved successfully", Rules: rules})
+	}
+}
+
+// UpdateAlertRule godoc
+// @Summary Update a low-stock alert rule
+// @Description Update an alert rule by ID, e.g. its recipients or cooldown, or set is_active to false to pause it.
+// @Description Requires manager permission
+// @Tags alert-rules
+// @Accept json
+// @Produce json
+// @Param id path int true "Alert rule ID"
+// @Param body body model.AlertRule true "Alert rule"
+// @Success 200 {object} model.AlertRule
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Router /alert-rules/{id} [put]
+func UpdateAlertRule(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		if _, ok := requireManager(c); !ok {
+			return
+		}
+
+		var rule model.AlertRule
+		if err := db.Where("id = ? AND account_id = ?", c.Param("id"), accountID).First(&rule).Error; err != nil {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Alert rule not found"})
+			return
+		}
+
+		id, createdBy := rule.ID, rule.CreatedBy
+		if err := c.ShouldBindJSON(&rule); err != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid request data"})
+			return
+		}
+		if err := utils.ValidateAlertRule(&rule); err != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: err.Error()})
+			return
+		}
+
+		rule.ID = id
+		rule.CreatedBy = createdBy
+		rule.AccountID = accountID.(uint)
+		if err := db.Save(&rule).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to update alert rule"})
+			return
+		}
+
+		c.JSON(http.StatusOK, rule)
+	}
+}
+
+// DeleteAlertRule godoc
+// @Summary Delete a low-stock alert rule
+// @Description Delete an alert rule by ID. Alerts it already queued for the digest are still sent. Requires manager
+// @Description permission
+// @Tags alert-rules
+// @Produce json
+// @Param id path int true "Alert rule ID"
+// @Success 200 {object} model.SuccessResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Router /alert-rules/{id} [delete]
+func DeleteAlertRule(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		if _, ok := requireManager(c); !ok {
+			return
+		}
+
+		var rule model.AlertRule
+		if err := db.Where("id = ? AND account_id = ?", c.Param("id"), accountID).First(&rule).Error; err != nil {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Alert rule not found"})
+			return
+		}
+
+		if err := db.Delete(&rule).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to delete alert rule"})
+			return
+		}
+
+		c.JSON(http.StatusOK, model.SuccessResponse{Message: "Alert rule deleted successfully"})
+	}
+}
+
+// GetAlertRuleRecipients godoc
+// @Summary Preview the recipients of a low-stock alert rule
+// @Description List the email addresses the rule currently notifies, looked up in user-management
+// @Tags alert-rules
+// @Produce json
+// @Param id path int true "Alert rule ID"
+// @Success 200 {array} string
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 502 {object} model.ErrorResponse
+// @Router /alert-rules/{id}/recipients [get]
+func GetAlertRuleRecipients(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		var rule model.AlertRule
+		if err := db.Where("id = ? AND account_id = ?", c.Param("id"), accountID).First(&rule).Error; err != nil {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Alert rule not found"})
+			return
+		}
+
+		token, _ := utils.ExtractToken(c)
+		users, err := utils.FetchAccountUsers(token)
+		if err != nil {
+			log.Printf("Failed to fetch users of account %v: %v", accountID, err)
+			c.JSON(http.StatusBadGateway, model.ErrorResponse{Error: "Failed to look up recipients"})
+			return
+		}
+
+		c.JSON(http.StatusOK, utils.AlertRecipients(rule, users))
+	}
+}
+
+// GetLowStockAlerts godoc
+// @Summary Get low-stock alerts
+// @Description Retrieve the alerts the account's rules raised, newest first, with who was notified and when.
+// @Description pending=true lists the alerts waiting for the next daily digest
+// @Tags alert-rules
+// @Produce json
+// @Param rule_id query int false "Alert rule ID"
+// @Param stock_id query int false "Stock ID"
+// @Param pending query bool false "Only digest alerts not sent yet"
+// @Success 200 {object} model.LowStockAlertsResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /low-stock-alerts [get]
+func GetLowStockAlerts(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		query := db.Where("account_id = ?", accountID)
+		for _, filter := range []string{"rule_id", "stock_id"} {
+			if value := c.Query(filter); value != "" {
+				query = query.Where(filter+" = ?", value)
+			}
+		}
+		if c.Query("pending") == "true" {
+			query = query.Where("mode = ? AND sent_at IS NULL AND error = ?", model.AlertDigest, "")
+		}
+
+		var alerts []model.LowStockAlert
+		if err := query.Order("created_at DESC, id DESC").Find(&alerts).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to retrieve low stock alerts"})
+			return
+		}
+
+		c.JSON(http.StatusOK, model.LowStockAlertsResponse{Message: "Low stock alerts retrieved successfully", Alerts: alerts})
+	}
+}
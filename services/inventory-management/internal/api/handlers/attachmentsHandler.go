@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"errors"
+	"inventory-management/internal/model"
+	"inventory-management/internal/storage"
+	"inventory-management/internal/utils"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// UploadAttachment godoc
+// @Summary Attach a file to a product
+// @Description Upload an image, spec sheet, safety data sheet or other document for a product as multipart form data.
+// @Description The content type is detected from the file and must suit the kind: images are JPEG, PNG, GIF or WebP,
+// @Description spec sheets and SDS are PDF, documents are PDF, text, CSV or Office files. Files are limited to
+// @Description ATTACHMENT_MAX_BYTES and count against the account's attachment quota; images get a thumbnail
+// @Tags attachments
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param file formData file true "File to attach"
+// @Param kind formData string false "image, spec_sheet, sds or document; detected from the file when omitted"
+// @Success 201 {object} model.Attachment
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Failure 413 {object} model.ErrorResponse
+// @Failure 415 {object} model.ErrorResponse
+// @Router /products/{id}/attachments [post]
+func UploadAttachment(db *gorm.DB, store storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		// Leave room for the multipart framing around the largest allowed file
+		maxSize := utils.MaxAttachmentSize()
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+1<<20)
+		header, err := c.FormFile("file")
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) || (err == nil && header.Size > maxSize) {
+			c.JSON(http.StatusRequestEntityTooLarge, model.ErrorResponse{Error: utils.ErrAttachmentTooLarge.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "file is required"})
+			return
+		}
+
+		var product model.Product
+		if err := db.Where("id = ? AND account_id = ?", c.Param("id"), accountID).First(&product).Error; err != nil {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Product not found"})
+			return
+		}
+
+		file, err := header.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Failed to read file"})
+			return
+		}
+		defer file.Close()
+		data, err := io.ReadAll(io.LimitReader(file, maxSize+1))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Failed to read file"})
+			return
+		}
+
+		attachment := model.Attachment{
+			ProductID:  product.ID,
+			Kind:       model.AttachmentKind(c.PostForm("kind")),
+			FileName:   header.Filename,
+			UploadedBy: utils.CurrentUserID(c),
+			AccountID:  accountID.(uint),
+		}
+		if err := utils.SaveAttachment(c.Request.Context(), db, store, &attachment, data); err != nil {
+			writeAttachmentError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, attachment)
+	}
+}
+
+// GetProductAttachments godoc
+// @Summary Get a product's attachments
+// @Description Retrieve the images and documents attached to a product, newest first
+// @Tags attachments
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param kind query string false "image, spec_sheet, sds or document"
+// @Success 200 {object} model.AttachmentsResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /products/{id}/attachments [get]
+func GetProductAttachments(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		query := db.Where("product_id = ? AND account_id = ?", c.Param("id"), accountID)
+		if kind := c.Query("kind"); kind != "" {
+			query = query.Where("kind = ?", kind)
+		}
+
+		var attachments []model.Attachment
+		if err := query.Order("created_at DESC, id DESC").Find(&attachments).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to retrieve attachments"})
+			return
+		}
+
+		c.JSON(http.StatusOK, model.AttachmentsResponse{Message: "Attachments retrieved successfully", Attachments: attachments})
+	}
+}
+
+// DownloadAttachment godoc
+// @Summary Download an attachment
+// @Description Download an attached file, or with thumbnail=true the JPEG thumbnail of an image. Images and PDFs are
+// @Description served inline, other files as downloads
+// @Tags attachments
+// @Produce octet-stream
+// @Param id path int true "Attachment ID"
+// @Param thumbnail query bool false "Download the thumbnail instead of the file"
+// @Success 200 {file} file
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 502 {object} model.ErrorResponse
+// @Router /attachments/{id}/download [get]
+func DownloadAttachment(db *gorm.DB, store storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		var attachment model.Attachment
+		if err := db.Where("id = ? AND account_id = ?", c.Param("id"), accountID).First(&attachment).Error; err != nil {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Attachment not found"})
+			return
+		}
+
+		key, size, contentType, fileName := attachment.StorageKey, attachment.Size, attachment.ContentType, attachment.FileName
+		if c.Query("thumbnail") == "true" {
+			if attachment.ThumbnailKey == "" {
+				c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Attachment has no thumbnail"})
+				return
+			}
+			key, size, contentType = attachment.ThumbnailKey, attachment.ThumbnailSize, "image/jpeg"
+			fileName = strings.TrimSuffix(fileName, filepath.Ext(fileName)) + "-thumbnail.jpg"
+		}
+
+		body, err := store.Get(c.Request.Context(), key)
+		if errors.Is(err, storage.ErrNotFound) {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Attachment file not found"})
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to read attachment %d from storage: %v", attachment.ID, err)
+			c.JSON(http.StatusBadGateway, model.ErrorResponse{Error: "Failed to read attachment"})
+			return
+		}
+		defer body.Close()
+
+		disposition := "attachment"
+		if strings.HasPrefix(contentType, "image/") || contentType == "application/pdf" {
+			disposition = "inline"
+		}
+		c.DataFromReader(http.StatusOK, size, contentType, body, map[string]string{
+			"Content-Disposition":    mime.FormatMediaType(disposition, map[string]string{"filename": fileName}),
+			"X-Content-Type-Options": "nosniff",
+		})
+	}
+}
+
+// DeleteAttachment godoc
+// @Summary Delete an attachment
+// @Description Delete an attachment with its file and thumbnail, freeing its share of the account's quota
+// @Tags attachments
+// @Produce json
+// @Param id path int true "Attachment ID"
+// @Success 200 {object} model.SuccessResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 502 {object} model.ErrorResponse
+// @Router /attachments/{id} [delete]
+func DeleteAttachment(db *gorm.DB, store storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		var attachment model.Attachment
+		if err := db.Where("id = ? AND account_id = ?", c.Param("id"), accountID).First(&attachment).Error; err != nil {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Attachment not found"})
+			return
+		}
+
+		if err := utils.DeleteAttachment(c.Request.Context(), db, store, attachment); err != nil {
+			log.Printf("Failed to delete attachment %d: %v", attachment.ID, err)
+			c.JSON(http.StatusBadGateway, model.ErrorResponse{Error: "Failed to delete attachment"})
+			return
+		}
+
+		c.JSON(http.StatusOK, model.SuccessResponse{Message: "Attachment deleted successfully"})
+	}
+}
+
+// GetAttachmentUsage godoc
+// @Summary Get attachment storage usage
+// @Description Retrieve how much of the account's attachment quota its files and thumbnails use
+// @Tags attachments
+// @Produce json
+// @Success 200 {object} model.AttachmentUsage
+// @Failure 500 {object} model.ErrorResponse
+// @Router /attachments/usage [get]
+func GetAttachmentUsage(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		usage, err := utils.GetAttachmentUsage(db, accountID.(uint))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to retrieve attachment usage"})
+			return
+		}
+
+		c.JSON(http.StatusOK, usage)
+	}
+}
+
+// writeAttachmentError answers an upload that could not be saved
+func writeAttachmentError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, utils.ErrAttachmentTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, model.ErrorResponse{Error: err.Error()})
+	case errors.Is(err, utils.ErrAttachmentType):
+		c.JSON(http.StatusUnsupportedMediaType, model.ErrorResponse{Error: err.Error()})
+	case errors.Is(err, utils.ErrAttachmentQuota):
+		c.JSON(http.StatusConflict, model.ErrorResponse{Error: err.Error()})
+	case errors.Is(err, utils.ErrAttachmentKind), errors.Is(err, utils.ErrInvalidImage):
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: err.Error()})
+	default:
+		log.Printf("Failed to save attachment: %v", err)
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to save attachment"})
+	}
+}
@@ -0,0 +1,323 @@
+package handlers
+
+import (
+	"inventory-management/internal/model"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CreateBin godoc
+// @Summary Create a new bin
+// @Description Create a new bin inside one of the account's zones
+// @Tags bins
+// @Accept json
+// @Produce json
+// @Param body body model.Bin true "Bin data"
+// @Success 200 {object} model.Bin
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Router /bins [post]
+func CreateBin(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		var bin model.Bin
+		if err := c.ShouldBindJSON(&bin); err != nil || bin.Code == "" {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid request data"})
+			return
+		}
+
+		if err := db.Where("id = ? AND account_id = ?", bin.ZoneID, accountID).First(&model.Zone{}).Error; err != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Zone not found"})
+			return
+		}
+
+		var duplicates int64
+		db.Model(&model.Bin{}).Where("zone_id = ? AND code = ? AND account_id = ?", bin.ZoneID, bin.Code, accountID).Count(&duplicates)
+		if duplicates > 0 {
+			c.JSON(http.StatusConflict, model.ErrorResponse{Error: "Bin code already exists in zone"})
+			return
+		}
+		if binBarcodeTaken(db, accountID, bin.Barcode, 0) {
+			c.JSON(http.StatusConflict, model.ErrorResponse{Error: "Bin barcode already exists"})
+			return
+		}
+
+		bin.AccountID = accountID.(uint)
+		bin.Zone = nil
+		if err := db.Create(&bin).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to create bin"})
+			return
+		}
+
+		c.JSON(http.StatusOK, bin)
+	}
+}
+
+// GetBins godoc
+// @Summary Get all bins
+// @Description Retrieve all bins of the account, optionally filtered by zone or warehouse
+// @Tags bins
+// @Produce json
+// @Param zone_id query int false "Zone ID"
+// @Param warehouse_id query int false "Warehouse ID"
+// @Success 200 {object} model.BinsResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /bins [get]
+func GetBins(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		query := db.Preload("Zone.Warehouse").Where("account_id = ?", accountID)
+		if zoneID := c.Query("zone_id"); zoneID != "" {
+			query = query.Where("zone_id = ?", zoneID)
+		}
+		if warehouseID := c.Query("warehouse_id"); warehouseID != "" {
+			query = query.Where("zone_id IN (?)", db.Model(&model.Zone{}).Select("id").Where("warehouse_id = ?", warehouseID))
+		}
+
+		var bins []model.Bin
+		if err := query.Find(&bins).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to retrieve bins"})
+			return
+		}
+
+		c.JSON(http.StatusOK, model.BinsResponse{
+			Message: "Bins retrieved successfully",
+			Bins:    bins,
+		})
+	}
+}
+
+// UpdateBin godoc
+// @Summary Update a bin
+// @Description Update a bin by ID
+// @Tags bins
+// @Accept json
+// @Produce json
+// @Param id path int true "Bin ID"
+// @Param body body model.Bin true "Bin data"
+// @Success 200 {object} model.Bin
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Router /bins/{id} [put]
+func UpdateBin(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		var bin model.Bin
+		if err := db.Where("id = ? AND account_id = ?", c.Param("id"), accountID).First(&bin).Error; err != nil {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Bin not found"})
+			return
+		}
+
+		if err := c.ShouldBindJSON(&bin); err != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid request data"})
+			return
+		}
+
+		if err := db.Where("id = ? AND account_id = ?", bin.ZoneID, accountID).First(&model.Zone{}).Error; err != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Zone not found"})
+			return
+		}
+		if binBarcodeTaken(db, accountID, bin.Barcode, bin.ID) {
+			c.JSON(http.StatusConflict, model.ErrorResponse{Error: "Bin barcode already exists"})
+			return
+		}
+
+		bin.AccountID = accountID.(uint)
+		bin.Zone = nil
+		if err := db.Save(&bin).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to update bin"})
+			return
+		}
+
+		c.JSON(http.StatusOK, bin)
+	}
+}
+
+// SoftDeleteBin godoc
+// @Summary Soft delete a bin
+// @Description Soft delete a bin by ID. Bins that still hold stock cannot be deleted
+// @Tags bins
+// @Produce json
+// @Param id path int true "Bin ID"
+// @Success 200 {object} model.SuccessResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Router /bins/{id} [delete]
+func SoftDeleteBin(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		var bin model.Bin
+		if err := db.Where("id = ? AND account_id = ?", c.Param("id"), accountID).First(&bin).Error; err != nil {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Bin not found"})
+			return
+		}
+
+		var stockCount int64
+		db.Model(&model.Stock{}).Where("bin_id = ? AND account_id = ? AND quantity > 0", bin.ID, accountID).Count(&stockCount)
+		if stockCount > 0 {
+			c.JSON(http.StatusConflict, model.ErrorResponse{Error: "Bin still holds stock"})
+			return
+		}
+
+		if err := db.Delete(&bin).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to delete bin"})
+			return
+		}
+
+		c.JSON(http.StatusOK, model.SuccessResponse{Message: "Bin deleted successfully"})
+	}
+}
+
+// HardDeleteBin godoc
+// @Summary Hard delete a bin
+// @Description Permanently delete a bin by ID. Bins that still hold stock cannot be deleted
+// @Tags bins
+// @Produce json
+// @Param id path int true "Bin ID"
+// @Success 200 {object} model.SuccessResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Router /bins/hard/{id} [delete]
+func HardDeleteBin(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		var bin model.Bin
+		if err := db.Unscoped().Where("id = ? AND account_id = ?", c.Param("id"), accountID).First(&bin).Error; err != nil {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Bin not found"})
+			return
+		}
+
+		var stockCount int64
+		db.Model(&model.Stock{}).Where("bin_id = ? AND account_id = ? AND quantity > 0", bin.ID, accountID).Count(&stockCount)
+		if stockCount > 0 {
+			c.JSON(http.StatusConflict, model.ErrorResponse{Error: "Bin still holds stock"})
+			return
+		}
+
+		if err := db.Unscoped().Delete(&bin).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to delete bin"})
+			return
+		}
+
+		c.JSON(http.StatusOK, model.SuccessResponse{Message: "Bin deleted permanently"})
+	}
+}
+
+// RecoverBin godoc
+// @Summary Recover a deleted bin
+// @Description Recover a soft-deleted bin by ID
+// @Tags bins
+// @Produce json
+// @Param id path int true "Bin ID"
+// @Success 200 {object} model.SuccessResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /bins/{id}/recover [patch]
+func RecoverBin(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		if err := db.Unscoped().Model(&model.Bin{}).Where("id = ? AND account_id = ?", c.Param("id"), accountID).Update("deleted_at", nil).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to recover bin"})
+			return
+		}
+
+		c.JSON(http.StatusOK, model.SuccessResponse{Message: "Bin recovered successfully"})
+	}
+}
+
+// LookupBin godoc
+// @Summary Resolve a scan to a bin
+// @Description Find the bin a scanned label belongs to, matching the bin barcode or, when it is unique
+// @Description in the account, the bin code, and list the stock it holds
+// @Tags bins
+// @Produce json
+// @Param barcode query string true "Scanned bin label"
+// @Success 200 {object} model.BinLookupResponse
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Router /bins/lookup [get]
+func LookupBin(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		scan := c.Query("barcode")
+		if scan == "" {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "barcode is required"})
+			return
+		}
+
+		var bins []model.Bin
+		db.Preload("Zone.Warehouse").Where("barcode = ? AND account_id = ?", scan, accountID).Limit(1).Find(&bins)
+		if len(bins) == 0 {
+			// Codes only have to be unique within a zone, so a code is only a match when it is unambiguous
+			db.Preload("Zone.Warehouse").Where("code = ? AND account_id = ?", scan, accountID).Limit(2).Find(&bins)
+		}
+		if len(bins) != 1 {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "No bin matches the scanned code"})
+			return
+		}
+
+		var stocks []model.Stock
+		if err := db.Preload("Product.Units").Preload("Bin.Zone.Warehouse").
+			Where("bin_id = ? AND account_id = ?", bins[0].ID, accountID).Find(&stocks).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to retrieve stocks"})
+			return
+		}
+
+		response := model.BinLookupResponse{
+			Message: "Bin found",
+			Bin:     bins[0],
+			Stocks:  []model.StockResponse{},
+		}
+		for _, stock := range stocks {
+			response.Stocks = append(response.Stocks, toStockResponse(stock))
+		}
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// binBarcodeTaken reports whether another bin of the account already uses the barcode
+func binBarcodeTaken(db *gorm.DB, accountID interface{}, barcode string, binID uint) bool {
+	if barcode == "" {
+		return false
+	}
+	var count int64
+	db.Model(&model.Bin{}).Where("barcode = ? AND account_id = ? AND id <> ?", barcode, accountID, binID).Count(&count)
+	return count > 0
+}
@@ -1,7 +1,10 @@
 package handlers
 
 import (
+	"errors"
 	"inventory-management/internal/model"
+	"inventory-management/internal/utils"
+	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -10,7 +13,7 @@ import (
 
 // CreateCategory godoc
 // @Summary Create a new category
-// @Description Create a new category in the inventory
+// @Description Create a new category in the inventory, optionally below a parent category
 // @Tags categories
 // @Accept json
 // @Produce json
@@ -32,6 +35,10 @@ func CreateCategory(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
+		if !writeCategoryParentError(c, utils.ValidateCategoryParent(db, accountID.(uint), 0, category.ParentID)) {
+			return
+		}
+
 		category.AccountID = accountID.(uint)
 		if result := db.Create(&category); result.Error != nil {
 			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to create category"})
@@ -53,6 +60,7 @@ func CreateCategory(db *gorm.DB) gin.HandlerFunc {
 // @Success 200 {object} model.SuccessResponse
 // @Failure 400 {object} model.ErrorResponse
 // @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
 // @Router /categories/{id} [put]
 func UpdateCategory(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -70,10 +78,16 @@ func UpdateCategory(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
+		existingID := category.ID
 		if err := c.ShouldBindJSON(&category); err != nil {
 			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid request data"})
 			return
 		}
+		category.ID = existingID
+
+		if !writeCategoryParentError(c, utils.ValidateCategoryParent(db, accountID.(uint), category.ID, category.ParentID)) {
+			return
+		}
 
 		category.AccountID = accountID.(uint)
 		if err := db.Save(&category).Error; err != nil {
@@ -90,6 +104,7 @@ func UpdateCategory(db *gorm.DB) gin.HandlerFunc {
 // @Description Retrieve all categories or filter by query parameters
 // @Tags categories
 // @Produce json
+// @Param parent_id query int false "Parent category ID, 0 for top-level categories"
 // @Success 200 {object} model.CategoriesResponse
 // @Failure 500 {object} model.ErrorResponse
 // @Router /categories [get]
@@ -101,8 +116,15 @@ func GetCategories(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
+		query := db.Where("account_id = ?", accountID)
+		if parentID := c.Query("parent_id"); parentID == "0" {
+			query = query.Where("parent_id IS NULL")
+		} else if parentID != "" {
+			query = query.Where("parent_id = ?", parentID)
+		}
+
 		var categories []model.Category
-		if err := db.Where("account_id = ?", accountID).Find(&categories).Error; err != nil {
+		if err := query.Find(&categories).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to retrieve categories"})
 			return
 		}
@@ -110,8 +132,9 @@ func GetCategories(db *gorm.DB) gin.HandlerFunc {
 		var categoryResponses []model.CategoryResponse
 		for _, category := range categories {
 			categoryResponses = append(categoryResponses, model.CategoryResponse{
-				ID:   category.ID,
-				Name: category.Name,
+				ID:       category.ID,
+				Name:     category.Name,
+				ParentID: category.ParentID,
 			})
 		}
 
@@ -124,12 +147,15 @@ func GetCategories(db *gorm.DB) gin.HandlerFunc {
 
 // SoftDeleteCategory godoc
 // @Summary Soft delete a category
-// @Description Soft deletes a category and reassigns its products to the default category
+// @Description Soft deletes a category and reassigns its products to the default category. A category with
+// @Description subcategories is only deleted with reparent=true, which moves them to the category's parent
 // @Tags categories
 // @Produce json
 // @Param id path int true "Category ID"
+// @Param reparent query bool false "Move subcategories to the deleted category's parent"
 // @Success 200 {object} model.SuccessResponse
 // @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
 // @Failure 500 {object} model.ErrorResponse
 // @Router /categories/{id} [delete]
 func SoftDeleteCategory(db *gorm.DB) gin.HandlerFunc {
@@ -147,6 +173,10 @@ func SoftDeleteCategory(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
+		if !releaseSubcategories(c, db, category) {
+			return
+		}
+
 		var defaultCategory model.Category
 		if err := db.First(&defaultCategory, "name = ? AND account_id = ?", "Uncategorized", accountID).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Default category not found"})
@@ -169,12 +199,15 @@ func SoftDeleteCategory(db *gorm.DB) gin.HandlerFunc {
 
 // HardDeleteCategory godoc
 // @Summary Hard delete a category
-// @Description Hard deletes a category and reassigns its products to the default category
+// @Description Hard deletes a category and reassigns its products to the default category. A category with
+// @Description subcategories is only deleted with reparent=true, which moves them to the category's parent
 // @Tags categories
 // @Produce json
 // @Param id path int true "Category ID"
+// @Param reparent query bool false "Move subcategories to the deleted category's parent"
 // @Success 200 {object} model.SuccessResponse
 // @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
 // @Failure 500 {object} model.ErrorResponse
 // @Router /categories/{id}/hard [delete]
 func HardDeleteCategory(db *gorm.DB) gin.HandlerFunc {
@@ -192,6 +225,10 @@ func HardDeleteCategory(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
+		if !releaseSubcategories(c, db, category) {
+			return
+		}
+
 		var defaultCategory model.Category
 		if err := db.First(&defaultCategory, "name = ? AND account_id = ?", "Uncategorized", accountID).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Default category not found"})
@@ -245,3 +282,115 @@ func RecoverCategory(db *gorm.DB) gin.HandlerFunc {
 		c.JSON(http.StatusOK, model.SuccessResponse{Message: "Category recovered successfully"})
 	}
 }
+
+// GetCategoryTree godoc
+// @Summary Get the category tree
+// @Description Retrieve the account's categories nested below their parents
+// @Tags categories
+// @Produce json
+// @Success 200 {object} model.CategoryTreeResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /categories/tree [get]
+func GetCategoryTree(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		var categories []model.Category
+		if err := db.Where("account_id = ?", accountID).Order("name").Find(&categories).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to retrieve categories"})
+			return
+		}
+
+		c.JSON(http.StatusOK, model.CategoryTreeResponse{
+			Message:    "Category tree retrieved successfully",
+			Categories: utils.BuildCategoryTree(categories),
+		})
+	}
+}
+
+// MoveCategory godoc
+// @Summary Move a category
+// @Description Move a category and its subcategories below another category, or to the top level with a null parent_id
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Param id path int true "Category ID"
+// @Param body body model.MoveCategoryRequest true "New parent"
+// @Success 200 {object} model.SuccessResponse
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Router /categories/{id}/move [post]
+func MoveCategory(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		var request model.MoveCategoryRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid request data"})
+			return
+		}
+
+		var category model.Category
+		if err := db.Where("id = ? AND account_id = ?", c.Param("id"), accountID).First(&category).Error; err != nil {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Category not found"})
+			return
+		}
+
+		if !writeCategoryParentError(c, utils.ValidateCategoryParent(db, accountID.(uint), category.ID, request.ParentID)) {
+			return
+		}
+
+		if err := db.Model(&category).Update("parent_id", request.ParentID).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to move category"})
+			return
+		}
+
+		c.JSON(http.StatusOK, model.SuccessResponse{Message: "Category moved successfully"})
+	}
+}
+
+// releaseSubcategories moves the subcategories of a category that is about to be deleted
+// to its parent when reparent=true is given, and refuses the delete otherwise
+func releaseSubcategories(c *gin.Context, db *gorm.DB, category model.Category) bool {
+	var children int64
+	db.Model(&model.Category{}).Where("parent_id = ? AND account_id = ?", category.ID, category.AccountID).Count(&children)
+	if children == 0 {
+		return true
+	}
+
+	if c.Query("reparent") != "true" {
+		c.JSON(http.StatusConflict, model.ErrorResponse{Error: "Category has subcategories; delete with reparent=true to move them to its parent"})
+		return false
+	}
+	if err := db.Model(&model.Category{}).Where("parent_id = ? AND account_id = ?", category.ID, category.AccountID).
+		Update("parent_id", category.ParentID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to reparent subcategories"})
+		return false
+	}
+	return true
+}
+
+// writeCategoryParentError maps parent validation errors to responses and reports whether err was nil
+func writeCategoryParentError(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, utils.ErrParentCategoryNotFound):
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: err.Error()})
+	case errors.Is(err, utils.ErrCategoryCycle):
+		c.JSON(http.StatusConflict, model.ErrorResponse{Error: err.Error()})
+	default:
+		log.Printf("Failed to validate parent category: %v", err)
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to validate parent category"})
+	}
+	return false
+}
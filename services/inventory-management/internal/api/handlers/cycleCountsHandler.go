@@ -0,0 +1,305 @@
+package handlers
+
+import (
+	"errors"
+	"inventory-management/internal/model"
+	"inventory-management/internal/utils"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CreateCycleCountPlan godoc
+// @Summary Create a cycle-count plan
+// @Description Create a plan that counts stock by bin, by ABC class or as a random sample and generate its count tasks
+// @Tags cycle-counts
+// @Accept json
+// @Produce json
+// @Param body body model.CycleCountPlan true "Cycle-count plan"
+// @Success 200 {object} model.CycleCountPlan
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Router /cycle-counts [post]
+func CreateCycleCountPlan(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		var plan model.CycleCountPlan
+		if err := c.ShouldBindJSON(&plan); err != nil || plan.TolerancePercent < 0 {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid request data"})
+			return
+		}
+
+		switch {
+		case plan.Type == model.CycleCountByBin && len(plan.BinIDs) == 0:
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "bin_ids is required for bin plans"})
+			return
+		case plan.Type == model.CycleCountByABCClass && plan.ABCClass == "":
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "abc_class is required for ABC class plans"})
+			return
+		case plan.Type == model.CycleCountRandom && plan.SampleSize <= 0:
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "sample_size must be positive for random plans"})
+			return
+		case plan.Type != model.CycleCountByBin && plan.Type != model.CycleCountByABCClass && plan.Type != model.CycleCountRandom:
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "type must be bin, abc_class or random"})
+			return
+		}
+
+		plan.ID = 0
+		plan.AccountID = accountID.(uint)
+		plan.Status = model.CycleCountPlanOpen
+		plan.CreatedBy = utils.CurrentUserID(c)
+		plan.Tasks = nil
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&plan).Error; err != nil {
+				return err
+			}
+			tasks, err := utils.GenerateCycleCountTasks(tx, plan)
+			plan.Tasks = tasks
+			return err
+		})
+		if errors.Is(err, utils.ErrNothingToCount) {
+			c.JSON(http.StatusConflict, model.ErrorResponse{Error: err.Error()})
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to create cycle count plan: %v", err)
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to create cycle count plan"})
+			return
+		}
+
+		c.JSON(http.StatusOK, plan)
+	}
+}
+
+// GetCycleCountPlans godoc
+// @Summary Get cycle-count plans
+// @Description Retrieve the account's cycle-count plans with their tasks
+// @Tags cycle-counts
+// @Produce json
+// @Param status query string false "Plan status (open, completed)"
+// @Success 200 {object} model.CycleCountPlansResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /cycle-counts [get]
+func GetCycleCountPlans(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		query := db.Preload("Tasks").Where("account_id = ?", accountID)
+		if status := c.Query("status"); status != "" {
+			query = query.Where("status = ?", status)
+		}
+
+		var plans []model.CycleCountPlan
+		if err := query.Order("id").Find(&plans).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to retrieve cycle count plans"})
+			return
+		}
+
+		c.JSON(http.StatusOK, model.CycleCountPlansResponse{
+			Message: "Cycle count plans retrieved successfully",
+			Plans:   plans,
+		})
+	}
+}
+
+// GetCycleCountTasks godoc
+// @Summary Get cycle-count tasks
+// @Description Retrieve count tasks, e.g. the pending work list or the variances waiting for approval
+// @Tags cycle-counts
+// @Produce json
+// @Param plan_id query int false "Plan ID"
+// @Param status query string false "Task status (pending, pending_approval, completed, approved, rejected)"
+// @Param bin_id query int false "Bin ID"
+// @Success 200 {object} model.CycleCountTasksResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /cycle-counts/tasks [get]
+func GetCycleCountTasks(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		query := db.Where("account_id = ?", accountID)
+		if planID := c.Query("plan_id"); planID != "" {
+			query = query.Where("plan_id = ?", planID)
+		}
+		if status := c.Query("status"); status != "" {
+			query = query.Where("status = ?", status)
+		}
+		if binID := c.Query("bin_id"); binID != "" {
+			query = query.Where("bin_id = ?", binID)
+		}
+
+		var tasks []model.CycleCountTask
+		if err := query.Order("id").Find(&tasks).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to retrieve cycle count tasks"})
+			return
+		}
+
+		c.JSON(http.StatusOK, model.CycleCountTasksResponse{
+			Message: "Cycle count tasks retrieved successfully",
+			Tasks:   tasks,
+		})
+	}
+}
+
+// SubmitCycleCount godoc
+// @Summary Submit a counted quantity
+// @Description Record the counted quantity of a task. Variances within the plan's tolerance adjust the stock
+// @Description immediately, larger ones wait for a manager's approval
+// @Tags cycle-counts
+// @Accept json
+// @Produce json
+// @Param id path int true "Task ID"
+// @Param body body model.SubmitCountRequest true "Count"
+// @Success 200 {object} model.CycleCountTask
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Router /cycle-counts/tasks/{id}/count [post]
+func SubmitCycleCount(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		var request model.SubmitCountRequest
+		if err := c.ShouldBindJSON(&request); err != nil || request.CountedQuantity == nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid request data"})
+			return
+		}
+
+		var task model.CycleCountTask
+		if err := db.Where("id = ? AND account_id = ?", c.Param("id"), accountID).First(&task).Error; err != nil {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Cycle count task not found"})
+			return
+		}
+		if task.Status != model.CycleCountTaskPending {
+			c.JSON(http.StatusConflict, model.ErrorResponse{Error: "Cycle count task has already been counted"})
+			return
+		}
+
+		var plan model.CycleCountPlan
+		if err := db.First(&plan, task.PlanID).Error; err != nil {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Cycle count plan not found"})
+			return
+		}
+
+		task.Note = request.Note
+		err := db.Transaction(func(tx *gorm.DB) error {
+			return utils.SubmitCycleCount(tx, &task, plan, *request.CountedQuantity, request.SerialNumbers, utils.CurrentUserID(c))
+		})
+		if !writeCycleCountError(c, err) {
+			return
+		}
+
+		c.JSON(http.StatusOK, task)
+	}
+}
+
+// ApproveCycleCount godoc
+// @Summary Approve a count variance
+// @Description Approve a count that was beyond tolerance and adjust the stock. Requires manager permission
+// @Tags cycle-counts
+// @Accept json
+// @Produce json
+// @Param id path int true "Task ID"
+// @Param body body model.ReviewCountRequest false "Review note"
+// @Success 200 {object} model.CycleCountTask
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Router /cycle-counts/tasks/{id}/approve [post]
+func ApproveCycleCount(db *gorm.DB) gin.HandlerFunc {
+	return reviewCycleCount(db, true)
+}
+
+// RejectCycleCount godoc
+// @Summary Reject a count variance
+// @Description Reject a count that was beyond tolerance; the stock is left unchanged. Requires manager permission
+// @Tags cycle-counts
+// @Accept json
+// @Produce json
+// @Param id path int true "Task ID"
+// @Param body body model.ReviewCountRequest false "Review note"
+// @Success 200 {object} model.CycleCountTask
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Router /cycle-counts/tasks/{id}/reject [post]
+func RejectCycleCount(db *gorm.DB) gin.HandlerFunc {
+	return reviewCycleCount(db, false)
+}
+
+func reviewCycleCount(db *gorm.DB, approve bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		managerID, ok := requireManager(c)
+		if !ok {
+			return
+		}
+
+		var request model.ReviewCountRequest
+		if c.Request.ContentLength > 0 {
+			if err := c.ShouldBindJSON(&request); err != nil {
+				c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid request data"})
+				return
+			}
+		}
+
+		var task model.CycleCountTask
+		if err := db.Where("id = ? AND account_id = ?", c.Param("id"), accountID).First(&task).Error; err != nil {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Cycle count task not found"})
+			return
+		}
+		if task.Status != model.CycleCountTaskPendingApproval {
+			c.JSON(http.StatusConflict, model.ErrorResponse{Error: "Cycle count task is not waiting for approval"})
+			return
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			return utils.ReviewCycleCount(tx, &task, approve, request.Note, managerID)
+		})
+		if !writeCycleCountError(c, err) {
+			return
+		}
+
+		c.JSON(http.StatusOK, task)
+	}
+}
+
+// writeCycleCountError maps cycle-count errors to responses and reports whether err was nil
+func writeCycleCountError(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, utils.ErrSerialCountMismatch):
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: err.Error()})
+	case errors.Is(err, utils.ErrCountBelowReserved), errors.Is(err, utils.ErrStaleCount), errors.Is(err, utils.ErrDuplicateSerial):
+		c.JSON(http.StatusConflict, model.ErrorResponse{Error: err.Error()})
+	default:
+		log.Printf("Failed to process cycle count: %v", err)
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to process cycle count"})
+	}
+	return false
+}
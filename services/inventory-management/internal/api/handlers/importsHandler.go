@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"inventory-management/internal/model"
+	"inventory-management/internal/utils"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// errImportRolledBack ends the import transaction without committing it
+var errImportRolledBack = errors.New("import rolled back")
+
+// ImportRecords godoc
+// @Summary Import records from CSV or JSON
+// @Description Stream a CSV file with a header row or a JSON array of objects into the account. Categories and
+// @Description suppliers are upserted by name, products by SKU (or name without a SKU) and stock rows by product,
+// @Description location and lot. Rows that fail validation are reported and skipped, unless all_or_nothing is set,
+// @Description in which case any failure rolls the whole import back. dry_run validates without saving. Stock
+// @Description rows whose quantity would change by more than the account's adjustment approval thresholds fail;
+// @Description such changes go through a stock adjustment.
+// @Tags imports
+// @Accept json
+// @Accept text/csv
+// @Produce json
+// @Param entity path string true "Entity (products, categories, suppliers, stocks)"
+// @Param format query string false "File format (csv, json); defaults to the Content-Type"
+// @Param dry_run query bool false "Validate only"
+// @Param all_or_nothing query bool false "Roll back the whole import if any row fails"
+// @Success 200 {object} model.ImportResult
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 422 {object} model.ImportResult
+// @Router /imports/{entity} [post]
+func ImportRecords(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		entity := c.Param("entity")
+		if _, ok := utils.ImportColumns[entity]; !ok {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Unknown import entity"})
+			return
+		}
+
+		dryRun, _ := strconv.ParseBool(c.Query("dry_run"))
+		allOrNothing, _ := strconv.ParseBool(c.Query("all_or_nothing"))
+
+		reader, err := utils.NewRowReader(c.Request.Body, requestFormat(c))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: err.Error()})
+			return
+		}
+
+		result := model.ImportResult{
+			Entity:       entity,
+			DryRun:       dryRun,
+			AllOrNothing: allOrNothing,
+			Errors:       []model.ImportRowError{},
+		}
+		userID := utils.CurrentUserID(c)
+
+		// Every row runs in a savepoint so a rejected row leaves the rest of the import intact
+		err = db.Transaction(func(tx *gorm.DB) error {
+			for line := 1; ; line++ {
+				row, err := reader.Next()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					return fmt.Errorf("row %d: %w", line, err)
+				}
+
+				var created bool
+				var key string
+				err = tx.Transaction(func(rowTx *gorm.DB) error {
+					var err error
+					created, key, err = utils.ImportRecord(rowTx, entity, accountID.(uint), userID, row)
+					return err
+				})
+				switch {
+				case err != nil:
+					result.Failed++
+					result.Errors = append(result.Errors, model.ImportRowError{Row: line, Key: key, Error: err.Error()})
+				case created:
+					result.Created++
+				default:
+					result.Updated++
+				}
+			}
+
+			if dryRun || (allOrNothing && result.Failed > 0) {
+				return errImportRolledBack
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, errImportRolledBack) {
+			log.Printf("Failed to import %s: %v", entity, err)
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Malformed import file: " + err.Error()})
+			return
+		}
+
+		result.Committed = err == nil
+		switch {
+		case dryRun:
+			result.Message = "Import validated, nothing was saved"
+		case !result.Committed:
+			result.Message = "Import rolled back because rows failed"
+			c.JSON(http.StatusUnprocessableEntity, result)
+			return
+		case result.Failed > 0:
+			result.Message = "Import completed with rejected rows"
+		default:
+			result.Message = "Import completed successfully"
+		}
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// ExportRecords godoc
+// @Summary Export records as CSV or JSON
+// @Description Stream all records of the account in the columns accepted by the import endpoint
+// @Tags imports
+// @Produce json
+// @Produce text/csv
+// @Param entity path string true "Entity (products, categories, suppliers, stocks)"
+// @Param format query string false "File format (csv, json), default json"
+// @Success 200 {string} string "Exported records"
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Router /exports/{entity} [get]
+func ExportRecords(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		entity := c.Param("entity")
+		columns, ok := utils.ImportColumns[entity]
+		if !ok {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Unknown export entity"})
+			return
+		}
+
+		format := c.DefaultQuery("format", "json")
+		if format != "csv" && format != "json" {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "format must be csv or json"})
+			return
+		}
+
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.%s", entity, format))
+		err := writeExport(c, format, columns, func(emit func([]interface{}) error) error {
+			return utils.ExportRecords(db, entity, accountID.(uint), emit)
+		})
+
+		// The status line has already been sent, so a failure can only be logged
+		if err != nil {
+			log.Printf("Failed to export %s: %v", entity, err)
+		}
+	}
+}
+
+// requestFormat picks the import format from the format query parameter or the Content-Type
+func requestFormat(c *gin.Context) string {
+	if format := c.Query("format"); format != "" {
+		return format
+	}
+	if strings.Contains(c.ContentType(), "csv") {
+		return "csv"
+	}
+	return "json"
+}
+
+// exportCell formats an exported value for CSV, leaving missing values empty
+func exportCell(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case *uint:
+		if v == nil {
+			return ""
+		}
+		return strconv.FormatUint(uint64(*v), 10)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// writeExport streams the records export emits as a CSV file with a header row, or as a
+// JSON array of objects keyed by column
+func writeExport(c *gin.Context, format string, columns []string, export func(emit func([]interface{}) error) error) error {
+	if format == "csv" {
+		c.Header("Content-Type", "text/csv")
+		writer := csv.NewWriter(c.Writer)
+		writer.Write(columns)
+		err := export(func(values []interface{}) error {
+			record := make([]string, len(values))
+			for i, value := range values {
+				record[i] = exportCell(value)
+			}
+			return writer.Write(record)
+		})
+		writer.Flush()
+		return err
+	}
+
+	c.Header("Content-Type", "application/json")
+	encoder := json.NewEncoder(c.Writer)
+	separator := "["
+	err := export(func(values []interface{}) error {
+		record := make(map[string]interface{}, len(values))
+		for i, value := range values {
+			record[columns[i]] = value
+		}
+		c.Writer.WriteString(separator)
+		separator = ","
+		return encoder.Encode(record)
+	})
+	if separator == "[" {
+		c.Writer.WriteString("[")
+	}
+	c.Writer.WriteString("]")
+	return err
+}
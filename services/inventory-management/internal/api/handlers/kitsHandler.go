@@ -0,0 +1,316 @@
+package handlers
+
+import (
+	"errors"
+	"inventory-management/internal/model"
+	"inventory-management/internal/utils"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetKitComponents godoc
+// @Summary Get the bill of materials of a kit
+// @Description Retrieve the components of a kit with the number of kits assembled and available to sell, including
+// @Description those that can be built from the components on hand
+// @Tags kits
+// @Produce json
+// @Param id path int true "Product ID"
+// @Success 200 {object} model.KitResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /products/{id}/components [get]
+func GetKitComponents(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		var product model.Product
+		if err := db.Where("id = ? AND account_id = ?", c.Param("id"), accountID).First(&product).Error; err != nil {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Product not found"})
+			return
+		}
+
+		response, err := kitResponse(db, product.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to retrieve kit components"})
+			return
+		}
+		response.Message = "Kit components retrieved successfully"
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// SetKitComponents godoc
+// @Summary Set the bill of materials of a kit
+// @Description Replace the components of a kit and the quantity of each that goes into one kit. A product with
+// @Description components is sold as a kit; an empty list makes it a regular product again
+// @Tags kits
+// @Accept json
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param body body model.KitComponentsRequest true "Components"
+// @Success 200 {object} model.KitResponse
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Router /products/{id}/components [put]
+func SetKitComponents(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		var product model.Product
+		if err := db.Where("id = ? AND account_id = ?", c.Param("id"), accountID).First(&product).Error; err != nil {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Product not found"})
+			return
+		}
+
+		var request model.KitComponentsRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid request data"})
+			return
+		}
+
+		err := utils.ValidateKitComponents(db, product, request.Components)
+		switch {
+		case errors.Is(err, utils.ErrNestedKit), errors.Is(err, utils.ErrSerializedKit):
+			c.JSON(http.StatusConflict, model.ErrorResponse{Error: err.Error()})
+			return
+		case err != nil:
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: err.Error()})
+			return
+		}
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("kit_id = ?", product.ID).Delete(&model.KitComponent{}).Error; err != nil {
+				return err
+			}
+			for _, component := range request.Components {
+				component.ID = 0
+				component.KitID = product.ID
+				component.Component = nil
+				component.AccountID = accountID.(uint)
+				if err := tx.Create(&component).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to update kit components"})
+			return
+		}
+
+		response, err := kitResponse(db, product.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to retrieve kit components"})
+			return
+		}
+		response.Message = "Kit components updated successfully"
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// CreateKitAssembly godoc
+// @Summary Create a kit assembly work order
+// @Description Plan the assembly of a quantity of kits into a bin or location. Components are taken from stock
+// @Description when the work order is completed
+// @Tags kits
+// @Accept json
+// @Produce json
+// @Param body body model.KitAssembly true "Work order"
+// @Success 201 {object} model.KitAssembly
+// @Failure 400 {object} model.ErrorResponse
+// @Router /kit-assemblies [post]
+func CreateKitAssembly(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		var assembly model.KitAssembly
+		if err := c.ShouldBindJSON(&assembly); err != nil || assembly.Quantity == 0 || (assembly.BinID == nil && assembly.Location == "") {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid request data"})
+			return
+		}
+
+		if err := db.Where("id = ? AND account_id = ?", assembly.KitID, accountID).First(&model.Product{}).Error; err != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Kit not found"})
+			return
+		}
+		components, err := utils.KitComponents(db, assembly.KitID)
+		if err != nil || len(components) == 0 {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: utils.ErrNotAKit.Error()})
+			return
+		}
+		if assembly.BinID != nil {
+			if err := db.Where("id = ? AND account_id = ?", *assembly.BinID, accountID).First(&model.Bin{}).Error; err != nil {
+				c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Bin not found"})
+				return
+			}
+		}
+
+		assembly.ID = 0
+		assembly.Status = model.KitAssemblyOpen
+		assembly.UnitCost = 0
+		assembly.StockID = nil
+		assembly.CompletedBy = nil
+		assembly.CompletedAt = nil
+		assembly.CreatedBy = utils.CurrentUserID(c)
+		assembly.AccountID = accountID.(uint)
+		if err := db.Create(&assembly).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to create kit assembly"})
+			return
+		}
+
+		c.JSON(http.StatusCreated, assembly)
+	}
+}
+
+// GetKitAssemblies godoc
+// @Summary Get kit assembly work orders
+// @Description Retrieve the account's kit assembly work orders, newest first
+// @Tags kits
+// @Produce json
+// @Param status query string false "open, completed or cancelled"
+// @Param kit_id query int false "Kit product ID"
+// @Success 200 {object} model.KitAssembliesResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /kit-assemblies [get]
+func GetKitAssemblies(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		query := db.Where("account_id = ?", accountID)
+		if status := c.Query("status"); status != "" {
+			query = query.Where("status = ?", status)
+		}
+		if kitID := c.Query("kit_id"); kitID != "" {
+			query = query.Where("kit_id = ?", kitID)
+		}
+
+		var assemblies []model.KitAssembly
+		if err := query.Order("created_at DESC, id DESC").Find(&assemblies).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to retrieve kit assemblies"})
+			return
+		}
+
+		c.JSON(http.StatusOK, model.KitAssembliesResponse{Message: "Kit assemblies retrieved successfully", Assemblies: assemblies})
+	}
+}
+
+// CompleteKitAssembly godoc
+// @Summary Complete a kit assembly work order
+// @Description Take the components of the work order out of stock and add the assembled kits at its bin or location,
+// @Description valued at the cost of the components. Fails without changing stock when a component is short
+// @Tags kits
+// @Produce json
+// @Param id path int true "Work order ID"
+// @Success 200 {object} model.KitAssembly
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Router /kit-assemblies/{id}/complete [post]
+func CompleteKitAssembly(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		var assembly model.KitAssembly
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("id = ? AND account_id = ?", c.Param("id"), accountID).First(&assembly).Error; err != nil {
+				return err
+			}
+			return utils.CompleteKitAssembly(tx, &assembly, utils.CurrentUserID(c))
+		})
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Kit assembly not found"})
+			return
+		case errors.Is(err, utils.ErrInsufficientStock):
+			c.JSON(http.StatusConflict, model.ErrorResponse{Error: "Not enough components in stock"})
+			return
+		case errors.Is(err, utils.ErrKitAssemblyNotOpen), errors.Is(err, utils.ErrNotAKit):
+			c.JSON(http.StatusConflict, model.ErrorResponse{Error: err.Error()})
+			return
+		case err != nil:
+			log.Printf("Failed to complete kit assembly %s: %v", c.Param("id"), err)
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to complete kit assembly"})
+			return
+		}
+
+		c.JSON(http.StatusOK, assembly)
+	}
+}
+
+// CancelKitAssembly godoc
+// @Summary Cancel a kit assembly work order
+// @Description Cancel an open work order; no stock is changed
+// @Tags kits
+// @Produce json
+// @Param id path int true "Work order ID"
+// @Success 200 {object} model.KitAssembly
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Router /kit-assemblies/{id}/cancel [post]
+func CancelKitAssembly(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		var assembly model.KitAssembly
+		if err := db.Where("id = ? AND account_id = ?", c.Param("id"), accountID).First(&assembly).Error; err != nil {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Kit assembly not found"})
+			return
+		}
+		if assembly.Status != model.KitAssemblyOpen {
+			c.JSON(http.StatusConflict, model.ErrorResponse{Error: utils.ErrKitAssemblyNotOpen.Error()})
+			return
+		}
+
+		assembly.Status = model.KitAssemblyCancelled
+		if err := db.Model(&assembly).Update("status", assembly.Status).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to cancel kit assembly"})
+			return
+		}
+
+		c.JSON(http.StatusOK, assembly)
+	}
+}
+
+func kitResponse(db *gorm.DB, kitID uint) (model.KitResponse, error) {
+	response := model.KitResponse{KitID: kitID, Components: []model.KitComponent{}}
+	if err := db.Preload("Component").Where("kit_id = ?", kitID).Order("id").Find(&response.Components).Error; err != nil {
+		return response, err
+	}
+
+	assembled, fromComponents, err := utils.KitAvailability(db, kitID, response.Components)
+	if err != nil {
+		return response, err
+	}
+	response.Assembled = assembled
+	response.Available = assembled + fromComponents
+	return response, nil
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"inventory-management/internal/model"
+	"inventory-management/internal/utils"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requireManager checks with the user service that the caller holds PermissionManager.
+// It writes the error response and returns false when they do not.
+func requireManager(c *gin.Context) (*uint, bool) {
+	userID := utils.CurrentUserID(c)
+	token, err := utils.ExtractToken(c)
+	if userID == nil || err != nil {
+		c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "User not found"})
+		return nil, false
+	}
+
+	user, err := utils.FetchUser(token, *userID)
+	if err != nil {
+		log.Printf("Failed to fetch user %d: %v", *userID, err)
+		c.JSON(http.StatusForbidden, model.ErrorResponse{Error: "Could not verify user permissions"})
+		return nil, false
+	}
+
+	if user.Permission != model.PermissionManager {
+		c.JSON(http.StatusForbidden, model.ErrorResponse{Error: "Manager permission required"})
+		return nil, false
+	}
+
+	return userID, true
+}
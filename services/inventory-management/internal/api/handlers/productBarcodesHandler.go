@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"errors"
+	"inventory-management/internal/model"
+	"inventory-management/internal/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CreateProductBarcode godoc
+// @Summary Add a barcode to a product
+// @Description Register an EAN-13, UPC-A, GTIN-14 or Code128 barcode the product can be scanned by,
+// @Description optionally for one of its units of measure
+// @Tags products
+// @Accept json
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param body body model.ProductBarcode true "Barcode"
+// @Success 200 {object} model.ProductBarcode
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Router /products/{id}/barcodes [post]
+func CreateProductBarcode(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		var product model.Product
+		if err := db.Preload("Units").Where("id = ? AND account_id = ?", c.Param("id"), accountID).First(&product).Error; err != nil {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Product not found"})
+			return
+		}
+
+		var barcode model.ProductBarcode
+		if err := c.ShouldBindJSON(&barcode); err != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid request data"})
+			return
+		}
+
+		key, err := utils.BarcodeLookupKey(barcode.Symbology, barcode.Code)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: err.Error()})
+			return
+		}
+		if _, ok := product.UnitFactor(barcode.Unit); !ok {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: utils.ErrUnknownUnit.Error()})
+			return
+		}
+
+		var duplicates int64
+		db.Model(&model.ProductBarcode{}).Where("lookup_key = ? AND account_id = ?", key, accountID).Count(&duplicates)
+		if duplicates > 0 {
+			c.JSON(http.StatusConflict, model.ErrorResponse{Error: "Barcode is already assigned to a product"})
+			return
+		}
+
+		barcode.ID = 0
+		barcode.ProductID = product.ID
+		barcode.LookupKey = key
+		barcode.AccountID = accountID.(uint)
+		if err := db.Create(&barcode).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to create barcode"})
+			return
+		}
+
+		c.JSON(http.StatusOK, barcode)
+	}
+}
+
+// GetProductBarcodes godoc
+// @Summary Get the barcodes of a product
+// @Description Retrieve all barcodes registered for the product
+// @Tags products
+// @Produce json
+// @Param id path int true "Product ID"
+// @Success 200 {object} model.ProductBarcodesResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Router /products/{id}/barcodes [get]
+func GetProductBarcodes(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		var product model.Product
+		if err := db.Preload("Barcodes").Where("id = ? AND account_id = ?", c.Param("id"), accountID).First(&product).Error; err != nil {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Product not found"})
+			return
+		}
+
+		barcodes := product.Barcodes
+		if barcodes == nil {
+			barcodes = []model.ProductBarcode{}
+		}
+		c.JSON(http.StatusOK, model.ProductBarcodesResponse{
+			Message:  "Barcodes retrieved successfully",
+			Barcodes: barcodes,
+		})
+	}
+}
+
+// DeleteProductBarcode godoc
+// @Summary Delete a barcode
+// @Description Remove a barcode from a product
+// @Tags products
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param barcode_id path int true "Barcode ID"
+// @Success 200 {object} model.SuccessResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Router /products/{id}/barcodes/{barcode_id} [delete]
+func DeleteProductBarcode(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		var barcode model.ProductBarcode
+		if err := db.Where("id = ? AND product_id = ? AND account_id = ?", c.Param("barcode_id"), c.Param("id"), accountID).First(&barcode).Error; err != nil {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Barcode not found"})
+			return
+		}
+
+		if err := db.Delete(&barcode).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to delete barcode"})
+			return
+		}
+
+		c.JSON(http.StatusOK, model.SuccessResponse{Message: "Barcode deleted successfully"})
+	}
+}
+
+// LookupProduct godoc
+// @Summary Resolve a scan to a product
+// @Description Find the product a scanned barcode or SKU belongs to, together with the unit of measure
+// @Description the barcode identifies and its conversion factor
+// @Tags products
+// @Produce json
+// @Param barcode query string true "Scanned barcode or SKU"
+// @Success 200 {object} model.ProductLookupResponse
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Router /products/lookup [get]
+func LookupProduct(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		scan := c.Query("barcode")
+		if scan == "" {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "barcode is required"})
+			return
+		}
+
+		query := db.Preload("Category").Preload("Supplier").Preload("Units").Preload("Barcodes").Where("account_id = ?", accountID)
+
+		var product model.Product
+		var barcode model.ProductBarcode
+		err := db.Where("lookup_key IN ? AND account_id = ?", utils.ScanLookupKeys(scan), accountID).First(&barcode).Error
+		switch {
+		case err == nil:
+			err = query.First(&product, barcode.ProductID).Error
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			err = query.Where("sku = ?", scan).First(&product).Error
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "No product matches the scanned code"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to look up product"})
+			return
+		}
+
+		response := model.ProductLookupResponse{
+			Message: "Product found",
+			Product: product,
+			Unit:    product.BaseUnit,
+			Factor:  1,
+		}
+		if barcode.ID != 0 {
+			response.Barcode = &barcode
+			if barcode.Unit != "" {
+				response.Unit = barcode.Unit
+				response.Factor, _ = product.UnitFactor(barcode.Unit)
+			}
+		}
+		c.JSON(http.StatusOK, response)
+	}
+}
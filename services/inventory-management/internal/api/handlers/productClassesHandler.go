@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"inventory-management/internal/model"
+	"inventory-management/internal/utils"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ClassifyProducts godoc
+// @Summary Recompute ABC/XYZ classes
+// @Description Rank every product by the value of the orders shipped in the account's classification window (A: first
+// @Description 80% of the value, B: next 15%, C: the rest) and by how much its weekly demand varied (X: up to 50% of the
+// @Description average, Y: up to 100%, Z: more or no demand), and store the classes on the products. Runs nightly;
+// @Description requires manager permission
+// @Tags products
+// @Produce json
+// @Success 200 {object} model.ClassificationSummary
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /products/classify [post]
+func ClassifyProducts(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		if _, ok := requireManager(c); !ok {
+			return
+		}
+
+		summary, err := utils.ClassifyProducts(db, accountID.(uint), time.Now())
+		if err != nil {
+			log.Printf("Failed to classify products: %v", err)
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to classify products"})
+			return
+		}
+
+		summary.Message = "Products classified successfully"
+		c.JSON(http.StatusOK, summary)
+	}
+}
+
+// GetProductClasses godoc
+// @Summary Get the ABC/XYZ class breakdown
+// @Description Count the account's products in each ABC/XYZ class pair as last classified, and the order window
+// @Description the classes were computed over
+// @Tags products
+// @Produce json
+// @Success 200 {object} model.ClassificationSummary
+// @Failure 500 {object} model.ErrorResponse
+// @Router /products/classes [get]
+func GetProductClasses(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		counts, classifiedAt, err := utils.ClassificationCounts(db, accountID.(uint))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to retrieve product classes"})
+			return
+		}
+
+		summary := model.ClassificationSummary{
+			Message:      "Product classes retrieved successfully",
+			ClassifiedAt: classifiedAt,
+			Classes:      counts,
+		}
+		if classifiedAt != nil {
+			settings, err := utils.LoadAccountSettings(db, accountID.(uint))
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to retrieve product classes"})
+				return
+			}
+			summary.From = classifiedAt.AddDate(0, 0, -settings.ClassificationWindowDays)
+			summary.To = *classifiedAt
+		}
+		c.JSON(http.StatusOK, summary)
+	}
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"inventory-management/internal/model"
+	"inventory-management/internal/utils"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetProductThresholds godoc
+// @Summary Get the stock thresholds of a product
+// @Description Retrieve the product's reorder point and quantity with the lead time quoted by the supplier it is
+// @Description replenished from, e.g. for demand forecasting to recommend new thresholds
+// @Tags products
+// @Produce json
+// @Param id path int true "Product ID"
+// @Success 200 {object} model.ProductThresholds
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /products/{id}/thresholds [get]
+func GetProductThresholds(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		var product model.Product
+		if err := db.Where("id = ? AND account_id = ?", c.Param("id"), accountID).First(&product).Error; err != nil {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Product not found"})
+			return
+		}
+
+		thresholds, err := productThresholds(db, product)
+		if err != nil {
+			log.Printf("Failed to retrieve thresholds of product %d: %v", product.ID, err)
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to retrieve thresholds"})
+			return
+		}
+
+		c.JSON(http.StatusOK, thresholds)
+	}
+}
+
+// UpdateProductThresholds godoc
+// @Summary Update the stock thresholds of a product
+// @Description Set the product's reorder point and quantity, and the low-stock threshold of its stock rows: either
+// @Description the same low_stock_threshold on every row, or a product-wide safety_stock split across the rows by
+// @Description their share of the product's shipments over the last 90 days (evenly when nothing shipped).
+// @Description Omitted fields are left unchanged. Requires manager permission
+// @Tags products
+// @Accept json
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param body body model.ProductThresholds true "Thresholds"
+// @Success 200 {object} model.ProductThresholds
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Router /products/{id}/thresholds [put]
+func UpdateProductThresholds(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		if _, ok := requireManager(c); !ok {
+			return
+		}
+
+		var product model.Product
+		if err := db.Where("id = ? AND account_id = ?", c.Param("id"), accountID).First(&product).Error; err != nil {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Product not found"})
+			return
+		}
+
+		var input model.ProductThresholds
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid request data"})
+			return
+		}
+		if input.LowStockThreshold != nil && *input.LowStockThreshold < 0 {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "low_stock_threshold must not be negative"})
+			return
+		}
+		if input.LowStockThreshold != nil && input.SafetyStock != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Set either low_stock_threshold or safety_stock"})
+			return
+		}
+
+		updates := map[string]interface{}{}
+		if input.ReorderPoint != nil {
+			updates["reorder_point"] = *input.ReorderPoint
+		}
+		if input.ReorderQuantity != nil {
+			updates["reorder_quantity"] = *input.ReorderQuantity
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if len(updates) > 0 {
+				if err := tx.Model(&product).Updates(updates).Error; err != nil {
+					return err
+				}
+			}
+			if input.LowStockThreshold != nil {
+				return tx.Model(&model.Stock{}).Where("product_id = ? AND account_id = ?", product.ID, accountID).
+					Update("low_stock_threshold", *input.LowStockThreshold).Error
+			}
+			if input.SafetyStock != nil {
+				return utils.SplitSafetyStock(tx, product, *input.SafetyStock, time.Now())
+			}
+			return nil
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to update thresholds"})
+			return
+		}
+
+		thresholds, err := productThresholds(db, product)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to retrieve thresholds"})
+			return
+		}
+		thresholds.LowStockThreshold = input.LowStockThreshold
+		thresholds.SafetyStock = input.SafetyStock
+		c.JSON(http.StatusOK, thresholds)
+	}
+}
+
+func productThresholds(db *gorm.DB, product model.Product) (model.ProductThresholds, error) {
+	thresholds := model.ProductThresholds{
+		ProductID:       product.ID,
+		SupplierID:      product.ReplenishmentSupplierID(),
+		ReorderPoint:    &product.ReorderPoint,
+		ReorderQuantity: &product.ReorderQuantity,
+	}
+
+	leadTime, err := utils.ProductLeadTime(db, product, time.Now())
+	if err != nil {
+		return thresholds, err
+	}
+	thresholds.LeadTimeDays = leadTime
+
+	err = db.Model(&model.Stock{}).Where("product_id = ?", product.ID).
+		Select("COALESCE(SUM(quantity), 0)").Scan(&thresholds.OnHand).Error
+	return thresholds, err
+}
@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"inventory-management/internal/model"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CreateProductUnit godoc
+// @Summary Add a unit of measure to a product
+// @Description Define a pack size of the product with the number of base units it holds
+// @Tags products
+// @Accept json
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param body body model.ProductUnit true "Unit of measure"
+// @Success 200 {object} model.ProductUnit
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Router /products/{id}/units [post]
+func CreateProductUnit(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		var product model.Product
+		if err := db.Where("id = ? AND account_id = ?", c.Param("id"), accountID).First(&product).Error; err != nil {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Product not found"})
+			return
+		}
+
+		var unit model.ProductUnit
+		if err := c.ShouldBindJSON(&unit); err != nil || unit.Name == "" || unit.Factor == 0 {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid request data"})
+			return
+		}
+		if unit.Name == product.BaseUnit {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Unit name is the product's base unit"})
+			return
+		}
+
+		var duplicates int64
+		db.Model(&model.ProductUnit{}).Where("product_id = ? AND name = ?", product.ID, unit.Name).Count(&duplicates)
+		if duplicates > 0 {
+			c.JSON(http.StatusConflict, model.ErrorResponse{Error: "Unit already exists for product"})
+			return
+		}
+
+		unit.ID = 0
+		unit.ProductID = product.ID
+		unit.AccountID = accountID.(uint)
+		if err := db.Create(&unit).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to create unit"})
+			return
+		}
+
+		c.JSON(http.StatusOK, unit)
+	}
+}
+
+// GetProductUnits godoc
+// @Summary Get the units of measure of a product
+// @Description Retrieve the product's base unit and its pack sizes
+// @Tags products
+// @Produce json
+// @Param id path int true "Product ID"
+// @Success 200 {object} model.ProductUnitsResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Router /products/{id}/units [get]
+func GetProductUnits(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		var product model.Product
+		if err := db.Preload("Units").Where("id = ? AND account_id = ?", c.Param("id"), accountID).First(&product).Error; err != nil {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Product not found"})
+			return
+		}
+
+		units := product.Units
+		if units == nil {
+			units = []model.ProductUnit{}
+		}
+		c.JSON(http.StatusOK, model.ProductUnitsResponse{
+			Message:  "Units retrieved successfully",
+			BaseUnit: product.BaseUnit,
+			Units:    units,
+		})
+	}
+}
+
+// UpdateProductUnit godoc
+// @Summary Update a unit of measure
+// @Description Update the name or conversion factor of a product's unit. A unit that is in use cannot be renamed
+// @Tags products
+// @Accept json
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param unit_id path int true "Unit ID"
+// @Param body body model.ProductUnit true "Unit of measure"
+// @Success 200 {object} model.ProductUnit
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Router /products/{id}/units/{unit_id} [put]
+func UpdateProductUnit(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		var unit model.ProductUnit
+		if err := db.Where("id = ? AND product_id = ? AND account_id = ?", c.Param("unit_id"), c.Param("id"), accountID).First(&unit).Error; err != nil {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Unit not found"})
+			return
+		}
+
+		unitID, productID, previousName := unit.ID, unit.ProductID, unit.Name
+		if err := c.ShouldBindJSON(&unit); err != nil || unit.Name == "" || unit.Factor == 0 {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid request data"})
+			return
+		}
+		unit.ID, unit.ProductID = unitID, productID
+
+		if unit.Name != previousName {
+			var product model.Product
+			if err := db.Select("base_unit").First(&product, unit.ProductID).Error; err != nil {
+				c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Product not found"})
+				return
+			}
+			if unit.Name == product.BaseUnit {
+				c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Unit name is the product's base unit"})
+				return
+			}
+
+			var duplicates int64
+			db.Model(&model.ProductUnit{}).Where("product_id = ? AND name = ? AND id <> ?", unit.ProductID, unit.Name, unit.ID).Count(&duplicates)
+			if duplicates > 0 {
+				c.JSON(http.StatusConflict, model.ErrorResponse{Error: "Unit already exists for product"})
+				return
+			}
+
+			// Stock rows, purchase order lines and barcodes refer to units by name, so a unit
+			// in use keeps its name
+			if unitInUse(db, unit.ProductID, previousName) {
+				c.JSON(http.StatusConflict, model.ErrorResponse{Error: "Unit is in use"})
+				return
+			}
+		}
+
+		unit.AccountID = accountID.(uint)
+		if err := db.Save(&unit).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to update unit"})
+			return
+		}
+
+		c.JSON(http.StatusOK, unit)
+	}
+}
+
+// DeleteProductUnit godoc
+// @Summary Delete a unit of measure
+// @Description Delete a product's unit. Units that stock rows or purchase order lines are handled in, or that
+// @Description barcodes identify, cannot be deleted
+// @Tags products
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param unit_id path int true "Unit ID"
+// @Success 200 {object} model.SuccessResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Router /products/{id}/units/{unit_id} [delete]
+func DeleteProductUnit(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		var unit model.ProductUnit
+		if err := db.Where("id = ? AND product_id = ? AND account_id = ?", c.Param("unit_id"), c.Param("id"), accountID).First(&unit).Error; err != nil {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Unit not found"})
+			return
+		}
+
+		if unitInUse(db, unit.ProductID, unit.Name) {
+			c.JSON(http.StatusConflict, model.ErrorResponse{Error: "Unit is in use"})
+			return
+		}
+
+		if err := db.Delete(&unit).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to delete unit"})
+			return
+		}
+
+		c.JSON(http.StatusOK, model.SuccessResponse{Message: "Unit deleted successfully"})
+	}
+}
+
+// unitInUse reports whether any stock row or purchase order line of the product is handled
+// in the unit, or any of its barcodes identifies it
+func unitInUse(db *gorm.DB, productID uint, name string) bool {
+	var stocks, lines, barcodes int64
+	db.Model(&model.Stock{}).Where("product_id = ? AND unit_of_measure = ?", productID, name).Count(&stocks)
+	db.Model(&model.PurchaseOrderLine{}).Where("product_id = ? AND unit_of_measure = ?", productID, name).Count(&lines)
+	db.Model(&model.ProductBarcode{}).Where("product_id = ? AND unit = ?", productID, name).Count(&barcodes)
+	return stocks+lines+barcodes > 0
+}
@@ -2,7 +2,9 @@ package handlers
 
 import (
 	"inventory-management/internal/model"
+	"inventory-management/internal/utils"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -17,15 +19,35 @@ import (
 // @Param product body model.Product true "Product to create"
 // @Success 200 {object} model.Product
 // @Failure 400 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
 // @Router /products [post]
 func CreateProduct(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
 		var product model.Product
 		if err := c.ShouldBindJSON(&product); err != nil {
 			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid request data"})
 			return
 		}
 
+		if !validPreferredSupplier(db, accountID, product) {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Preferred supplier not found"})
+			return
+		}
+		if skuTaken(db, accountID, product.SKU, 0) {
+			c.JSON(http.StatusConflict, model.ErrorResponse{Error: "SKU already exists"})
+			return
+		}
+
+		product.AccountID = accountID.(uint)
+		product.Units = nil
+		product.Barcodes = nil
+		product.PreferredSupplier = nil
 		if err := db.Create(&product).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to create product"})
 			return
@@ -37,13 +59,17 @@ func CreateProduct(db *gorm.DB) gin.HandlerFunc {
 
 // GetProducts godoc
 // @Summary Get all products or filter by various criteria
-// @Description Retrieve all products or filter by ID, name, category ID, or supplier ID
+// @Description Retrieve all products or filter by ID, name, SKU, category ID, supplier ID or ABC/XYZ class
 // @Tags products
 // @Produce json
 // @Param id query int false "Product ID"
 // @Param name query string false "Product name"
+// @Param sku query string false "SKU"
 // @Param category_id query int false "Category ID"
+// @Param include_subcategories query bool false "Also match products in the category's subcategories"
 // @Param supplier_id query int false "Supplier ID"
+// @Param abc_class query string false "ABC class (A, B or C)"
+// @Param xyz_class query string false "XYZ class (X, Y or Z)"
 // @Success 200 {array} model.Product
 // @Router /products [get]
 func GetProducts(db *gorm.DB) gin.HandlerFunc {
@@ -56,7 +82,7 @@ func GetProducts(db *gorm.DB) gin.HandlerFunc {
 
 		var products []model.Product
 
-		query := db.Preload("Category").Preload("Supplier").Preload("Stocks").Where("account_id = ?", accountID)
+		query := db.Preload("Category").Preload("Supplier").Preload("Stocks").Preload("Units").Preload("Barcodes").Where("account_id = ?", accountID)
 
 		if id := c.Query("id"); id != "" {
 			if err := query.Where("id = ?", id).First(&products).Error; err != nil {
@@ -71,14 +97,38 @@ func GetProducts(db *gorm.DB) gin.HandlerFunc {
 			query = query.Where("name LIKE ?", "%"+name+"%")
 		}
 
+		if sku := c.Query("sku"); sku != "" {
+			query = query.Where("sku = ?", sku)
+		}
+
 		if categoryID := c.Query("category_id"); categoryID != "" {
-			query = query.Where("category_id = ?", categoryID)
+			if c.Query("include_subcategories") == "true" {
+				rootID, err := strconv.ParseUint(categoryID, 10, 0)
+				if err != nil {
+					c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid category ID"})
+					return
+				}
+				categoryIDs, err := utils.CategoryDescendantIDs(db, accountID.(uint), uint(rootID))
+				if err != nil {
+					c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to retrieve products"})
+					return
+				}
+				query = query.Where("category_id IN ?", categoryIDs)
+			} else {
+				query = query.Where("category_id = ?", categoryID)
+			}
 		}
 
 		if supplierID := c.Query("supplier_id"); supplierID != "" {
 			query = query.Where("supplier_id = ?", supplierID)
 		}
 
+		for _, class := range []string{"abc_class", "xyz_class"} {
+			if value := c.Query(class); value != "" {
+				query = query.Where(class+" = ?", value)
+			}
+		}
+
 		if err := query.Find(&products).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to retrieve products"})
 			return
@@ -88,6 +138,80 @@ func GetProducts(db *gorm.DB) gin.HandlerFunc {
 	}
 }
 
+// SearchProducts godoc
+// @Summary Search products
+// @Description Full-text search across product name, SKU, description and supplier name, best matches first.
+// @Description Every word matches as a prefix and names and SKUs tolerate typos. The response counts the matches
+// @Description by category, supplier and stock status; each count ignores its own filter
+// @Tags products
+// @Produce json
+// @Param q query string false "Search text"
+// @Param category_id query int false "Category ID, including its subcategories"
+// @Param supplier_id query int false "Supplier ID"
+// @Param stock_status query string false "in_stock, low_stock or out_of_stock"
+// @Param limit query int false "Results per page, 20 by default and at most 100"
+// @Param offset query int false "Results to skip"
+// @Success 200 {object} model.ProductSearchResponse
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /products/search [get]
+func SearchProducts(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		search := utils.ProductSearch{Query: c.Query("q"), Limit: 20}
+		for param, target := range map[string]**uint{"category_id": &search.CategoryID, "supplier_id": &search.SupplierID} {
+			if value := c.Query(param); value != "" {
+				id, err := strconv.ParseUint(value, 10, 0)
+				if err != nil {
+					c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid " + param})
+					return
+				}
+				parsed := uint(id)
+				*target = &parsed
+			}
+		}
+
+		switch status := model.StockStatus(c.Query("stock_status")); status {
+		case "", model.StockStatusInStock, model.StockStatusLowStock, model.StockStatusOutOfStock:
+			search.StockStatus = status
+		default:
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "stock_status must be in_stock, low_stock or out_of_stock"})
+			return
+		}
+
+		if limit := c.Query("limit"); limit != "" {
+			value, err := strconv.Atoi(limit)
+			if err != nil || value < 1 {
+				c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid limit"})
+				return
+			}
+			search.Limit = min(value, 100)
+		}
+		if offset := c.Query("offset"); offset != "" {
+			value, err := strconv.Atoi(offset)
+			if err != nil || value < 0 {
+				c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid offset"})
+				return
+			}
+			search.Offset = value
+		}
+
+		response, err := utils.SearchProducts(db, accountID.(uint), search)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to search products"})
+			return
+		}
+
+		response.Message = "Products retrieved successfully"
+		c.JSON(http.StatusOK, response)
+	}
+}
+
 // UpdateProduct godoc
 // @Summary Update an existing product
 // @Description Update details of an existing product
@@ -99,6 +223,7 @@ func GetProducts(db *gorm.DB) gin.HandlerFunc {
 // @Success 200 {object} model.Product
 // @Failure 400 {object} model.ErrorResponse
 // @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
 // @Router /products/{id} [put]
 func UpdateProduct(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -119,7 +244,19 @@ func UpdateProduct(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
+		if !validPreferredSupplier(db, accountID, product) {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Preferred supplier not found"})
+			return
+		}
+		if skuTaken(db, accountID, product.SKU, product.ID) {
+			c.JSON(http.StatusConflict, model.ErrorResponse{Error: "SKU already exists"})
+			return
+		}
+
 		product.AccountID = accountID.(uint)
+		product.Units = nil
+		product.Barcodes = nil
+		product.PreferredSupplier = nil
 		if err := db.Save(&product).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to update product"})
 			return
@@ -173,12 +310,39 @@ func HardDeleteProduct(db *gorm.DB) gin.HandlerFunc {
 
 		tx := db.Begin()
 
+		var stocks []model.Stock
+		if err := tx.Where("product_id = ? AND account_id = ?", c.Param("id"), accountID).Find(&stocks).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to delete associated stocks"})
+			return
+		}
+
+		for _, stock := range stocks {
+			removed := stock.Quantity
+			stock.Quantity = 0
+			if err := utils.RecordStockMovement(tx, stock, -int(removed), model.StockMovement{
+				Reason: model.MovementDeleted,
+				UserID: utils.CurrentUserID(c),
+			}); err != nil {
+				tx.Rollback()
+				c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to delete associated stocks"})
+				return
+			}
+		}
+
 		if err := tx.Where("product_id = ? AND account_id = ?", c.Param("id"), accountID).Unscoped().Delete(&model.Stock{}).Error; err != nil {
 			tx.Rollback()
 			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to delete associated stocks"})
 			return
 		}
 
+		// The product leaves the bills of materials of kits, and its own if it is a kit
+		if err := tx.Where("(kit_id = ? OR component_id = ?) AND account_id = ?", c.Param("id"), c.Param("id"), accountID).Delete(&model.KitComponent{}).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to delete kit components"})
+			return
+		}
+
 		if err := tx.Unscoped().Where("id = ? AND account_id = ?", c.Param("id"), accountID).Delete(&model.Product{}).Error; err != nil {
 			tx.Rollback()
 			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Product not found"})
@@ -216,3 +380,22 @@ func RecoverProduct(db *gorm.DB) gin.HandlerFunc {
 		c.JSON(http.StatusOK, model.SuccessResponse{Message: "Product recovered"})
 	}
 }
+
+// skuTaken reports whether another product of the account already uses the SKU.
+// Soft-deleted products keep their SKU so they can be recovered.
+func skuTaken(db *gorm.DB, accountID interface{}, sku string, productID uint) bool {
+	if sku == "" {
+		return false
+	}
+	var count int64
+	db.Unscoped().Model(&model.Product{}).Where("sku = ? AND account_id = ? AND id <> ?", sku, accountID, productID).Count(&count)
+	return count > 0
+}
+
+// validPreferredSupplier reports whether the product's preferred supplier, if any, belongs to the account
+func validPreferredSupplier(db *gorm.DB, accountID interface{}, product model.Product) bool {
+	if product.PreferredSupplierID == nil {
+		return true
+	}
+	return db.Where("id = ? AND account_id = ?", *product.PreferredSupplierID, accountID).First(&model.Supplier{}).Error == nil
+}
@@ -0,0 +1,518 @@
+package handlers
+
+import (
+	"errors"
+	"inventory-management/internal/model"
+	"inventory-management/internal/utils"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetPurchaseOrders godoc
+// @Summary Get purchase orders
+// @Description Retrieve the account's purchase orders with their lines, e.g. the drafts proposed by replenishment
+// @Tags purchase-orders
+// @Produce json
+// @Param status query string false "Status (draft, sent, partially_received, closed)"
+// @Param supplier_id query int false "Supplier ID"
+// @Success 200 {object} model.PurchaseOrdersResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /purchase-orders [get]
+func GetPurchaseOrders(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		query := db.Preload("Lines").Preload("Supplier").Where("account_id = ?", accountID)
+		if status := c.Query("status"); status != "" {
+			query = query.Where("status = ?", status)
+		}
+		if supplierID := c.Query("supplier_id"); supplierID != "" {
+			query = query.Where("supplier_id = ?", supplierID)
+		}
+
+		var orders []model.PurchaseOrder
+		if err := query.Order("id").Find(&orders).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to retrieve purchase orders"})
+			return
+		}
+
+		c.JSON(http.StatusOK, model.PurchaseOrdersResponse{
+			Message:        "Purchase orders retrieved successfully",
+			PurchaseOrders: orders,
+		})
+	}
+}
+
+// GetPurchaseOrder godoc
+// @Summary Get a purchase order
+// @Description Retrieve a purchase order with its lines
+// @Tags purchase-orders
+// @Produce json
+// @Param id path int true "Purchase order ID"
+// @Success 200 {object} model.PurchaseOrder
+// @Failure 404 {object} model.ErrorResponse
+// @Router /purchase-orders/{id} [get]
+func GetPurchaseOrder(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		var order model.PurchaseOrder
+		if err := db.Preload("Lines").Preload("Supplier").Where("id = ? AND account_id = ?", c.Param("id"), accountID).First(&order).Error; err != nil {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Purchase order not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, order)
+	}
+}
+
+// CreatePurchaseOrder godoc
+// @Summary Create a purchase order
+// @Description Create a draft purchase order for a supplier with an expected date and lines. Requires manager permission
+// @Tags purchase-orders
+// @Accept json
+// @Produce json
+// @Param body body model.PurchaseOrder true "Purchase order"
+// @Success 201 {object} model.PurchaseOrder
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /purchase-orders [post]
+func CreatePurchaseOrder(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		managerID, ok := requireManager(c)
+		if !ok {
+			return
+		}
+
+		var request model.PurchaseOrder
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid request data"})
+			return
+		}
+		if err := db.Where("id = ? AND account_id = ?", request.SupplierID, accountID).First(&model.Supplier{}).Error; err != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Supplier not found"})
+			return
+		}
+
+		order := model.PurchaseOrder{
+			SupplierID: request.SupplierID,
+			Status:     model.PurchaseOrderDraft,
+			ExpectedAt: request.ExpectedAt,
+			Note:       request.Note,
+			CreatedBy:  managerID,
+			AccountID:  accountID.(uint),
+		}
+		if !validPurchaseOrderLines(c, db, order, request.Lines) {
+			return
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Omit("Lines", "Supplier").Create(&order).Error; err != nil {
+				return err
+			}
+			for i := range request.Lines {
+				request.Lines[i].PurchaseOrderID = order.ID
+			}
+			if len(request.Lines) == 0 {
+				return nil
+			}
+			return tx.Create(&request.Lines).Error
+		})
+		if err != nil {
+			log.Printf("Failed to create purchase order: %v", err)
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to create purchase order"})
+			return
+		}
+
+		db.Preload("Lines").Preload("Supplier").First(&order, order.ID)
+		c.JSON(http.StatusCreated, order)
+	}
+}
+
+// UpdatePurchaseOrder godoc
+// @Summary Edit a draft purchase order
+// @Description Change the supplier, expected date, note or lines of a draft purchase order. The lines in the
+// @Description request replace the current lines. Requires manager permission
+// @Tags purchase-orders
+// @Accept json
+// @Produce json
+// @Param id path int true "Purchase order ID"
+// @Param body body model.PurchaseOrder true "Purchase order"
+// @Success 200 {object} model.PurchaseOrder
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Router /purchase-orders/{id} [put]
+func UpdatePurchaseOrder(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		if _, ok := requireManager(c); !ok {
+			return
+		}
+
+		var order model.PurchaseOrder
+		if err := db.Where("id = ? AND account_id = ?", c.Param("id"), accountID).First(&order).Error; err != nil {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Purchase order not found"})
+			return
+		}
+		if order.Status != model.PurchaseOrderDraft {
+			c.JSON(http.StatusConflict, model.ErrorResponse{Error: "Only draft purchase orders can be edited"})
+			return
+		}
+
+		var request model.PurchaseOrder
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid request data"})
+			return
+		}
+
+		if request.SupplierID != 0 {
+			if err := db.Where("id = ? AND account_id = ?", request.SupplierID, accountID).First(&model.Supplier{}).Error; err != nil {
+				c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Supplier not found"})
+				return
+			}
+			order.SupplierID = request.SupplierID
+		}
+		order.ExpectedAt = request.ExpectedAt
+		order.Note = request.Note
+
+		if !validPurchaseOrderLines(c, db, order, request.Lines) {
+			return
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Omit("Lines", "Supplier").Save(&order).Error; err != nil {
+				return err
+			}
+			if request.Lines == nil {
+				return nil
+			}
+			if err := tx.Where("purchase_order_id = ?", order.ID).Delete(&model.PurchaseOrderLine{}).Error; err != nil {
+				return err
+			}
+			if len(request.Lines) == 0 {
+				return nil
+			}
+			return tx.Create(&request.Lines).Error
+		})
+		if err != nil {
+			log.Printf("Failed to update purchase order %d: %v", order.ID, err)
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to update purchase order"})
+			return
+		}
+
+		db.Preload("Lines").Preload("Supplier").First(&order, order.ID)
+		c.JSON(http.StatusOK, order)
+	}
+}
+
+// SendPurchaseOrder godoc
+// @Summary Send a draft purchase order
+// @Description Mark a reviewed draft as sent and email it to the supplier. The supplier's quoted lead time is
+// @Description recorded on the order and sets the expected date when none was agreed. Requires manager permission
+// @Tags purchase-orders
+// @Produce json
+// @Param id path int true "Purchase order ID"
+// @Success 200 {object} model.SendPurchaseOrderResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Router /purchase-orders/{id}/send [post]
+func SendPurchaseOrder(db *gorm.DB, ns *utils.NotificationService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		managerID, ok := requireManager(c)
+		if !ok {
+			return
+		}
+
+		var order model.PurchaseOrder
+		if err := db.Preload("Lines").Preload("Supplier").Where("id = ? AND account_id = ?", c.Param("id"), accountID).First(&order).Error; err != nil {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Purchase order not found"})
+			return
+		}
+		if order.Status != model.PurchaseOrderDraft {
+			c.JSON(http.StatusConflict, model.ErrorResponse{Error: "Only draft purchase orders can be sent"})
+			return
+		}
+		if len(order.Lines) == 0 {
+			c.JSON(http.StatusConflict, model.ErrorResponse{Error: "Purchase order has no lines"})
+			return
+		}
+
+		now := time.Now()
+		leadTime, err := utils.QuotedLeadTime(db, order, now)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to look up supplier lead time"})
+			return
+		}
+		updates := map[string]interface{}{
+			"status":         model.PurchaseOrderSent,
+			"sent_by":        managerID,
+			"sent_at":        now,
+			"lead_time_days": leadTime,
+		}
+		// Without a date agreed with the supplier, the order is expected after the quoted lead time
+		if order.ExpectedAt == nil && leadTime > 0 {
+			expected := now.AddDate(0, 0, int(leadTime))
+			updates["expected_at"] = expected
+		}
+		if err := db.Model(&order).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to send purchase order"})
+			return
+		}
+
+		response := model.SendPurchaseOrderResponse{
+			Message:       "Purchase order sent successfully",
+			PurchaseOrder: order,
+		}
+
+		// The order stands once it is marked as sent; a failed email can be resent by hand
+		if order.Supplier == nil || order.Supplier.Email == "" {
+			response.EmailError = "supplier has no email address"
+		} else if err := ns.SendPurchaseOrder(order.Supplier.Email, order); err != nil {
+			log.Printf("Failed to email purchase order %d: %v", order.ID, err)
+			response.EmailError = err.Error()
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// DeletePurchaseOrder godoc
+// @Summary Discard a draft purchase order
+// @Description Delete a draft purchase order and its lines. Requires manager permission
+// @Tags purchase-orders
+// @Produce json
+// @Param id path int true "Purchase order ID"
+// @Success 200 {object} model.SuccessResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Router /purchase-orders/{id} [delete]
+func DeletePurchaseOrder(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		if _, ok := requireManager(c); !ok {
+			return
+		}
+
+		var order model.PurchaseOrder
+		if err := db.Where("id = ? AND account_id = ?", c.Param("id"), accountID).First(&order).Error; err != nil {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Purchase order not found"})
+			return
+		}
+		if order.Status != model.PurchaseOrderDraft {
+			c.JSON(http.StatusConflict, model.ErrorResponse{Error: "Only draft purchase orders can be deleted"})
+			return
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("purchase_order_id = ?", order.ID).Delete(&model.PurchaseOrderLine{}).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&order).Error
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to delete purchase order"})
+			return
+		}
+
+		c.JSON(http.StatusOK, model.SuccessResponse{Message: "Purchase order deleted successfully"})
+	}
+}
+
+// ClosePurchaseOrder godoc
+// @Summary Close a purchase order
+// @Description Close a sent or partially received purchase order when no more stock is expected from the supplier.
+// @Description Quantities not yet received no longer count as on order. Requires manager permission
+// @Tags purchase-orders
+// @Produce json
+// @Param id path int true "Purchase order ID"
+// @Success 200 {object} model.PurchaseOrder
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Router /purchase-orders/{id}/close [post]
+func ClosePurchaseOrder(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		if _, ok := requireManager(c); !ok {
+			return
+		}
+
+		var order model.PurchaseOrder
+		if err := db.Where("id = ? AND account_id = ?", c.Param("id"), accountID).First(&order).Error; err != nil {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Purchase order not found"})
+			return
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			return utils.ClosePurchaseOrder(tx, &order)
+		})
+		if errors.Is(err, utils.ErrPurchaseOrderNotReceivable) {
+			c.JSON(http.StatusConflict, model.ErrorResponse{Error: "Only sent or partially received purchase orders can be closed"})
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to close purchase order %d: %v", order.ID, err)
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to close purchase order"})
+			return
+		}
+
+		db.Preload("Lines").Preload("Supplier").First(&order, order.ID)
+		c.JSON(http.StatusOK, order)
+	}
+}
+
+// GetPurchaseOrderReceipts godoc
+// @Summary Get receipts of a purchase order
+// @Description Retrieve the stock received against the lines of a purchase order
+// @Tags purchase-orders
+// @Produce json
+// @Param id path int true "Purchase order ID"
+// @Success 200 {object} model.PurchaseOrderReceiptsResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /purchase-orders/{id}/receipts [get]
+func GetPurchaseOrderReceipts(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		var order model.PurchaseOrder
+		if err := db.Where("id = ? AND account_id = ?", c.Param("id"), accountID).First(&order).Error; err != nil {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Purchase order not found"})
+			return
+		}
+
+		var receipts []model.PurchaseOrderReceipt
+		if err := db.Where("purchase_order_id = ?", order.ID).Order("id").Find(&receipts).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to retrieve receipts"})
+			return
+		}
+
+		c.JSON(http.StatusOK, model.PurchaseOrderReceiptsResponse{
+			Message:  "Receipts retrieved successfully",
+			Receipts: receipts,
+		})
+	}
+}
+
+// GenerateReplenishmentOrders godoc
+// @Summary Run replenishment now
+// @Description Check the account's products against their reorder points and add proposals to draft purchase
+// @Description orders grouped by supplier, as the hourly job does. Requires manager permission
+// @Tags purchase-orders
+// @Produce json
+// @Success 200 {object} model.ReplenishmentResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /purchase-orders/replenish [post]
+func GenerateReplenishmentOrders(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		if _, ok := requireManager(c); !ok {
+			return
+		}
+
+		account := accountID.(uint)
+		orders, err := utils.GenerateReplenishment(db, &account)
+		if err != nil {
+			log.Printf("Failed to generate replenishment orders: %v", err)
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to generate replenishment orders"})
+			return
+		}
+
+		c.JSON(http.StatusOK, model.ReplenishmentResponse{
+			Message:        "Replenishment proposals generated",
+			PurchaseOrders: orders,
+		})
+	}
+}
+
+// validPurchaseOrderLines checks that each requested line orders a positive quantity of a
+// product of the account, delivered in one of its units, and prepares it for the order,
+// pricing lines without a unit cost from the supplier's price list. It writes the error
+// response when a line is invalid.
+func validPurchaseOrderLines(c *gin.Context, db *gorm.DB, order model.PurchaseOrder, lines []model.PurchaseOrderLine) bool {
+	for i := range lines {
+		line := &lines[i]
+		if line.Quantity == 0 {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Line quantities must be positive"})
+			return false
+		}
+		if err := db.Where("id = ? AND account_id = ?", line.ProductID, order.AccountID).First(&model.Product{}).Error; err != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Product not found"})
+			return false
+		}
+		if _, err := utils.ToBaseQuantity(db, line.ProductID, line.UnitOfMeasure, 1); err != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Unknown unit of measure"})
+			return false
+		}
+		if line.UnitCost == 0 && order.SupplierID != 0 {
+			price, err := utils.EffectiveSupplierPrice(db, order.SupplierID, line.ProductID, line.Quantity, time.Now())
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to look up supplier price"})
+				return false
+			}
+			if price != nil {
+				line.UnitCost = price.UnitCost
+			}
+		}
+		line.ID = 0
+		line.PurchaseOrderID = order.ID
+		line.ReceivedQuantity = 0
+		line.Closed = false
+		line.AccountID = order.AccountID
+	}
+	return true
+}
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"errors"
+	"inventory-management/internal/model"
+	"inventory-management/internal/utils"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetSerialNumbers godoc
+// @Summary Get serial numbers
+// @Description Retrieve the serial numbers of the account, optionally filtered by product, stock, status or order
+// @Tags serials
+// @Produce json
+// @Param product_id query int false "Product ID"
+// @Param stock_id query int false "Stock ID"
+// @Param status query string false "Serial status (in_stock, shipped, removed)"
+// @Param order_id query int false "Order ID"
+// @Success 200 {object} model.SerialNumbersResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /serials [get]
+func GetSerialNumbers(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		query := db.Where("account_id = ?", accountID)
+		if productID := c.Query("product_id"); productID != "" {
+			query = query.Where("product_id = ?", productID)
+		}
+		if stockID := c.Query("stock_id"); stockID != "" {
+			query = query.Where("stock_id = ?", stockID)
+		}
+		if status := c.Query("status"); status != "" {
+			query = query.Where("status = ?", status)
+		}
+		if orderID := c.Query("order_id"); orderID != "" {
+			query = query.Where("order_id = ?", orderID)
+		}
+
+		var serialNumbers []model.SerialNumber
+		if err := query.Order("serial").Find(&serialNumbers).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to retrieve serial numbers"})
+			return
+		}
+
+		c.JSON(http.StatusOK, model.SerialNumbersResponse{
+			Message:       "Serial numbers retrieved successfully",
+			SerialNumbers: serialNumbers,
+		})
+	}
+}
+
+// GetSerialHistory godoc
+// @Summary Get the history of a serial number
+// @Description Retrieve where a serial number was received, moved and shipped, including the shipments
+// @Description recorded by the shipping-receiving service
+// @Tags serials
+// @Produce json
+// @Param serial path string true "Serial number"
+// @Success 200 {object} model.SerialHistoryResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /serials/{serial}/history [get]
+func GetSerialHistory(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		serial := c.Param("serial")
+		var serialNumbers []model.SerialNumber
+		if err := db.Where("serial = ? AND account_id = ?", serial, accountID).Find(&serialNumbers).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to retrieve serial number"})
+			return
+		}
+		if len(serialNumbers) == 0 {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Serial number not found"})
+			return
+		}
+
+		var events []model.SerialEvent
+		if err := db.Where("serial = ? AND account_id = ?", serial, accountID).Order("created_at, id").Find(&events).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to retrieve serial history"})
+			return
+		}
+
+		response := model.SerialHistoryResponse{
+			Message:       "Serial history retrieved successfully",
+			Serial:        serial,
+			SerialNumbers: serialNumbers,
+			Events:        events,
+			Shipments:     []model.SerialShipment{},
+		}
+
+		// The inventory history stands on its own, so a shipping service outage only
+		// leaves out the shipment details
+		token, err := utils.ExtractToken(c)
+		if err == nil {
+			var shipments []model.SerialShipment
+			shipments, err = utils.FetchSerialShipments(token, serial)
+			if shipments != nil {
+				response.Shipments = shipments
+			}
+		}
+		if err != nil {
+			log.Printf("Failed to fetch shipments for serial %s: %v", serial, err)
+			response.ShipmentLookupError = err.Error()
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// MoveSerialNumbers godoc
+// @Summary Move serial numbers to another stock row
+// @Description Move serialized units into another stock row of the same product, adjusting both quantities
+// @Tags serials
+// @Accept json
+// @Produce json
+// @Param body body model.MoveSerialsRequest true "Serials and target stock"
+// @Success 200 {object} model.SuccessResponse
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Router /serials/move [post]
+func MoveSerialNumbers(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		var request model.MoveSerialsRequest
+		if err := c.ShouldBindJSON(&request); err != nil || len(request.SerialNumbers) == 0 {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid request data"})
+			return
+		}
+
+		var target model.Stock
+		if err := db.Where("id = ? AND account_id = ?", request.ToStockID, accountID).First(&target).Error; err != nil {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Stock not found"})
+			return
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			return utils.MoveSerials(tx, request.SerialNumbers, target, utils.CurrentUserID(c))
+		})
+		switch {
+		case errors.Is(err, utils.ErrSerialNotFound):
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: err.Error()})
+			return
+		case errors.Is(err, utils.ErrInsufficientStock):
+			c.JSON(http.StatusConflict, model.ErrorResponse{Error: "Serial numbers are reserved by open orders"})
+			return
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to move serial numbers"})
+			return
+		}
+
+		c.JSON(http.StatusOK, model.SuccessResponse{Message: "Serial numbers moved successfully"})
+	}
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"inventory-management/internal/model"
+	"inventory-management/internal/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetSettings godoc
+// @Summary Get account settings
+// @Description Retrieve the account's inventory settings, such as the costing method and adjustment approval thresholds
+// @Tags settings
+// @Produce json
+// @Success 200 {object} model.AccountSettings
+// @Failure 500 {object} model.ErrorResponse
+// @Router /settings [get]
+func GetSettings(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		settings, err := utils.LoadAccountSettings(db, accountID.(uint))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to retrieve settings"})
+			return
+		}
+
+		c.JSON(http.StatusOK, settings)
+	}
+}
+
+// UpdateSettings godoc
+// @Summary Update account settings
+// @Description Change the account's inventory settings. A new costing method applies to stock consumed from then on;
+// @Description costs already booked are kept. New adjustment thresholds apply to adjustments requested from then on.
+// @Description A lower attachment quota keeps existing attachments but refuses uploads until usage drops below it.
+// @Description A new classification window applies from the next ABC/XYZ classification run.
+// @Description Requires manager permission
+// @Tags settings
+// @Accept json
+// @Produce json
+// @Param body body model.AccountSettings true "Settings"
+// @Success 200 {object} model.AccountSettings
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Router /settings [put]
+func UpdateSettings(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		if _, ok := requireManager(c); !ok {
+			return
+		}
+
+		settings, err := utils.LoadAccountSettings(db, accountID.(uint))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to retrieve settings"})
+			return
+		}
+		id := settings.ID
+		if err := c.ShouldBindJSON(&settings); err != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid request data"})
+			return
+		}
+		settings.ID = id
+		settings.AccountID = accountID.(uint)
+
+		if settings.CostingMethod != model.CostingFIFO && settings.CostingMethod != model.CostingAverage {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "costing_method must be fifo or average"})
+			return
+		}
+		if settings.AdjustmentValueThreshold < 0 {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "adjustment_value_threshold must not be negative"})
+			return
+		}
+		// Zero restores the default window
+		if settings.ClassificationWindowDays != 0 && (settings.ClassificationWindowDays < 7 || settings.ClassificationWindowDays > 730) {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "classification_window_days must be between 7 and 730"})
+			return
+		}
+		if settings.AttachmentQuotaBytes < 0 {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "attachment_quota_bytes must not be negative"})
+			return
+		}
+
+		if err := db.Save(&settings).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to update settings"})
+			return
+		}
+
+		c.JSON(http.StatusOK, settings)
+	}
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"errors"
+	"inventory-management/internal/model"
+	"inventory-management/internal/utils"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GenerateSlottingRecommendations godoc
+// @Summary Generate re-slotting recommendations
+// @Description Rank the warehouse's stock rows by how often they were picked for orders over the last days
+// @Description (30 by default) and suggest moving the busiest ones outside the golden zone to the free golden-zone
+// @Description bins closest to dispatch. Each suggestion estimates the walking distance it would have saved over
+// @Description the period. Replaces the warehouse's pending recommendations
+// @Tags slotting
+// @Accept json
+// @Produce json
+// @Param body body model.GenerateSlottingRequest true "Warehouse and period"
+// @Success 200 {object} model.SlottingRecommendationsResponse
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /slotting/recommendations [post]
+func GenerateSlottingRecommendations(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		var request model.GenerateSlottingRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid request data"})
+			return
+		}
+		if request.Days == 0 {
+			request.Days = 30
+		}
+		if request.Days < 1 || request.Days > 365 {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "days must be between 1 and 365"})
+			return
+		}
+
+		var warehouse model.Warehouse
+		if err := db.Where("id = ? AND account_id = ?", request.WarehouseID, accountID).First(&warehouse).Error; err != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Warehouse not found"})
+			return
+		}
+
+		now := time.Now()
+		var recommendations []model.SlottingRecommendation
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var err error
+			recommendations, err = utils.RecommendSlotting(tx, warehouse, now.AddDate(0, 0, -request.Days), now)
+			return err
+		})
+		if err != nil {
+			log.Printf("Failed to generate slotting recommendations: %v", err)
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to generate slotting recommendations"})
+			return
+		}
+
+		c.JSON(http.StatusOK, slottingResponse("Slotting recommendations generated successfully", recommendations))
+	}
+}
+
+// GetSlottingRecommendations godoc
+// @Summary Get re-slotting recommendations
+// @Description Retrieve re-slotting recommendations, largest saving first, optionally filtered by warehouse or status
+// @Tags slotting
+// @Produce json
+// @Param warehouse_id query int false "Warehouse ID"
+// @Param status query string false "pending, accepted or dismissed"
+// @Success 200 {object} model.SlottingRecommendationsResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /slotting/recommendations [get]
+func GetSlottingRecommendations(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		query := db.Where("account_id = ?", accountID)
+		if warehouseID := c.Query("warehouse_id"); warehouseID != "" {
+			query = query.Where("warehouse_id = ?", warehouseID)
+		}
+		if status := c.Query("status"); status != "" {
+			query = query.Where("status = ?", status)
+		}
+
+		var recommendations []model.SlottingRecommendation
+		if err := query.Order("distance_saving DESC, id").Find(&recommendations).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to retrieve slotting recommendations"})
+			return
+		}
+
+		c.JSON(http.StatusOK, slottingResponse("Slotting recommendations retrieved successfully", recommendations))
+	}
+}
+
+// AcceptSlottingRecommendation godoc
+// @Summary Accept a re-slotting recommendation
+// @Description Create a transfer order moving the stock row's available quantity to the recommended bin.
+// @Description Requires manager permission
+// @Tags slotting
+// @Produce json
+// @Param id path int true "Recommendation ID"
+// @Success 200 {object} model.SlottingRecommendation
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Router /slotting/recommendations/{id}/accept [post]
+func AcceptSlottingRecommendation(db *gorm.DB) gin.HandlerFunc {
+	return reviewSlotting(db, utils.AcceptSlottingRecommendation)
+}
+
+// DismissSlottingRecommendation godoc
+// @Summary Dismiss a re-slotting recommendation
+// @Description Close a recommendation without moving stock. Requires manager permission
+// @Tags slotting
+// @Produce json
+// @Param id path int true "Recommendation ID"
+// @Success 200 {object} model.SlottingRecommendation
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Router /slotting/recommendations/{id}/dismiss [post]
+func DismissSlottingRecommendation(db *gorm.DB) gin.HandlerFunc {
+	return reviewSlotting(db, utils.DismissSlottingRecommendation)
+}
+
+func reviewSlotting(db *gorm.DB, review func(tx *gorm.DB, recommendation *model.SlottingRecommendation, userID *uint) error) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		managerID, ok := requireManager(c)
+		if !ok {
+			return
+		}
+
+		var recommendation model.SlottingRecommendation
+		if err := db.Where("id = ? AND account_id = ?", c.Param("id"), accountID).First(&recommendation).Error; err != nil {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Slotting recommendation not found"})
+			return
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			return review(tx, &recommendation, managerID)
+		})
+		switch {
+		case err == nil:
+			c.JSON(http.StatusOK, recommendation)
+		case errors.Is(err, utils.ErrRecommendationReviewed), errors.Is(err, utils.ErrStaleRecommendation), errors.Is(err, utils.ErrInsufficientStock):
+			c.JSON(http.StatusConflict, model.ErrorResponse{Error: err.Error()})
+		default:
+			log.Printf("Failed to review slotting recommendation: %v", err)
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to review slotting recommendation"})
+		}
+	}
+}
+
+func slottingResponse(message string, recommendations []model.SlottingRecommendation) model.SlottingRecommendationsResponse {
+	response := model.SlottingRecommendationsResponse{Message: message, Recommendations: recommendations}
+	for _, recommendation := range recommendations {
+		response.TotalSaving += recommendation.DistanceSaving
+	}
+	return response
+}
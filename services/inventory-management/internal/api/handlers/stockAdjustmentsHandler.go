@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"errors"
+	"inventory-management/internal/kafka"
+	"inventory-management/internal/model"
+	"inventory-management/internal/utils"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CreateStockAdjustment godoc
+// @Summary Adjust a stock item
+// @Description Add or remove units of a stock item with a reason code (damage, shrinkage, found or correction) and
+// @Description an optional note. Adjustments within the account's quantity and value thresholds are applied right
+// @Description away; larger ones are returned as pending until a manager approves them. Removals take available
+// @Description units unless from_status names a hold status, e.g. to write off damaged stock
+// @Tags stock-adjustments
+// @Accept json
+// @Produce json
+// @Param body body model.StockAdjustment true "Adjustment"
+// @Success 201 {object} model.StockAdjustment
+// @Success 202 {object} model.StockAdjustment
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Router /stock-adjustments [post]
+func CreateStockAdjustment(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		var adjustment model.StockAdjustment
+		if err := c.ShouldBindJSON(&adjustment); err != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid request data"})
+			return
+		}
+
+		adjustment.ID = 0
+		adjustment.ReviewedBy = nil
+		adjustment.ReviewedAt = nil
+		adjustment.ReviewNote = ""
+		adjustment.Published = false
+		adjustment.RequestedBy = utils.CurrentUserID(c)
+		adjustment.AccountID = accountID.(uint)
+		err := db.Transaction(func(tx *gorm.DB) error {
+			return utils.RequestStockAdjustment(tx, &adjustment)
+		})
+		if !writeAdjustmentError(c, err) {
+			return
+		}
+
+		if adjustment.Status == model.AdjustmentPending {
+			c.JSON(http.StatusAccepted, adjustment)
+			return
+		}
+		publishAdjustment(db, &adjustment)
+		c.JSON(http.StatusCreated, adjustment)
+	}
+}
+
+// GetStockAdjustments godoc
+// @Summary Get stock adjustments
+// @Description Retrieve the account's stock adjustments, newest first, e.g. the pending ones waiting for approval
+// @Tags stock-adjustments
+// @Produce json
+// @Param status query string false "pending, approved or rejected"
+// @Param reason query string false "damage, shrinkage, found or correction"
+// @Param stock_id query int false "Stock ID"
+// @Param product_id query int false "Product ID"
+// @Param from query string false "Requested from (YYYY-MM-DD or RFC3339)"
+// @Param to query string false "Requested until (YYYY-MM-DD or RFC3339)"
+// @Success 200 {object} model.StockAdjustmentsResponse
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /stock-adjustments [get]
+func GetStockAdjustments(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		from, to, ok := scorecardPeriod(c)
+		if !ok {
+			return
+		}
+
+		query := db.Where("account_id = ?", accountID)
+		for _, filter := range []string{"status", "reason", "stock_id", "product_id"} {
+			if value := c.Query(filter); value != "" {
+				query = query.Where(filter+" = ?", value)
+			}
+		}
+		if from != nil {
+			query = query.Where("created_at >= ?", *from)
+		}
+		if to != nil {
+			query = query.Where("created_at <= ?", *to)
+		}
+
+		var adjustments []model.StockAdjustment
+		if err := query.Order("created_at DESC, id DESC").Find(&adjustments).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to retrieve stock adjustments"})
+			return
+		}
+
+		c.JSON(http.StatusOK, model.StockAdjustmentsResponse{Message: "Stock adjustments retrieved successfully", Adjustments: adjustments})
+	}
+}
+
+// ApproveStockAdjustment godoc
+// @Summary Approve a stock adjustment
+// @Description Approve a pending adjustment and apply it to the stock. Requires manager permission
+// @Tags stock-adjustments
+// @Accept json
+// @Produce json
+// @Param id path int true "Adjustment ID"
+// @Param body body model.ReviewAdjustmentRequest false "Review note"
+// @Success 200 {object} model.StockAdjustment
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Router /stock-adjustments/{id}/approve [post]
+func ApproveStockAdjustment(db *gorm.DB) gin.HandlerFunc {
+	return reviewStockAdjustment(db, true)
+}
+
+// RejectStockAdjustment godoc
+// @Summary Reject a stock adjustment
+// @Description Reject a pending adjustment; the stock is left unchanged. Requires manager permission
+// @Tags stock-adjustments
+// @Accept json
+// @Produce json
+// @Param id path int true "Adjustment ID"
+// @Param body body model.ReviewAdjustmentRequest false "Review note"
+// @Success 200 {object} model.StockAdjustment
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Router /stock-adjustments/{id}/reject [post]
+func RejectStockAdjustment(db *gorm.DB) gin.HandlerFunc {
+	return reviewStockAdjustment(db, false)
+}
+
+// PublishStockAdjustment godoc
+// @Summary Publish a stock adjustment to reporting
+// @Description Send an approved adjustment to reporting-analytics again, e.g. after Kafka was unavailable when it
+// @Description was approved. Reporting ignores adjustments it already has
+// @Tags stock-adjustments
+// @Produce json
+// @Param id path int true "Adjustment ID"
+// @Success 200 {object} model.StockAdjustment
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Failure 502 {object} model.ErrorResponse
+// @Router /stock-adjustments/{id}/publish [post]
+func PublishStockAdjustment(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		var adjustment model.StockAdjustment
+		if err := db.Where("id = ? AND account_id = ?", c.Param("id"), accountID).First(&adjustment).Error; err != nil {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Stock adjustment not found"})
+			return
+		}
+		if adjustment.Status != model.AdjustmentApproved {
+			c.JSON(http.StatusConflict, model.ErrorResponse{Error: "Only approved adjustments are reported"})
+			return
+		}
+
+		if !publishAdjustment(db, &adjustment) {
+			c.JSON(http.StatusBadGateway, model.ErrorResponse{Error: "Failed to publish stock adjustment"})
+			return
+		}
+		c.JSON(http.StatusOK, adjustment)
+	}
+}
+
+func reviewStockAdjustment(db *gorm.DB, approve bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		managerID, ok := requireManager(c)
+		if !ok {
+			return
+		}
+
+		var request model.ReviewAdjustmentRequest
+		if c.Request.ContentLength > 0 {
+			if err := c.ShouldBindJSON(&request); err != nil {
+				c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid request data"})
+				return
+			}
+		}
+
+		var adjustment model.StockAdjustment
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("id = ? AND account_id = ?", c.Param("id"), accountID).First(&adjustment).Error; err != nil {
+				return err
+			}
+			return utils.ReviewStockAdjustment(tx, &adjustment, approve, request.Note, managerID)
+		})
+		if !writeAdjustmentError(c, err) {
+			return
+		}
+
+		if approve {
+			publishAdjustment(db, &adjustment)
+		}
+		c.JSON(http.StatusOK, adjustment)
+	}
+}
+
+// publishAdjustment reports an approved adjustment to reporting-analytics and marks it as
+// published. A failure is logged and leaves the adjustment to be published again later.
+func publishAdjustment(db *gorm.DB, adjustment *model.StockAdjustment) bool {
+	if err := kafka.PublishStockAdjustment(adjustment.Event()); err != nil {
+		log.Printf("Failed to publish stock adjustment %d: %v", adjustment.ID, err)
+		return false
+	}
+	adjustment.Published = true
+	if err := db.Model(adjustment).Update("published", true).Error; err != nil {
+		log.Printf("Published stock adjustment %d but failed to mark it: %v", adjustment.ID, err)
+	}
+	return true
+}
+
+// writeAdjustmentError maps adjustment errors to responses and reports whether err was nil
+func writeAdjustmentError(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Stock adjustment or stock not found"})
+	case errors.Is(err, utils.ErrAdjustmentNotPending), errors.Is(err, utils.ErrAdjustmentBelowReserved), errors.Is(err, utils.ErrDuplicateSerial):
+		c.JSON(http.StatusConflict, model.ErrorResponse{Error: err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: err.Error()})
+	}
+	return false
+}
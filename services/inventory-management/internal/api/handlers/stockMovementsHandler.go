@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"inventory-management/internal/model"
+	"inventory-management/internal/utils"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetStockMovements godoc
+// @Summary Get stock movements
+// @Description Query the append-only stock movement ledger by product, stock, bin and date range
+// @Tags stock-movements
+// @Produce json
+// @Param product_id query int false "Product ID"
+// @Param stock_id query int false "Stock ID"
+// @Param bin_id query int false "Bin ID"
+// @Param order_id query int false "Order ID"
+// @Param from query string false "Start date (YYYY-MM-DD or RFC3339)"
+// @Param to query string false "End date (YYYY-MM-DD or RFC3339)"
+// @Success 200 {object} model.StockMovementsResponse
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /stock-movements [get]
+func GetStockMovements(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		query := db.Where("account_id = ?", accountID)
+
+		if productID := c.Query("product_id"); productID != "" {
+			query = query.Where("product_id = ?", productID)
+		}
+
+		if stockID := c.Query("stock_id"); stockID != "" {
+			query = query.Where("stock_id = ?", stockID)
+		}
+
+		if binID := c.Query("bin_id"); binID != "" {
+			query = query.Where("bin_id = ?", binID)
+		}
+
+		if orderID := c.Query("order_id"); orderID != "" {
+			query = query.Where("order_id = ?", orderID)
+		}
+
+		if from := c.Query("from"); from != "" {
+			fromTime, err := parseDateParam(from, false)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid from date"})
+				return
+			}
+			query = query.Where("created_at >= ?", fromTime)
+		}
+
+		if to := c.Query("to"); to != "" {
+			toTime, err := parseDateParam(to, true)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid to date"})
+				return
+			}
+			query = query.Where("created_at <= ?", toTime)
+		}
+
+		var movements []model.StockMovement
+		if err := query.Order("created_at, id").Find(&movements).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to retrieve stock movements"})
+			return
+		}
+
+		c.JSON(http.StatusOK, model.StockMovementsResponse{
+			Message:   "Stock movements retrieved successfully",
+			Movements: movements,
+		})
+	}
+}
+
+// VerifyStockLedger godoc
+// @Summary Verify stock balances against the ledger
+// @Description Replay the movement ledger and check that every stock quantity equals the sum of its movements
+// @Tags stock-movements
+// @Produce json
+// @Param product_id query int false "Product ID"
+// @Param stock_id query int false "Stock ID"
+// @Success 200 {object} model.LedgerVerificationResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /stock-movements/verify [get]
+func VerifyStockLedger(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		query := db.Where("account_id = ?", accountID)
+		if productID := c.Query("product_id"); productID != "" {
+			query = query.Where("product_id = ?", productID)
+		}
+		if stockID := c.Query("stock_id"); stockID != "" {
+			query = query.Where("id = ?", stockID)
+		}
+
+		var stocks []model.Stock
+		if err := query.Find(&stocks).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to retrieve stocks"})
+			return
+		}
+
+		response := model.LedgerVerificationResponse{
+			Message:    "Stock ledger verified",
+			Consistent: true,
+			Stocks:     []model.LedgerCheck{},
+		}
+		for _, stock := range stocks {
+			balance, err := utils.LedgerBalance(db, stock.ID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to replay stock movements"})
+				return
+			}
+
+			check := model.LedgerCheck{
+				StockID:       stock.ID,
+				ProductID:     stock.ProductID,
+				Quantity:      int(stock.Quantity),
+				LedgerBalance: balance,
+				Consistent:    balance == int(stock.Quantity),
+			}
+			response.Consistent = response.Consistent && check.Consistent
+			response.Stocks = append(response.Stocks, check)
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// parseDateParam accepts either a plain date or an RFC3339 timestamp. A plain date used
+// as the end of a range covers the whole day.
+func parseDateParam(value string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, nil
+}
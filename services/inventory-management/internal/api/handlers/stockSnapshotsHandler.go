@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"fmt"
+	"inventory-management/internal/model"
+	"inventory-management/internal/utils"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetStockAsOf godoc
+// @Summary Stock on hand as of a point in time
+// @Description Reconstruct the quantity on hand at any moment by stock row, product or location, from the latest
+// @Description daily snapshot before it and the stock movements since. A plain date means the end of that day, so
+// @Description as_of=2024-12-31 is the stock at midnight on the 31st. format=csv returns a spreadsheet
+// @Tags stock-snapshots
+// @Produce json
+// @Produce text/csv
+// @Param as_of query string false "Point in time (YYYY-MM-DD or RFC3339), default now"
+// @Param group_by query string false "stock (default), product or location"
+// @Param product_id query int false "Product ID"
+// @Param bin_id query int false "Bin ID"
+// @Param location query string false "Location"
+// @Param format query string false "json (default) or csv"
+// @Success 200 {object} model.StockAsOfReport
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /stock-snapshots/as-of [get]
+func GetStockAsOf(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		groupBy := c.DefaultQuery("group_by", "stock")
+		if !slices.Contains(utils.StockGroups, groupBy) {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "group_by must be stock, product or location"})
+			return
+		}
+		format := c.DefaultQuery("format", "json")
+		if format != "csv" && format != "json" {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "format must be csv or json"})
+			return
+		}
+
+		asOf := time.Now()
+		if value := c.Query("as_of"); value != "" {
+			t, err := parseDateParam(value, true)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid as_of date"})
+				return
+			}
+			asOf = t
+		}
+
+		filter := utils.StockFilter{Location: c.Query("location")}
+		for param, target := range map[string]**uint{"product_id": &filter.ProductID, "bin_id": &filter.BinID} {
+			if value := c.Query(param); value != "" {
+				id, err := strconv.ParseUint(value, 10, 0)
+				if err != nil {
+					c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid " + param})
+					return
+				}
+				parsed := uint(id)
+				*target = &parsed
+			}
+		}
+
+		report, err := utils.StockAsOf(db, accountID.(uint), asOf, groupBy, filter)
+		if err != nil {
+			log.Printf("Failed to reconstruct stock as of %s: %v", asOf, err)
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to reconstruct stock"})
+			return
+		}
+		report.Message = "Stock on hand reconstructed successfully"
+
+		if format == "json" {
+			c.JSON(http.StatusOK, report)
+			return
+		}
+
+		columns := []string{"stock_id", "product_id", "sku", "product_name", "lot_number", "bin_id", "location", "quantity"}
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=stock-as-of-%s.csv", asOf.Format("2006-01-02")))
+		err = writeExport(c, format, columns, func(emit func([]interface{}) error) error {
+			for _, position := range report.Positions {
+				if err := emit([]interface{}{
+					position.StockID, position.ProductID, position.SKU, position.ProductName,
+					position.LotNumber, position.BinID, position.Location, position.Quantity,
+				}); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			log.Printf("Failed to export stock as of %s: %v", asOf, err)
+		}
+	}
+}
+
+// TakeStockSnapshot godoc
+// @Summary Take a stock snapshot
+// @Description Record the account's stock on hand at a point in time, by default the last midnight. Snapshots are taken
+// @Description every night automatically; this backfills a missed one. Taking an existing snapshot returns it unchanged.
+// @Description Requires manager permission
+// @Tags stock-snapshots
+// @Accept json
+// @Produce json
+// @Param body body model.TakeSnapshotRequest false "Point in time"
+// @Success 200 {object} model.StockSnapshot
+// @Success 201 {object} model.StockSnapshot
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Router /stock-snapshots [post]
+func TakeStockSnapshot(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		if _, ok := requireManager(c); !ok {
+			return
+		}
+
+		var request model.TakeSnapshotRequest
+		if c.Request.ContentLength > 0 {
+			if err := c.ShouldBindJSON(&request); err != nil {
+				c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid request data"})
+				return
+			}
+		}
+		now := time.Now()
+		takenAt := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		if request.TakenAt != nil {
+			takenAt = *request.TakenAt
+		}
+		if takenAt.After(now) {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "taken_at must not be in the future"})
+			return
+		}
+
+		snapshot, created, err := utils.TakeStockSnapshot(db, accountID.(uint), takenAt)
+		if err != nil {
+			log.Printf("Failed to take stock snapshot: %v", err)
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to take stock snapshot"})
+			return
+		}
+
+		status := http.StatusOK
+		if created {
+			status = http.StatusCreated
+		}
+		c.JSON(status, snapshot)
+	}
+}
+
+// GetStockSnapshots godoc
+// @Summary Get stock snapshots
+// @Description Retrieve the account's stock snapshots, newest first, optionally taken within a date range
+// @Tags stock-snapshots
+// @Produce json
+// @Param from query string false "Taken from (YYYY-MM-DD or RFC3339)"
+// @Param to query string false "Taken until (YYYY-MM-DD or RFC3339)"
+// @Success 200 {object} model.StockSnapshotsResponse
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /stock-snapshots [get]
+func GetStockSnapshots(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		query := db.Where("account_id = ?", accountID)
+		if from := c.Query("from"); from != "" {
+			fromTime, err := parseDateParam(from, false)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid from date"})
+				return
+			}
+			query = query.Where("taken_at >= ?", fromTime)
+		}
+		if to := c.Query("to"); to != "" {
+			toTime, err := parseDateParam(to, true)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid to date"})
+				return
+			}
+			query = query.Where("taken_at <= ?", toTime)
+		}
+
+		var snapshots []model.StockSnapshot
+		if err := query.Order("taken_at DESC").Find(&snapshots).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to retrieve stock snapshots"})
+			return
+		}
+
+		c.JSON(http.StatusOK, model.StockSnapshotsResponse{Message: "Stock snapshots retrieved successfully", Snapshots: snapshots})
+	}
+}
+
+// ExportStockSnapshot godoc
+// @Summary Export a stock snapshot
+// @Description Stream every line of a snapshot with its product, lot and location for audit
+// @Tags stock-snapshots
+// @Produce text/csv
+// @Produce json
+// @Param id path int true "Snapshot ID"
+// @Param format query string false "csv (default) or json"
+// @Success 200 {string} string "Exported snapshot"
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Router /stock-snapshots/{id}/export [get]
+func ExportStockSnapshot(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		format := c.DefaultQuery("format", "csv")
+		if format != "csv" && format != "json" {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "format must be csv or json"})
+			return
+		}
+
+		var snapshot model.StockSnapshot
+		if err := db.Where("id = ? AND account_id = ?", c.Param("id"), accountID).First(&snapshot).Error; err != nil {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Stock snapshot not found"})
+			return
+		}
+
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=stock-snapshot-%s.%s", snapshot.TakenAt.Format("2006-01-02T1504"), format))
+		err := writeExport(c, format, utils.SnapshotExportColumns, func(emit func([]interface{}) error) error {
+			return utils.ExportStockSnapshot(db, snapshot, emit)
+		})
+
+		// The status line has already been sent, so a failure can only be logged
+		if err != nil {
+			log.Printf("Failed to export stock snapshot %d: %v", snapshot.ID, err)
+		}
+	}
+}
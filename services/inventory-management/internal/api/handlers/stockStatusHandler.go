@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"errors"
+	"inventory-management/internal/model"
+	"inventory-management/internal/utils"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ChangeStockStatus godoc
+// @Summary Move stock between statuses
+// @Description Move quantity of a stock item between available, quarantine, damaged and qc_hold with a reason code
+// @Description (return, inbound_qc, damage, investigation, inspection_passed or inspection_failed) and an optional
+// @Description note. Only available quantity can be reserved for orders or transferred; only unreserved available
+// @Description quantity can be put on hold
+// @Tags stocks
+// @Accept json
+// @Produce json
+// @Param id path int true "Stock ID"
+// @Param body body model.ChangeStockStatusRequest true "Status change"
+// @Success 200 {object} model.StockStatusChange
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Router /stocks/{id}/status [post]
+func ChangeStockStatus(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		var request model.ChangeStockStatusRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid request data"})
+			return
+		}
+		if !slices.Contains(model.InventoryStatuses, request.FromStatus) || !slices.Contains(model.InventoryStatuses, request.ToStatus) {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: utils.ErrUnknownStatus.Error()})
+			return
+		}
+		if request.FromStatus == request.ToStatus {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "from_status and to_status must differ"})
+			return
+		}
+		if !slices.Contains(model.StatusChangeReasons, request.Reason) {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "reason must be return, inbound_qc, damage, investigation, inspection_passed or inspection_failed"})
+			return
+		}
+
+		var stock model.Stock
+		if err := db.Where("id = ? AND account_id = ?", c.Param("id"), accountID).First(&stock).Error; err != nil {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Stock not found"})
+			return
+		}
+
+		change := model.StockStatusChange{
+			StockID:    stock.ID,
+			FromStatus: request.FromStatus,
+			ToStatus:   request.ToStatus,
+			Quantity:   request.Quantity,
+			Reason:     request.Reason,
+			Note:       request.Note,
+			UserID:     utils.CurrentUserID(c),
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			return utils.ChangeStockStatus(tx, &change)
+		})
+		if errors.Is(err, utils.ErrInsufficientStatusQuantity) {
+			c.JSON(http.StatusConflict, model.ErrorResponse{Error: err.Error()})
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to change stock status: %v", err)
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to change stock status"})
+			return
+		}
+
+		c.JSON(http.StatusOK, change)
+	}
+}
+
+// GetStockStatusChanges godoc
+// @Summary Get stock status changes
+// @Description Retrieve the history of quantity moved between statuses, newest first
+// @Tags stocks
+// @Produce json
+// @Param stock_id query int false "Stock ID"
+// @Param product_id query int false "Product ID"
+// @Param reason query string false "Reason code"
+// @Param from query string false "Changed from (YYYY-MM-DD or RFC3339)"
+// @Param to query string false "Changed until (YYYY-MM-DD or RFC3339)"
+// @Success 200 {object} model.StockStatusChangesResponse
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /stocks/status-changes [get]
+func GetStockStatusChanges(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		query := db.Where("account_id = ?", accountID)
+		if stockID := c.Query("stock_id"); stockID != "" {
+			query = query.Where("stock_id = ?", stockID)
+		}
+		if productID := c.Query("product_id"); productID != "" {
+			query = query.Where("product_id = ?", productID)
+		}
+		if reason := c.Query("reason"); reason != "" {
+			query = query.Where("reason = ?", reason)
+		}
+		if from := c.Query("from"); from != "" {
+			t, err := parseDateParam(from, false)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid from date"})
+				return
+			}
+			query = query.Where("created_at >= ?", t)
+		}
+		if to := c.Query("to"); to != "" {
+			t, err := parseDateParam(to, true)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid to date"})
+				return
+			}
+			query = query.Where("created_at <= ?", t)
+		}
+
+		var changes []model.StockStatusChange
+		if err := query.Order("created_at DESC, id DESC").Find(&changes).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to retrieve stock status changes"})
+			return
+		}
+
+		c.JSON(http.StatusOK, model.StockStatusChangesResponse{
+			Message: "Stock status changes retrieved successfully",
+			Changes: changes,
+		})
+	}
+}
+
+// GetHeldStock godoc
+// @Summary Report held stock
+// @Description Report the quantity on hold (quarantine, damaged or qc_hold) by stock item, product or status, valued
+// @Description at the products' current cost. Stock items show since when they hold quantity in each status
+// @Tags stocks
+// @Produce json
+// @Param group_by query string false "stock (default), product or status"
+// @Param product_id query int false "Product ID"
+// @Param status query string false "quarantine, damaged or qc_hold"
+// @Param format query string false "json (default) or csv"
+// @Success 200 {object} model.HeldStockReport
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /stocks/held [get]
+func GetHeldStock(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		groupBy := c.DefaultQuery("group_by", "stock")
+		if !slices.Contains(utils.HeldStockGroups, groupBy) {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "group_by must be stock, product or status"})
+			return
+		}
+		format := c.DefaultQuery("format", "json")
+		if format != "csv" && format != "json" {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "format must be csv or json"})
+			return
+		}
+		status := model.InventoryStatus(c.Query("status"))
+		if status != "" && (status == model.InventoryAvailable || !slices.Contains(model.InventoryStatuses, status)) {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "status must be quarantine, damaged or qc_hold"})
+			return
+		}
+
+		var productID *uint
+		if value := c.Query("product_id"); value != "" {
+			id, err := strconv.ParseUint(value, 10, 0)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid product_id"})
+				return
+			}
+			parsed := uint(id)
+			productID = &parsed
+		}
+
+		report, err := utils.HeldStockReport(db, accountID.(uint), groupBy, productID, status)
+		if err != nil {
+			log.Printf("Failed to report held stock: %v", err)
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to report held stock"})
+			return
+		}
+		report.Message = "Held stock retrieved successfully"
+
+		if format == "json" {
+			c.JSON(http.StatusOK, report)
+			return
+		}
+
+		c.Header("Content-Disposition", "attachment; filename=held-stock.csv")
+		err = writeExport(c, format, utils.HeldStockColumns, func(emit func([]interface{}) error) error {
+			for _, line := range report.Lines {
+				heldSince := ""
+				if line.HeldSince != nil {
+					heldSince = line.HeldSince.Format(time.RFC3339)
+				}
+				if err := emit([]interface{}{
+					line.StockID, line.ProductID, line.SKU, line.ProductName, line.LotNumber, line.BinID,
+					line.Location, line.Status, line.Quantity, line.Value, heldSince,
+				}); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			log.Printf("Failed to export held stock: %v", err)
+		}
+	}
+}
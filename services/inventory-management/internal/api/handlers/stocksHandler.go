@@ -1,10 +1,14 @@
 package handlers
 
 import (
+	"errors"
 	"inventory-management/internal/model"
 	"inventory-management/internal/utils"
 	"log"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -12,7 +16,8 @@ import (
 
 // CreateStock godoc
 // @Summary Create a new stock item
-// @Description Create a new stock item in the inventory
+// @Description Create a new stock item in the inventory. Received goods can be parked on hold right away, e.g. for
+// @Description inbound QC, by setting quarantine_quantity, damaged_quantity or qc_hold_quantity
 // @Tags stocks
 // @Accept json
 // @Produce json
@@ -35,9 +40,56 @@ func CreateStock(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
+		if !validLotDates(stock) {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Expiry date must be after manufacture date"})
+			return
+		}
+
+		if err := resolveStockBin(db, &stock, accountID); err != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Bin not found"})
+			return
+		}
+
+		var product model.Product
+		if err := db.Preload("Units").Where("id = ? AND account_id = ?", stock.ProductID, accountID).First(&product).Error; err != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Product not found"})
+			return
+		}
+		if !applyStockUnit(product, &stock) {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: utils.ErrUnknownUnit.Error()})
+			return
+		}
+		if product.IsSerialized && len(stock.SerialNumbers) != int(stock.Quantity) {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: utils.ErrSerialCountMismatch.Error()})
+			return
+		}
+		if stock.Held() > stock.Quantity {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Quantity on hold exceeds the quantity"})
+			return
+		}
+
 		stock.AccountID = accountID.(uint)
-		if result := db.Create(&stock); result.Error != nil {
-			log.Printf("Failed to create stock: %v", result.Error)
+		stock.ReservedQuantity = 0
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&stock).Error; err != nil {
+				return err
+			}
+			if product.IsSerialized {
+				if err := utils.SyncStockSerials(tx, stock, stock.SerialNumbers, utils.CurrentUserID(c)); err != nil {
+					return err
+				}
+			}
+			return utils.RecordStockMovement(tx, stock, int(stock.Quantity), model.StockMovement{
+				Reason: model.MovementReceipt,
+				UserID: utils.CurrentUserID(c),
+			})
+		})
+		if errors.Is(err, utils.ErrDuplicateSerial) {
+			c.JSON(http.StatusConflict, model.ErrorResponse{Error: err.Error()})
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to create stock: %v", err)
 			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to create stock"})
 			return
 		}
@@ -49,7 +101,9 @@ func CreateStock(db *gorm.DB) gin.HandlerFunc {
 
 // UpdateStock godoc
 // @Summary Update a stock item
-// @Description Update a stock item by ID
+// @Description Update a stock item by ID, e.g. its lot or low-stock threshold. The quantity is changed with a stock
+// @Description adjustment and the bin or location with a transfer instead, so the movement ledger sees every unit
+// @Description move; the product cannot be changed. Serials of serialized stock can be swapped one for one
 // @Tags stocks
 // @Accept json
 // @Produce json
@@ -74,13 +128,78 @@ func UpdateStock(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
+		existing := stock
 		if err := c.ShouldBindJSON(&stock); err != nil {
 			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid request data"})
 			return
 		}
 
+		// The path decides which row is updated, reservations are owned by the order flow
+		// and holds by status changes, so none of them can be changed through the request body
+		stock.ID = existing.ID
+		stock.ReservedQuantity = existing.ReservedQuantity
+		stock.QuarantineQuantity = existing.QuarantineQuantity
+		stock.DamagedQuantity = existing.DamagedQuantity
+		stock.QCHoldQuantity = existing.QCHoldQuantity
+
+		// Units only change product or place through the ledger, e.g. with a transfer
+		if stock.ProductID != existing.ProductID {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Product cannot be changed, create a new stock item instead"})
+			return
+		}
+		if (stock.BinID == nil) != (existing.BinID == nil) || (stock.BinID != nil && *stock.BinID != *existing.BinID) ||
+			stock.Location != existing.Location {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Stock is moved to another bin or location with a transfer"})
+			return
+		}
+
+		var product model.Product
+		if err := db.Preload("Units").Where("id = ? AND account_id = ?", stock.ProductID, accountID).First(&product).Error; err != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Product not found"})
+			return
+		}
+		if !applyStockUnit(product, &stock) {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: utils.ErrUnknownUnit.Error()})
+			return
+		}
+
+		if stock.Quantity != existing.Quantity {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Quantity is changed with a stock adjustment"})
+			return
+		}
+
+		if !validLotDates(stock) {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Expiry date must be after manufacture date"})
+			return
+		}
+
+		if err := resolveStockBin(db, &stock, accountID); err != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Bin not found"})
+			return
+		}
+
+		// Serialized stock may swap the serials it holds for others, one for one
+		syncSerials := product.IsSerialized && stock.SerialNumbers != nil
+		if syncSerials && len(stock.SerialNumbers) != int(stock.Quantity) {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: utils.ErrSerialCountMismatch.Error()})
+			return
+		}
+
 		stock.AccountID = accountID.(uint)
-		if result := db.Save(&stock); result.Error != nil {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Save(&stock).Error; err != nil {
+				return err
+			}
+			if syncSerials {
+				return utils.SyncStockSerials(tx, stock, stock.SerialNumbers, utils.CurrentUserID(c))
+			}
+			return nil
+		})
+		if errors.Is(err, utils.ErrDuplicateSerial) {
+			c.JSON(http.StatusConflict, model.ErrorResponse{Error: err.Error()})
+			return
+		}
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to update stock"})
 			return
 		}
@@ -91,10 +210,21 @@ func UpdateStock(db *gorm.DB) gin.HandlerFunc {
 
 // GetStocks godoc
 // @Summary Get all stock items
-// @Description Retrieve all stock items or filter by query parameters
+// @Description Retrieve all stock items or filter by product, bin, zone, warehouse or the product's ABC/XYZ class.
+// @Description Use group_by=warehouse or group_by=zone to also receive aggregated totals.
+// @Description Quantity picked for transfers but not yet received is listed under in_transit, narrowed to transfers
+// @Description from or to the filtered bin, zone or warehouse.
 // @Tags stocks
 // @Produce json
+// @Param product_id query int false "Product ID"
+// @Param bin_id query int false "Bin ID"
+// @Param zone_id query int false "Zone ID"
+// @Param warehouse_id query int false "Warehouse ID"
+// @Param abc_class query string false "ABC class of the product (A, B or C)"
+// @Param xyz_class query string false "XYZ class of the product (X, Y or Z)"
+// @Param group_by query string false "Aggregate quantities by warehouse or zone"
 // @Success 200 {object} model.StocksResponse
+// @Failure 400 {object} model.ErrorResponse
 // @Failure 500 {object} model.ErrorResponse
 // @Router /stocks [get]
 func GetStocks(db *gorm.DB) gin.HandlerFunc {
@@ -105,25 +235,248 @@ func GetStocks(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
+		groupBy := c.Query("group_by")
+		if groupBy != "" && groupBy != "warehouse" && groupBy != "zone" {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "group_by must be warehouse or zone"})
+			return
+		}
+
+		query := db.Where("account_id = ?", accountID).Preload("Product.Units").Preload("Bin.Zone.Warehouse")
+
+		if productID := c.Query("product_id"); productID != "" {
+			query = query.Where("product_id = ?", productID)
+		}
+
+		bins := db.Model(&model.Bin{}).Select("bins.id")
+		filterLocation := false
+		if binID := c.Query("bin_id"); binID != "" {
+			bins = bins.Where("bins.id = ?", binID)
+			filterLocation = true
+		}
+
+		if zoneID := c.Query("zone_id"); zoneID != "" {
+			bins = bins.Where("bins.zone_id = ?", zoneID)
+			filterLocation = true
+		}
+
+		if warehouseID := c.Query("warehouse_id"); warehouseID != "" {
+			bins = bins.Joins("JOIN zones ON zones.id = bins.zone_id").Where("zones.warehouse_id = ?", warehouseID)
+			filterLocation = true
+		}
+		if filterLocation {
+			query = query.Where("bin_id IN (?)", bins)
+		}
+
+		classified := db.Model(&model.Product{}).Select("id").Where("account_id = ?", accountID)
+		filterClass := false
+		for _, class := range []string{"abc_class", "xyz_class"} {
+			if value := c.Query(class); value != "" {
+				classified = classified.Where(class+" = ?", value)
+				filterClass = true
+			}
+		}
+		if filterClass {
+			query = query.Where("product_id IN (?)", classified)
+		}
+
 		var stocks []model.Stock
-		if result := db.Where("account_id = ?", accountID).Preload("Product").Find(&stocks); result.Error != nil {
+		if result := query.Find(&stocks); result.Error != nil {
 			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to retrieve stocks"})
 			return
 		}
 
 		var stockResponses []model.StockResponse
 		for _, stock := range stocks {
-			stockResponses = append(stockResponses, model.StockResponse{
-				ID:          stock.ID,
-				ProductName: stock.Product.Name,
-				Quantity:    int(stock.Quantity),
-				Location:    stock.Location,
+			stockResponses = append(stockResponses, toStockResponse(stock))
+		}
+
+		// Picked transfers are in neither stock row, so they are listed on their own
+		transferQuery := db.Where("account_id = ? AND status IN ?", accountID,
+			[]model.TransferStatus{model.TransferPicked, model.TransferInTransit, model.TransferPartiallyReceived})
+		if productID := c.Query("product_id"); productID != "" {
+			transferQuery = transferQuery.Where("product_id = ?", productID)
+		}
+		if filterClass {
+			transferQuery = transferQuery.Where("product_id IN (?)", classified)
+		}
+		if filterLocation {
+			// A transfer is in view when it leaves from or goes to one of the filtered bins
+			binStocks := db.Model(&model.Stock{}).Select("id").Where("bin_id IN (?)", bins)
+			transferQuery = transferQuery.Where(db.Where("from_stock_id IN (?)", binStocks).
+				Or("to_stock_id IN (?)", binStocks).Or("to_bin_id IN (?)", bins))
+		}
+		var transfers []model.TransferOrder
+		if err := transferQuery.Order("id").Find(&transfers).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to retrieve stocks in transit"})
+			return
+		}
+
+		response := model.StocksResponse{
+			Message:   "Stocks retrieved successfully",
+			Stocks:    stockResponses,
+			InTransit: []model.InTransitStock{},
+		}
+		for _, transfer := range transfers {
+			response.InTransit = append(response.InTransit, model.InTransitStock{
+				TransferID:  transfer.ID,
+				ProductID:   transfer.ProductID,
+				FromStockID: transfer.FromStockID,
+				ToStockID:   transfer.ToStockID,
+				ToBinID:     transfer.ToBinID,
+				Quantity:    int(transfer.InTransit()),
+				Status:      string(transfer.Status),
 			})
 		}
+		if groupBy != "" {
+			response.Totals = aggregateStocks(stocks, groupBy)
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// toStockResponse flattens a stock row and its preloaded location into a response item
+func toStockResponse(stock model.Stock) model.StockResponse {
+	response := model.StockResponse{
+		ID:          stock.ID,
+		ProductName: stock.Product.Name,
+		Quantity:    int(stock.Quantity),
+		Reserved:    int(stock.ReservedQuantity),
+		Available:   int(stock.Available()),
+		Quarantine:  int(stock.QuarantineQuantity),
+		Damaged:     int(stock.DamagedQuantity),
+		QCHold:      int(stock.QCHoldQuantity),
+		Location:    stock.Location,
+		BinID:       stock.BinID,
+		LotNumber:   stock.LotNumber,
+		ExpiresAt:   stock.ExpiresAt,
+	}
+	if stock.Bin != nil && stock.Bin.Zone != nil {
+		response.ZoneID = &stock.Bin.Zone.ID
+		response.WarehouseID = &stock.Bin.Zone.WarehouseID
+	}
 
-		c.JSON(http.StatusOK, model.StocksResponse{
-			Message: "Stocks retrieved successfully",
-			Stocks:  stockResponses,
+	response.UnitOfMeasure = stock.UnitOfMeasure
+	if response.UnitOfMeasure == "" {
+		response.UnitOfMeasure = stock.Product.BaseUnit
+	}
+	if factor, ok := stock.Product.UnitFactor(stock.UnitOfMeasure); ok && factor > 0 {
+		response.UnitQuantity = float64(stock.Quantity) / float64(factor)
+	}
+	return response
+}
+
+// applyStockUnit checks the stock's unit of measure against the product and, when the
+// quantity was entered as unit_quantity, converts it into base units
+func applyStockUnit(product model.Product, stock *model.Stock) bool {
+	factor, ok := product.UnitFactor(stock.UnitOfMeasure)
+	if !ok {
+		return false
+	}
+	if stock.UnitQuantity != nil {
+		stock.Quantity = *stock.UnitQuantity * factor
+	}
+	return true
+}
+
+// aggregateStocks sums stock quantities per warehouse or zone. Stock without a bin
+// is reported under ID 0.
+func aggregateStocks(stocks []model.Stock, groupBy string) []model.StockLocationTotal {
+	totals := []model.StockLocationTotal{}
+	index := map[uint]int{}
+
+	for _, stock := range stocks {
+		var id uint
+		name := "Unassigned"
+		if stock.Bin != nil && stock.Bin.Zone != nil {
+			if groupBy == "zone" {
+				id, name = stock.Bin.Zone.ID, stock.Bin.Zone.Name
+			} else if stock.Bin.Zone.Warehouse != nil {
+				id, name = stock.Bin.Zone.Warehouse.ID, stock.Bin.Zone.Warehouse.Name
+			}
+		}
+
+		i, ok := index[id]
+		if !ok {
+			i = len(totals)
+			index[id] = i
+			totals = append(totals, model.StockLocationTotal{ID: id, Name: name})
+		}
+		totals[i].Quantity += int(stock.Quantity)
+	}
+
+	return totals
+}
+
+// resolveStockBin verifies that the stock's bin belongs to the account and
+// derives the location label from the warehouse/zone/bin path
+func resolveStockBin(db *gorm.DB, stock *model.Stock, accountID interface{}) error {
+	stock.Bin = nil
+	if stock.BinID == nil {
+		return nil
+	}
+
+	var bin model.Bin
+	if err := db.Preload("Zone.Warehouse").Where("id = ? AND account_id = ?", *stock.BinID, accountID).First(&bin).Error; err != nil {
+		return err
+	}
+
+	stock.Location = bin.Label()
+	return nil
+}
+
+// GetExpiringStocks godoc
+// @Summary Get lots expiring soon
+// @Description Retrieve stock lots that expire within the given number of days, including lots that already expired
+// @Tags stocks
+// @Produce json
+// @Param days query int false "Number of days ahead to look (default 30)"
+// @Success 200 {object} model.ExpiringLotsResponse
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /stocks/expiring [get]
+func GetExpiringStocks(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+		if err != nil || days < 0 {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "days must be a non-negative number"})
+			return
+		}
+
+		now := time.Now()
+		var stocks []model.Stock
+		if err := db.Preload("Product.Units").
+			Where("account_id = ? AND quantity > 0 AND expires_at IS NOT NULL AND expires_at <= ?", accountID, now.AddDate(0, 0, days)).
+			Order("expires_at, id").Find(&stocks).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to retrieve expiring stocks"})
+			return
+		}
+
+		lots := []model.ExpiringLot{}
+		for _, stock := range stocks {
+			lots = append(lots, model.ExpiringLot{
+				StockID:         stock.ID,
+				ProductID:       stock.ProductID,
+				ProductName:     stock.Product.Name,
+				LotNumber:       stock.LotNumber,
+				Quantity:        int(stock.Quantity),
+				Location:        stock.Location,
+				ExpiresAt:       *stock.ExpiresAt,
+				DaysUntilExpiry: int(math.Floor(stock.ExpiresAt.Sub(now).Hours() / 24)),
+				Expired:         stock.IsExpired(now),
+			})
+		}
+
+		c.JSON(http.StatusOK, model.ExpiringLotsResponse{
+			Message: "Expiring stocks retrieved successfully",
+			Days:    days,
+			Lots:    lots,
 		})
 	}
 }
@@ -136,6 +489,7 @@ func GetStocks(db *gorm.DB) gin.HandlerFunc {
 // @Param id path int true "Stock ID"
 // @Success 200 {object} model.SuccessResponse
 // @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
 // @Failure 500 {object} model.ErrorResponse
 // @Router /stocks/{id} [delete]
 func SoftDeleteStock(db *gorm.DB) gin.HandlerFunc {
@@ -146,8 +500,28 @@ func SoftDeleteStock(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		stockID := c.Param("id")
-		if result := db.Where("id = ? AND account_id = ?", stockID, accountID).Delete(&model.Stock{}); result.Error != nil {
+		var stock model.Stock
+		if err := db.Where("id = ? AND account_id = ?", c.Param("id"), accountID).First(&stock).Error; err != nil {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Stock not found"})
+			return
+		}
+		if hasActiveReservations(db, stock.ID) {
+			c.JSON(http.StatusConflict, model.ErrorResponse{Error: "Stock has active reservations"})
+			return
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Delete(&stock).Error; err != nil {
+				return err
+			}
+			removed := stock.Quantity
+			stock.Quantity = 0
+			return utils.RecordStockMovement(tx, stock, -int(removed), model.StockMovement{
+				Reason: model.MovementDeleted,
+				UserID: utils.CurrentUserID(c),
+			})
+		})
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to delete stock"})
 			return
 		}
@@ -163,6 +537,8 @@ func SoftDeleteStock(db *gorm.DB) gin.HandlerFunc {
 // @Produce json
 // @Param id path int true "Stock ID"
 // @Success 200 {object} model.SuccessResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
 // @Failure 500 {object} model.ErrorResponse
 // @Router /stocks/{id}/hard [delete]
 func HardDeleteStock(db *gorm.DB) gin.HandlerFunc {
@@ -173,8 +549,35 @@ func HardDeleteStock(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		stockID := c.Param("id")
-		if result := db.Unscoped().Where("id = ? AND account_id = ?", stockID, accountID).Delete(&model.Stock{}); result.Error != nil {
+		var stock model.Stock
+		if err := db.Unscoped().Where("id = ? AND account_id = ?", c.Param("id"), accountID).First(&stock).Error; err != nil {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Stock not found"})
+			return
+		}
+		if hasActiveReservations(db, stock.ID) {
+			c.JSON(http.StatusConflict, model.ErrorResponse{Error: "Stock has active reservations"})
+			return
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Unscoped().Delete(&stock).Error; err != nil {
+				return err
+			}
+			if err := utils.RemoveStockSerials(tx, stock.ID, utils.CurrentUserID(c)); err != nil {
+				return err
+			}
+			// Soft-deleted stock was already written off in the ledger
+			if stock.DeletedAt.Valid {
+				return nil
+			}
+			removed := stock.Quantity
+			stock.Quantity = 0
+			return utils.RecordStockMovement(tx, stock, -int(removed), model.StockMovement{
+				Reason: model.MovementDeleted,
+				UserID: utils.CurrentUserID(c),
+			})
+		})
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to delete stock"})
 			return
 		}
@@ -202,7 +605,25 @@ func RecoverStock(db *gorm.DB) gin.HandlerFunc {
 		}
 
 		stockID := c.Param("id")
-		if result := db.Model(&model.Stock{}).Unscoped().Where("id = ? AND account_id = ?", stockID, accountID).Update("deleted_at", nil); result.Error != nil {
+		var stock model.Stock
+		if err := db.Unscoped().Where("id = ? AND account_id = ?", stockID, accountID).First(&stock).Error; err != nil {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Stock not found"})
+			return
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&model.Stock{}).Unscoped().Where("id = ?", stock.ID).Update("deleted_at", nil).Error; err != nil {
+				return err
+			}
+			if !stock.DeletedAt.Valid {
+				return nil
+			}
+			return utils.RecordStockMovement(tx, stock, int(stock.Quantity), model.StockMovement{
+				Reason: model.MovementRecovered,
+				UserID: utils.CurrentUserID(c),
+			})
+		})
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to recover stock"})
 			return
 		}
@@ -213,13 +634,15 @@ func RecoverStock(db *gorm.DB) gin.HandlerFunc {
 
 // CheckStock godoc
 // @Summary Check stock levels
-// @Description Check the stock levels for a product
+// @Description Check the stock level of a stock item. When it is at or below its low-stock threshold, the account's
+// @Description alert rules that cover it notify their recipients or queue it for the daily digest
 // @Tags inventory
 // @Produce json
-// @Param id path int true "Product ID"
-// @Success 200 {object} model.SuccessResponse
+// @Param id path int true "Stock ID"
+// @Success 200 {object} model.StockResponse
 // @Failure 404 {object} model.ErrorResponse
-// @Router /inventory/check/{id} [get]
+// @Failure 500 {object} model.ErrorResponse
+// @Router /stocks/check/{id} [get]
 func CheckStock(db *gorm.DB, ns *utils.NotificationService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		accountID, exists := c.Get("account_id")
@@ -236,13 +659,34 @@ func CheckStock(db *gorm.DB, ns *utils.NotificationService) gin.HandlerFunc {
 			return
 		}
 
-		if stock.Quantity < uint(stock.LowStockThreshold) {
-			// Send email notification
-			if err := ns.SendLowStockNotification("customer@example.com"); err != nil {
-				c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to send notification email"})
+		if stock.Available() <= uint(stock.LowStockThreshold) {
+			token, _ := utils.ExtractToken(c)
+			if _, err := utils.NotifyLowStock(db, ns, stock, token); err != nil {
+				log.Printf("Failed to notify low stock of stock %d: %v", stock.ID, err)
+				c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to send low stock alerts"})
 				return
 			}
 		}
-		c.JSON(http.StatusOK, model.StockResponse{Message: "Stock level checked", ID: stock.ID, ProductName: stock.Product.Name, Quantity: int(stock.Quantity), Location: stock.Location})
+		response := toStockResponse(stock)
+		response.Message = "Stock level checked"
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// hasActiveReservations reports whether open orders or transfers still hold quantity of
+// the stock row or are on their way to it
+func hasActiveReservations(db *gorm.DB, stockID uint) bool {
+	var count int64
+	db.Model(&model.StockReservation{}).Where("stock_id = ? AND status = ?", stockID, model.ReservationActive).Count(&count)
+	if count > 0 {
+		return true
 	}
+
+	db.Model(&model.TransferOrder{}).Where("(from_stock_id = ? OR to_stock_id = ?) AND status IN ?", stockID, stockID, model.OpenTransferStatuses).Count(&count)
+	return count > 0
+}
+
+// validLotDates checks that a lot does not expire before it was manufactured
+func validLotDates(stock model.Stock) bool {
+	return stock.ManufacturedAt == nil || stock.ExpiresAt == nil || stock.ExpiresAt.After(*stock.ManufacturedAt)
 }
@@ -0,0 +1,381 @@
+package handlers
+
+import (
+	"errors"
+	"inventory-management/internal/model"
+	"inventory-management/internal/utils"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetSupplierPrices godoc
+// @Summary Get a supplier's price list
+// @Description Retrieve the unit costs and quoted lead times of a supplier, optionally for one product or only the
+// @Description entries in effect at a given date
+// @Tags suppliers
+// @Produce json
+// @Param id path int true "Supplier ID"
+// @Param product_id query int false "Product ID"
+// @Param at query string false "Only entries in effect at this date (YYYY-MM-DD or RFC3339)"
+// @Success 200 {object} model.SupplierPricesResponse
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Router /suppliers/{id}/prices [get]
+func GetSupplierPrices(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		var supplier model.Supplier
+		if err := db.Where("id = ? AND account_id = ?", c.Param("id"), accountID).First(&supplier).Error; err != nil {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Supplier not found"})
+			return
+		}
+
+		query := db.Where("supplier_id = ?", supplier.ID)
+		if productID := c.Query("product_id"); productID != "" {
+			query = query.Where("product_id = ?", productID)
+		}
+		if at := c.Query("at"); at != "" {
+			atTime, err := parseDateParam(at, false)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid at date"})
+				return
+			}
+			query = query.Where("effective_from <= ? AND (effective_to IS NULL OR effective_to > ?)", atTime, atTime)
+		}
+
+		var prices []model.SupplierPrice
+		if err := query.Order("product_id, min_quantity, effective_from").Find(&prices).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to retrieve supplier prices"})
+			return
+		}
+
+		c.JSON(http.StatusOK, model.SupplierPricesResponse{
+			Message: "Supplier prices retrieved successfully",
+			Prices:  prices,
+		})
+	}
+}
+
+// CreateSupplierPrice godoc
+// @Summary Add a price list entry
+// @Description Add the unit cost and quoted lead time of a product from a supplier for a period. Entries for the same
+// @Description product and quantity break must not overlap. Requires manager permission
+// @Tags suppliers
+// @Accept json
+// @Produce json
+// @Param id path int true "Supplier ID"
+// @Param body body model.SupplierPrice true "Price list entry"
+// @Success 201 {object} model.SupplierPrice
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Router /suppliers/{id}/prices [post]
+func CreateSupplierPrice(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		if _, ok := requireManager(c); !ok {
+			return
+		}
+
+		var supplier model.Supplier
+		if err := db.Where("id = ? AND account_id = ?", c.Param("id"), accountID).First(&supplier).Error; err != nil {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Supplier not found"})
+			return
+		}
+
+		var price model.SupplierPrice
+		if err := c.ShouldBindJSON(&price); err != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid request data"})
+			return
+		}
+		price.ID = 0
+		price.SupplierID = supplier.ID
+		price.AccountID = supplier.AccountID
+
+		if !validSupplierPrice(c, db, &price) {
+			return
+		}
+		if err := db.Create(&price).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to create supplier price"})
+			return
+		}
+
+		c.JSON(http.StatusCreated, price)
+	}
+}
+
+// UpdateSupplierPrice godoc
+// @Summary Update a price list entry
+// @Description Change the cost, quantity break, lead time or period of a price list entry, e.g. to end it when a new
+// @Description price takes effect. Requires manager permission
+// @Tags suppliers
+// @Accept json
+// @Produce json
+// @Param id path int true "Supplier ID"
+// @Param price_id path int true "Price list entry ID"
+// @Param body body model.SupplierPrice true "Price list entry"
+// @Success 200 {object} model.SupplierPrice
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Router /suppliers/{id}/prices/{price_id} [put]
+func UpdateSupplierPrice(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		if _, ok := requireManager(c); !ok {
+			return
+		}
+
+		var price model.SupplierPrice
+		if err := db.Where("id = ? AND supplier_id = ? AND account_id = ?", c.Param("price_id"), c.Param("id"), accountID).
+			First(&price).Error; err != nil {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Supplier price not found"})
+			return
+		}
+
+		id, supplierID := price.ID, price.SupplierID
+		if err := c.ShouldBindJSON(&price); err != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid request data"})
+			return
+		}
+		price.ID = id
+		price.SupplierID = supplierID
+		price.AccountID = accountID.(uint)
+
+		if !validSupplierPrice(c, db, &price) {
+			return
+		}
+		if err := db.Save(&price).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to update supplier price"})
+			return
+		}
+
+		c.JSON(http.StatusOK, price)
+	}
+}
+
+// DeleteSupplierPrice godoc
+// @Summary Delete a price list entry
+// @Description Remove a price list entry. Requires manager permission
+// @Tags suppliers
+// @Produce json
+// @Param id path int true "Supplier ID"
+// @Param price_id path int true "Price list entry ID"
+// @Success 200 {object} model.SuccessResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Router /suppliers/{id}/prices/{price_id} [delete]
+func DeleteSupplierPrice(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		if _, ok := requireManager(c); !ok {
+			return
+		}
+
+		var price model.SupplierPrice
+		if err := db.Where("id = ? AND supplier_id = ? AND account_id = ?", c.Param("price_id"), c.Param("id"), accountID).
+			First(&price).Error; err != nil {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Supplier price not found"})
+			return
+		}
+
+		if err := db.Delete(&price).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to delete supplier price"})
+			return
+		}
+
+		c.JSON(http.StatusOK, model.SuccessResponse{Message: "Supplier price deleted successfully"})
+	}
+}
+
+// GetSupplierScorecards godoc
+// @Summary Compare supplier scorecards
+// @Description Score the account's suppliers on the purchase orders sent in a period: on-time delivery rate, fill rate
+// @Description and average lead time against the quoted lead time. With product_id only the suppliers with a price for
+// @Description the product are scored, together with their current unit cost and lead time for it
+// @Tags suppliers
+// @Produce json
+// @Param from query string false "Orders sent from (YYYY-MM-DD or RFC3339)"
+// @Param to query string false "Orders sent until (YYYY-MM-DD or RFC3339)"
+// @Param product_id query int false "Product ID"
+// @Success 200 {object} model.SupplierScorecardsResponse
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /suppliers/scorecards [get]
+func GetSupplierScorecards(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		from, to, ok := scorecardPeriod(c)
+		if !ok {
+			return
+		}
+
+		scorecards, err := utils.SupplierScorecards(db, accountID.(uint), nil, from, to)
+		if err != nil {
+			log.Printf("Failed to compute supplier scorecards: %v", err)
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to compute supplier scorecards"})
+			return
+		}
+
+		if productID := c.Query("product_id"); productID != "" {
+			id, err := strconv.ParseUint(productID, 10, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid product ID"})
+				return
+			}
+
+			priced := []model.SupplierScorecard{}
+			for _, scorecard := range scorecards {
+				price, err := utils.EffectiveSupplierPrice(db, scorecard.SupplierID, uint(id), 1, time.Now())
+				if err != nil {
+					c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to look up supplier price"})
+					return
+				}
+				if price == nil {
+					continue
+				}
+				leadTime := scorecard.QuotedLeadTimeDays
+				if price.LeadTimeDays > 0 {
+					leadTime = price.LeadTimeDays
+				}
+				scorecard.UnitCost = &price.UnitCost
+				scorecard.ProductLeadTimeDays = &leadTime
+				priced = append(priced, scorecard)
+			}
+			scorecards = priced
+		}
+
+		c.JSON(http.StatusOK, model.SupplierScorecardsResponse{
+			Message:    "Supplier scorecards computed successfully",
+			Scorecards: scorecards,
+		})
+	}
+}
+
+// GetSupplierScorecard godoc
+// @Summary Get a supplier scorecard
+// @Description Score a supplier on the purchase orders sent in a period: on-time delivery rate, fill rate and average
+// @Description lead time against the quoted lead time
+// @Tags suppliers
+// @Produce json
+// @Param id path int true "Supplier ID"
+// @Param from query string false "Orders sent from (YYYY-MM-DD or RFC3339)"
+// @Param to query string false "Orders sent until (YYYY-MM-DD or RFC3339)"
+// @Success 200 {object} model.SupplierScorecard
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Router /suppliers/{id}/scorecard [get]
+func GetSupplierScorecard(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		var supplier model.Supplier
+		if err := db.Where("id = ? AND account_id = ?", c.Param("id"), accountID).First(&supplier).Error; err != nil {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Supplier not found"})
+			return
+		}
+
+		from, to, ok := scorecardPeriod(c)
+		if !ok {
+			return
+		}
+
+		scorecards, err := utils.SupplierScorecards(db, supplier.AccountID, &supplier.ID, from, to)
+		if err != nil || len(scorecards) != 1 {
+			log.Printf("Failed to compute scorecard of supplier %d: %v", supplier.ID, err)
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to compute supplier scorecard"})
+			return
+		}
+
+		c.JSON(http.StatusOK, scorecards[0])
+	}
+}
+
+// validSupplierPrice checks the product and period of a price list entry. It writes the
+// error response and returns false when the entry cannot be saved.
+func validSupplierPrice(c *gin.Context, db *gorm.DB, price *model.SupplierPrice) bool {
+	if err := db.Where("id = ? AND account_id = ?", price.ProductID, price.AccountID).First(&model.Product{}).Error; err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Product not found"})
+		return false
+	}
+	if price.UnitCost < 0 {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Unit cost must not be negative"})
+		return false
+	}
+	if price.EffectiveFrom.IsZero() {
+		price.EffectiveFrom = time.Now()
+	}
+
+	err := utils.ValidateSupplierPrice(db, *price)
+	switch {
+	case errors.Is(err, utils.ErrInvalidPricePeriod):
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: err.Error()})
+		return false
+	case errors.Is(err, utils.ErrOverlappingPrice):
+		c.JSON(http.StatusConflict, model.ErrorResponse{Error: err.Error()})
+		return false
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to validate supplier price"})
+		return false
+	}
+	return true
+}
+
+// scorecardPeriod reads the optional from and to dates of a scorecard request. It writes
+// a 400 and returns false when a date is malformed.
+func scorecardPeriod(c *gin.Context) (*time.Time, *time.Time, bool) {
+	var from, to *time.Time
+	if value := c.Query("from"); value != "" {
+		t, err := parseDateParam(value, false)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid from date"})
+			return nil, nil, false
+		}
+		from = &t
+	}
+	if value := c.Query("to"); value != "" {
+		t, err := parseDateParam(value, true)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid to date"})
+			return nil, nil, false
+		}
+		to = &t
+	}
+	return from, to, true
+}
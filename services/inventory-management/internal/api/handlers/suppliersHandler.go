@@ -109,11 +109,12 @@ func GetSuppliers(db *gorm.DB) gin.HandlerFunc {
 		var supplierResponses []model.SupplierResponse
 		for _, supplier := range suppliers {
 			supplierResponses = append(supplierResponses, model.SupplierResponse{
-				ID:          supplier.ID,
-				Name:        supplier.Name,
-				Description: supplier.Description,
-				Email:       supplier.Email,
-				Contact:     supplier.Contact,
+				ID:           supplier.ID,
+				Name:         supplier.Name,
+				Description:  supplier.Description,
+				Email:        supplier.Email,
+				Contact:      supplier.Contact,
+				LeadTimeDays: supplier.LeadTimeDays,
 			})
 		}
 
@@ -169,7 +170,7 @@ func SoftDeleteSupplier(db *gorm.DB) gin.HandlerFunc {
 
 // HardDeleteSupplier godoc
 // @Summary Hard delete a supplier
-// @Description Hard deletes a supplier by ID and sets the supplier field in related products to null
+// @Description Hard deletes a supplier by ID with its price list and sets the supplier field in related products to null
 // @Tags suppliers
 // @Produce json
 // @Param id path int true "Supplier ID"
@@ -199,6 +200,12 @@ func HardDeleteSupplier(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
+		// Drop the supplier's price list
+		if err := db.Where("supplier_id = ? AND account_id = ?", supplierID, accountID).Delete(&model.SupplierPrice{}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to delete supplier prices"})
+			return
+		}
+
 		// Hard delete the supplier
 		if err := db.Unscoped().Delete(&supplier).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to delete supplier"})
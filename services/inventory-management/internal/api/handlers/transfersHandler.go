@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"errors"
+	"inventory-management/internal/model"
+	"inventory-management/internal/utils"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CreateTransfer godoc
+// @Summary Create a transfer order
+// @Description Move quantity from a stock row to another stock row of the same product, or to a bin in any
+// @Description of the account's warehouses. The quantity is held at the source until it is picked
+// @Tags transfers
+// @Accept json
+// @Produce json
+// @Param body body model.TransferOrder true "Transfer order"
+// @Success 200 {object} model.TransferOrder
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Router /transfers [post]
+func CreateTransfer(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		var transfer model.TransferOrder
+		if err := c.ShouldBindJSON(&transfer); err != nil || transfer.Quantity == 0 || (transfer.ToStockID == nil) == (transfer.ToBinID == nil) {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid request data"})
+			return
+		}
+
+		var source model.Stock
+		if err := db.Where("id = ? AND account_id = ?", transfer.FromStockID, accountID).First(&source).Error; err != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Source stock not found"})
+			return
+		}
+
+		if transfer.ToStockID != nil {
+			var destination model.Stock
+			if err := db.Where("id = ? AND account_id = ?", *transfer.ToStockID, accountID).First(&destination).Error; err != nil {
+				c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Destination stock not found"})
+				return
+			}
+			if destination.ID == source.ID || destination.ProductID != source.ProductID {
+				c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Destination must be another stock row of the same product"})
+				return
+			}
+		} else {
+			if err := db.Where("id = ? AND account_id = ?", *transfer.ToBinID, accountID).First(&model.Bin{}).Error; err != nil {
+				c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Bin not found"})
+				return
+			}
+			if source.BinID != nil && *source.BinID == *transfer.ToBinID {
+				c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Destination bin must differ from the source bin"})
+				return
+			}
+		}
+
+		transfer.ID = 0
+		transfer.ReceivedQuantity = 0
+		transfer.AccountID = accountID.(uint)
+		transfer.CreatedBy = utils.CurrentUserID(c)
+		err := db.Transaction(func(tx *gorm.DB) error {
+			return utils.CreateTransfer(tx, &transfer)
+		})
+		if !writeTransferError(c, err) {
+			return
+		}
+
+		c.JSON(http.StatusOK, transfer)
+	}
+}
+
+// GetTransfers godoc
+// @Summary Get transfer orders
+// @Description Retrieve transfer orders, optionally filtered by status, product or stock row
+// @Tags transfers
+// @Produce json
+// @Param status query string false "Transfer status"
+// @Param product_id query int false "Product ID"
+// @Param stock_id query int false "Source or destination stock ID"
+// @Success 200 {object} model.TransfersResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /transfers [get]
+func GetTransfers(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		query := db.Where("account_id = ?", accountID)
+		if status := c.Query("status"); status != "" {
+			query = query.Where("status = ?", status)
+		}
+		if productID := c.Query("product_id"); productID != "" {
+			query = query.Where("product_id = ?", productID)
+		}
+		if stockID := c.Query("stock_id"); stockID != "" {
+			query = query.Where("from_stock_id = ? OR to_stock_id = ?", stockID, stockID)
+		}
+
+		var transfers []model.TransferOrder
+		if err := query.Order("id").Find(&transfers).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to retrieve transfers"})
+			return
+		}
+
+		c.JSON(http.StatusOK, model.TransfersResponse{
+			Message:   "Transfers retrieved successfully",
+			Transfers: transfers,
+		})
+	}
+}
+
+// PickTransfer godoc
+// @Summary Pick a transfer
+// @Description Take the transfer quantity out of the source stock row
+// @Tags transfers
+// @Produce json
+// @Param id path int true "Transfer ID"
+// @Success 200 {object} model.TransferOrder
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Router /transfers/{id}/pick [post]
+func PickTransfer(db *gorm.DB) gin.HandlerFunc {
+	return transferAction(db, func(c *gin.Context, tx *gorm.DB, transfer *model.TransferOrder) error {
+		return utils.PickTransfer(tx, transfer, utils.CurrentUserID(c))
+	})
+}
+
+// DispatchTransfer godoc
+// @Summary Dispatch a transfer
+// @Description Mark a picked transfer as in transit
+// @Tags transfers
+// @Produce json
+// @Param id path int true "Transfer ID"
+// @Success 200 {object} model.TransferOrder
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Router /transfers/{id}/dispatch [post]
+func DispatchTransfer(db *gorm.DB) gin.HandlerFunc {
+	return transferAction(db, func(c *gin.Context, tx *gorm.DB, transfer *model.TransferOrder) error {
+		return utils.DispatchTransfer(tx, transfer)
+	})
+}
+
+// ReceiveTransfer godoc
+// @Summary Receive a transfer
+// @Description Book all or part of the quantity in transit into the destination
+// @Tags transfers
+// @Accept json
+// @Produce json
+// @Param id path int true "Transfer ID"
+// @Param body body model.ReceiveTransferRequest true "Received quantity"
+// @Success 200 {object} model.TransferOrder
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Router /transfers/{id}/receive [post]
+func ReceiveTransfer(db *gorm.DB) gin.HandlerFunc {
+	return transferAction(db, func(c *gin.Context, tx *gorm.DB, transfer *model.TransferOrder) error {
+		var request model.ReceiveTransferRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			return errInvalidRequest
+		}
+		return utils.ReceiveTransfer(tx, transfer, request.Quantity, request.SerialNumbers, utils.CurrentUserID(c))
+	})
+}
+
+// CancelTransfer godoc
+// @Summary Cancel a transfer
+// @Description Cancel a pending or picked transfer and return its quantity to the source stock row
+// @Tags transfers
+// @Produce json
+// @Param id path int true "Transfer ID"
+// @Success 200 {object} model.TransferOrder
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Router /transfers/{id}/cancel [post]
+func CancelTransfer(db *gorm.DB) gin.HandlerFunc {
+	return transferAction(db, func(c *gin.Context, tx *gorm.DB, transfer *model.TransferOrder) error {
+		return utils.CancelTransfer(tx, transfer, utils.CurrentUserID(c))
+	})
+}
+
+// CloseTransfer godoc
+// @Summary Close a transfer short
+// @Description Close a transfer whose remaining in-transit quantity will not arrive
+// @Tags transfers
+// @Produce json
+// @Param id path int true "Transfer ID"
+// @Success 200 {object} model.TransferOrder
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Router /transfers/{id}/close [post]
+func CloseTransfer(db *gorm.DB) gin.HandlerFunc {
+	return transferAction(db, func(c *gin.Context, tx *gorm.DB, transfer *model.TransferOrder) error {
+		return utils.CloseTransfer(tx, transfer, utils.CurrentUserID(c))
+	})
+}
+
+var errInvalidRequest = errors.New("invalid request data")
+
+// transferAction loads the account's transfer from the path and runs a state change on it
+// inside a transaction
+func transferAction(db *gorm.DB, action func(c *gin.Context, tx *gorm.DB, transfer *model.TransferOrder) error) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		var transfer model.TransferOrder
+		if err := db.Where("id = ? AND account_id = ?", c.Param("id"), accountID).First(&transfer).Error; err != nil {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Transfer not found"})
+			return
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			return action(c, tx, &transfer)
+		})
+		if !writeTransferError(c, err) {
+			return
+		}
+
+		c.JSON(http.StatusOK, transfer)
+	}
+}
+
+// writeTransferError maps transfer errors to responses and reports whether err was nil
+func writeTransferError(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, errInvalidRequest):
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid request data"})
+	case errors.Is(err, utils.ErrSerialCountMismatch), errors.Is(err, utils.ErrSerialNotFound), errors.Is(err, utils.ErrReceiveExceedsTransfer):
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: err.Error()})
+	case errors.Is(err, utils.ErrInsufficientStock), errors.Is(err, utils.ErrInvalidTransferState):
+		c.JSON(http.StatusConflict, model.ErrorResponse{Error: err.Error()})
+	default:
+		log.Printf("Failed to process transfer: %v", err)
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to process transfer"})
+	}
+	return false
+}
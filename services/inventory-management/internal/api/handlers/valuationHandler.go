@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"inventory-management/internal/model"
+	"inventory-management/internal/utils"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetValuation godoc
+// @Summary Inventory valuation report
+// @Description Report the on-hand quantity and value of the account's stock by product, category or warehouse as of a
+// @Description date, costed with the account's FIFO or weighted-average method. format=csv returns a spreadsheet
+// @Tags valuation
+// @Produce json
+// @Produce text/csv
+// @Param group_by query string false "product (default), category or warehouse"
+// @Param as_of query string false "Value stock as of this date (YYYY-MM-DD or RFC3339), default now"
+// @Param format query string false "json (default) or csv"
+// @Success 200 {object} model.ValuationReport
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /valuation [get]
+func GetValuation(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		groupBy := c.DefaultQuery("group_by", "product")
+		if !slices.Contains(utils.ValuationGroups, groupBy) {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "group_by must be product, category or warehouse"})
+			return
+		}
+		format := c.DefaultQuery("format", "json")
+		if format != "csv" && format != "json" {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "format must be csv or json"})
+			return
+		}
+
+		asOf := time.Now()
+		if value := c.Query("as_of"); value != "" {
+			t, err := parseDateParam(value, true)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid as_of date"})
+				return
+			}
+			asOf = t
+		}
+
+		method, err := utils.AccountCostingMethod(db, accountID.(uint))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to retrieve costing method"})
+			return
+		}
+		lines, err := utils.Valuation(db, accountID.(uint), asOf, groupBy)
+		if err != nil {
+			log.Printf("Failed to value inventory: %v", err)
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to value inventory"})
+			return
+		}
+
+		report := model.ValuationReport{
+			Message: "Inventory valuation computed successfully",
+			AsOf:    asOf,
+			GroupBy: groupBy,
+			Method:  method,
+			Lines:   lines,
+		}
+		for _, line := range lines {
+			report.TotalQuantity += line.Quantity
+			report.TotalValue += line.Value
+		}
+
+		if format == "json" {
+			c.JSON(http.StatusOK, report)
+			return
+		}
+
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=valuation-%s-%s.csv", groupBy, asOf.Format("2006-01-02")))
+		c.Header("Content-Type", "text/csv")
+		writer := csv.NewWriter(c.Writer)
+		writer.Write([]string{groupBy + "_id", "name", "quantity", "unit_cost", "value"})
+		for _, line := range lines {
+			writer.Write([]string{
+				strconv.FormatUint(uint64(line.ID), 10), line.Name, strconv.Itoa(line.Quantity),
+				strconv.FormatFloat(line.UnitCost, 'f', 4, 64), strconv.FormatFloat(line.Value, 'f', 2, 64),
+			})
+		}
+		writer.Write([]string{"", "Total", strconv.Itoa(report.TotalQuantity), "", strconv.FormatFloat(report.TotalValue, 'f', 2, 64)})
+		writer.Flush()
+	}
+}
+
+// GetCOGS godoc
+// @Summary Cost of goods sold per order
+// @Description Retrieve the cost of the stock shipped for each order, for one order or the shipments in a period
+// @Tags valuation
+// @Produce json
+// @Param order_id query int false "Order ID"
+// @Param from query string false "Shipped from (YYYY-MM-DD or RFC3339)"
+// @Param to query string false "Shipped until (YYYY-MM-DD or RFC3339)"
+// @Success 200 {object} model.COGSResponse
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /valuation/cogs [get]
+func GetCOGS(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		var orderID *uint
+		if value := c.Query("order_id"); value != "" {
+			id, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid order ID"})
+				return
+			}
+			order := uint(id)
+			orderID = &order
+		}
+		from, to, ok := scorecardPeriod(c)
+		if !ok {
+			return
+		}
+
+		orders, err := utils.OrderCOGS(db, accountID.(uint), orderID, from, to)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to retrieve cost of goods sold"})
+			return
+		}
+
+		response := model.COGSResponse{Message: "Cost of goods sold retrieved successfully", Orders: orders}
+		for _, order := range orders {
+			response.Total += order.Amount
+		}
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// GetCostLayers godoc
+// @Summary Get cost layers
+// @Description Retrieve the cost layers stock was received in, e.g. the open layers of a product under FIFO
+// @Tags valuation
+// @Produce json
+// @Param product_id query int false "Product ID"
+// @Param open query bool false "Only layers with remaining quantity"
+// @Success 200 {object} model.CostLayersResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /valuation/layers [get]
+func GetCostLayers(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		query := db.Where("account_id = ?", accountID)
+		if productID := c.Query("product_id"); productID != "" {
+			query = query.Where("product_id = ?", productID)
+		}
+		if c.Query("open") == "true" {
+			query = query.Where("remaining > 0")
+		}
+
+		var layers []model.CostLayer
+		if err := query.Order("created_at, id").Find(&layers).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to retrieve cost layers"})
+			return
+		}
+
+		c.JSON(http.StatusOK, model.CostLayersResponse{Message: "Cost layers retrieved successfully", Layers: layers})
+	}
+}
@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"inventory-management/internal/model"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CreateWarehouse godoc
+// @Summary Create a new warehouse
+// @Description Create a new warehouse for the account
+// @Tags warehouses
+// @Accept json
+// @Produce json
+// @Param body body model.Warehouse true "Warehouse data"
+// @Success 200 {object} model.Warehouse
+// @Failure 400 {object} model.ErrorResponse
+// @Router /warehouses [post]
+func CreateWarehouse(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		var warehouse model.Warehouse
+		if err := c.ShouldBindJSON(&warehouse); err != nil || warehouse.Name == "" {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid request data"})
+			return
+		}
+
+		warehouse.AccountID = accountID.(uint)
+		warehouse.Zones = nil
+		if err := db.Create(&warehouse).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to create warehouse"})
+			return
+		}
+
+		c.JSON(http.StatusOK, warehouse)
+	}
+}
+
+// GetWarehouses godoc
+// @Summary Get all warehouses
+// @Description Retrieve all warehouses of the account together with their zones and bins
+// @Tags warehouses
+// @Produce json
+// @Success 200 {object} model.WarehousesResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /warehouses [get]
+func GetWarehouses(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		var warehouses []model.Warehouse
+		if err := db.Preload("Zones.Bins").Where("account_id = ?", accountID).Find(&warehouses).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to retrieve warehouses"})
+			return
+		}
+
+		c.JSON(http.StatusOK, model.WarehousesResponse{
+			Message:    "Warehouses retrieved successfully",
+			Warehouses: warehouses,
+		})
+	}
+}
+
+// UpdateWarehouse godoc
+// @Summary Update a warehouse
+// @Description Update a warehouse by ID
+// @Tags warehouses
+// @Accept json
+// @Produce json
+// @Param id path int true "Warehouse ID"
+// @Param body body model.Warehouse true "Warehouse data"
+// @Success 200 {object} model.Warehouse
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Router /warehouses/{id} [put]
+func UpdateWarehouse(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		var warehouse model.Warehouse
+		if err := db.Where("id = ? AND account_id = ?", c.Param("id"), accountID).First(&warehouse).Error; err != nil {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Warehouse not found"})
+			return
+		}
+
+		if err := c.ShouldBindJSON(&warehouse); err != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid request data"})
+			return
+		}
+
+		warehouse.AccountID = accountID.(uint)
+		warehouse.Zones = nil
+		if err := db.Save(&warehouse).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to update warehouse"})
+			return
+		}
+
+		c.JSON(http.StatusOK, warehouse)
+	}
+}
+
+// SoftDeleteWarehouse godoc
+// @Summary Soft delete a warehouse
+// @Description Soft delete a warehouse by ID. Warehouses that still have zones cannot be deleted
+// @Tags warehouses
+// @Produce json
+// @Param id path int true "Warehouse ID"
+// @Success 200 {object} model.SuccessResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Router /warehouses/{id} [delete]
+func SoftDeleteWarehouse(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		var warehouse model.Warehouse
+		if err := db.Where("id = ? AND account_id = ?", c.Param("id"), accountID).First(&warehouse).Error; err != nil {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Warehouse not found"})
+			return
+		}
+
+		var zoneCount int64
+		db.Model(&model.Zone{}).Where("warehouse_id = ? AND account_id = ?", warehouse.ID, accountID).Count(&zoneCount)
+		if zoneCount > 0 {
+			c.JSON(http.StatusConflict, model.ErrorResponse{Error: "Warehouse still has zones"})
+			return
+		}
+
+		if err := db.Delete(&warehouse).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to delete warehouse"})
+			return
+		}
+
+		c.JSON(http.StatusOK, model.SuccessResponse{Message: "Warehouse deleted successfully"})
+	}
+}
+
+// HardDeleteWarehouse godoc
+// @Summary Hard delete a warehouse
+// @Description Permanently delete a warehouse by ID. Warehouses that still have zones cannot be deleted
+// @Tags warehouses
+// @Produce json
+// @Param id path int true "Warehouse ID"
+// @Success 200 {object} model.SuccessResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Router /warehouses/hard/{id} [delete]
+func HardDeleteWarehouse(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		var warehouse model.Warehouse
+		if err := db.Unscoped().Where("id = ? AND account_id = ?", c.Param("id"), accountID).First(&warehouse).Error; err != nil {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Warehouse not found"})
+			return
+		}
+
+		var zoneCount int64
+		db.Unscoped().Model(&model.Zone{}).Where("warehouse_id = ? AND account_id = ?", warehouse.ID, accountID).Count(&zoneCount)
+		if zoneCount > 0 {
+			c.JSON(http.StatusConflict, model.ErrorResponse{Error: "Warehouse still has zones"})
+			return
+		}
+
+		if err := db.Unscoped().Delete(&warehouse).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to delete warehouse"})
+			return
+		}
+
+		c.JSON(http.StatusOK, model.SuccessResponse{Message: "Warehouse deleted permanently"})
+	}
+}
+
+// RecoverWarehouse godoc
+// @Summary Recover a deleted warehouse
+// @Description Recover a soft-deleted warehouse by ID
+// @Tags warehouses
+// @Produce json
+// @Param id path int true "Warehouse ID"
+// @Success 200 {object} model.SuccessResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /warehouses/{id}/recover [patch]
+func RecoverWarehouse(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		if err := db.Unscoped().Model(&model.Warehouse{}).Where("id = ? AND account_id = ?", c.Param("id"), accountID).Update("deleted_at", nil).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to recover warehouse"})
+			return
+		}
+
+		c.JSON(http.StatusOK, model.SuccessResponse{Message: "Warehouse recovered successfully"})
+	}
+}
@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"inventory-management/internal/model"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CreateZone godoc
+// @Summary Create a new zone
+// @Description Create a new zone inside one of the account's warehouses
+// @Tags zones
+// @Accept json
+// @Produce json
+// @Param body body model.Zone true "Zone data"
+// @Success 200 {object} model.Zone
+// @Failure 400 {object} model.ErrorResponse
+// @Router /zones [post]
+func CreateZone(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		var zone model.Zone
+		if err := c.ShouldBindJSON(&zone); err != nil || zone.Name == "" {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid request data"})
+			return
+		}
+
+		if err := db.Where("id = ? AND account_id = ?", zone.WarehouseID, accountID).First(&model.Warehouse{}).Error; err != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Warehouse not found"})
+			return
+		}
+
+		zone.AccountID = accountID.(uint)
+		zone.Warehouse = nil
+		zone.Bins = nil
+		if err := db.Create(&zone).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to create zone"})
+			return
+		}
+
+		c.JSON(http.StatusOK, zone)
+	}
+}
+
+// GetZones godoc
+// @Summary Get all zones
+// @Description Retrieve all zones of the account, optionally filtered by warehouse
+// @Tags zones
+// @Produce json
+// @Param warehouse_id query int false "Warehouse ID"
+// @Success 200 {object} model.ZonesResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /zones [get]
+func GetZones(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		query := db.Preload("Bins").Where("account_id = ?", accountID)
+		if warehouseID := c.Query("warehouse_id"); warehouseID != "" {
+			query = query.Where("warehouse_id = ?", warehouseID)
+		}
+
+		var zones []model.Zone
+		if err := query.Find(&zones).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to retrieve zones"})
+			return
+		}
+
+		c.JSON(http.StatusOK, model.ZonesResponse{
+			Message: "Zones retrieved successfully",
+			Zones:   zones,
+		})
+	}
+}
+
+// UpdateZone godoc
+// @Summary Update a zone
+// @Description Update a zone by ID
+// @Tags zones
+// @Accept json
+// @Produce json
+// @Param id path int true "Zone ID"
+// @Param body body model.Zone true "Zone data"
+// @Success 200 {object} model.Zone
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Router /zones/{id} [put]
+func UpdateZone(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		var zone model.Zone
+		if err := db.Where("id = ? AND account_id = ?", c.Param("id"), accountID).First(&zone).Error; err != nil {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Zone not found"})
+			return
+		}
+
+		if err := c.ShouldBindJSON(&zone); err != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid request data"})
+			return
+		}
+
+		if err := db.Where("id = ? AND account_id = ?", zone.WarehouseID, accountID).First(&model.Warehouse{}).Error; err != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Warehouse not found"})
+			return
+		}
+
+		zone.AccountID = accountID.(uint)
+		zone.Warehouse = nil
+		zone.Bins = nil
+		if err := db.Save(&zone).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to update zone"})
+			return
+		}
+
+		c.JSON(http.StatusOK, zone)
+	}
+}
+
+// SoftDeleteZone godoc
+// @Summary Soft delete a zone
+// @Description Soft delete a zone by ID. Zones that still have bins cannot be deleted
+// @Tags zones
+// @Produce json
+// @Param id path int true "Zone ID"
+// @Success 200 {object} model.SuccessResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Router /zones/{id} [delete]
+func SoftDeleteZone(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		var zone model.Zone
+		if err := db.Where("id = ? AND account_id = ?", c.Param("id"), accountID).First(&zone).Error; err != nil {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Zone not found"})
+			return
+		}
+
+		var binCount int64
+		db.Model(&model.Bin{}).Where("zone_id = ? AND account_id = ?", zone.ID, accountID).Count(&binCount)
+		if binCount > 0 {
+			c.JSON(http.StatusConflict, model.ErrorResponse{Error: "Zone still has bins"})
+			return
+		}
+
+		if err := db.Delete(&zone).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to delete zone"})
+			return
+		}
+
+		c.JSON(http.StatusOK, model.SuccessResponse{Message: "Zone deleted successfully"})
+	}
+}
+
+// HardDeleteZone godoc
+// @Summary Hard delete a zone
+// @Description Permanently delete a zone by ID. Zones that still have bins cannot be deleted
+// @Tags zones
+// @Produce json
+// @Param id path int true "Zone ID"
+// @Success 200 {object} model.SuccessResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Router /zones/hard/{id} [delete]
+func HardDeleteZone(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		var zone model.Zone
+		if err := db.Unscoped().Where("id = ? AND account_id = ?", c.Param("id"), accountID).First(&zone).Error; err != nil {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Zone not found"})
+			return
+		}
+
+		var binCount int64
+		db.Unscoped().Model(&model.Bin{}).Where("zone_id = ? AND account_id = ?", zone.ID, accountID).Count(&binCount)
+		if binCount > 0 {
+			c.JSON(http.StatusConflict, model.ErrorResponse{Error: "Zone still has bins"})
+			return
+		}
+
+		if err := db.Unscoped().Delete(&zone).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to delete zone"})
+			return
+		}
+
+		c.JSON(http.StatusOK, model.SuccessResponse{Message: "Zone deleted permanently"})
+	}
+}
+
+// RecoverZone godoc
+// @Summary Recover a deleted zone
+// @Description Recover a soft-deleted zone by ID
+// @Tags zones
+// @Produce json
+// @Param id path int true "Zone ID"
+// @Success 200 {object} model.SuccessResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /zones/{id}/recover [patch]
+func RecoverZone(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		if err := db.Unscoped().Model(&model.Zone{}).Where("id = ? AND account_id = ?", c.Param("id"), accountID).Update("deleted_at", nil).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to recover zone"})
+			return
+		}
+
+		c.JSON(http.StatusOK, model.SuccessResponse{Message: "Zone recovered successfully"})
+	}
+}
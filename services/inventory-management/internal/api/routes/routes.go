@@ -3,13 +3,14 @@ package routes
 import (
 	"inventory-management/internal/api/handlers"
 	"inventory-management/internal/middleware"
+	"inventory-management/internal/storage"
 	"inventory-management/internal/utils"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
-func Routers(r *gin.Engine, db *gorm.DB, ns *utils.NotificationService) {
+func Routers(r *gin.Engine, db *gorm.DB, ns *utils.NotificationService, store storage.Storage) {
 
 	r.Use(middleware.AuthMiddleware(db))
 	r.Use(middleware.CORSMiddleware())
@@ -17,14 +18,38 @@ func Routers(r *gin.Engine, db *gorm.DB, ns *utils.NotificationService) {
 	products := r.Group("/products")
 	products.POST("", handlers.CreateProduct(db))
 	products.GET("", handlers.GetProducts(db))
+	products.GET("/lookup", handlers.LookupProduct(db))
+	products.GET("/search", handlers.SearchProducts(db))
+	products.GET("/classes", handlers.GetProductClasses(db))
+	products.POST("/classify", handlers.ClassifyProducts(db))
 	products.PUT("/:id", handlers.UpdateProduct(db))
 	products.DELETE("/:id", handlers.SoftDeleteProduct(db))
 	products.DELETE("hard/:id", handlers.HardDeleteProduct(db))
 	products.PATCH("/:id/recover", handlers.RecoverProduct(db))
+	products.GET("/:id/units", handlers.GetProductUnits(db))
+	products.POST("/:id/units", handlers.CreateProductUnit(db))
+	products.PUT("/:id/units/:unit_id", handlers.UpdateProductUnit(db))
+	products.DELETE("/:id/units/:unit_id", handlers.DeleteProductUnit(db))
+	products.GET("/:id/barcodes", handlers.GetProductBarcodes(db))
+	products.POST("/:id/barcodes", handlers.CreateProductBarcode(db))
+	products.DELETE("/:id/barcodes/:barcode_id", handlers.DeleteProductBarcode(db))
+	products.GET("/:id/thresholds", handlers.GetProductThresholds(db))
+	products.PUT("/:id/thresholds", handlers.UpdateProductThresholds(db))
+	products.GET("/:id/components", handlers.GetKitComponents(db))
+	products.PUT("/:id/components", handlers.SetKitComponents(db))
+	products.GET("/:id/attachments", handlers.GetProductAttachments(db))
+	products.POST("/:id/attachments", handlers.UploadAttachment(db, store))
+
+	attachments := r.Group("/attachments")
+	attachments.GET("/usage", handlers.GetAttachmentUsage(db))
+	attachments.GET("/:id/download", handlers.DownloadAttachment(db, store))
+	attachments.DELETE("/:id", handlers.DeleteAttachment(db, store))
 
 	categories := r.Group("/categories")
 	categories.POST("", handlers.CreateCategory(db))
 	categories.GET("", handlers.GetCategories(db))
+	categories.GET("/tree", handlers.GetCategoryTree(db))
+	categories.POST("/:id/move", handlers.MoveCategory(db))
 	categories.PUT("/:id", handlers.UpdateCategory(db))
 	categories.DELETE("/:id", handlers.SoftDeleteCategory(db))
 	categories.DELETE("/hard/:id", handlers.HardDeleteCategory(db))
@@ -33,17 +58,134 @@ func Routers(r *gin.Engine, db *gorm.DB, ns *utils.NotificationService) {
 	stocks := r.Group("/stocks")
 	stocks.POST("", handlers.CreateStock(db))
 	stocks.GET("", handlers.GetStocks(db))
+	stocks.GET("/expiring", handlers.GetExpiringStocks(db))
+	stocks.GET("/held", handlers.GetHeldStock(db))
+	stocks.GET("/status-changes", handlers.GetStockStatusChanges(db))
+	stocks.POST("/:id/status", handlers.ChangeStockStatus(db))
 	stocks.PUT("/:id", handlers.UpdateStock(db))
 	stocks.DELETE("/:id", handlers.SoftDeleteStock(db))
 	stocks.DELETE("/hard/:id", handlers.HardDeleteStock(db))
 	stocks.PATCH("/:id/recover", handlers.RecoverStock(db))
 	stocks.GET("/check/:id", handlers.CheckStock(db, ns))
 
+	stockMovements := r.Group("/stock-movements")
+	stockMovements.GET("", handlers.GetStockMovements(db))
+	stockMovements.GET("/verify", handlers.VerifyStockLedger(db))
+
+	stockSnapshots := r.Group("/stock-snapshots")
+	stockSnapshots.POST("", handlers.TakeStockSnapshot(db))
+	stockSnapshots.GET("", handlers.GetStockSnapshots(db))
+	stockSnapshots.GET("/as-of", handlers.GetStockAsOf(db))
+	stockSnapshots.GET("/:id/export", handlers.ExportStockSnapshot(db))
+
+	serials := r.Group("/serials")
+	serials.GET("", handlers.GetSerialNumbers(db))
+	serials.POST("/move", handlers.MoveSerialNumbers(db))
+	serials.GET("/:serial/history", handlers.GetSerialHistory(db))
+
+	transfers := r.Group("/transfers")
+	transfers.POST("", handlers.CreateTransfer(db))
+	transfers.GET("", handlers.GetTransfers(db))
+	transfers.POST("/:id/pick", handlers.PickTransfer(db))
+	transfers.POST("/:id/dispatch", handlers.DispatchTransfer(db))
+	transfers.POST("/:id/receive", handlers.ReceiveTransfer(db))
+	transfers.POST("/:id/cancel", handlers.CancelTransfer(db))
+	transfers.POST("/:id/close", handlers.CloseTransfer(db))
+
+	slotting := r.Group("/slotting")
+	slotting.POST("/recommendations", handlers.GenerateSlottingRecommendations(db))
+	slotting.GET("/recommendations", handlers.GetSlottingRecommendations(db))
+	slotting.POST("/recommendations/:id/accept", handlers.AcceptSlottingRecommendation(db))
+	slotting.POST("/recommendations/:id/dismiss", handlers.DismissSlottingRecommendation(db))
+
+	cycleCounts := r.Group("/cycle-counts")
+	cycleCounts.POST("", handlers.CreateCycleCountPlan(db))
+	cycleCounts.GET("", handlers.GetCycleCountPlans(db))
+	cycleCounts.GET("/tasks", handlers.GetCycleCountTasks(db))
+	cycleCounts.POST("/tasks/:id/count", handlers.SubmitCycleCount(db))
+	cycleCounts.POST("/tasks/:id/approve", handlers.ApproveCycleCount(db))
+	cycleCounts.POST("/tasks/:id/reject", handlers.RejectCycleCount(db))
+
+	r.POST("/imports/:entity", handlers.ImportRecords(db))
+	r.GET("/exports/:entity", handlers.ExportRecords(db))
+
+	purchaseOrders := r.Group("/purchase-orders")
+	purchaseOrders.GET("", handlers.GetPurchaseOrders(db))
+	purchaseOrders.POST("", handlers.CreatePurchaseOrder(db))
+	purchaseOrders.POST("/replenish", handlers.GenerateReplenishmentOrders(db))
+	purchaseOrders.GET("/:id", handlers.GetPurchaseOrder(db))
+	purchaseOrders.PUT("/:id", handlers.UpdatePurchaseOrder(db))
+	purchaseOrders.DELETE("/:id", handlers.DeletePurchaseOrder(db))
+	purchaseOrders.POST("/:id/send", handlers.SendPurchaseOrder(db, ns))
+	purchaseOrders.POST("/:id/close", handlers.ClosePurchaseOrder(db))
+	purchaseOrders.GET("/:id/receipts", handlers.GetPurchaseOrderReceipts(db))
+
+	alertRules := r.Group("/alert-rules")
+	alertRules.POST("", handlers.CreateAlertRule(db))
+	alertRules.GET("", handlers.GetAlertRules(db))
+	alertRules.PUT("/:id", handlers.UpdateAlertRule(db))
+	alertRules.DELETE("/:id", handlers.DeleteAlertRule(db))
+	alertRules.GET("/:id/recipients", handlers.GetAlertRuleRecipients(db))
+	r.GET("/low-stock-alerts", handlers.GetLowStockAlerts(db))
+
+	stockAdjustments := r.Group("/stock-adjustments")
+	stockAdjustments.POST("", handlers.CreateStockAdjustment(db))
+	stockAdjustments.GET("", handlers.GetStockAdjustments(db))
+	stockAdjustments.POST("/:id/approve", handlers.ApproveStockAdjustment(db))
+	stockAdjustments.POST("/:id/reject", handlers.RejectStockAdjustment(db))
+	stockAdjustments.POST("/:id/publish", handlers.PublishStockAdjustment(db))
+
+	kitAssemblies := r.Group("/kit-assemblies")
+	kitAssemblies.POST("", handlers.CreateKitAssembly(db))
+	kitAssemblies.GET("", handlers.GetKitAssemblies(db))
+	kitAssemblies.POST("/:id/complete", handlers.CompleteKitAssembly(db))
+	kitAssemblies.POST("/:id/cancel", handlers.CancelKitAssembly(db))
+
+	valuation := r.Group("/valuation")
+	valuation.GET("", handlers.GetValuation(db))
+	valuation.GET("/cogs", handlers.GetCOGS(db))
+	valuation.GET("/layers", handlers.GetCostLayers(db))
+
+	settings := r.Group("/settings")
+	settings.GET("", handlers.GetSettings(db))
+	settings.PUT("", handlers.UpdateSettings(db))
+
 	suppliers := r.Group("/suppliers")
 	suppliers.POST("", handlers.CreateSupplier(db))
 	suppliers.GET("", handlers.GetSuppliers(db))
+	suppliers.GET("/scorecards", handlers.GetSupplierScorecards(db))
 	suppliers.PUT("/:id", handlers.UpdateSupplier(db))
 	suppliers.DELETE("/:id", handlers.SoftDeleteSupplier(db))
 	suppliers.DELETE("/hard/:id", handlers.HardDeleteSupplier(db))
 	suppliers.PATCH("/:id/recover", handlers.RecoverSupplier(db))
+	suppliers.GET("/:id/scorecard", handlers.GetSupplierScorecard(db))
+	suppliers.GET("/:id/prices", handlers.GetSupplierPrices(db))
+	suppliers.POST("/:id/prices", handlers.CreateSupplierPrice(db))
+	suppliers.PUT("/:id/prices/:price_id", handlers.UpdateSupplierPrice(db))
+	suppliers.DELETE("/:id/prices/:price_id", handlers.DeleteSupplierPrice(db))
+
+	warehouses := r.Group("/warehouses")
+	warehouses.POST("", handlers.CreateWarehouse(db))
+	warehouses.GET("", handlers.GetWarehouses(db))
+	warehouses.PUT("/:id", handlers.UpdateWarehouse(db))
+	warehouses.DELETE("/:id", handlers.SoftDeleteWarehouse(db))
+	warehouses.DELETE("/hard/:id", handlers.HardDeleteWarehouse(db))
+	warehouses.PATCH("/:id/recover", handlers.RecoverWarehouse(db))
+
+	zones := r.Group("/zones")
+	zones.POST("", handlers.CreateZone(db))
+	zones.GET("", handlers.GetZones(db))
+	zones.PUT("/:id", handlers.UpdateZone(db))
+	zones.DELETE("/:id", handlers.SoftDeleteZone(db))
+	zones.DELETE("/hard/:id", handlers.HardDeleteZone(db))
+	zones.PATCH("/:id/recover", handlers.RecoverZone(db))
+
+	bins := r.Group("/bins")
+	bins.POST("", handlers.CreateBin(db))
+	bins.GET("", handlers.GetBins(db))
+	bins.GET("/lookup", handlers.LookupBin(db))
+	bins.PUT("/:id", handlers.UpdateBin(db))
+	bins.DELETE("/:id", handlers.SoftDeleteBin(db))
+	bins.DELETE("/hard/:id", handlers.HardDeleteBin(db))
+	bins.PATCH("/:id/recover", handlers.RecoverBin(db))
 }
@@ -3,6 +3,8 @@ package initializers
 import (
 	"fmt"
 	"inventory-management/internal/model"
+	"inventory-management/internal/utils"
+	"log"
 	"os"
 
 	"gorm.io/driver/postgres"
@@ -26,5 +28,15 @@ func ConnectToDB() {
 		panic("Failed to connect to db")
 	}
 
-	DB.AutoMigrate(&model.Product{}, &model.Stock{}, &model.Category{}, &model.Supplier{})
+	DB.AutoMigrate(&model.Product{}, &model.Stock{}, &model.Category{}, &model.Supplier{}, &model.Warehouse{}, &model.Zone{}, &model.Bin{}, &model.StockReservation{}, &model.StockMovement{}, &model.SerialNumber{}, &model.SerialEvent{}, &model.CycleCountPlan{}, &model.CycleCountTask{}, &model.TransferOrder{}, &model.ProductUnit{}, &model.ProductBarcode{}, &model.PurchaseOrder{}, &model.PurchaseOrderLine{}, &model.PurchaseOrderReceipt{}, &model.SupplierPrice{}, &model.AccountSettings{}, &model.CostLayer{}, &model.CostConsumption{}, &model.KitComponent{}, &model.KitAssembly{}, &model.StockAdjustment{}, &model.AlertRule{}, &model.LowStockAlert{}, &model.Attachment{}, &model.StockSnapshot{}, &model.StockSnapshotLine{}, &model.SlottingRecommendation{}, &model.StockStatusChange{})
+
+	if err := utils.RecordOpeningBalances(DB); err != nil {
+		log.Printf("Failed to record opening balances: %v", err)
+	}
+	if err := utils.RecordOpeningCostLayers(DB); err != nil {
+		log.Printf("Failed to record opening cost layers: %v", err)
+	}
+	if err := utils.CreateSearchIndexes(DB); err != nil {
+		log.Printf("Failed to create search indexes: %v", err)
+	}
 }
@@ -0,0 +1,45 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"inventory-management/internal/model"
+	"log"
+	"os"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// PublishStockAdjustment publishes an approved stock adjustment to STOCK_ADJUSTMENT_TOPIC
+// for reporting-analytics
+func PublishStockAdjustment(event model.StockAdjustmentEvent) error {
+	brokers := os.Getenv("KAFKA_BROKERS")
+	topic := os.Getenv("STOCK_ADJUSTMENT_TOPIC")
+
+	if brokers == "" || topic == "" {
+		return fmt.Errorf("KAFKA_BROKERS or STOCK_ADJUSTMENT_TOPIC environment variable not set")
+	}
+
+	writer := kafka.Writer{
+		Addr:     kafka.TCP(brokers),
+		Topic:    topic,
+		Balancer: &kafka.LeastBytes{},
+	}
+	defer writer.Close()
+
+	messageBytes, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal stock adjustment: %w", err)
+	}
+
+	if err := writer.WriteMessages(context.Background(), kafka.Message{
+		Key:   []byte(fmt.Sprintf("product_%d", event.ProductID)),
+		Value: messageBytes,
+	}); err != nil {
+		return fmt.Errorf("failed to write stock adjustment to kafka: %w", err)
+	}
+
+	log.Printf("Published stock adjustment: %s\n", string(messageBytes))
+	return nil
+}
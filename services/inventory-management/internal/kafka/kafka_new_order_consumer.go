@@ -3,15 +3,22 @@ package kafka
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"inventory-management/internal/initializers"
 	"inventory-management/internal/model"
+	"inventory-management/internal/utils"
 	"log"
 	"os"
+	"slices"
 
 	"github.com/segmentio/kafka-go"
 )
 
-func ConsumerOrderEvents() {
+// ConsumerOrderEvents reserves stock for new orders and releases it for cancelled ones.
+// Orders of serialized products are reported as awaiting serials instead of ready, so they
+// are not shipped automatically and wait for a shipment that names their serial numbers.
+// Stock that runs low is reported on LOW_STOCK_TOPIC and through the account's alert rules.
+func ConsumerOrderEvents(ns *utils.NotificationService) {
 	r := kafka.NewReader(kafka.ReaderConfig{
 		Brokers:  []string{os.Getenv("KAFKA_BROKERS")},
 		Topic:    os.Getenv("ORDER_EVENT_TOPIC"),
@@ -34,51 +41,90 @@ func ConsumerOrderEvents() {
 			continue
 		}
 
-		if event.Action == "create" {
-			processOrderCreation(event)
-		} else if event.Action == "cancel" {
+		switch event.Action {
+		case "create":
+			processOrderCreation(event, ns)
+		case "cancel", "cancelled":
 			processOrderCancellation(event)
 		}
 	}
 }
 
-func processOrderCreation(event model.OrderEvent) {
+func processOrderCreation(event model.OrderEvent, ns *utils.NotificationService) {
 	tx := initializers.DB.Begin()
 	if tx.Error != nil {
 		log.Printf("Database transaction error: %v\n", tx.Error)
 		return
 	}
 
-	var stock model.Stock
-	if err := tx.Set("gorm:query_option", "FOR UPDATE").Where("product_id = ?", event.ProductID).First(&stock).Error; err != nil {
-		log.Printf("Error finding stock: %v\n", err)
+	log.Printf("Processing order creation for OrderID: %d, ProductID: %d, Requested Quantity: %d %s\n", event.OrderID, event.ProductID, event.Quantity, event.UnitOfMeasure)
+
+	// Orders may be placed in any of the product's units; availability is in base units
+	quantity, err := utils.ToBaseQuantity(tx, event.ProductID, event.UnitOfMeasure, event.Quantity)
+	if errors.Is(err, utils.ErrUnknownUnit) {
+		log.Printf("Unknown unit of measure %q for product_id %d\n", event.UnitOfMeasure, event.ProductID)
+		tx.Rollback()
+		publishInventoryStatus(event.OrderID, event.ProductID, event.Quantity, "Unknown Unit of Measure")
+		return
+	}
+	if err != nil {
+		log.Printf("Error converting order quantity: %v\n", err)
 		tx.Rollback()
 		return
 	}
 
-	log.Printf("Processing order creation for OrderID: %d, ProductID: %d, Requested Quantity: %d, Available Stock: %d\n", event.OrderID, event.ProductID, event.Quantity, stock.Quantity)
-
-	if stock.Quantity >= event.Quantity {
-		stock.Quantity -= event.Quantity
-		if err := tx.Save(&stock).Error; err != nil {
-			log.Printf("Error updating stock: %v\n", err)
-			tx.Rollback()
-		} else {
-			log.Printf("Stock updated successfully: %+v\n", stock)
-			if err := tx.Commit().Error; err != nil {
-				log.Printf("Error committing transaction: %v\n", err)
-				tx.Rollback()
-			} else {
-				publishInventoryStatus(event.OrderID, event.ProductID, event.Quantity, "Ready for Shipping")
-				if stock.Quantity <= uint(stock.LowStockThreshold) {
-					publishLowStockNotification(stock.ProductID, stock.Quantity, stock.LowStockThreshold)
-				}
-			}
-		}
-	} else {
+	stocks, err := utils.ReserveStock(tx, event.OrderID, event.ProductID, quantity)
+	if errors.Is(err, utils.ErrInsufficientStock) {
 		log.Printf("Not enough stock for product_id %d\n", event.ProductID)
 		tx.Rollback()
 		publishInventoryStatus(event.OrderID, event.ProductID, event.Quantity, "Out of Stock")
+		return
+	}
+	if err != nil {
+		log.Printf("Error reserving stock: %v\n", err)
+		tx.Rollback()
+		return
+	}
+
+	status := "Ready for Shipping"
+	serialized, err := utils.RequiresSerials(tx, stocks)
+	if err != nil {
+		log.Printf("Error checking for serialized products: %v\n", err)
+		tx.Rollback()
+		return
+	}
+	if serialized {
+		status = "Awaiting Serials"
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Printf("Error committing transaction: %v\n", err)
+		tx.Rollback()
+		return
+	}
+
+	log.Printf("Stock reserved successfully for OrderID: %d\n", event.OrderID)
+	publishInventoryStatus(event.OrderID, event.ProductID, event.Quantity, status)
+	for _, stock := range stocks {
+		if stock.Available() <= uint(stock.LowStockThreshold) {
+			notifyLowStock(stock, ns)
+		}
+	}
+
+	// Low stock turns into a replenishment proposal as soon as the reorder point is reached.
+	// A kit order draws on its components, which are replenished in their own right.
+	replenish := []uint{event.ProductID}
+	for _, stock := range stocks {
+		if !slices.Contains(replenish, stock.ProductID) {
+			replenish = append(replenish, stock.ProductID)
+		}
+	}
+	for _, productID := range replenish {
+		if order, err := utils.ReplenishProduct(initializers.DB, productID); err != nil {
+			log.Printf("Error proposing replenishment for product_id %d: %v\n", productID, err)
+		} else if order != nil {
+			log.Printf("Replenishment for product_id %d proposed on purchase order %d\n", productID, order.ID)
+		}
 	}
 }
 
@@ -89,26 +135,21 @@ func processOrderCancellation(event model.OrderEvent) {
 		return
 	}
 
-	var stock model.Stock
-	if err := tx.Set("gorm:query_option", "FOR UPDATE").Where("product_id = ?", event.ProductID).First(&stock).Error; err != nil {
-		log.Printf("Error finding stock: %v\n", err)
+	released, err := utils.ReleaseReservations(tx, event.OrderID, model.ReservationReleased)
+	if err != nil {
+		log.Printf("Error releasing reservations: %v\n", err)
 		tx.Rollback()
 		return
 	}
 
-	stock.Quantity += event.Quantity
-	if err := tx.Save(&stock).Error; err != nil {
-		log.Printf("Error updating stock: %v\n", err)
+	if err := tx.Commit().Error; err != nil {
+		log.Printf("Error committing transaction: %v\n", err)
 		tx.Rollback()
-	} else {
-		log.Printf("Stock updated successfully after cancellation: %+v\n", stock)
-		if err := tx.Commit().Error; err != nil {
-			log.Printf("Error committing transaction: %v\n", err)
-			tx.Rollback()
-		} else {
-			publishInventoryStatus(event.OrderID, event.ProductID, event.Quantity, "Cancelled")
-		}
+		return
 	}
+
+	log.Printf("Released %d reservations after cancellation of OrderID: %d\n", released, event.OrderID)
+	publishInventoryStatus(event.OrderID, event.ProductID, event.Quantity, "Cancelled")
 }
 
 func publishInventoryStatus(orderID uint, productID uint, quantity uint, status string) {
@@ -149,7 +190,26 @@ func publishInventoryStatus(orderID uint, productID uint, quantity uint, status
 	}
 }
 
-func publishLowStockNotification(productID uint, quantity uint, lowStockThreshold int) {
+// notifyLowStock runs a low stock row through the account's alert rules and publishes it
+// with the recipients that were alerted
+func notifyLowStock(stock model.Stock, ns *utils.NotificationService) {
+	var recipients []string
+	token, err := utils.AccountToken(stock.AccountID)
+	if err == nil {
+		var alerts []model.LowStockAlert
+		alerts, err = utils.NotifyLowStock(initializers.DB, ns, stock, token)
+		for _, alert := range alerts {
+			recipients = append(recipients, alert.Recipients...)
+		}
+	}
+	if err != nil {
+		log.Printf("Error sending low stock alerts for stock %d: %v\n", stock.ID, err)
+	}
+
+	publishLowStockNotification(stock.ProductID, stock.Available(), stock.LowStockThreshold, recipients)
+}
+
+func publishLowStockNotification(productID uint, quantity uint, lowStockThreshold int, recipients []string) {
 	brokers := os.Getenv("KAFKA_BROKERS")
 	topic := os.Getenv("LOW_STOCK_TOPIC")
 
@@ -167,6 +227,7 @@ func publishLowStockNotification(productID uint, quantity uint, lowStockThreshol
 		"product_id":          productID,
 		"quantity":            quantity,
 		"low_stock_threshold": lowStockThreshold,
+		"recipients":          recipients,
 	}
 
 	messageBytes, err := json.Marshal(message)
@@ -0,0 +1,88 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"inventory-management/internal/initializers"
+	"inventory-management/internal/model"
+	"inventory-management/internal/utils"
+	"log"
+	"os"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// ConsumerReceipts reads the receipts the dock records against purchase order lines and
+// posts the received quantities to stock.
+func ConsumerReceipts() {
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:  []string{os.Getenv("KAFKA_BROKERS")},
+		Topic:    os.Getenv("RECEIPT_EVENT_TOPIC"),
+		GroupID:  "inventory-management-group",
+		MinBytes: 10e3, // 10KB
+		MaxBytes: 10e6, // 10MB
+	})
+
+	for {
+		m, err := r.ReadMessage(context.Background())
+		if err != nil {
+			log.Printf("Error reading message: %v\n", err)
+			continue
+		}
+		log.Printf("Received message: %s\n", string(m.Value))
+
+		var event model.ReceiptEvent
+		if err := json.Unmarshal(m.Value, &event); err != nil {
+			log.Printf("Error unmarshalling message: %v\n", err)
+			continue
+		}
+
+		processReceipt(event)
+	}
+}
+
+// processReceipt posts a receipt to stock and reports the outcome back to the dock, which
+// keeps failed receipts unposted until they are corrected and reposted
+func processReceipt(event model.ReceiptEvent) {
+	status := model.ReceiptStatusEvent{
+		ReceiptID:       event.ReceiptID,
+		PurchaseOrderID: event.PurchaseOrderID,
+		AccountID:       event.AccountID,
+	}
+	if err := postReceipt(event); err != nil {
+		log.Printf("Error posting receipt %d for purchase order %d: %v\n", event.ReceiptID, event.PurchaseOrderID, err)
+		status.Error = err.Error()
+	} else {
+		status.Posted = true
+	}
+
+	if err := PublishReceiptStatus(status); err != nil {
+		log.Printf("Error publishing status of receipt %d: %v\n", event.ReceiptID, err)
+	}
+}
+
+func postReceipt(event model.ReceiptEvent) error {
+	tx := initializers.DB.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	receipt, err := utils.PostReceipt(tx, event)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if receipt == nil {
+		log.Printf("Receipt %d was already posted\n", event.ReceiptID)
+		return nil
+	}
+	log.Printf("Posted %d units of product %d to stock %d from purchase order %d\n",
+		receipt.Quantity, receipt.ProductID, receipt.StockID, receipt.PurchaseOrderID)
+	return nil
+}
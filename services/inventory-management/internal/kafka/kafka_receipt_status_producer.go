@@ -0,0 +1,45 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"inventory-management/internal/model"
+	"log"
+	"os"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// PublishReceiptStatus tells shipping-receiving on RECEIPT_STATUS_TOPIC whether a receipt
+// was posted to stock
+func PublishReceiptStatus(event model.ReceiptStatusEvent) error {
+	brokers := os.Getenv("KAFKA_BROKERS")
+	topic := os.Getenv("RECEIPT_STATUS_TOPIC")
+
+	if brokers == "" || topic == "" {
+		return fmt.Errorf("KAFKA_BROKERS or RECEIPT_STATUS_TOPIC environment variable not set")
+	}
+
+	writer := kafka.Writer{
+		Addr:     kafka.TCP(brokers),
+		Topic:    topic,
+		Balancer: &kafka.LeastBytes{},
+	}
+	defer writer.Close()
+
+	messageBytes, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal receipt status: %w", err)
+	}
+
+	if err := writer.WriteMessages(context.Background(), kafka.Message{
+		Key:   []byte(fmt.Sprintf("purchase_order_%d", event.PurchaseOrderID)),
+		Value: messageBytes,
+	}); err != nil {
+		return fmt.Errorf("failed to write receipt status to kafka: %w", err)
+	}
+
+	log.Printf("Published receipt status: %s\n", string(messageBytes))
+	return nil
+}
@@ -0,0 +1,79 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"inventory-management/internal/initializers"
+	"inventory-management/internal/model"
+	"inventory-management/internal/utils"
+	"log"
+	"os"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// ConsumerShippingStatus reads shipping status changes and consumes the stock reserved
+// for orders once they have been shipped, marking the shipped serial numbers.
+func ConsumerShippingStatus() {
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:  []string{os.Getenv("KAFKA_BROKERS")},
+		Topic:    os.Getenv("SHIPPING_STATUS_TOPIC"),
+		GroupID:  "inventory-management-group",
+		MinBytes: 10e3, // 10KB
+		MaxBytes: 10e6, // 10MB
+	})
+
+	for {
+		m, err := r.ReadMessage(context.Background())
+		if err != nil {
+			log.Printf("Error reading message: %v\n", err)
+			continue
+		}
+		log.Printf("Received message: %s\n", string(m.Value))
+
+		var event model.ShippingStatusEvent
+		if err := json.Unmarshal(m.Value, &event); err != nil {
+			log.Printf("Error unmarshalling message: %v\n", err)
+			continue
+		}
+
+		if event.Action == "Shipped" {
+			processOrderShipment(event)
+		}
+	}
+}
+
+func processOrderShipment(event model.ShippingStatusEvent) {
+	tx := initializers.DB.Begin()
+	if tx.Error != nil {
+		log.Printf("Database transaction error: %v\n", tx.Error)
+		return
+	}
+
+	var shipmentID *uint
+	if event.ShippingID != 0 {
+		shipmentID = &event.ShippingID
+	}
+
+	stocks, err := utils.ConsumeReservations(tx, event.OrderID, shipmentID)
+	if err != nil {
+		log.Printf("Error consuming reservations: %v\n", err)
+		tx.Rollback()
+		return
+	}
+
+	if err := utils.ShipSerials(tx, event.OrderID, shipmentID, stocks, event.SerialNumbers); err != nil {
+		// The reservations stay in place until the shipment is sent with matching serials
+		log.Printf("Rejected shipment of OrderID %d: %v\n", event.OrderID, err)
+		tx.Rollback()
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Printf("Error committing transaction: %v\n", err)
+		tx.Rollback()
+		return
+	}
+
+	log.Printf("Consumed reservations of %d stock rows for shipped OrderID: %d\n", len(stocks), event.OrderID)
+}
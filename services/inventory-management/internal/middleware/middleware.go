@@ -71,6 +71,9 @@ func AuthMiddleware(db *gorm.DB) gin.HandlerFunc {
 		}
 
 		c.Set("account_id", uint(accountID))
+		if userID, ok := claims["sub"].(float64); ok {
+			c.Set("user_id", uint(userID))
+		}
 
 		c.Next()
 	}
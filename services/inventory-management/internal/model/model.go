@@ -15,13 +15,92 @@ type Product struct {
 	DeletedAt   gorm.DeletedAt `gorm:"index"`
 	AccountID   uint           `gorm:"index"` // Foreign key to Account
 	Name        string         `json:"name"`
+	SKU         string         `gorm:"index" json:"sku"` // Unique within the account
 	Description string         `json:"description"`
 	Price       float64        `json:"price"`
 	CategoryID  uint           `json:"category_id"`
 	Category    Category       `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"category"`
 	SupplierID  uint           `json:"supplier_id"`
 	Supplier    Supplier       `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"supplier"`
-	Stocks      []Stock        `json:"stocks" gorm:"foreignKey:ProductID;constraint:OnDelete:CASCADE;"`
+	// IsSerialized products require a serial number for every unit received, moved or shipped
+	IsSerialized bool `json:"is_serialized"`
+	// ABCClass ranks the product by the value it sells (A, B or C) and XYZClass by how
+	// steady its demand is (X, Y or Z). Both are recomputed from recent orders and drive
+	// cycle-count frequency and slotting.
+	ABCClass     string     `gorm:"index" json:"abc_class"`
+	XYZClass     string     `gorm:"index" json:"xyz_class"`
+	ClassifiedAt *time.Time `json:"classified_at"`
+	// ReorderPoint and ReorderQuantity drive replenishment: once the available quantity
+	// plus what is already on order falls to the reorder point, the reorder quantity is
+	// proposed on a draft purchase order. A zero reorder quantity disables replenishment.
+	ReorderPoint        uint      `json:"reorder_point"`
+	ReorderQuantity     uint      `json:"reorder_quantity"`
+	PreferredSupplierID *uint     `gorm:"index" json:"preferred_supplier_id"` // Falls back to SupplierID
+	PreferredSupplier   *Supplier `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"preferred_supplier,omitempty"`
+	// BaseUnit is the unit stock quantities and reservations are kept in
+	BaseUnit string           `gorm:"default:each" json:"base_unit"`
+	Units    []ProductUnit    `gorm:"foreignKey:ProductID;constraint:OnDelete:CASCADE;" json:"units,omitempty"`
+	Barcodes []ProductBarcode `gorm:"foreignKey:ProductID;constraint:OnDelete:CASCADE;" json:"barcodes,omitempty"`
+	Stocks   []Stock          `json:"stocks" gorm:"foreignKey:ProductID;constraint:OnDelete:CASCADE;"`
+}
+
+// ReplenishmentSupplierID returns the supplier the product is reordered from: the
+// preferred supplier when set, its supplier otherwise
+func (p Product) ReplenishmentSupplierID() uint {
+	if p.PreferredSupplierID != nil {
+		return *p.PreferredSupplierID
+	}
+	return p.SupplierID
+}
+
+// UnitFactor returns how many base units one unit holds, using the preloaded Units. An
+// empty unit or the base unit itself has factor 1.
+func (p Product) UnitFactor(unit string) (uint, bool) {
+	if unit == "" || unit == p.BaseUnit {
+		return 1, true
+	}
+	for _, productUnit := range p.Units {
+		if productUnit.Name == unit {
+			return productUnit.Factor, true
+		}
+	}
+	return 0, false
+}
+
+// ProductUnit is a pack size of a product, e.g. a case of 12 or a pallet of 480 base units
+type ProductUnit struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	ProductID uint      `gorm:"uniqueIndex:idx_product_unit" json:"product_id"`
+	Name      string    `gorm:"uniqueIndex:idx_product_unit" json:"name"`
+	Factor    uint      `json:"factor"` // Number of base units in one unit
+	AccountID uint      `gorm:"index"`  // Foreign key to Account
+}
+
+// BarcodeSymbology is the encoding a product barcode is printed in
+type BarcodeSymbology string
+
+const (
+	BarcodeEAN13   BarcodeSymbology = "ean13"
+	BarcodeUPCA    BarcodeSymbology = "upca"
+	BarcodeGTIN14  BarcodeSymbology = "gtin14"
+	BarcodeCode128 BarcodeSymbology = "code128"
+)
+
+// ProductBarcode is one of the codes a product can be scanned by. A barcode may identify
+// a pack size of the product, e.g. the GTIN-14 printed on a case.
+type ProductBarcode struct {
+	ID        uint             `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time        `json:"created_at"`
+	ProductID uint             `gorm:"index" json:"product_id"`
+	Symbology BarcodeSymbology `json:"symbology"`
+	Code      string           `json:"code"`
+	// LookupKey is the code normalized for scan lookups; EAN-13, UPC-A and GTIN-14 codes
+	// are stored as GTIN-14 so a scan matches whichever form the scanner reports
+	LookupKey string `gorm:"uniqueIndex:idx_barcode_lookup" json:"-"`
+	Unit      string `json:"unit,omitempty"`                 // Unit of measure the barcode identifies, empty for the base unit
+	AccountID uint   `gorm:"uniqueIndex:idx_barcode_lookup"` // Foreign key to Account
 }
 
 type Stock struct {
@@ -32,9 +111,773 @@ type Stock struct {
 	ProductID         uint           `json:"product_id"`
 	Product           Product        `json:"product"`
 	Quantity          uint           `json:"quantity"`
+	ReservedQuantity  uint           `json:"reserved_quantity"`
+	LotNumber         string         `gorm:"index" json:"lot_number"`
+	ManufacturedAt    *time.Time     `json:"manufactured_at"`
+	ExpiresAt         *time.Time     `gorm:"index" json:"expires_at"`
 	Location          string         `json:"location"`
+	BinID             *uint          `gorm:"index" json:"bin_id"`
+	Bin               *Bin           `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"bin,omitempty"`
 	AccountID         uint           `gorm:"index"` // Foreign key to Account
 	LowStockThreshold int            `json:"low_stock_threshold"`
+	// UnitOfMeasure is the unit the row is handled in. Quantity is always kept in the
+	// product's base unit; UnitQuantity lets a receipt be entered in UnitOfMeasure instead.
+	UnitOfMeasure string `json:"unit_of_measure"`
+	UnitQuantity  *uint  `gorm:"-" json:"unit_quantity,omitempty"`
+	// SerialNumbers is the full set of serials held by the row when creating or updating
+	// stock of a serialized product
+	SerialNumbers []string `gorm:"-" json:"serial_numbers,omitempty"`
+	// Quantity on hold is on hand but cannot be reserved or transferred until it is
+	// released back to available, e.g. returns awaiting inspection or goods in inbound QC
+	QuarantineQuantity uint `json:"quarantine_quantity"`
+	DamagedQuantity    uint `json:"damaged_quantity"`
+	QCHoldQuantity     uint `json:"qc_hold_quantity"`
+}
+
+// IsExpired reports whether the stock's lot is past its expiry date at the given time
+func (s Stock) IsExpired(at time.Time) bool {
+	return s.ExpiresAt != nil && !s.ExpiresAt.After(at)
+}
+
+// Available returns the on-hand quantity that is neither reserved by open orders nor on hold
+func (s Stock) Available() uint {
+	if s.ReservedQuantity+s.Held() >= s.Quantity {
+		return 0
+	}
+	return s.Quantity - s.ReservedQuantity - s.Held()
+}
+
+// Held returns the on-hand quantity in any of the hold statuses
+func (s Stock) Held() uint {
+	return s.QuarantineQuantity + s.DamagedQuantity + s.QCHoldQuantity
+}
+
+// HeldQuantity returns a pointer to the row's quantity in a hold status, or nil for
+// available and unknown statuses
+func (s *Stock) HeldQuantity(status InventoryStatus) *uint {
+	switch status {
+	case InventoryQuarantine:
+		return &s.QuarantineQuantity
+	case InventoryDamaged:
+		return &s.DamagedQuantity
+	case InventoryQCHold:
+		return &s.QCHoldQuantity
+	}
+	return nil
+}
+
+// InventoryStatus is the bucket a unit of stock is in. Only available units can be
+// allocated to orders; the others are on hold.
+type InventoryStatus string
+
+const (
+	InventoryAvailable  InventoryStatus = "available"
+	InventoryQuarantine InventoryStatus = "quarantine"
+	InventoryDamaged    InventoryStatus = "damaged"
+	InventoryQCHold     InventoryStatus = "qc_hold"
+)
+
+// InventoryStatuses are the buckets stock can be moved between
+var InventoryStatuses = []InventoryStatus{InventoryAvailable, InventoryQuarantine, InventoryDamaged, InventoryQCHold}
+
+type StatusChangeReason string
+
+const (
+	StatusChangeReturn           StatusChangeReason = "return"
+	StatusChangeInboundQC        StatusChangeReason = "inbound_qc"
+	StatusChangeDamage           StatusChangeReason = "damage"
+	StatusChangeInvestigation    StatusChangeReason = "investigation"
+	StatusChangeInspectionPassed StatusChangeReason = "inspection_passed"
+	StatusChangeInspectionFailed StatusChangeReason = "inspection_failed"
+)
+
+// StatusChangeReasons are the reason codes a status change can be recorded with
+var StatusChangeReasons = []StatusChangeReason{
+	StatusChangeReturn, StatusChangeInboundQC, StatusChangeDamage,
+	StatusChangeInvestigation, StatusChangeInspectionPassed, StatusChangeInspectionFailed,
+}
+
+// StockStatusChange records quantity of a stock row moved from one status to another.
+// The on-hand quantity does not change, so it is kept apart from the movement ledger.
+type StockStatusChange struct {
+	ID         uint               `gorm:"primarykey" json:"id"`
+	CreatedAt  time.Time          `gorm:"index" json:"created_at"`
+	StockID    uint               `gorm:"index" json:"stock_id"`
+	ProductID  uint               `gorm:"index" json:"product_id"`
+	FromStatus InventoryStatus    `json:"from_status"`
+	ToStatus   InventoryStatus    `json:"to_status"`
+	Quantity   uint               `json:"quantity"`
+	Reason     StatusChangeReason `gorm:"index" json:"reason"`
+	Note       string             `json:"note,omitempty"`
+	UserID     *uint              `json:"user_id,omitempty"`
+	AccountID  uint               `gorm:"index"` // Foreign key to Account
+}
+
+type ReservationStatus string
+
+const (
+	ReservationActive   ReservationStatus = "active"
+	ReservationConsumed ReservationStatus = "consumed"
+	ReservationReleased ReservationStatus = "released"
+	ReservationExpired  ReservationStatus = "expired"
+)
+
+// StockReservation holds quantity of a stock row for an order until it is shipped,
+// cancelled or expires
+type StockReservation struct {
+	ID        uint              `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+	OrderID   uint              `gorm:"index" json:"order_id"`
+	StockID   uint              `gorm:"index" json:"stock_id"`
+	ProductID uint              `gorm:"index" json:"product_id"`
+	Quantity  uint              `json:"quantity"`
+	Status    ReservationStatus `gorm:"index" json:"status"`
+	ExpiresAt time.Time         `gorm:"index" json:"expires_at"`
+	// ShortQuantity is what could not be deducted when an expired reservation was shipped
+	// after its stock had been reserved for other orders or put on hold
+	ShortQuantity uint `json:"short_quantity,omitempty"`
+	AccountID     uint `gorm:"index"` // Foreign key to Account
+}
+
+type MovementReason string
+
+const (
+	MovementOpeningBalance MovementReason = "opening_balance"
+	MovementReceipt        MovementReason = "receipt"
+	MovementAdjustment     MovementReason = "adjustment"
+	MovementShipment       MovementReason = "shipment"
+	MovementDeleted        MovementReason = "deleted"
+	MovementRecovered      MovementReason = "recovered"
+	MovementTransfer       MovementReason = "transfer"
+	MovementCycleCount     MovementReason = "cycle_count"
+	MovementImport         MovementReason = "import"
+	MovementAssembly       MovementReason = "assembly"
+)
+
+// ErrImmutableMovement is returned when something tries to change a recorded stock movement
+var ErrImmutableMovement = errors.New("stock movements are append-only")
+
+// StockMovement is an immutable ledger entry written for every change of Stock.Quantity
+type StockMovement struct {
+	ID         uint           `gorm:"primarykey" json:"id"`
+	CreatedAt  time.Time      `gorm:"index" json:"created_at"`
+	StockID    uint           `gorm:"index" json:"stock_id"`
+	ProductID  uint           `gorm:"index" json:"product_id"`
+	BinID      *uint          `gorm:"index" json:"bin_id"`
+	Delta      int            `json:"delta"`
+	Balance    uint           `json:"balance"`
+	Reason     MovementReason `gorm:"index" json:"reason"`
+	OrderID    *uint          `gorm:"index" json:"order_id,omitempty"`
+	ShipmentID *uint          `json:"shipment_id,omitempty"`
+	UserID     *uint          `json:"user_id,omitempty"`
+	Note       string         `json:"note,omitempty"`
+	AccountID  uint           `gorm:"index"` // Foreign key to Account
+	// UnitCost is the cost of received units, e.g. from a purchase order line. Increases
+	// without a cost are valued at the product's current cost.
+	UnitCost *float64 `gorm:"-" json:"-"`
+}
+
+func (m *StockMovement) BeforeUpdate(tx *gorm.DB) error {
+	return ErrImmutableMovement
+}
+
+func (m *StockMovement) BeforeDelete(tx *gorm.DB) error {
+	return ErrImmutableMovement
+}
+
+type SerialStatus string
+
+const (
+	SerialInStock   SerialStatus = "in_stock"
+	SerialInTransit SerialStatus = "in_transit"
+	SerialShipped   SerialStatus = "shipped"
+	SerialRemoved   SerialStatus = "removed"
+)
+
+// SerialNumber is a single tracked unit of a serialized product. StockID points at the
+// stock row currently holding the unit, or the last one that held it once it has left.
+type SerialNumber struct {
+	ID         uint         `gorm:"primarykey" json:"id"`
+	CreatedAt  time.Time    `json:"created_at"`
+	UpdatedAt  time.Time    `json:"updated_at"`
+	Serial     string       `gorm:"uniqueIndex:idx_serial_product" json:"serial"`
+	ProductID  uint         `gorm:"uniqueIndex:idx_serial_product" json:"product_id"`
+	StockID    *uint        `gorm:"index" json:"stock_id"`
+	Status     SerialStatus `gorm:"index" json:"status"`
+	OrderID    *uint        `gorm:"index" json:"order_id,omitempty"`
+	ShipmentID *uint        `json:"shipment_id,omitempty"`
+	AccountID  uint         `gorm:"uniqueIndex:idx_serial_product"` // Foreign key to Account
+}
+
+type SerialEventType string
+
+const (
+	SerialEventReceived SerialEventType = "received"
+	SerialEventMoved    SerialEventType = "moved"
+	SerialEventShipped  SerialEventType = "shipped"
+	SerialEventRemoved  SerialEventType = "removed"
+)
+
+// SerialEvent is one step in the history of a serial number
+type SerialEvent struct {
+	ID             uint            `gorm:"primarykey" json:"id"`
+	CreatedAt      time.Time       `gorm:"index" json:"created_at"`
+	SerialNumberID uint            `gorm:"index" json:"serial_number_id"`
+	Serial         string          `gorm:"index" json:"serial"`
+	ProductID      uint            `json:"product_id"`
+	Event          SerialEventType `json:"event"`
+	FromStockID    *uint           `json:"from_stock_id,omitempty"`
+	ToStockID      *uint           `json:"to_stock_id,omitempty"`
+	OrderID        *uint           `json:"order_id,omitempty"`
+	ShipmentID     *uint           `json:"shipment_id,omitempty"`
+	UserID         *uint           `json:"user_id,omitempty"`
+	AccountID      uint            `gorm:"index"` // Foreign key to Account
+}
+
+type TransferStatus string
+
+const (
+	TransferPending           TransferStatus = "pending"
+	TransferPicked            TransferStatus = "picked"
+	TransferInTransit         TransferStatus = "in_transit"
+	TransferPartiallyReceived TransferStatus = "partially_received"
+	TransferReceived          TransferStatus = "received"
+	TransferClosed            TransferStatus = "closed"
+	TransferCancelled         TransferStatus = "cancelled"
+)
+
+// OpenTransferStatuses are the states in which a transfer still holds or carries stock
+var OpenTransferStatuses = []TransferStatus{TransferPending, TransferPicked, TransferInTransit, TransferPartiallyReceived}
+
+// TransferOrder moves quantity from one stock row to another stock row or to a bin,
+// possibly in another warehouse. The quantity is held at the source while pending,
+// leaves the source when picked and is in transit until it is received.
+type TransferOrder struct {
+	ID               uint           `gorm:"primarykey" json:"id"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	FromStockID      uint           `gorm:"index" json:"from_stock_id"`
+	ToStockID        *uint          `gorm:"index" json:"to_stock_id,omitempty"`
+	ToBinID          *uint          `json:"to_bin_id,omitempty"`
+	ProductID        uint           `gorm:"index" json:"product_id"`
+	Quantity         uint           `json:"quantity"`
+	ReceivedQuantity uint           `json:"received_quantity"`
+	SerialNumbers    []string       `gorm:"serializer:json" json:"serial_numbers,omitempty"`
+	Status           TransferStatus `gorm:"index" json:"status"`
+	Note             string         `json:"note,omitempty"`
+	CreatedBy        *uint          `json:"created_by,omitempty"`
+	PickedAt         *time.Time     `json:"picked_at,omitempty"`
+	DispatchedAt     *time.Time     `json:"dispatched_at,omitempty"`
+	ReceivedAt       *time.Time     `json:"received_at,omitempty"`
+	AccountID        uint           `gorm:"index"` // Foreign key to Account
+}
+
+// InTransit returns the quantity that has left the source but not reached the destination
+func (t TransferOrder) InTransit() uint {
+	if t.Status != TransferPicked && t.Status != TransferInTransit && t.Status != TransferPartiallyReceived {
+		return 0
+	}
+	return t.Quantity - t.ReceivedQuantity
+}
+
+type CostingMethod string
+
+const (
+	CostingFIFO    CostingMethod = "fifo"
+	CostingAverage CostingMethod = "average"
+)
+
+// AccountSettings holds the inventory settings an account can configure
+type AccountSettings struct {
+	ID            uint          `gorm:"primarykey" json:"-"`
+	UpdatedAt     time.Time     `json:"updated_at"`
+	CostingMethod CostingMethod `json:"costing_method"`
+	// Stock adjustments of more units or more value than these thresholds wait for a
+	// manager's approval. Zero means no threshold.
+	AdjustmentQuantityThreshold uint    `json:"adjustment_quantity_threshold"`
+	AdjustmentValueThreshold    float64 `json:"adjustment_value_threshold"`
+	// AttachmentQuotaBytes caps the storage the account's attachments and their thumbnails
+	// use. Zero means the service default, ATTACHMENT_QUOTA_BYTES.
+	AttachmentQuotaBytes int64 `json:"attachment_quota_bytes"`
+	// ClassificationWindowDays is how many days of orders ABC/XYZ classification looks back
+	ClassificationWindowDays int  `json:"classification_window_days"`
+	AccountID                uint `gorm:"uniqueIndex" json:"account_id"`
+}
+
+type AdjustmentReason string
+
+const (
+	AdjustmentDamage     AdjustmentReason = "damage"
+	AdjustmentShrinkage  AdjustmentReason = "shrinkage"
+	AdjustmentFound      AdjustmentReason = "found"
+	AdjustmentCorrection AdjustmentReason = "correction"
+)
+
+// AdjustmentReasons are the reason codes a stock adjustment can be recorded with
+var AdjustmentReasons = []AdjustmentReason{AdjustmentDamage, AdjustmentShrinkage, AdjustmentFound, AdjustmentCorrection}
+
+type AdjustmentStatus string
+
+const (
+	AdjustmentPending  AdjustmentStatus = "pending"
+	AdjustmentApproved AdjustmentStatus = "approved"
+	AdjustmentRejected AdjustmentStatus = "rejected"
+)
+
+// StockAdjustment is a change of a stock row's quantity outside of receipts, shipments and
+// transfers, e.g. damaged or found units. Adjustments beyond the account's thresholds are
+// pending until a manager approves them; the stock only changes once approved.
+type StockAdjustment struct {
+	ID        uint             `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time        `gorm:"index" json:"created_at"`
+	UpdatedAt time.Time        `json:"updated_at"`
+	StockID   uint             `gorm:"index" json:"stock_id"`
+	ProductID uint             `gorm:"index" json:"product_id"`
+	Delta     int              `json:"delta"` // Units added (positive) or removed (negative)
+	Reason    AdjustmentReason `gorm:"index" json:"reason"`
+	Note      string           `json:"note"`
+	// SerialNumbers are the serials added or removed, one per unit, for serialized products
+	SerialNumbers []string `gorm:"serializer:json" json:"serial_numbers,omitempty"`
+	// FromStatus lets a removal take units out of a hold status, e.g. to write off damaged
+	// stock. Removals take available units otherwise.
+	FromStatus  InventoryStatus  `json:"from_status,omitempty"`
+	UnitCost    float64          `json:"unit_cost"` // Current cost of the product when requested
+	Value       float64          `json:"value"`     // Cost of the units adjusted
+	Status      AdjustmentStatus `gorm:"index" json:"status"`
+	RequestedBy *uint            `json:"requested_by"`
+	ReviewedBy  *uint            `json:"reviewed_by"`
+	ReviewedAt  *time.Time       `json:"reviewed_at"`
+	ReviewNote  string           `json:"review_note"`
+	Published   bool             `json:"published"` // Sent to reporting-analytics
+	AccountID   uint             `gorm:"index"`     // Foreign key to Account
+}
+
+// StockAdjustmentEvent reports an approved stock adjustment to reporting-analytics
+type StockAdjustmentEvent struct {
+	AdjustmentID uint             `json:"adjustment_id"`
+	StockID      uint             `json:"stock_id"`
+	ProductID    uint             `json:"product_id"`
+	Delta        int              `json:"delta"`
+	Reason       AdjustmentReason `json:"reason"`
+	Note         string           `json:"note"`
+	UnitCost     float64          `json:"unit_cost"`
+	Value        float64          `json:"value"`
+	RequestedBy  *uint            `json:"requested_by"`
+	ApprovedBy   *uint            `json:"approved_by"`
+	AdjustedAt   time.Time        `json:"adjusted_at"`
+	AccountID    uint             `json:"account_id"`
+}
+
+// Event returns the reporting event of an approved adjustment
+func (a StockAdjustment) Event() StockAdjustmentEvent {
+	adjustedAt := a.UpdatedAt
+	if a.ReviewedAt != nil {
+		adjustedAt = *a.ReviewedAt
+	}
+	return StockAdjustmentEvent{
+		AdjustmentID: a.ID,
+		StockID:      a.StockID,
+		ProductID:    a.ProductID,
+		Delta:        a.Delta,
+		Reason:       a.Reason,
+		Note:         a.Note,
+		UnitCost:     a.UnitCost,
+		Value:        a.Value,
+		RequestedBy:  a.RequestedBy,
+		ApprovedBy:   a.ReviewedBy,
+		AdjustedAt:   adjustedAt,
+		AccountID:    a.AccountID,
+	}
+}
+
+// ReviewAdjustmentRequest carries a manager's note when approving or rejecting an adjustment
+type ReviewAdjustmentRequest struct {
+	Note string `json:"note"`
+}
+
+// StockAdjustmentsResponse represents the response for retrieving stock adjustments
+type StockAdjustmentsResponse struct {
+	Message     string            `json:"message"`
+	Adjustments []StockAdjustment `json:"adjustments"`
+}
+
+type AlertMode string
+
+const (
+	AlertImmediate AlertMode = "immediate" // Notify as soon as stock runs low
+	AlertDigest    AlertMode = "digest"    // Collect low stock into one email a day
+)
+
+// AlertRule decides who is told when stock runs low. A rule covers the stock of the
+// listed products, categories (with their subcategories) and warehouses; each list that
+// is given must match, and a rule without any covers all of the account's stock. The
+// listed users, users with the listed roles and users in the listed departments are
+// notified, at most once per stock row within the cooldown.
+type AlertRule struct {
+	ID              uint           `gorm:"primarykey" json:"id"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `gorm:"index"`
+	Name            string         `json:"name"`
+	ProductIDs      []uint         `gorm:"serializer:json" json:"product_ids"`
+	CategoryIDs     []uint         `gorm:"serializer:json" json:"category_ids"`
+	WarehouseIDs    []uint         `gorm:"serializer:json" json:"warehouse_ids"`
+	UserIDs         []uint         `gorm:"serializer:json" json:"user_ids"`
+	RoleIDs         []uint         `gorm:"serializer:json" json:"role_ids"`
+	Departments     []string       `gorm:"serializer:json" json:"departments"` // Department names
+	Mode            AlertMode      `json:"mode"`
+	CooldownMinutes uint           `json:"cooldown_minutes"` // Zero alerts on every low stock check
+	IsActive        bool           `gorm:"default:true" json:"is_active"`
+	CreatedBy       *uint          `json:"created_by"`
+	AccountID       uint           `gorm:"index"` // Foreign key to Account
+}
+
+// LowStockAlert records that a rule fired for a low stock row. Digest alerts wait with
+// an empty SentAt until the daily digest goes out.
+type LowStockAlert struct {
+	ID          uint       `gorm:"primarykey" json:"id"`
+	CreatedAt   time.Time  `gorm:"index" json:"created_at"`
+	RuleID      uint       `gorm:"index" json:"rule_id"`
+	StockID     uint       `gorm:"index" json:"stock_id"`
+	ProductID   uint       `json:"product_id"`
+	ProductName string     `json:"product_name"`
+	Available   uint       `json:"available"`
+	Threshold   int        `json:"threshold"`
+	Mode        AlertMode  `json:"mode"`
+	Recipients  []string   `gorm:"serializer:json" json:"recipients"`
+	SentAt      *time.Time `json:"sent_at"`
+	Error       string     `json:"error,omitempty"`
+	AccountID   uint       `gorm:"index"` // Foreign key to Account
+}
+
+// AlertRulesResponse represents the response for retrieving alert rules
+type AlertRulesResponse struct {
+	Message string      `json:"message"`
+	Rules   []AlertRule `json:"rules"`
+}
+
+// LowStockAlertsResponse represents the response for retrieving low stock alerts
+type LowStockAlertsResponse struct {
+	Message string          `json:"message"`
+	Alerts  []LowStockAlert `json:"alerts"`
+}
+
+// DirectoryUser is a user of the account as listed by user-management, with the
+// role and department alert rules select recipients by
+type DirectoryUser struct {
+	ID         uint   `json:"id"`
+	Name       string `json:"name"`
+	Email      string `json:"email"`
+	RoleID     uint   `json:"role_id"`
+	Department string `json:"department"`
+}
+
+// CostLayer is a quantity of a product that entered stock at one unit cost. Shipments and
+// other decreases consume the remaining quantity of the layers, oldest first.
+type CostLayer struct {
+	ID         uint           `gorm:"primarykey" json:"id"`
+	CreatedAt  time.Time      `gorm:"index" json:"created_at"`
+	ProductID  uint           `gorm:"index" json:"product_id"`
+	StockID    uint           `json:"stock_id"`
+	MovementID uint           `json:"movement_id"`
+	Reason     MovementReason `json:"reason"`
+	Quantity   uint           `json:"quantity"`
+	Remaining  uint           `json:"remaining"`
+	UnitCost   float64        `json:"unit_cost"`
+	AccountID  uint           `gorm:"index"` // Foreign key to Account
+}
+
+// CostConsumption records the cost taken out of stock by a decrease. Under FIFO the unit
+// cost is the cost of the consumed layer, under weighted average the product's average
+// cost at the time. LayerID is nil for quantity that no layer covered.
+type CostConsumption struct {
+	ID         uint           `gorm:"primarykey" json:"id"`
+	CreatedAt  time.Time      `gorm:"index" json:"created_at"`
+	LayerID    *uint          `gorm:"index" json:"layer_id,omitempty"`
+	ProductID  uint           `gorm:"index" json:"product_id"`
+	StockID    uint           `json:"stock_id"`
+	MovementID uint           `json:"movement_id"`
+	Reason     MovementReason `json:"reason"`
+	OrderID    *uint          `gorm:"index" json:"order_id,omitempty"`
+	ShipmentID *uint          `json:"shipment_id,omitempty"`
+	Quantity   uint           `json:"quantity"`
+	UnitCost   float64        `json:"unit_cost"`
+	Amount     float64        `json:"amount"`
+	AccountID  uint           `gorm:"index"` // Foreign key to Account
+}
+
+type PurchaseOrderStatus string
+
+const (
+	PurchaseOrderDraft             PurchaseOrderStatus = "draft"
+	PurchaseOrderSent              PurchaseOrderStatus = "sent"
+	PurchaseOrderPartiallyReceived PurchaseOrderStatus = "partially_received"
+	PurchaseOrderClosed            PurchaseOrderStatus = "closed"
+)
+
+// OpenPurchaseOrderStatuses are the states in which ordered quantity is still expected
+var OpenPurchaseOrderStatuses = []PurchaseOrderStatus{PurchaseOrderDraft, PurchaseOrderSent, PurchaseOrderPartiallyReceived}
+
+// PurchaseOrder orders products from one supplier. Replenishment proposals are created
+// as drafts that a manager reviews, edits and sends.
+type PurchaseOrder struct {
+	ID            uint                `gorm:"primarykey" json:"id"`
+	CreatedAt     time.Time           `json:"created_at"`
+	UpdatedAt     time.Time           `json:"updated_at"`
+	SupplierID    uint                `gorm:"index" json:"supplier_id"`
+	Supplier      *Supplier           `json:"supplier,omitempty"`
+	Status        PurchaseOrderStatus `gorm:"index" json:"status"`
+	AutoGenerated bool                `json:"auto_generated"`
+	ExpectedAt    *time.Time          `json:"expected_at,omitempty"`
+	Note          string              `json:"note,omitempty"`
+	CreatedBy     *uint               `json:"created_by,omitempty"`
+	SentBy        *uint               `json:"sent_by,omitempty"`
+	SentAt        *time.Time          `json:"sent_at,omitempty"`
+	LeadTimeDays  uint                `json:"lead_time_days"`
+	ClosedAt      *time.Time          `json:"closed_at,omitempty"`
+	Lines         []PurchaseOrderLine `gorm:"foreignKey:PurchaseOrderID;constraint:OnDelete:CASCADE;" json:"lines"`
+	AccountID     uint                `gorm:"index"` // Foreign key to Account
+}
+
+// PurchaseOrderLine is the quantity of one product on a purchase order, in base units.
+// A line is closed once it is fully received or the dock declares a short receipt final.
+type PurchaseOrderLine struct {
+	ID               uint    `gorm:"primarykey" json:"id"`
+	PurchaseOrderID  uint    `gorm:"index" json:"purchase_order_id"`
+	ProductID        uint    `gorm:"index" json:"product_id"`
+	Quantity         uint    `json:"quantity"`
+	UnitCost         float64 `json:"unit_cost"`
+	ReceivedQuantity uint    `json:"received_quantity"`
+	Closed           bool    `json:"closed"`
+	// UnitOfMeasure is the unit the supplier delivers the line in and the default unit of
+	// its receipts. Quantity, ReceivedQuantity and UnitCost are in the product's base unit.
+	UnitOfMeasure string `json:"unit_of_measure,omitempty"`
+	AccountID     uint   `gorm:"index"` // Foreign key to Account
+}
+
+// Outstanding returns the quantity of the line that is still expected from the supplier
+func (l PurchaseOrderLine) Outstanding() uint {
+	if l.Closed || l.ReceivedQuantity >= l.Quantity {
+		return 0
+	}
+	return l.Quantity - l.ReceivedQuantity
+}
+
+// PurchaseOrderReceipt records stock received against a purchase order line. ReceiptID is
+// the shipping-receiving receipt, which makes redelivered receipt events harmless.
+type PurchaseOrderReceipt struct {
+	ID              uint      `gorm:"primarykey" json:"id"`
+	CreatedAt       time.Time `json:"created_at"`
+	ReceiptID       uint      `gorm:"uniqueIndex:idx_po_receipt" json:"receipt_id"`
+	PurchaseOrderID uint      `gorm:"index" json:"purchase_order_id"`
+	LineID          uint      `gorm:"index" json:"line_id"`
+	ProductID       uint      `gorm:"index" json:"product_id"`
+	StockID         uint      `json:"stock_id"`
+	Quantity        uint      `json:"quantity"`
+	ReceivedAt      time.Time `json:"received_at"`
+	ReceivedBy      *uint     `json:"received_by,omitempty"`
+	AccountID       uint      `gorm:"uniqueIndex:idx_po_receipt"` // Foreign key to Account
+}
+
+type CycleCountPlanType string
+
+const (
+	CycleCountByBin      CycleCountPlanType = "bin"
+	CycleCountByABCClass CycleCountPlanType = "abc_class"
+	CycleCountRandom     CycleCountPlanType = "random"
+)
+
+type CycleCountPlanStatus string
+
+const (
+	CycleCountPlanOpen      CycleCountPlanStatus = "open"
+	CycleCountPlanCompleted CycleCountPlanStatus = "completed"
+)
+
+// CycleCountPlan selects stock rows to be counted. Counted variances above
+// TolerancePercent of the system quantity need a manager's approval.
+type CycleCountPlan struct {
+	ID               uint                 `gorm:"primarykey" json:"id"`
+	CreatedAt        time.Time            `json:"created_at"`
+	UpdatedAt        time.Time            `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt       `gorm:"index"`
+	Name             string               `json:"name"`
+	Type             CycleCountPlanType   `json:"type"`
+	BinIDs           []uint               `gorm:"serializer:json" json:"bin_ids,omitempty"`
+	ABCClass         string               `json:"abc_class,omitempty"`
+	SampleSize       int                  `json:"sample_size,omitempty"`
+	TolerancePercent float64              `json:"tolerance_percent"`
+	Status           CycleCountPlanStatus `gorm:"index" json:"status"`
+	CreatedBy        *uint                `json:"created_by,omitempty"`
+	AccountID        uint                 `gorm:"index"` // Foreign key to Account
+	Tasks            []CycleCountTask     `gorm:"foreignKey:PlanID;constraint:OnDelete:CASCADE;" json:"tasks,omitempty"`
+}
+
+type CycleCountTaskStatus string
+
+const (
+	CycleCountTaskPending         CycleCountTaskStatus = "pending"
+	CycleCountTaskPendingApproval CycleCountTaskStatus = "pending_approval"
+	CycleCountTaskCompleted       CycleCountTaskStatus = "completed"
+	CycleCountTaskApproved        CycleCountTaskStatus = "approved"
+	CycleCountTaskRejected        CycleCountTaskStatus = "rejected"
+)
+
+// CycleCountTask asks for one stock row to be counted. SystemQuantity is the on-hand
+// quantity when the count was submitted and Variance the difference to the count.
+type CycleCountTask struct {
+	ID              uint                 `gorm:"primarykey" json:"id"`
+	CreatedAt       time.Time            `json:"created_at"`
+	UpdatedAt       time.Time            `json:"updated_at"`
+	PlanID          uint                 `gorm:"index" json:"plan_id"`
+	StockID         uint                 `gorm:"index" json:"stock_id"`
+	ProductID       uint                 `gorm:"index" json:"product_id"`
+	BinID           *uint                `json:"bin_id,omitempty"`
+	Status          CycleCountTaskStatus `gorm:"index" json:"status"`
+	SystemQuantity  uint                 `json:"system_quantity"`
+	CountedQuantity *uint                `json:"counted_quantity"`
+	SerialNumbers   []string             `gorm:"serializer:json" json:"serial_numbers,omitempty"`
+	Variance        int                  `json:"variance"`
+	CountedBy       *uint                `json:"counted_by,omitempty"`
+	CountedAt       *time.Time           `json:"counted_at,omitempty"`
+	ReviewedBy      *uint                `json:"reviewed_by,omitempty"`
+	ReviewedAt      *time.Time           `json:"reviewed_at,omitempty"`
+	Note            string               `json:"note,omitempty"`
+	AccountID       uint                 `gorm:"index"` // Foreign key to Account
+}
+
+// ChangeStockStatusRequest moves quantity of a stock row from one status to another
+type ChangeStockStatusRequest struct {
+	FromStatus InventoryStatus    `json:"from_status" binding:"required"`
+	ToStatus   InventoryStatus    `json:"to_status" binding:"required"`
+	Quantity   uint               `json:"quantity" binding:"required"`
+	Reason     StatusChangeReason `json:"reason" binding:"required"`
+	Note       string             `json:"note"`
+}
+
+type StockStatusChangesResponse struct {
+	Message string              `json:"message"`
+	Changes []StockStatusChange `json:"changes"`
+}
+
+// HeldStock is quantity on hold in a stock row, or summed over a product or status.
+// Value is the held quantity at the product's current cost.
+type HeldStock struct {
+	StockID     uint            `json:"stock_id,omitempty"`
+	ProductID   uint            `json:"product_id,omitempty"`
+	ProductName string          `json:"product_name,omitempty"`
+	SKU         string          `json:"sku,omitempty"`
+	LotNumber   string          `json:"lot_number,omitempty"`
+	BinID       *uint           `json:"bin_id,omitempty"`
+	Location    string          `json:"location,omitempty"`
+	Status      InventoryStatus `json:"status"`
+	Quantity    uint            `json:"quantity"`
+	Value       float64         `json:"value"`
+	// HeldSince is when quantity last moved into the status, if it was moved there by a status change
+	HeldSince *time.Time `json:"held_since,omitempty"`
+}
+
+type HeldStockReport struct {
+	Message       string      `json:"message"`
+	GroupBy       string      `json:"group_by"`
+	Lines         []HeldStock `json:"lines"`
+	TotalQuantity uint        `json:"total_quantity"`
+	TotalValue    float64     `json:"total_value"`
+}
+
+type SlottingStatus string
+
+const (
+	SlottingPending   SlottingStatus = "pending"
+	SlottingAccepted  SlottingStatus = "accepted"
+	SlottingDismissed SlottingStatus = "dismissed"
+)
+
+// SlottingRecommendation suggests moving a fast-moving stock row to a golden-zone bin
+// closer to dispatch. Picks are the order picks from the row over the analysed window
+// and DistanceSaving the metres that would not have been walked had it been in ToBinID.
+type SlottingRecommendation struct {
+	ID             uint           `gorm:"primarykey" json:"id"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	WarehouseID    uint           `gorm:"index" json:"warehouse_id"`
+	StockID        uint           `gorm:"index" json:"stock_id"`
+	ProductID      uint           `gorm:"index" json:"product_id"`
+	FromBinID      uint           `json:"from_bin_id"`
+	ToBinID        uint           `json:"to_bin_id"`
+	Quantity       uint           `json:"quantity"`
+	Picks          int            `json:"picks"`
+	FromDistance   float64        `json:"from_distance"`
+	ToDistance     float64        `json:"to_distance"`
+	DistanceSaving float64        `json:"distance_saving"`
+	Status         SlottingStatus `gorm:"index" json:"status"`
+	TransferID     *uint          `json:"transfer_id,omitempty"`
+	ReviewedBy     *uint          `json:"reviewed_by,omitempty"`
+	ReviewedAt     *time.Time     `json:"reviewed_at,omitempty"`
+	AccountID      uint           `gorm:"index"` // Foreign key to Account
+}
+
+// Warehouse is a physical building belonging to an account
+type Warehouse struct {
+	ID          uint           `gorm:"primarykey" json:"id"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index"`
+	Name        string         `json:"name"`
+	Address     string         `json:"address"`
+	Description string         `json:"description"`
+	// DispatchX and DispatchY place the dispatch area on the floor plan, in metres.
+	// Picks are walked from and back to it.
+	DispatchX float64 `json:"dispatch_x"`
+	DispatchY float64 `json:"dispatch_y"`
+	AccountID uint    `gorm:"index"` // Foreign key to Account
+	Zones     []Zone  `json:"zones,omitempty" gorm:"foreignKey:WarehouseID"`
+}
+
+// Zone is an area inside a warehouse, such as an aisle or a cold room
+type Zone struct {
+	ID          uint           `gorm:"primarykey" json:"id"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index"`
+	WarehouseID uint           `gorm:"index" json:"warehouse_id"`
+	Warehouse   *Warehouse     `json:"warehouse,omitempty"`
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	AccountID   uint           `gorm:"index"` // Foreign key to Account
+	Bins        []Bin          `json:"bins,omitempty" gorm:"foreignKey:ZoneID"`
+}
+
+// Bin is the smallest addressable storage location inside a zone
+type Bin struct {
+	ID          uint           `gorm:"primarykey" json:"id"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index"`
+	ZoneID      uint           `gorm:"index" json:"zone_id"`
+	Zone        *Zone          `json:"zone,omitempty"`
+	Code        string         `gorm:"index" json:"code"`
+	Barcode     string         `gorm:"index" json:"barcode"` // Printed bin label, unique within the account
+	Description string         `json:"description"`
+	// X and Y place the bin on the warehouse floor plan, in metres. Golden-zone bins are
+	// the ones within easy reach, e.g. at waist height, and are kept for fast movers.
+	X          float64 `json:"x"`
+	Y          float64 `json:"y"`
+	GoldenZone bool    `json:"golden_zone"`
+	AccountID  uint    `gorm:"index"` // Foreign key to Account
+}
+
+// Label returns a human readable "warehouse/zone/bin" path for the bin.
+// Zone and Zone.Warehouse must be preloaded for the full path.
+func (b Bin) Label() string {
+	if b.Zone == nil {
+		return b.Code
+	}
+	if b.Zone.Warehouse == nil {
+		return b.Zone.Name + "/" + b.Code
+	}
+	return b.Zone.Warehouse.Name + "/" + b.Zone.Name + "/" + b.Code
 }
 
 type Category struct {
@@ -44,6 +887,7 @@ type Category struct {
 	DeletedAt   gorm.DeletedAt `gorm:"index"`
 	Name        string         `json:"name"`
 	Description string         `json:"description"`
+	ParentID    *uint          `gorm:"index" json:"parent_id"` // Nil for top-level categories
 	AccountID   uint           `gorm:"index"`
 }
 
@@ -56,7 +900,51 @@ type Supplier struct {
 	Description string         `json:"description"`
 	Email       string         `json:"email"`
 	Contact     string         `json:"contact"`
-	AccountID   uint           `gorm:"index"` // Foreign key to Account
+	// LeadTimeDays is the quoted lead time for products without a price list entry of their own
+	LeadTimeDays uint `json:"lead_time_days"`
+	AccountID    uint `gorm:"index"` // Foreign key to Account
+}
+
+// SupplierPrice is a price list entry: the unit cost and quoted lead time of a product from
+// a supplier, valid from EffectiveFrom until EffectiveTo (open-ended when nil). MinQuantity
+// gives quantity breaks; the entry with the highest break not above the ordered quantity applies.
+type SupplierPrice struct {
+	ID            uint       `gorm:"primarykey" json:"id"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	SupplierID    uint       `gorm:"index" json:"supplier_id"`
+	ProductID     uint       `gorm:"index" json:"product_id"`
+	UnitCost      float64    `json:"unit_cost"`
+	MinQuantity   uint       `json:"min_quantity"`
+	LeadTimeDays  uint       `json:"lead_time_days"`
+	EffectiveFrom time.Time  `json:"effective_from"`
+	EffectiveTo   *time.Time `json:"effective_to,omitempty"`
+	AccountID     uint       `gorm:"index"` // Foreign key to Account
+}
+
+// Covers reports whether the price is in effect at the given time
+func (p SupplierPrice) Covers(at time.Time) bool {
+	return !at.Before(p.EffectiveFrom) && (p.EffectiveTo == nil || at.Before(*p.EffectiveTo))
+}
+
+// SupplierScorecard measures a supplier's deliveries on purchase orders sent in a period.
+// On-time and fill rates are taken over closed orders, lead times over every order that
+// received stock. Variance is the actual lead time minus the quoted one, in days.
+type SupplierScorecard struct {
+	SupplierID                  uint     `json:"supplier_id"`
+	SupplierName                string   `json:"supplier_name"`
+	QuotedLeadTimeDays          uint     `json:"quoted_lead_time_days"`
+	ReceivedOrders              int      `json:"received_orders"`
+	ClosedOrders                int      `json:"closed_orders"`
+	OnTimeOrders                int      `json:"on_time_orders"`
+	OnTimeRate                  float64  `json:"on_time_rate"`
+	OrderedQuantity             uint     `json:"ordered_quantity"`
+	ReceivedQuantity            uint     `json:"received_quantity"`
+	FillRate                    float64  `json:"fill_rate"`
+	AverageLeadTimeDays         float64  `json:"average_lead_time_days"`
+	AverageLeadTimeVarianceDays float64  `json:"average_lead_time_variance_days"`
+	UnitCost                    *float64 `json:"unit_cost,omitempty"`
+	ProductLeadTimeDays         *uint    `json:"product_lead_time_days,omitempty"`
 }
 
 type Order struct {
@@ -149,10 +1037,11 @@ type Department struct {
 }
 
 type OrderEvent struct {
-	OrderID   uint   `json:"order_id"`
-	ProductID uint   `json:"product_id"`
-	Quantity  uint   `json:"quantity"`
-	Action    string `json:"action"`
+	OrderID       uint   `json:"order_id"`
+	ProductID     uint   `json:"product_id"`
+	Quantity      uint   `json:"quantity"`
+	UnitOfMeasure string `json:"unit_of_measure,omitempty"`
+	Action        string `json:"action"`
 }
 
 type InventoryStatusEvent struct {
@@ -160,6 +1049,44 @@ type InventoryStatusEvent struct {
 	Status  string `json:"status"`
 }
 
+// ShippingStatusEvent is consumed from SHIPPING_STATUS_TOPIC
+type ShippingStatusEvent struct {
+	OrderID       uint     `json:"order_id"`
+	ShippingID    uint     `json:"shipping_id"`
+	Action        string   `json:"action"`
+	SerialNumbers []string `json:"serial_numbers,omitempty"`
+}
+
+// ReceiptEvent is consumed from RECEIPT_EVENT_TOPIC when the dock receives stock against
+// a purchase order line. CloseLine is set when no more of the line is expected.
+type ReceiptEvent struct {
+	ReceiptID       uint       `json:"receipt_id"`
+	PurchaseOrderID uint       `json:"purchase_order_id"`
+	LineID          uint       `json:"line_id"`
+	ProductID       uint       `json:"product_id"`
+	Quantity        uint       `json:"quantity"`
+	UnitOfMeasure   string     `json:"unit_of_measure,omitempty"` // Unit of Quantity, the line's unit when empty
+	BinID           *uint      `json:"bin_id,omitempty"`
+	Location        string     `json:"location,omitempty"`
+	LotNumber       string     `json:"lot_number,omitempty"`
+	ExpiresAt       *time.Time `json:"expires_at,omitempty"`
+	SerialNumbers   []string   `json:"serial_numbers,omitempty"`
+	CloseLine       bool       `json:"close_line"`
+	ReceivedBy      *uint      `json:"received_by,omitempty"`
+	ReceivedAt      time.Time  `json:"received_at"`
+	AccountID       uint       `json:"account_id"`
+}
+
+// ReceiptStatusEvent is published to RECEIPT_STATUS_TOPIC once a receipt was posted to
+// stock, or with the reason it could not be, so the dock can correct and repost it
+type ReceiptStatusEvent struct {
+	ReceiptID       uint   `json:"receipt_id"`
+	PurchaseOrderID uint   `json:"purchase_order_id"`
+	Posted          bool   `json:"posted"`
+	Error           string `json:"error,omitempty"`
+	AccountID       uint   `json:"account_id"`
+}
+
 type ErrorResponse struct {
 	Error string `json:"message"`
 }
@@ -169,16 +1096,103 @@ type SuccessResponse struct {
 }
 
 type StockResponse struct {
-	Message     string `json:"message"`
-	ID          uint   `json:"id"`
-	ProductName string `json:"product_name"`
+	Message       string     `json:"message"`
+	ID            uint       `json:"id"`
+	ProductName   string     `json:"product_name"`
+	Quantity      int        `json:"quantity"`
+	Reserved      int        `json:"reserved_quantity"`
+	Available     int        `json:"available_quantity"`
+	Quarantine    int        `json:"quarantine_quantity,omitempty"`
+	Damaged       int        `json:"damaged_quantity,omitempty"`
+	QCHold        int        `json:"qc_hold_quantity,omitempty"`
+	Location      string     `json:"location"`
+	BinID         *uint      `json:"bin_id,omitempty"`
+	ZoneID        *uint      `json:"zone_id,omitempty"`
+	WarehouseID   *uint      `json:"warehouse_id,omitempty"`
+	LotNumber     string     `json:"lot_number,omitempty"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	UnitOfMeasure string     `json:"unit_of_measure,omitempty"`
+	UnitQuantity  float64    `json:"unit_quantity,omitempty"`
+}
+
+// ExpiringLot is a stock lot that expires within the requested window
+type ExpiringLot struct {
+	StockID         uint      `json:"stock_id"`
+	ProductID       uint      `json:"product_id"`
+	ProductName     string    `json:"product_name"`
+	LotNumber       string    `json:"lot_number"`
+	Quantity        int       `json:"quantity"`
+	Location        string    `json:"location"`
+	ExpiresAt       time.Time `json:"expires_at"`
+	DaysUntilExpiry int       `json:"days_until_expiry"`
+	Expired         bool      `json:"expired"`
+}
+
+type ExpiringLotsResponse struct {
+	Message string        `json:"message"`
+	Days    int           `json:"days"`
+	Lots    []ExpiringLot `json:"lots"`
+}
+
+// StockLocationTotal is the aggregated quantity held in a single warehouse or zone
+type StockLocationTotal struct {
+	ID       uint   `json:"id"`
+	Name     string `json:"name"`
+	Quantity int    `json:"quantity"`
+}
+
+// InTransitStock is quantity carried by a transfer. It is no longer part of the source
+// row and not yet part of the destination, so it is reported separately.
+type InTransitStock struct {
+	TransferID  uint   `json:"transfer_id"`
+	ProductID   uint   `json:"product_id"`
+	FromStockID uint   `json:"from_stock_id"`
+	ToStockID   *uint  `json:"to_stock_id,omitempty"`
+	ToBinID     *uint  `json:"to_bin_id,omitempty"`
 	Quantity    int    `json:"quantity"`
-	Location    string `json:"location"`
+	Status      string `json:"status"`
 }
 
 type StocksResponse struct {
-	Message string          `json:"message"`
-	Stocks  []StockResponse `json:"stocks"`
+	Message   string               `json:"message"`
+	Stocks    []StockResponse      `json:"stocks"`
+	Totals    []StockLocationTotal `json:"totals,omitempty"`
+	InTransit []InTransitStock     `json:"in_transit"`
+}
+
+type StockMovementsResponse struct {
+	Message   string          `json:"message"`
+	Movements []StockMovement `json:"movements"`
+}
+
+// LedgerCheck compares the current quantity of a stock row with the replayed sum of its movements
+type LedgerCheck struct {
+	StockID       uint `json:"stock_id"`
+	ProductID     uint `json:"product_id"`
+	Quantity      int  `json:"quantity"`
+	LedgerBalance int  `json:"ledger_balance"`
+	Consistent    bool `json:"consistent"`
+}
+
+type LedgerVerificationResponse struct {
+	Message    string        `json:"message"`
+	Consistent bool          `json:"consistent"`
+	Stocks     []LedgerCheck `json:"stocks"`
+}
+
+type WarehousesResponse struct {
+	Message    string      `json:"message"`
+	Warehouses []Warehouse `json:"warehouses"`
+}
+
+type ZonesResponse struct {
+	Message string `json:"message"`
+	Zones   []Zone `json:"zones"`
+}
+
+type BinsResponse struct {
+	Message string `json:"message"`
+	Bins    []Bin  `json:"bins"`
 }
 
 type CategoryResponse struct {
@@ -193,12 +1207,175 @@ type CategoriesResponse struct {
 	Categories []CategoryResponse `json:"categories"`
 }
 
+// CategoryNode is a category with its subcategories nested below it
+type CategoryNode struct {
+	ID          uint           `json:"id"`
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	ParentID    *uint          `json:"parent_id"`
+	Children    []CategoryNode `json:"children"`
+}
+
+// CategoryTreeResponse represents the response for retrieving the category tree
+type CategoryTreeResponse struct {
+	Message    string         `json:"message"`
+	Categories []CategoryNode `json:"categories"`
+}
+
+// MoveCategoryRequest represents the request to move a category below another one.
+// A null parent_id moves it to the top level.
+type MoveCategoryRequest struct {
+	ParentID *uint `json:"parent_id"`
+}
+
 type SupplierResponse struct {
-	ID          uint   `json:"id"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Email       string `json:"email"`
-	Contact     string `json:"contact"`
+	ID           uint   `json:"id"`
+	Name         string `json:"name"`
+	Description  string `json:"description"`
+	Email        string `json:"email"`
+	Contact      string `json:"contact"`
+	LeadTimeDays uint   `json:"lead_time_days"`
+}
+
+type SupplierPricesResponse struct {
+	Message string          `json:"message"`
+	Prices  []SupplierPrice `json:"prices"`
+}
+
+type SupplierScorecardsResponse struct {
+	Message    string              `json:"message"`
+	Scorecards []SupplierScorecard `json:"scorecards"`
+}
+
+type StockStatus string
+
+const (
+	StockStatusInStock    StockStatus = "in_stock"
+	StockStatusLowStock   StockStatus = "low_stock" // At or below the reorder point
+	StockStatusOutOfStock StockStatus = "out_of_stock"
+)
+
+// ProductSearchHit is a product found by search with its relevance and availability
+type ProductSearchHit struct {
+	Product     Product     `json:"product"`
+	Rank        float64     `json:"rank"`
+	Available   uint        `json:"available"`
+	StockStatus StockStatus `json:"stock_status"`
+}
+
+// SearchFacet counts the matching products of one category or supplier
+type SearchFacet struct {
+	ID    uint   `json:"id"`
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+// StockStatusFacet counts the matching products in one stock status
+type StockStatusFacet struct {
+	Status StockStatus `json:"status"`
+	Count  int64       `json:"count"`
+}
+
+// ProductSearchFacets break the matching products down for narrowing the search. Each
+// facet ignores its own filter so the other choices stay visible.
+type ProductSearchFacets struct {
+	Categories  []SearchFacet      `json:"categories"`
+	Suppliers   []SearchFacet      `json:"suppliers"`
+	StockStatus []StockStatusFacet `json:"stock_status"`
+}
+
+// ProductSearchResponse represents the response for a product search
+type ProductSearchResponse struct {
+	Message string              `json:"message"`
+	Total   int64               `json:"total"`
+	Results []ProductSearchHit  `json:"results"`
+	Facets  ProductSearchFacets `json:"facets"`
+}
+
+type AttachmentKind string
+
+const (
+	AttachmentImage     AttachmentKind = "image"
+	AttachmentSpecSheet AttachmentKind = "spec_sheet"
+	AttachmentSDS       AttachmentKind = "sds" // Safety data sheet
+	AttachmentDocument  AttachmentKind = "document"
+)
+
+// Attachment is an image or document attached to a product. The file and, for images,
+// its thumbnail are kept in the attachment storage under StorageKey and ThumbnailKey.
+type Attachment struct {
+	ID          uint           `gorm:"primarykey" json:"id"`
+	CreatedAt   time.Time      `json:"created_at"`
+	ProductID   uint           `gorm:"index" json:"product_id"`
+	Kind        AttachmentKind `gorm:"index" json:"kind"`
+	FileName    string         `json:"file_name"`
+	ContentType string         `json:"content_type"` // Detected from the file's content
+	Size        int64          `json:"size"`
+	StorageKey  string         `json:"-"`
+	// ThumbnailKey is empty when no thumbnail could be made, e.g. for documents
+	ThumbnailKey  string `json:"-"`
+	ThumbnailSize int64  `json:"thumbnail_size"`
+	UploadedBy    *uint  `json:"uploaded_by"`
+	AccountID     uint   `gorm:"index" json:"account_id"`
+}
+
+// AttachmentsResponse represents the response for a list of attachments
+type AttachmentsResponse struct {
+	Message     string       `json:"message"`
+	Attachments []Attachment `json:"attachments"`
+}
+
+// AttachmentUsage is the storage an account's attachments use against its quota
+type AttachmentUsage struct {
+	Count      int64 `json:"count"`
+	UsedBytes  int64 `json:"used_bytes"`
+	QuotaBytes int64 `json:"quota_bytes"`
+}
+
+// ClassCount is the number of products in an ABC/XYZ class pair and the value they sold
+type ClassCount struct {
+	ABCClass string  `json:"abc_class"`
+	XYZClass string  `json:"xyz_class"`
+	Products int     `json:"products"`
+	Value    float64 `json:"value"`
+}
+
+// ClassificationSummary breaks an account's products down by ABC and XYZ class
+type ClassificationSummary struct {
+	Message      string       `json:"message"`
+	From         time.Time    `json:"from"`
+	To           time.Time    `json:"to"`
+	ClassifiedAt *time.Time   `json:"classified_at"`
+	Classes      []ClassCount `json:"classes"`
+}
+
+// GenerateSlottingRequest asks for re-slotting recommendations for a warehouse based
+// on the picks of the last Days days
+type GenerateSlottingRequest struct {
+	WarehouseID uint `json:"warehouse_id" binding:"required"`
+	Days        int  `json:"days"`
+}
+
+type SlottingRecommendationsResponse struct {
+	Message         string                   `json:"message"`
+	Recommendations []SlottingRecommendation `json:"recommendations"`
+	TotalSaving     float64                  `json:"total_saving"`
+}
+
+// ProductThresholds are the stock levels that trigger replenishment and low-stock alerts
+// for a product, with the lead time they have to cover. On update, omitted fields are
+// left unchanged and LowStockThreshold is set on every stock row of the product.
+type ProductThresholds struct {
+	ProductID         uint  `json:"product_id"`
+	SupplierID        uint  `json:"supplier_id"`
+	LeadTimeDays      uint  `json:"lead_time_days"`
+	OnHand            uint  `json:"on_hand"`
+	ReorderPoint      *uint `json:"reorder_point"`
+	ReorderQuantity   *uint `json:"reorder_quantity"`
+	LowStockThreshold *int  `json:"low_stock_threshold,omitempty"`
+	// SafetyStock is a product-wide safety stock that is split across the product's stock
+	// rows as their low-stock thresholds, by each row's share of recent shipments
+	SafetyStock *uint `json:"safety_stock,omitempty"`
 }
 
 // SuppliersResponse represents the response for retrieving supplier items
@@ -206,3 +1383,292 @@ type SuppliersResponse struct {
 	Message   string             `json:"message"`
 	Suppliers []SupplierResponse `json:"suppliers"`
 }
+
+type MoveSerialsRequest struct {
+	SerialNumbers []string `json:"serial_numbers"`
+	ToStockID     uint     `json:"to_stock_id"`
+}
+
+type SerialNumbersResponse struct {
+	Message       string         `json:"message"`
+	SerialNumbers []SerialNumber `json:"serial_numbers"`
+}
+
+// SerialShipment is a shipment recorded by the shipping-receiving service for a serial
+type SerialShipment struct {
+	ShippingID   uint      `json:"shipping_id"`
+	OrderID      uint      `json:"order_id"`
+	Status       string    `json:"status"`
+	ShippingDate time.Time `json:"shipping_date"`
+	RecordedAt   time.Time `json:"recorded_at"`
+}
+
+type SerialHistoryResponse struct {
+	Message       string           `json:"message"`
+	Serial        string           `json:"serial"`
+	SerialNumbers []SerialNumber   `json:"serial_numbers"`
+	Events        []SerialEvent    `json:"events"`
+	Shipments     []SerialShipment `json:"shipments"`
+	// ShipmentLookupError is set when the shipping-receiving service could not be reached
+	ShipmentLookupError string `json:"shipment_lookup_error,omitempty"`
+}
+
+type CycleCountPlansResponse struct {
+	Message string           `json:"message"`
+	Plans   []CycleCountPlan `json:"plans"`
+}
+
+type CycleCountTasksResponse struct {
+	Message string           `json:"message"`
+	Tasks   []CycleCountTask `json:"tasks"`
+}
+
+type SubmitCountRequest struct {
+	CountedQuantity *uint    `json:"counted_quantity"`
+	SerialNumbers   []string `json:"serial_numbers"`
+	Note            string   `json:"note"`
+}
+
+type ReviewCountRequest struct {
+	Note string `json:"note"`
+}
+
+type TransfersResponse struct {
+	Message   string          `json:"message"`
+	Transfers []TransferOrder `json:"transfers"`
+}
+
+type ReceiveTransferRequest struct {
+	Quantity      uint     `json:"quantity"`
+	SerialNumbers []string `json:"serial_numbers"`
+}
+
+// ImportRowError reports why a row of an import file was rejected. Row counts data rows from 1.
+type ImportRowError struct {
+	Row   int    `json:"row"`
+	Key   string `json:"key,omitempty"`
+	Error string `json:"error"`
+}
+
+// ImportResult summarizes an import. Committed is false for dry runs and for
+// all-or-nothing imports that were rolled back because of a failed row.
+type ImportResult struct {
+	Message      string           `json:"message"`
+	Entity       string           `json:"entity"`
+	DryRun       bool             `json:"dry_run"`
+	AllOrNothing bool             `json:"all_or_nothing"`
+	Committed    bool             `json:"committed"`
+	Created      int              `json:"created"`
+	Updated      int              `json:"updated"`
+	Failed       int              `json:"failed"`
+	Errors       []ImportRowError `json:"errors"`
+}
+
+// ValuationLine is the on-hand quantity and value of one product, category or warehouse
+type ValuationLine struct {
+	ID       uint    `json:"id"`
+	Name     string  `json:"name"`
+	Quantity int     `json:"quantity"`
+	Value    float64 `json:"value"`
+	UnitCost float64 `json:"unit_cost,omitempty"`
+}
+
+type ValuationReport struct {
+	Message       string          `json:"message"`
+	AsOf          time.Time       `json:"as_of"`
+	GroupBy       string          `json:"group_by"`
+	Method        CostingMethod   `json:"costing_method"`
+	TotalQuantity int             `json:"total_quantity"`
+	TotalValue    float64         `json:"total_value"`
+	Lines         []ValuationLine `json:"lines"`
+}
+
+// StockSnapshot records the account's stock on hand at a point in time, normally
+// midnight, with one line per stock row that held quantity
+type StockSnapshot struct {
+	ID            uint      `gorm:"primarykey" json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	TakenAt       time.Time `gorm:"uniqueIndex:idx_snapshot_account_time" json:"taken_at"`
+	Lines         int       `json:"lines"`
+	TotalQuantity int       `json:"total_quantity"`
+	AccountID     uint      `gorm:"uniqueIndex:idx_snapshot_account_time" json:"account_id"`
+}
+
+// StockSnapshotLine is the quantity of one stock row in a snapshot. BinID is the bin the
+// row was in at the time.
+type StockSnapshotLine struct {
+	ID         uint  `gorm:"primarykey" json:"id"`
+	SnapshotID uint  `gorm:"index" json:"snapshot_id"`
+	StockID    uint  `gorm:"index" json:"stock_id"`
+	ProductID  uint  `gorm:"index" json:"product_id"`
+	BinID      *uint `json:"bin_id"`
+	Quantity   int   `json:"quantity"`
+	AccountID  uint  `gorm:"index" json:"account_id"`
+}
+
+// TakeSnapshotRequest asks for a snapshot at a point in time, by default the last midnight
+type TakeSnapshotRequest struct {
+	TakenAt *time.Time `json:"taken_at"`
+}
+
+// StockSnapshotsResponse represents the response for a list of stock snapshots
+type StockSnapshotsResponse struct {
+	Message   string          `json:"message"`
+	Snapshots []StockSnapshot `json:"snapshots"`
+}
+
+// StockPosition is the quantity on hand of a stock row, product or location at a point in
+// time. Only the fields of the grouping are set.
+type StockPosition struct {
+	StockID     uint   `json:"stock_id,omitempty"`
+	ProductID   uint   `json:"product_id,omitempty"`
+	ProductName string `json:"product_name,omitempty"`
+	SKU         string `json:"sku,omitempty"`
+	LotNumber   string `json:"lot_number,omitempty"`
+	BinID       *uint  `json:"bin_id,omitempty"`
+	Location    string `json:"location,omitempty"`
+	Quantity    int    `json:"quantity"`
+}
+
+// StockAsOfReport is the account's stock on hand reconstructed for a point in time.
+// SnapshotID is the snapshot the reconstruction started from, if any.
+type StockAsOfReport struct {
+	Message       string          `json:"message"`
+	AsOf          time.Time       `json:"as_of"`
+	GroupBy       string          `json:"group_by"`
+	SnapshotID    *uint           `json:"snapshot_id"`
+	TotalQuantity int             `json:"total_quantity"`
+	Positions     []StockPosition `json:"positions"`
+}
+
+// OrderCOGS is the cost of the goods shipped for an order
+type OrderCOGS struct {
+	OrderID   uint      `json:"order_id"`
+	Quantity  uint      `json:"quantity"`
+	Amount    float64   `json:"amount"`
+	ShippedAt time.Time `json:"shipped_at"`
+}
+
+type COGSResponse struct {
+	Message string      `json:"message"`
+	Total   float64     `json:"total"`
+	Orders  []OrderCOGS `json:"orders"`
+}
+
+type CostLayersResponse struct {
+	Message string      `json:"message"`
+	Layers  []CostLayer `json:"layers"`
+}
+
+type PurchaseOrdersResponse struct {
+	Message        string          `json:"message"`
+	PurchaseOrders []PurchaseOrder `json:"purchase_orders"`
+}
+
+// SendPurchaseOrderResponse reports a sent purchase order. EmailError is set when the
+// order was marked as sent but the supplier could not be emailed.
+type SendPurchaseOrderResponse struct {
+	Message       string        `json:"message"`
+	PurchaseOrder PurchaseOrder `json:"purchase_order"`
+	EmailError    string        `json:"email_error,omitempty"`
+}
+
+type PurchaseOrderReceiptsResponse struct {
+	Message  string                 `json:"message"`
+	Receipts []PurchaseOrderReceipt `json:"receipts"`
+}
+
+// ReplenishmentResponse reports the draft purchase orders a replenishment run created or extended
+type ReplenishmentResponse struct {
+	Message        string          `json:"message"`
+	PurchaseOrders []PurchaseOrder `json:"purchase_orders"`
+}
+
+type ProductBarcodesResponse struct {
+	Message  string           `json:"message"`
+	Barcodes []ProductBarcode `json:"barcodes"`
+}
+
+// ProductLookupResponse resolves a scanned code to a product. Barcode is nil when the
+// scan matched the product's SKU.
+type ProductLookupResponse struct {
+	Message string          `json:"message"`
+	Product Product         `json:"product"`
+	Barcode *ProductBarcode `json:"barcode,omitempty"`
+	Unit    string          `json:"unit"`
+	Factor  uint            `json:"factor"`
+}
+
+// BinLookupResponse resolves a scanned bin label to the bin and the stock it holds
+type BinLookupResponse struct {
+	Message string          `json:"message"`
+	Bin     Bin             `json:"bin"`
+	Stocks  []StockResponse `json:"stocks"`
+}
+
+type ProductUnitsResponse struct {
+	Message  string        `json:"message"`
+	BaseUnit string        `json:"base_unit"`
+	Units    []ProductUnit `json:"units"`
+}
+
+// KitComponent is one line of a kit's bill of materials: the quantity of a component
+// product that goes into one kit. A product with components is a kit.
+type KitComponent struct {
+	ID          uint      `gorm:"primarykey" json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	KitID       uint      `gorm:"index" json:"kit_id"`
+	ComponentID uint      `gorm:"index" json:"component_id"`
+	Component   *Product  `gorm:"foreignKey:ComponentID" json:"component,omitempty"`
+	Quantity    uint      `json:"quantity"`
+	AccountID   uint      `gorm:"index"` // Foreign key to Account
+}
+
+type KitAssemblyStatus string
+
+const (
+	KitAssemblyOpen      KitAssemblyStatus = "open"
+	KitAssemblyCompleted KitAssemblyStatus = "completed"
+	KitAssemblyCancelled KitAssemblyStatus = "cancelled"
+)
+
+// KitAssembly is a work order to build a quantity of kits from their components. On
+// completion the components leave stock and the kits are added at the bin or location.
+type KitAssembly struct {
+	ID          uint              `gorm:"primarykey" json:"id"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+	KitID       uint              `gorm:"index" json:"kit_id"`
+	Quantity    uint              `json:"quantity"`
+	BinID       *uint             `json:"bin_id"`
+	Location    string            `json:"location"`
+	Status      KitAssemblyStatus `gorm:"index" json:"status"`
+	UnitCost    float64           `json:"unit_cost"` // Component cost of one kit, booked on completion
+	StockID     *uint             `json:"stock_id"`  // Stock row the kits were added to
+	CreatedBy   *uint             `json:"created_by"`
+	CompletedBy *uint             `json:"completed_by"`
+	CompletedAt *time.Time        `json:"completed_at"`
+	AccountID   uint              `gorm:"index"` // Foreign key to Account
+}
+
+// KitComponentsRequest replaces the bill of materials of a kit
+type KitComponentsRequest struct {
+	Components []KitComponent `json:"components"`
+}
+
+// KitResponse is a kit's bill of materials with the number of kits that can be sold,
+// from assembled stock and from the components on hand
+type KitResponse struct {
+	Message    string         `json:"message"`
+	KitID      uint           `json:"kit_id"`
+	Assembled  uint           `json:"assembled"`
+	Available  uint           `json:"available"`
+	Components []KitComponent `json:"components"`
+}
+
+// KitAssembliesResponse represents the response for retrieving kit assembly work orders
+type KitAssembliesResponse struct {
+	Message    string        `json:"message"`
+	Assemblies []KitAssembly `json:"assemblies"`
+}
@@ -0,0 +1,181 @@
+package tests_test
+
+import (
+	"encoding/json"
+	"inventory-management/internal/api/routes"
+	"inventory-management/internal/middleware"
+	"inventory-management/internal/model"
+	"inventory-management/internal/utils"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// useDirectoryService points USER_SERVICE_URL at a fake user service that lists the
+// account's users for alert rules and knows a manager, and returns the manager's token
+func useDirectoryService(t *testing.T, managerID, accountID uint, users []model.DirectoryUser) string {
+	r := gin.New()
+	r.GET("/users/", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "Users retrieved successfully", "users": users})
+	})
+	r.GET("/users/:id", func(c *gin.Context) {
+		permission := model.PermissionWorker
+		if c.Param("id") == strconv.Itoa(int(managerID)) {
+			permission = model.PermissionManager
+		}
+		c.JSON(http.StatusOK, model.User{ID: managerID, AccountID: accountID, Permission: permission})
+	})
+	server := httptest.NewServer(r)
+
+	previous := os.Getenv("USER_SERVICE_URL")
+	os.Setenv("USER_SERVICE_URL", server.URL)
+	t.Cleanup(func() {
+		os.Setenv("USER_SERVICE_URL", previous)
+		server.Close()
+	})
+
+	return createTestToken(managerID, accountID)
+}
+
+func TestLowStockAlertRules(t *testing.T) {
+	db, token, testUser := setupTestEnvironment()
+	managerToken := useDirectoryService(t, 2, testUser.AccountID, []model.DirectoryUser{
+		{ID: 3, Email: "lead@example.com", RoleID: 5, Department: "Warehouse"},
+		{ID: 4, Email: "buyer@example.com", RoleID: 6, Department: "Purchasing"},
+		{ID: 5, Email: "picker@example.com", RoleID: 7, Department: "Warehouse"},
+	})
+
+	sender := &MockEmailSender{}
+	sender.On("SendEmail", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	ns := utils.NewNotificationService(sender)
+	r := gin.Default()
+	r.Use(middleware.CORSMiddleware())
+	r.Use(middleware.AuthMiddleware(db))
+	routes.Routers(r, db, ns, testStorage())
+
+	hardware := model.Category{Name: "Hardware", AccountID: testUser.AccountID}
+	db.Create(&hardware)
+	fasteners := model.Category{Name: "Fasteners", ParentID: &hardware.ID, AccountID: testUser.AccountID}
+	db.Create(&fasteners)
+	warehouse := model.Warehouse{Name: "Main", AccountID: testUser.AccountID}
+	db.Create(&warehouse)
+	zone := model.Zone{WarehouseID: warehouse.ID, Name: "A", AccountID: testUser.AccountID}
+	db.Create(&zone)
+	bin := model.Bin{ZoneID: zone.ID, Code: "A-01", AccountID: testUser.AccountID}
+	db.Create(&bin)
+
+	bolts := model.Product{Name: "Bolts", CategoryID: fasteners.ID, AccountID: testUser.AccountID}
+	db.Create(&bolts)
+	stock := model.Stock{ProductID: bolts.ID, Quantity: 5, LowStockThreshold: 10, BinID: &bin.ID, Location: "A-01", AccountID: testUser.AccountID}
+	db.Create(&stock)
+
+	createRule := func(rule model.AlertRule) model.AlertRule {
+		w := performRequest(r, "POST", "/alert-rules", managerToken, rule)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &rule))
+		return rule
+	}
+	sentTo := func(email string) int {
+		calls := 0
+		for _, call := range sender.Calls {
+			if call.Arguments.String(0) == email {
+				calls++
+			}
+		}
+		return calls
+	}
+
+	var immediate, digest model.AlertRule
+	t.Run("CreateRules", func(t *testing.T) {
+		w := performRequest(r, "POST", "/alert-rules", token, model.AlertRule{RoleIDs: []uint{5}})
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		w = performRequest(r, "POST", "/alert-rules", managerToken, model.AlertRule{CategoryIDs: []uint{hardware.ID}})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		// Covers the subcategory, notifies the role and the user once each
+		immediate = createRule(model.AlertRule{Name: "Hardware leads", CategoryIDs: []uint{hardware.ID}, RoleIDs: []uint{5}, UserIDs: []uint{3}, CooldownMinutes: 60})
+		assert.Equal(t, model.AlertImmediate, immediate.Mode)
+		digest = createRule(model.AlertRule{Name: "Purchasing digest", WarehouseIDs: []uint{warehouse.ID}, Departments: []string{"Purchasing"}, Mode: model.AlertDigest})
+		createRule(model.AlertRule{Name: "Other warehouse", WarehouseIDs: []uint{warehouse.ID + 100}, Departments: []string{"Warehouse"}})
+
+		w = performRequest(r, "GET", "/alert-rules/"+strconv.Itoa(int(immediate.ID))+"/recipients", token, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var recipients []string
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &recipients))
+		assert.Equal(t, []string{"lead@example.com"}, recipients)
+	})
+
+	t.Run("CheckStockWithCooldown", func(t *testing.T) {
+		checkPath := "/stocks/check/" + strconv.Itoa(int(stock.ID))
+		w := performRequest(r, "GET", checkPath, token, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		w = performRequest(r, "GET", checkPath, token, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		assert.Equal(t, 1, sentTo("lead@example.com"))
+		assert.Equal(t, 0, sentTo("buyer@example.com"))
+		assert.Equal(t, 0, sentTo("picker@example.com"))
+
+		w = performRequest(r, "GET", "/low-stock-alerts?rule_id="+strconv.Itoa(int(immediate.ID)), token, nil)
+		var response model.LowStockAlertsResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, 1, len(response.Alerts))
+		assert.Equal(t, []string{"lead@example.com"}, response.Alerts[0].Recipients)
+		assert.NotNil(t, response.Alerts[0].SentAt)
+	})
+
+	t.Run("DailyDigest", func(t *testing.T) {
+		w := performRequest(r, "GET", "/low-stock-alerts?pending=true", token, nil)
+		var response model.LowStockAlertsResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, 2, len(response.Alerts))
+		assert.Equal(t, digest.ID, response.Alerts[0].RuleID)
+
+		assert.NoError(t, utils.SendLowStockDigests(db, ns))
+		assert.Equal(t, 1, sentTo("buyer@example.com"))
+
+		w = performRequest(r, "GET", "/low-stock-alerts?pending=true", token, nil)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, 0, len(response.Alerts))
+	})
+
+	t.Run("PauseRule", func(t *testing.T) {
+		immediate.IsActive = false
+		immediate.CooldownMinutes = 0
+		w := performRequest(r, "PUT", "/alert-rules/"+strconv.Itoa(int(immediate.ID)), managerToken, immediate)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = performRequest(r, "GET", "/stocks/check/"+strconv.Itoa(int(stock.ID)), token, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, 1, sentTo("lead@example.com"))
+
+		w = performRequest(r, "DELETE", "/alert-rules/"+strconv.Itoa(int(digest.ID)), managerToken, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("AlertAtThreshold", func(t *testing.T) {
+		immediate.IsActive = true
+		w := performRequest(r, "PUT", "/alert-rules/"+strconv.Itoa(int(immediate.ID)), managerToken, immediate)
+		assert.Equal(t, http.StatusOK, w.Code)
+		db.Model(&model.Stock{}).Where("id = ?", stock.ID).Update("quantity", 10)
+
+		w = performRequest(r, "GET", "/stocks/check/"+strconv.Itoa(int(stock.ID)), token, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, 2, sentTo("lead@example.com"))
+	})
+
+	db.Exec("DELETE FROM low_stock_alerts")
+	db.Exec("DELETE FROM alert_rules")
+	db.Exec("DELETE FROM stocks")
+	db.Exec("DELETE FROM products")
+	db.Exec("DELETE FROM bins")
+	db.Exec("DELETE FROM zones")
+	db.Exec("DELETE FROM warehouses")
+	db.Exec("DELETE FROM categories")
+}
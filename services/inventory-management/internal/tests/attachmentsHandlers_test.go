@@ -0,0 +1,206 @@
+package tests_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"inventory-management/internal/model"
+	"inventory-management/internal/storage"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// uploadFile posts a file to a product's attachments as multipart form data
+func uploadFile(r *gin.Engine, token string, productID uint, kind, fileName string, data []byte) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	if kind != "" {
+		form.WriteField("kind", kind)
+	}
+	part, _ := form.CreateFormFile("file", fileName)
+	part.Write(data)
+	form.Close()
+
+	req, _ := http.NewRequest("POST", "/products/"+strconv.Itoa(int(productID))+"/attachments", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestProductAttachments(t *testing.T) {
+	db, token, testUser := setupTestEnvironment()
+	r := SetupRouter(db)
+
+	product := model.Product{Name: "Drill", AccountID: testUser.AccountID}
+	db.Create(&product)
+
+	photo := image.NewRGBA(image.Rect(0, 0, 600, 300))
+	for x := 0; x < 600; x++ {
+		for y := 0; y < 300; y++ {
+			photo.Set(x, y, color.RGBA{R: uint8(x), G: 80, B: uint8(y), A: 255})
+		}
+	}
+	var photoPNG bytes.Buffer
+	png.Encode(&photoPNG, photo)
+	pdf := []byte("%PDF-1.4\n1 0 obj << /Type /Catalog >> endobj\ntrailer << /Root 1 0 R >>\n%%EOF\n")
+
+	var photoAttachment, sds model.Attachment
+	t.Run("Upload", func(t *testing.T) {
+		w := uploadFile(r, token, product.ID, "", "photo.png", photoPNG.Bytes())
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &photoAttachment))
+		assert.Equal(t, model.AttachmentImage, photoAttachment.Kind)
+		assert.Equal(t, "image/png", photoAttachment.ContentType)
+		assert.Greater(t, photoAttachment.ThumbnailSize, int64(0))
+
+		w = uploadFile(r, token, product.ID, "sds", "../../safety data.pdf", pdf)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &sds))
+		assert.Equal(t, "safety data.pdf", sds.FileName)
+		assert.Equal(t, int64(0), sds.ThumbnailSize)
+
+		// The content decides the type, not the file name
+		w = uploadFile(r, token, product.ID, "sds", "sheet.pdf", []byte("just some text"))
+		assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+		w = uploadFile(r, token, product.ID, "photo", "photo.png", photoPNG.Bytes())
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w = uploadFile(r, token, product.ID, "image", "broken.png", photoPNG.Bytes()[:200])
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w = uploadFile(r, token, product.ID+100, "", "photo.png", photoPNG.Bytes())
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		t.Setenv("ATTACHMENT_MAX_BYTES", "64")
+		w = uploadFile(r, token, product.ID, "", "photo.png", photoPNG.Bytes())
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+		w = performRequest(r, "GET", "/products/"+strconv.Itoa(int(product.ID))+"/attachments?kind=sds", token, nil)
+		var response model.AttachmentsResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, 1, len(response.Attachments))
+		assert.Equal(t, sds.ID, response.Attachments[0].ID)
+	})
+
+	t.Run("Download", func(t *testing.T) {
+		w := performRequest(r, "GET", "/attachments/"+strconv.Itoa(int(sds.ID))+"/download", token, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, pdf, w.Body.Bytes())
+		assert.Equal(t, "application/pdf", w.Header().Get("Content-Type"))
+		assert.Equal(t, `inline; filename="safety data.pdf"`, w.Header().Get("Content-Disposition"))
+
+		w = performRequest(r, "GET", "/attachments/"+strconv.Itoa(int(photoAttachment.ID))+"/download?thumbnail=true", token, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		thumbnail, err := jpeg.Decode(w.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, image.Rect(0, 0, 256, 128), thumbnail.Bounds())
+
+		w = performRequest(r, "GET", "/attachments/"+strconv.Itoa(int(sds.ID))+"/download?thumbnail=true", token, nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		w = performRequest(r, "GET", "/attachments/"+strconv.Itoa(int(sds.ID))+"/download", createTestToken(1, testUser.AccountID+1), nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("Quota", func(t *testing.T) {
+		w := performRequest(r, "GET", "/attachments/usage", token, nil)
+		var usage model.AttachmentUsage
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &usage))
+		assert.Equal(t, int64(2), usage.Count)
+		assert.Equal(t, photoAttachment.Size+photoAttachment.ThumbnailSize+sds.Size, usage.UsedBytes)
+
+		db.Create(&model.AccountSettings{AttachmentQuotaBytes: usage.UsedBytes + 100, AccountID: testUser.AccountID})
+		w = uploadFile(r, token, product.ID, "", "photo.png", photoPNG.Bytes())
+		assert.Equal(t, http.StatusConflict, w.Code)
+		w = uploadFile(r, token, product.ID, "document", "notes.csv", []byte("sku,qty\nD-1,4\n"))
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var csv model.Attachment
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &csv))
+		assert.Equal(t, "text/csv", csv.ContentType)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		w := performRequest(r, "DELETE", "/attachments/"+strconv.Itoa(int(photoAttachment.ID)), token, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		w = performRequest(r, "GET", "/attachments/"+strconv.Itoa(int(photoAttachment.ID))+"/download", token, nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		// The freed space takes the image again
+		w = uploadFile(r, token, product.ID, "", "photo.png", photoPNG.Bytes())
+		assert.Equal(t, http.StatusCreated, w.Code)
+	})
+
+	db.Exec("DELETE FROM attachments")
+	db.Exec("DELETE FROM account_settings")
+	db.Exec("DELETE FROM products")
+}
+
+func TestS3Storage(t *testing.T) {
+	// A fake S3 endpoint that checks requests are signed and keeps objects in memory
+	var mu sync.Mutex
+	objects := map[string][]byte{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		sum := sha256.Sum256(body)
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=test-key/") ||
+			r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(sum[:]) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodPut:
+			objects[r.URL.EscapedPath()] = body
+		case http.MethodGet:
+			object, ok := objects[r.URL.EscapedPath()]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write(object)
+		case http.MethodDelete:
+			delete(objects, r.URL.EscapedPath())
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	store, err := storage.NewS3Storage(storage.S3Config{
+		Endpoint:        server.URL,
+		Bucket:          "attachments",
+		AccessKeyID:     "test-key",
+		SecretAccessKey: "test-secret",
+		PathStyle:       true,
+	})
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	assert.NoError(t, store.Put(ctx, "1/products/7/spec sheet.pdf", []byte("%PDF-1.4"), "application/pdf"))
+	assert.Contains(t, objects, "/attachments/1/products/7/spec%20sheet.pdf")
+
+	body, err := store.Get(ctx, "1/products/7/spec sheet.pdf")
+	assert.NoError(t, err)
+	data, _ := io.ReadAll(body)
+	body.Close()
+	assert.Equal(t, []byte("%PDF-1.4"), data)
+
+	assert.NoError(t, store.Delete(ctx, "1/products/7/spec sheet.pdf"))
+	_, err = store.Get(ctx, "1/products/7/spec sheet.pdf")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
@@ -6,10 +6,13 @@ import (
 	"inventory-management/internal/api/routes"
 	"inventory-management/internal/middleware"
 	"inventory-management/internal/model"
+	"inventory-management/internal/storage"
 	"inventory-management/internal/utils"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strconv"
 	"testing"
 	"time"
 
@@ -36,10 +39,19 @@ func SetupRouter(db *gorm.DB) *gin.Engine {
 	r.Use(middleware.CORSMiddleware())
 	r.Use(middleware.AuthMiddleware(db))
 	ns := utils.NewNotificationService(&MockEmailSender{})
-	routes.Routers(r, db, ns)
+	routes.Routers(r, db, ns, testStorage())
 	return r
 }
 
+// testStorage keeps attachments uploaded by tests in a temporary directory
+func testStorage() storage.Storage {
+	store, err := storage.NewLocalStorage(filepath.Join(os.TempDir(), "inventory-test-attachments"))
+	if err != nil {
+		panic(err)
+	}
+	return store
+}
+
 func createTestToken(userID uint, accountID uint) string {
 	claims := jwt.MapClaims{
 		"sub":        userID,
@@ -582,3 +594,72 @@ func TestRecoverCategory(t *testing.T) {
 	db.Exec("DELETE FROM roles")
 	db.Exec("DELETE FROM products")
 }
+
+func TestCategoryHierarchy(t *testing.T) {
+	db, token, testUser := setupTestEnvironment()
+	r := SetupRouter(db)
+
+	tools := model.Category{Name: "Tools", AccountID: testUser.AccountID}
+	db.Create(&tools)
+	power := model.Category{Name: "Power Tools", ParentID: &tools.ID, AccountID: testUser.AccountID}
+	db.Create(&power)
+	drills := model.Category{Name: "Drills", ParentID: &power.ID, AccountID: testUser.AccountID}
+	db.Create(&drills)
+	db.Create(&model.Category{Name: "Uncategorized", AccountID: testUser.AccountID})
+
+	db.Create(&model.Product{Name: "Hammer", CategoryID: tools.ID, AccountID: testUser.AccountID})
+	db.Create(&model.Product{Name: "Cordless Drill", CategoryID: drills.ID, AccountID: testUser.AccountID})
+
+	t.Run("Tree", func(t *testing.T) {
+		w := performRequest(r, "GET", "/categories/tree", token, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response model.CategoryTreeResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, 2, len(response.Categories))
+		assert.Equal(t, "Tools", response.Categories[0].Name)
+		assert.Equal(t, "Drills", response.Categories[0].Children[0].Children[0].Name)
+	})
+
+	t.Run("ProductsInSubtree", func(t *testing.T) {
+		var products []model.Product
+		w := performRequest(r, "GET", "/products?category_id="+strconv.Itoa(int(tools.ID)), token, nil)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &products))
+		assert.Equal(t, 1, len(products))
+
+		w = performRequest(r, "GET", "/products?include_subcategories=true&category_id="+strconv.Itoa(int(tools.ID)), token, nil)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &products))
+		assert.Equal(t, 2, len(products))
+	})
+
+	t.Run("PreventCycles", func(t *testing.T) {
+		w := performRequest(r, "POST", "/categories/"+strconv.Itoa(int(tools.ID))+"/move", token, model.MoveCategoryRequest{ParentID: &drills.ID})
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		w = performRequest(r, "PUT", "/categories/"+strconv.Itoa(int(power.ID)), token, model.Category{Name: "Power Tools", ParentID: &power.ID})
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		missing := uint(999999)
+		w = performRequest(r, "POST", "/categories/"+strconv.Itoa(int(drills.ID))+"/move", token, model.MoveCategoryRequest{ParentID: &missing})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = performRequest(r, "POST", "/categories/"+strconv.Itoa(int(drills.ID))+"/move", token, model.MoveCategoryRequest{ParentID: &tools.ID})
+		assert.Equal(t, http.StatusOK, w.Code)
+		db.First(&drills, drills.ID)
+		assert.Equal(t, tools.ID, *drills.ParentID)
+	})
+
+	t.Run("DeleteWithSubcategories", func(t *testing.T) {
+		db.Model(&drills).Update("parent_id", power.ID)
+
+		w := performRequest(r, "DELETE", "/categories/"+strconv.Itoa(int(power.ID)), token, nil)
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		w = performRequest(r, "DELETE", "/categories/"+strconv.Itoa(int(power.ID))+"?reparent=true", token, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		db.First(&drills, drills.ID)
+		assert.Equal(t, tools.ID, *drills.ParentID)
+	})
+
+	db.Exec("DELETE FROM products")
+	db.Exec("DELETE FROM categories")
+}
@@ -0,0 +1,119 @@
+package tests_test
+
+import (
+	"encoding/json"
+	"inventory-management/internal/model"
+	"inventory-management/internal/utils"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// useManagerService points USER_SERVICE_URL at a fake user service that knows a manager
+// with the given ID next to the default worker, and returns the manager's token.
+func useManagerService(t *testing.T, managerID, accountID uint) string {
+	r := gin.New()
+	r.GET("/users/:id", func(c *gin.Context) {
+		permission := model.PermissionWorker
+		if c.Param("id") == strconv.Itoa(int(managerID)) {
+			permission = model.PermissionManager
+		}
+		c.JSON(http.StatusOK, model.User{ID: managerID, AccountID: accountID, Permission: permission})
+	})
+	server := httptest.NewServer(r)
+
+	previous := os.Getenv("USER_SERVICE_URL")
+	os.Setenv("USER_SERVICE_URL", server.URL)
+	t.Cleanup(func() {
+		os.Setenv("USER_SERVICE_URL", previous)
+		server.Close()
+	})
+
+	return createTestToken(managerID, accountID)
+}
+
+func TestCycleCounts(t *testing.T) {
+	db, token, testUser := setupTestEnvironment()
+	r := SetupRouter(db)
+	managerToken := useManagerService(t, 2, testUser.AccountID)
+
+	product := model.Product{Name: "Counted Product", AccountID: testUser.AccountID, ABCClass: "A"}
+	db.Create(&product)
+	stock1 := model.Stock{ProductID: product.ID, Quantity: 100, AccountID: testUser.AccountID}
+	stock2 := model.Stock{ProductID: product.ID, Quantity: 10, AccountID: testUser.AccountID}
+	db.Create(&stock1)
+	db.Create(&stock2)
+
+	var plan model.CycleCountPlan
+
+	t.Run("CreatePlanByABCClass", func(t *testing.T) {
+		w := performRequest(r, "POST", "/cycle-counts", token, model.CycleCountPlan{Type: model.CycleCountByABCClass})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = performRequest(r, "POST", "/cycle-counts", token, model.CycleCountPlan{Name: "A items", Type: model.CycleCountByABCClass, ABCClass: "A", TolerancePercent: 5})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &plan))
+		assert.Equal(t, 2, len(plan.Tasks))
+
+		w = performRequest(r, "POST", "/cycle-counts", token, model.CycleCountPlan{Name: "Sample", Type: model.CycleCountRandom, SampleSize: 1})
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	countPath := func(task model.CycleCountTask, action string) string {
+		return "/cycle-counts/tasks/" + strconv.Itoa(int(task.ID)) + "/" + action
+	}
+
+	t.Run("CountWithinTolerance", func(t *testing.T) {
+		counted := uint(97)
+		w := performRequest(r, "POST", countPath(plan.Tasks[0], "count"), token, model.SubmitCountRequest{CountedQuantity: &counted})
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var task model.CycleCountTask
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &task))
+		assert.Equal(t, model.CycleCountTaskCompleted, task.Status)
+		assert.Equal(t, -3, task.Variance)
+
+		var stock model.Stock
+		db.First(&stock, stock1.ID)
+		assert.Equal(t, uint(97), stock.Quantity)
+		balance, _ := utils.LedgerBalance(db, stock1.ID)
+		assert.Equal(t, -3, balance)
+	})
+
+	t.Run("CountBeyondToleranceNeedsManager", func(t *testing.T) {
+		counted := uint(4)
+		w := performRequest(r, "POST", countPath(plan.Tasks[1], "count"), token, model.SubmitCountRequest{CountedQuantity: &counted})
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var stock model.Stock
+		db.First(&stock, stock2.ID)
+		assert.Equal(t, uint(10), stock.Quantity)
+
+		w = performRequest(r, "POST", countPath(plan.Tasks[1], "approve"), token, nil)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w = performRequest(r, "POST", countPath(plan.Tasks[1], "approve"), managerToken, model.ReviewCountRequest{Note: "Confirmed by recount"})
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		db.First(&stock, stock2.ID)
+		assert.Equal(t, uint(4), stock.Quantity)
+
+		w = performRequest(r, "POST", countPath(plan.Tasks[1], "reject"), managerToken, nil)
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		var completed model.CycleCountPlan
+		db.First(&completed, plan.ID)
+		assert.Equal(t, model.CycleCountPlanCompleted, completed.Status)
+	})
+
+	db.Exec("DELETE FROM cycle_count_tasks")
+	db.Exec("DELETE FROM cycle_count_plans")
+	db.Exec("DELETE FROM stock_movements")
+	db.Exec("DELETE FROM stocks")
+	db.Exec("DELETE FROM products")
+}
@@ -0,0 +1,158 @@
+package tests_test
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"inventory-management/internal/model"
+	"inventory-management/internal/utils"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func performUpload(r *gin.Engine, path, token, contentType, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", path, strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestImportExport(t *testing.T) {
+	db, token, testUser := setupTestEnvironment()
+	r := SetupRouter(db)
+
+	t.Run("ImportCategoriesAndSuppliers", func(t *testing.T) {
+		w := performUpload(r, "/imports/categories", token, "text/csv", "name,description\nTools,Hand tools\nGarden,Outdoor\n")
+		assert.Equal(t, http.StatusOK, w.Code)
+		var result model.ImportResult
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		assert.Equal(t, 2, result.Created)
+
+		// Seed files carry account_id and numeric fields, which are accepted and ignored
+		w = performUpload(r, "/imports/suppliers", token, "application/json",
+			`[{"name": "Acme", "email": "acme@example.com", "account_id": 7}]`)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var supplier model.Supplier
+		assert.NoError(t, db.Where("name = ?", "Acme").First(&supplier).Error)
+		assert.Equal(t, testUser.AccountID, supplier.AccountID)
+	})
+
+	t.Run("DryRun", func(t *testing.T) {
+		w := performUpload(r, "/imports/products?dry_run=true", token, "text/csv",
+			"sku,name,price,category\nHAM-1,Hammer,12.5,Tools\n")
+		assert.Equal(t, http.StatusOK, w.Code)
+		var result model.ImportResult
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		assert.Equal(t, 1, result.Created)
+		assert.False(t, result.Committed)
+
+		var count int64
+		db.Model(&model.Product{}).Where("sku = ?", "HAM-1").Count(&count)
+		assert.Equal(t, int64(0), count)
+	})
+
+	t.Run("AllOrNothing", func(t *testing.T) {
+		body := "sku,name,price,category\nHAM-1,Hammer,12.5,Tools\nSAW-1,Saw,abc,Tools\nRAK-1,Rake,9,Unknown\n"
+		w := performUpload(r, "/imports/products?all_or_nothing=true", token, "text/csv", body)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		var result model.ImportResult
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		assert.Equal(t, 2, result.Failed)
+		assert.Equal(t, 2, result.Errors[0].Row)
+		assert.Equal(t, "SAW-1", result.Errors[0].Key)
+
+		var count int64
+		db.Model(&model.Product{}).Where("sku = ?", "HAM-1").Count(&count)
+		assert.Equal(t, int64(0), count)
+
+		// Without all_or_nothing the valid rows are kept
+		w = performUpload(r, "/imports/products", token, "text/csv", body)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		assert.Equal(t, 1, result.Created)
+		assert.Equal(t, 2, result.Failed)
+	})
+
+	t.Run("UpsertBySKU", func(t *testing.T) {
+		w := performUpload(r, "/imports/products", token, "text/csv", "sku,price\nHAM-1,14\n")
+		var result model.ImportResult
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		assert.Equal(t, 1, result.Updated)
+
+		var product model.Product
+		db.Preload("Category").Where("sku = ?", "HAM-1").First(&product)
+		assert.Equal(t, 14.0, product.Price)
+		assert.Equal(t, "Hammer", product.Name)
+		assert.Equal(t, "Tools", product.Category.Name)
+	})
+
+	t.Run("ImportStocks", func(t *testing.T) {
+		body := "sku,location,quantity\nHAM-1,Shelf 1,40\n"
+		w := performUpload(r, "/imports/stocks", token, "text/csv", body)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = performUpload(r, "/imports/stocks", token, "text/csv", "sku,location,quantity\nHAM-1,Shelf 1,25\n")
+		var result model.ImportResult
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		assert.Equal(t, 1, result.Updated)
+
+		var stock model.Stock
+		db.Where("location = ?", "Shelf 1").First(&stock)
+		assert.Equal(t, uint(25), stock.Quantity)
+
+		var movements []model.StockMovement
+		db.Where("stock_id = ?", stock.ID).Order("id").Find(&movements)
+		assert.Equal(t, 2, len(movements))
+		assert.Equal(t, -15, movements[1].Delta)
+		assert.Equal(t, model.MovementImport, movements[1].Reason)
+
+		// Larger changes than an adjustment may make without approval are rejected
+		db.Create(&model.AccountSettings{AdjustmentQuantityThreshold: 10, AccountID: testUser.AccountID})
+		w = performUpload(r, "/imports/stocks", token, "text/csv", "sku,location,quantity\nHAM-1,Shelf 1,5\n")
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		assert.Equal(t, 1, result.Failed)
+		assert.Equal(t, utils.ErrAdjustmentNeedsApproval.Error(), result.Errors[0].Error)
+		db.First(&stock, stock.ID)
+		assert.Equal(t, uint(25), stock.Quantity)
+
+		w = performUpload(r, "/imports/stocks", token, "text/csv", "sku,location,quantity\nHAM-1,Shelf 1,20\n")
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		assert.Equal(t, 1, result.Updated)
+	})
+
+	t.Run("Export", func(t *testing.T) {
+		w := performRequest(r, "GET", "/exports/products?format=csv", token, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		records, err := csv.NewReader(w.Body).ReadAll()
+		assert.NoError(t, err)
+		assert.Equal(t, 2, len(records))
+		assert.Equal(t, "sku", records[0][1])
+		assert.Equal(t, "HAM-1", records[1][1])
+		assert.Equal(t, "Tools", records[1][4])
+
+		w = performRequest(r, "GET", "/exports/stocks", token, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var stocks []map[string]interface{}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &stocks))
+		assert.Equal(t, 1, len(stocks))
+		assert.Equal(t, "HAM-1", stocks[0]["sku"])
+
+		w = performRequest(r, "GET", "/exports/orders", token, nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	db.Exec("DELETE FROM account_settings")
+	db.Exec("DELETE FROM stock_movements")
+	db.Exec("DELETE FROM stocks")
+	db.Exec("DELETE FROM products")
+	db.Exec("DELETE FROM categories")
+	db.Exec("DELETE FROM suppliers")
+}
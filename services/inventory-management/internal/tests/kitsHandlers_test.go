@@ -0,0 +1,147 @@
+package tests_test
+
+import (
+	"encoding/json"
+	"inventory-management/internal/model"
+	"inventory-management/internal/utils"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKits(t *testing.T) {
+	db, token, testUser := setupTestEnvironment()
+	r := SetupRouter(db)
+
+	kit := model.Product{Name: "Gift Set", AccountID: testUser.AccountID}
+	db.Create(&kit)
+	mug := model.Product{Name: "Mug", AccountID: testUser.AccountID}
+	db.Create(&mug)
+	tea := model.Product{Name: "Tea", AccountID: testUser.AccountID}
+	db.Create(&tea)
+
+	receive := func(product model.Product, quantity uint, cost float64) model.Stock {
+		stock := model.Stock{ProductID: product.ID, Quantity: quantity, Location: "Shelf", AccountID: testUser.AccountID}
+		db.Create(&stock)
+		assert.NoError(t, utils.RecordStockMovement(db, stock, int(quantity), model.StockMovement{Reason: model.MovementReceipt, UnitCost: &cost}))
+		return stock
+	}
+	mugStock := receive(mug, 10, 1)
+	teaStock := receive(tea, 3, 3)
+
+	kitPath := "/products/" + strconv.Itoa(int(kit.ID)) + "/components"
+	getKit := func() model.KitResponse {
+		w := performRequest(r, "GET", kitPath, token, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response model.KitResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response
+	}
+
+	t.Run("BillOfMaterials", func(t *testing.T) {
+		w := performRequest(r, "PUT", kitPath, token, model.KitComponentsRequest{Components: []model.KitComponent{
+			{ComponentID: kit.ID, Quantity: 1},
+		}})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = performRequest(r, "PUT", kitPath, token, model.KitComponentsRequest{Components: []model.KitComponent{
+			{ComponentID: mug.ID, Quantity: 2},
+			{ComponentID: tea.ID, Quantity: 1},
+		}})
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		// A component cannot become a kit of its own
+		w = performRequest(r, "PUT", "/products/"+strconv.Itoa(int(mug.ID))+"/components", token, model.KitComponentsRequest{Components: []model.KitComponent{
+			{ComponentID: tea.ID, Quantity: 1},
+		}})
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		response := getKit()
+		assert.Equal(t, 2, len(response.Components))
+		assert.Equal(t, "Mug", response.Components[0].Component.Name)
+		assert.Equal(t, uint(0), response.Assembled)
+		assert.Equal(t, uint(3), response.Available)
+	})
+
+	t.Run("ReserveAllOrNothing", func(t *testing.T) {
+		_, err := utils.ReserveStock(db, 700, kit.ID, 4)
+		assert.ErrorIs(t, err, utils.ErrInsufficientStock)
+
+		var reservations int64
+		db.Model(&model.StockReservation{}).Where("order_id = ?", 700).Count(&reservations)
+		assert.Equal(t, int64(0), reservations)
+		db.First(&mugStock, mugStock.ID)
+		assert.Equal(t, uint(0), mugStock.ReservedQuantity)
+	})
+
+	var assembly model.KitAssembly
+	t.Run("Assembly", func(t *testing.T) {
+		w := performRequest(r, "POST", "/kit-assemblies", token, model.KitAssembly{KitID: mug.ID, Quantity: 2, Location: "Packing"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = performRequest(r, "POST", "/kit-assemblies", token, model.KitAssembly{KitID: kit.ID, Quantity: 2, Location: "Packing"})
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &assembly))
+		assert.Equal(t, model.KitAssemblyOpen, assembly.Status)
+
+		assemblyPath := "/kit-assemblies/" + strconv.Itoa(int(assembly.ID))
+		w = performRequest(r, "POST", assemblyPath+"/complete", token, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &assembly))
+		assert.Equal(t, model.KitAssemblyCompleted, assembly.Status)
+		assert.InDelta(t, 5, assembly.UnitCost, 0.001)
+
+		db.First(&mugStock, mugStock.ID)
+		db.First(&teaStock, teaStock.ID)
+		assert.Equal(t, uint(6), mugStock.Quantity)
+		assert.Equal(t, uint(1), teaStock.Quantity)
+		var kitStock model.Stock
+		db.First(&kitStock, *assembly.StockID)
+		assert.Equal(t, uint(2), kitStock.Quantity)
+		assert.Equal(t, "Packing", kitStock.Location)
+
+		w = performRequest(r, "POST", assemblyPath+"/complete", token, nil)
+		assert.Equal(t, http.StatusConflict, w.Code)
+		w = performRequest(r, "POST", assemblyPath+"/cancel", token, nil)
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		response := getKit()
+		assert.Equal(t, uint(2), response.Assembled)
+		assert.Equal(t, uint(3), response.Available)
+	})
+
+	t.Run("ReserveAndShipKit", func(t *testing.T) {
+		// Two assembled kits are used first, the third is reserved as components
+		touched, err := utils.ReserveStock(db, 701, kit.ID, 3)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, len(touched))
+
+		reserved := map[uint]uint{}
+		var reservations []model.StockReservation
+		db.Where("order_id = ?", 701).Find(&reservations)
+		for _, reservation := range reservations {
+			reserved[reservation.ProductID] += reservation.Quantity
+		}
+		assert.Equal(t, map[uint]uint{kit.ID: 2, mug.ID: 2, tea.ID: 1}, reserved)
+		assert.Equal(t, uint(0), getKit().Available)
+
+		orderID := uint(701)
+		_, err = utils.ConsumeReservations(db, orderID, nil)
+		assert.NoError(t, err)
+		cogs, err := utils.OrderCOGS(db, testUser.AccountID, &orderID, nil, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(cogs))
+		assert.InDelta(t, 2*5+2*1+3, cogs[0].Amount, 0.001)
+	})
+
+	db.Exec("DELETE FROM kit_assemblies")
+	db.Exec("DELETE FROM kit_components")
+	db.Exec("DELETE FROM stock_reservations")
+	db.Exec("DELETE FROM cost_consumptions")
+	db.Exec("DELETE FROM cost_layers")
+	db.Exec("DELETE FROM stock_movements")
+	db.Exec("DELETE FROM stocks")
+	db.Exec("DELETE FROM products")
+}
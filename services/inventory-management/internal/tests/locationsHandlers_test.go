@@ -0,0 +1,112 @@
+package tests_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"inventory-management/internal/model"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func performRequest(r *gin.Engine, method, path, token string, body interface{}) *httptest.ResponseRecorder {
+	var payload []byte
+	if body != nil {
+		payload, _ = json.Marshal(body)
+	}
+	req, _ := http.NewRequest(method, path, bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestLocationHierarchy(t *testing.T) {
+	db, token, testUser := setupTestEnvironment()
+	r := SetupRouter(db)
+
+	var warehouse model.Warehouse
+	var zone model.Zone
+	var bin model.Bin
+
+	t.Run("CreateWarehouseZoneBin", func(t *testing.T) {
+		w := performRequest(r, "POST", "/warehouses", token, model.Warehouse{Name: "North"})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &warehouse))
+		assert.Equal(t, testUser.AccountID, warehouse.AccountID)
+
+		w = performRequest(r, "POST", "/zones", token, model.Zone{Name: "Aisle A", WarehouseID: warehouse.ID})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &zone))
+
+		w = performRequest(r, "POST", "/bins", token, model.Bin{Code: "A-01", ZoneID: zone.ID})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &bin))
+	})
+
+	t.Run("CreateZoneForeignWarehouse", func(t *testing.T) {
+		foreign := model.Warehouse{Name: "Foreign", AccountID: testUser.AccountID + 1}
+		db.Create(&foreign)
+
+		w := performRequest(r, "POST", "/zones", token, model.Zone{Name: "Aisle X", WarehouseID: foreign.ID})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("CreateDuplicateBinCode", func(t *testing.T) {
+		w := performRequest(r, "POST", "/bins", token, model.Bin{Code: "A-01", ZoneID: zone.ID})
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("CreateStockInBin", func(t *testing.T) {
+		product := model.Product{Name: "Binned Product", AccountID: testUser.AccountID}
+		db.Create(&product)
+
+		w := performRequest(r, "POST", "/stocks", token, model.Stock{ProductID: product.ID, Quantity: 40, BinID: &bin.ID})
+		assert.Equal(t, http.StatusOK, w.Code)
+		var stock model.Stock
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &stock))
+		assert.Equal(t, "North/Aisle A/A-01", stock.Location)
+
+		db.Create(&model.Stock{ProductID: product.ID, Quantity: 5, Location: "Legacy shelf", AccountID: testUser.AccountID})
+	})
+
+	t.Run("GetStocksByWarehouse", func(t *testing.T) {
+		w := performRequest(r, "GET", "/stocks?warehouse_id="+strconv.Itoa(int(warehouse.ID))+"&group_by=warehouse", token, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response model.StocksResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, 1, len(response.Stocks))
+		assert.Equal(t, warehouse.ID, *response.Stocks[0].WarehouseID)
+		assert.Equal(t, []model.StockLocationTotal{{ID: warehouse.ID, Name: "North", Quantity: 40}}, response.Totals)
+	})
+
+	t.Run("GetStocksGroupedByZone", func(t *testing.T) {
+		w := performRequest(r, "GET", "/stocks?group_by=zone", token, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response model.StocksResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, 2, len(response.Totals))
+		assert.Contains(t, response.Totals, model.StockLocationTotal{ID: zone.ID, Name: "Aisle A", Quantity: 40})
+		assert.Contains(t, response.Totals, model.StockLocationTotal{ID: 0, Name: "Unassigned", Quantity: 5})
+	})
+
+	t.Run("DeleteWarehouseWithZones", func(t *testing.T) {
+		w := performRequest(r, "DELETE", "/warehouses/"+strconv.Itoa(int(warehouse.ID)), token, nil)
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	// Clean up the database
+	db.Exec("DELETE FROM stocks")
+	db.Exec("DELETE FROM products")
+	db.Exec("DELETE FROM bins")
+	db.Exec("DELETE FROM zones")
+	db.Exec("DELETE FROM warehouses")
+	db.Exec("DELETE FROM users")
+	db.Exec("DELETE FROM roles")
+}
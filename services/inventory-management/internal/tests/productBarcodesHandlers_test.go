@@ -0,0 +1,122 @@
+package tests_test
+
+import (
+	"encoding/json"
+	"inventory-management/internal/model"
+	"inventory-management/internal/utils"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBarcodeScanning(t *testing.T) {
+	db, token, testUser := setupTestEnvironment()
+	r := SetupRouter(db)
+
+	product := model.Product{Name: "Scanned Product", SKU: "SCN-001", AccountID: testUser.AccountID}
+	db.Create(&product)
+	db.Create(&model.ProductUnit{ProductID: product.ID, Name: "case", Factor: 12, AccountID: testUser.AccountID})
+	barcodesPath := "/products/" + strconv.Itoa(int(product.ID)) + "/barcodes"
+
+	t.Run("UniqueSKU", func(t *testing.T) {
+		w := performRequest(r, "POST", "/products", token, model.Product{Name: "Other Product", SKU: "SCN-001"})
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		w = performRequest(r, "GET", "/products?sku=SCN-001", token, nil)
+		var products []model.Product
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &products))
+		assert.Equal(t, 1, len(products))
+	})
+
+	t.Run("ValidateBarcodes", func(t *testing.T) {
+		_, err := utils.BarcodeLookupKey(model.BarcodeUPCA, "036000291452")
+		assert.NoError(t, err)
+		_, err = utils.BarcodeLookupKey(model.BarcodeUPCA, "036000291453")
+		assert.ErrorIs(t, err, utils.ErrInvalidBarcode)
+		_, err = utils.BarcodeLookupKey(model.BarcodeEAN13, "4006381333931")
+		assert.NoError(t, err)
+
+		w := performRequest(r, "POST", barcodesPath, token, model.ProductBarcode{Symbology: model.BarcodeEAN13, Code: "4006381333932"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = performRequest(r, "POST", barcodesPath, token, model.ProductBarcode{Symbology: model.BarcodeUPCA, Code: "036000291452"})
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		// The EAN-13 form of the same UPC-A is the same GTIN
+		w = performRequest(r, "POST", barcodesPath, token, model.ProductBarcode{Symbology: model.BarcodeEAN13, Code: "0036000291452"})
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		w = performRequest(r, "POST", barcodesPath, token, model.ProductBarcode{Symbology: model.BarcodeGTIN14, Code: "10036000291459", Unit: "case"})
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = performRequest(r, "POST", barcodesPath, token, model.ProductBarcode{Symbology: model.BarcodeCode128, Code: "INT-42", Unit: "pallet"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = performRequest(r, "GET", barcodesPath, token, nil)
+		var response model.ProductBarcodesResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, 2, len(response.Barcodes))
+	})
+
+	t.Run("LookupProduct", func(t *testing.T) {
+		var response model.ProductLookupResponse
+
+		w := performRequest(r, "GET", "/products/lookup?barcode=0036000291452", token, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, product.ID, response.Product.ID)
+		assert.Equal(t, uint(1), response.Factor)
+
+		w = performRequest(r, "GET", "/products/lookup?barcode=10036000291459", token, nil)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "case", response.Unit)
+		assert.Equal(t, uint(12), response.Factor)
+
+		w = performRequest(r, "GET", "/products/lookup?barcode=SCN-001", token, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = performRequest(r, "GET", "/products/lookup?barcode=999", token, nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("LookupBin", func(t *testing.T) {
+		warehouse := model.Warehouse{Name: "Scan Warehouse", AccountID: testUser.AccountID}
+		db.Create(&warehouse)
+		zone := model.Zone{Name: "Scan Zone", WarehouseID: warehouse.ID, AccountID: testUser.AccountID}
+		db.Create(&zone)
+
+		w := performRequest(r, "POST", "/bins", token, model.Bin{ZoneID: zone.ID, Code: "S-01", Barcode: "BIN0001"})
+		assert.Equal(t, http.StatusOK, w.Code)
+		var bin model.Bin
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &bin))
+
+		w = performRequest(r, "POST", "/bins", token, model.Bin{ZoneID: zone.ID, Code: "S-02", Barcode: "BIN0001"})
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		db.Create(&model.Stock{ProductID: product.ID, Quantity: 5, BinID: &bin.ID, AccountID: testUser.AccountID})
+
+		w = performRequest(r, "GET", "/bins/lookup?barcode=BIN0001", token, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response model.BinLookupResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, bin.ID, response.Bin.ID)
+		assert.Equal(t, 1, len(response.Stocks))
+		assert.Equal(t, "Scan Warehouse/Scan Zone/S-01", response.Bin.Label())
+
+		w = performRequest(r, "GET", "/bins/lookup?barcode=S-01", token, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = performRequest(r, "GET", "/bins/lookup?barcode=BIN9999", token, nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	db.Exec("DELETE FROM product_barcodes")
+	db.Exec("DELETE FROM product_units")
+	db.Exec("DELETE FROM stocks")
+	db.Exec("DELETE FROM bins")
+	db.Exec("DELETE FROM zones")
+	db.Exec("DELETE FROM warehouses")
+	db.Exec("DELETE FROM products")
+}
@@ -0,0 +1,110 @@
+package tests_test
+
+import (
+	"encoding/json"
+	"inventory-management/internal/model"
+	"inventory-management/internal/utils"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProductClasses(t *testing.T) {
+	db, token, testUser := setupTestEnvironment()
+	managerToken := useManagerService(t, 2, testUser.AccountID)
+	r := SetupRouter(db)
+
+	bolts := model.Product{Name: "Bolts", SKU: "BLT", Price: 10, AccountID: testUser.AccountID}
+	db.Create(&bolts)
+	nuts := model.Product{Name: "Nuts", SKU: "NUT", Price: 1, AccountID: testUser.AccountID}
+	db.Create(&nuts)
+	washers := model.Product{Name: "Washers", SKU: "WSH", Price: 2, AccountID: testUser.AccountID}
+	db.Create(&washers)
+	boltsStock := model.Stock{ProductID: bolts.ID, Quantity: 40, Location: "A-01", AccountID: testUser.AccountID}
+	db.Create(&boltsStock)
+	nutsStock := model.Stock{ProductID: nuts.ID, Quantity: 40, Location: "A-02", AccountID: testUser.AccountID}
+	db.Create(&nutsStock)
+
+	// The default window is 90 days, or 13 weeks
+	from := time.Now().AddDate(0, 0, -90)
+	orderID := uint(1)
+	shipped := func(stock model.Stock, quantity int, at time.Time, order *uint) {
+		db.Create(&model.StockMovement{
+			StockID: stock.ID, ProductID: stock.ProductID, Delta: -quantity, Reason: model.MovementShipment,
+			OrderID: order, CreatedAt: at, AccountID: testUser.AccountID,
+		})
+	}
+	// Bolts sell steadily, 10 a week for 100 a week in value
+	for week := 0; week < 13; week++ {
+		shipped(boltsStock, 10, from.Add(time.Duration(week)*7*24*time.Hour+time.Hour), &orderID)
+	}
+	// Nuts sell all at once, for 130 in value
+	shipped(nutsStock, 130, from.AddDate(0, 0, 30), &orderID)
+	// Neither older orders nor shipments outside an order count
+	shipped(nutsStock, 5000, from.AddDate(0, 0, -1), &orderID)
+	shipped(nutsStock, 5000, from.AddDate(0, 0, 40), nil)
+
+	t.Run("Classify", func(t *testing.T) {
+		w := performRequest(r, "POST", "/products/classify", token, nil)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w = performRequest(r, "POST", "/products/classify", managerToken, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var summary model.ClassificationSummary
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &summary))
+		assert.Equal(t, []model.ClassCount{
+			{ABCClass: "A", XYZClass: "X", Products: 1, Value: 1300},
+			{ABCClass: "B", XYZClass: "Z", Products: 1, Value: 130},
+			{ABCClass: "C", XYZClass: "Z", Products: 1},
+		}, summary.Classes)
+
+		for id, classes := range map[uint][2]string{bolts.ID: {"A", "X"}, nuts.ID: {"B", "Z"}, washers.ID: {"C", "Z"}} {
+			var product model.Product
+			db.First(&product, id)
+			assert.Equal(t, classes, [2]string{product.ABCClass, product.XYZClass})
+			assert.NotNil(t, product.ClassifiedAt)
+		}
+	})
+
+	t.Run("Breakdown", func(t *testing.T) {
+		w := performRequest(r, "GET", "/products/classes", token, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var summary model.ClassificationSummary
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &summary))
+		assert.Equal(t, 3, len(summary.Classes))
+		assert.Equal(t, 90*24*time.Hour, summary.To.Sub(summary.From))
+	})
+
+	t.Run("Filters", func(t *testing.T) {
+		w := performRequest(r, "GET", "/products?abc_class=A", token, nil)
+		var products []model.Product
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &products))
+		assert.Equal(t, 1, len(products))
+		assert.Equal(t, "Bolts", products[0].Name)
+
+		w = performRequest(r, "GET", "/products?xyz_class=Z", token, nil)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &products))
+		assert.Equal(t, 2, len(products))
+
+		w = performRequest(r, "GET", "/stocks?abc_class=B&xyz_class=Z", token, nil)
+		var stocks model.StocksResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &stocks))
+		assert.Equal(t, 1, len(stocks.Stocks))
+		assert.Equal(t, nutsStock.ID, stocks.Stocks[0].ID)
+	})
+
+	t.Run("Scheduled", func(t *testing.T) {
+		// Once the orders age out of the window everything falls to C
+		assert.NoError(t, utils.ClassifyAllProducts(db, time.Now().AddDate(1, 0, 0)))
+		var product model.Product
+		db.First(&product, bolts.ID)
+		assert.Equal(t, "C", product.ABCClass)
+		assert.Equal(t, "Z", product.XYZClass)
+	})
+
+	db.Exec("DELETE FROM stock_movements")
+	db.Exec("DELETE FROM stocks")
+	db.Exec("DELETE FROM products")
+}
@@ -0,0 +1,103 @@
+package tests_test
+
+import (
+	"encoding/json"
+	"inventory-management/internal/model"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchProducts(t *testing.T) {
+	db, token, testUser := setupTestEnvironment()
+	r := SetupRouter(db)
+
+	hardware := model.Category{Name: "Hardware", AccountID: testUser.AccountID}
+	db.Create(&hardware)
+	fasteners := model.Category{Name: "Fasteners", ParentID: &hardware.ID, AccountID: testUser.AccountID}
+	db.Create(&fasteners)
+	garden := model.Category{Name: "Garden", AccountID: testUser.AccountID}
+	db.Create(&garden)
+	acme := model.Supplier{Name: "Acme Steelworks", AccountID: testUser.AccountID}
+	db.Create(&acme)
+	greenleaf := model.Supplier{Name: "Greenleaf", AccountID: testUser.AccountID}
+	db.Create(&greenleaf)
+
+	bolts := model.Product{Name: "Steel bolts", SKU: "BLT-100", CategoryID: fasteners.ID, SupplierID: acme.ID, ReorderPoint: 10, AccountID: testUser.AccountID}
+	db.Create(&bolts)
+	nuts := model.Product{Name: "Hex nuts", SKU: "NUT-200", Description: "Fits steel bolts", CategoryID: fasteners.ID, SupplierID: acme.ID, ReorderPoint: 10, AccountID: testUser.AccountID}
+	db.Create(&nuts)
+	hose := model.Product{Name: "Garden hose", SKU: "HSE-300", CategoryID: garden.ID, SupplierID: greenleaf.ID, AccountID: testUser.AccountID}
+	db.Create(&hose)
+	other := model.Product{Name: "Steel bolts", SKU: "BLT-100", CategoryID: fasteners.ID, AccountID: testUser.AccountID + 1}
+	db.Create(&other)
+
+	db.Create(&model.Stock{ProductID: bolts.ID, Quantity: 50, ReservedQuantity: 5, Location: "A-01", AccountID: testUser.AccountID})
+	db.Create(&model.Stock{ProductID: nuts.ID, Quantity: 8, Location: "A-02", AccountID: testUser.AccountID})
+
+	search := func(query string) model.ProductSearchResponse {
+		w := performRequest(r, "GET", "/products/search?"+query, token, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response model.ProductSearchResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response
+	}
+	names := func(response model.ProductSearchResponse) []string {
+		var names []string
+		for _, hit := range response.Results {
+			names = append(names, hit.Product.Name)
+		}
+		return names
+	}
+
+	t.Run("RankedPrefixMatches", func(t *testing.T) {
+		// Name matches rank above the description match; "ste" matches as a prefix
+		response := search("q=ste+bol")
+		assert.Equal(t, int64(2), response.Total)
+		assert.Equal(t, []string{"Steel bolts", "Hex nuts"}, names(response))
+		assert.Equal(t, uint(45), response.Results[0].Available)
+		assert.Equal(t, model.StockStatusInStock, response.Results[0].StockStatus)
+		assert.Equal(t, model.StockStatusLowStock, response.Results[1].StockStatus)
+		assert.Greater(t, response.Results[0].Rank, response.Results[1].Rank)
+
+		assert.Equal(t, []string{"Hex nuts"}, names(search("q=nut-200")))
+	})
+
+	t.Run("SupplierName", func(t *testing.T) {
+		response := search("q=acme")
+		assert.Equal(t, int64(2), response.Total)
+		assert.Equal(t, acme.ID, response.Results[0].Product.Supplier.ID)
+	})
+
+	t.Run("Facets", func(t *testing.T) {
+		response := search("category_id=" + strconv.Itoa(int(hardware.ID)) + "&stock_status=low_stock")
+		assert.Equal(t, []string{"Hex nuts"}, names(response))
+		// Each facet ignores its own filter
+		assert.Equal(t, []model.SearchFacet{{ID: fasteners.ID, Name: "Fasteners", Count: 1}}, response.Facets.Categories)
+		assert.Equal(t, []model.SearchFacet{{ID: acme.ID, Name: "Acme Steelworks", Count: 1}}, response.Facets.Suppliers)
+		assert.Equal(t, []model.StockStatusFacet{
+			{Status: model.StockStatusInStock, Count: 1},
+			{Status: model.StockStatusLowStock, Count: 1},
+		}, response.Facets.StockStatus)
+
+		response = search("stock_status=out_of_stock")
+		assert.Equal(t, []string{"Garden hose"}, names(response))
+		assert.Equal(t, 3, len(response.Facets.StockStatus))
+	})
+
+	t.Run("Paging", func(t *testing.T) {
+		response := search("limit=1&offset=1")
+		assert.Equal(t, int64(3), response.Total)
+		assert.Equal(t, 1, len(response.Results))
+
+		w := performRequest(r, "GET", "/products/search?stock_status=lost", token, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	db.Exec("DELETE FROM stocks")
+	db.Exec("DELETE FROM products")
+	db.Exec("DELETE FROM suppliers")
+	db.Exec("DELETE FROM categories")
+}
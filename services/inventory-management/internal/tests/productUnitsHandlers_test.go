@@ -0,0 +1,113 @@
+package tests_test
+
+import (
+	"encoding/json"
+	"inventory-management/internal/model"
+	"inventory-management/internal/utils"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProductUnits(t *testing.T) {
+	db, token, testUser := setupTestEnvironment()
+	r := SetupRouter(db)
+
+	product := model.Product{Name: "Packed Product", AccountID: testUser.AccountID}
+	db.Create(&product)
+	unitsPath := "/products/" + strconv.Itoa(int(product.ID)) + "/units"
+
+	var caseUnit model.ProductUnit
+
+	t.Run("DefineUnits", func(t *testing.T) {
+		w := performRequest(r, "POST", unitsPath, token, model.ProductUnit{Name: "case", Factor: 12})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &caseUnit))
+
+		w = performRequest(r, "POST", unitsPath, token, model.ProductUnit{Name: "pallet", Factor: 480})
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = performRequest(r, "POST", unitsPath, token, model.ProductUnit{Name: "case", Factor: 6})
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		w = performRequest(r, "POST", unitsPath, token, model.ProductUnit{Name: "box", Factor: 0})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = performRequest(r, "GET", unitsPath, token, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response model.ProductUnitsResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "each", response.BaseUnit)
+		assert.Equal(t, 2, len(response.Units))
+	})
+
+	t.Run("ReceiveInPackSize", func(t *testing.T) {
+		cases := uint(3)
+		w := performRequest(r, "POST", "/stocks", token, model.Stock{ProductID: product.ID, UnitOfMeasure: "crate", UnitQuantity: &cases})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = performRequest(r, "POST", "/stocks", token, model.Stock{ProductID: product.ID, UnitOfMeasure: "case", UnitQuantity: &cases})
+		assert.Equal(t, http.StatusOK, w.Code)
+		var stock model.Stock
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &stock))
+		assert.Equal(t, uint(36), stock.Quantity)
+
+		w = performRequest(r, "GET", "/stocks?product_id="+strconv.Itoa(int(product.ID)), token, nil)
+		var response model.StocksResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "case", response.Stocks[0].UnitOfMeasure)
+		assert.Equal(t, float64(3), response.Stocks[0].UnitQuantity)
+
+		w = performRequest(r, "DELETE", unitsPath+"/"+strconv.Itoa(int(caseUnit.ID)), token, nil)
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("RenameUnit", func(t *testing.T) {
+		var palletUnit model.ProductUnit
+		db.Where("product_id = ? AND name = ?", product.ID, "pallet").First(&palletUnit)
+		palletPath := unitsPath + "/" + strconv.Itoa(int(palletUnit.ID))
+
+		w := performRequest(r, "PUT", palletPath, token, model.ProductUnit{Name: "case", Factor: 480})
+		assert.Equal(t, http.StatusConflict, w.Code)
+		w = performRequest(r, "PUT", palletPath, token, model.ProductUnit{Name: "each", Factor: 480})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		// A unit named by a barcode or a purchase order line keeps its name
+		barcode := model.ProductBarcode{ProductID: product.ID, Code: "PALLET-1", LookupKey: "PALLET-1", Unit: "pallet", AccountID: testUser.AccountID}
+		db.Create(&barcode)
+		w = performRequest(r, "PUT", palletPath, token, model.ProductUnit{Name: "skid", Factor: 480})
+		assert.Equal(t, http.StatusConflict, w.Code)
+		db.Delete(&barcode)
+
+		line := model.PurchaseOrderLine{ProductID: product.ID, Quantity: 480, UnitOfMeasure: "pallet", AccountID: testUser.AccountID}
+		db.Create(&line)
+		w = performRequest(r, "PUT", palletPath, token, model.ProductUnit{Name: "skid", Factor: 480})
+		assert.Equal(t, http.StatusConflict, w.Code)
+		db.Delete(&line)
+
+		w = performRequest(r, "PUT", palletPath, token, model.ProductUnit{Name: "skid", Factor: 480})
+		assert.Equal(t, http.StatusOK, w.Code)
+		w = performRequest(r, "PUT", palletPath, token, model.ProductUnit{Name: "pallet", Factor: 480})
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("ConvertOrderQuantity", func(t *testing.T) {
+		quantity, err := utils.ToBaseQuantity(db, product.ID, "pallet", 2)
+		assert.NoError(t, err)
+		assert.Equal(t, uint(960), quantity)
+
+		quantity, err = utils.ToBaseQuantity(db, product.ID, "", 5)
+		assert.NoError(t, err)
+		assert.Equal(t, uint(5), quantity)
+
+		_, err = utils.ToBaseQuantity(db, product.ID, "crate", 1)
+		assert.ErrorIs(t, err, utils.ErrUnknownUnit)
+	})
+
+	db.Exec("DELETE FROM product_units")
+	db.Exec("DELETE FROM stock_movements")
+	db.Exec("DELETE FROM stocks")
+	db.Exec("DELETE FROM products")
+}
@@ -0,0 +1,242 @@
+package tests_test
+
+import (
+	"encoding/json"
+	"inventory-management/internal/api/routes"
+	"inventory-management/internal/middleware"
+	"inventory-management/internal/model"
+	"inventory-management/internal/utils"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestReplenishment(t *testing.T) {
+	db, token, testUser := setupTestEnvironment()
+	managerToken := useManagerService(t, 2, testUser.AccountID)
+
+	sender := &MockEmailSender{}
+	sender.On("SendEmail", "orders@acme.example", mock.Anything, mock.Anything).Return(nil)
+	r := gin.Default()
+	r.Use(middleware.CORSMiddleware())
+	r.Use(middleware.AuthMiddleware(db))
+	routes.Routers(r, db, utils.NewNotificationService(sender), testStorage())
+
+	acme := model.Supplier{Name: "Acme", Email: "orders@acme.example", AccountID: testUser.AccountID}
+	db.Create(&acme)
+	other := model.Supplier{Name: "Other", AccountID: testUser.AccountID}
+	db.Create(&other)
+
+	bolts := model.Product{Name: "Bolts", SupplierID: other.ID, PreferredSupplierID: &acme.ID, ReorderPoint: 50, ReorderQuantity: 20, AccountID: testUser.AccountID}
+	db.Create(&bolts)
+	nuts := model.Product{Name: "Nuts", SupplierID: acme.ID, ReorderPoint: 10, ReorderQuantity: 100, AccountID: testUser.AccountID}
+	db.Create(&nuts)
+	db.Create(&model.Stock{ProductID: bolts.ID, Quantity: 30, ReservedQuantity: 10, AccountID: testUser.AccountID})
+	db.Create(&model.Stock{ProductID: nuts.ID, Quantity: 500, AccountID: testUser.AccountID})
+
+	var order model.PurchaseOrder
+
+	t.Run("ProposeDrafts", func(t *testing.T) {
+		w := performRequest(r, "POST", "/purchase-orders/replenish", token, nil)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w = performRequest(r, "POST", "/purchase-orders/replenish", managerToken, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response model.ReplenishmentResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, 1, len(response.PurchaseOrders))
+
+		order = response.PurchaseOrders[0]
+		assert.Equal(t, acme.ID, order.SupplierID)
+		assert.Equal(t, model.PurchaseOrderDraft, order.Status)
+		assert.True(t, order.AutoGenerated)
+		// 20 available plus one reorder quantity is still at the reorder point, so two are proposed
+		assert.Equal(t, 1, len(order.Lines))
+		assert.Equal(t, bolts.ID, order.Lines[0].ProductID)
+		assert.Equal(t, uint(40), order.Lines[0].Quantity)
+
+		// What is on order counts towards the position, so a second run proposes nothing
+		w = performRequest(r, "POST", "/purchase-orders/replenish", managerToken, nil)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, 0, len(response.PurchaseOrders))
+	})
+
+	t.Run("LowStockProposal", func(t *testing.T) {
+		db.Model(&model.Stock{}).Where("product_id = ?", nuts.ID).Update("reserved_quantity", 495)
+
+		proposed, err := utils.ReplenishProduct(db, nuts.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, order.ID, proposed.ID)
+
+		var lines []model.PurchaseOrderLine
+		db.Where("purchase_order_id = ?", order.ID).Order("id").Find(&lines)
+		assert.Equal(t, 2, len(lines))
+		assert.Equal(t, uint(100), lines[1].Quantity)
+	})
+
+	t.Run("EditAndSend", func(t *testing.T) {
+		path := "/purchase-orders/" + strconv.Itoa(int(order.ID))
+		edit := model.PurchaseOrder{Note: "Split delivery", Lines: []model.PurchaseOrderLine{{ProductID: bolts.ID, Quantity: 120}}}
+
+		w := performRequest(r, "PUT", path, token, edit)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w = performRequest(r, "PUT", path, managerToken, edit)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var updated model.PurchaseOrder
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &updated))
+		assert.Equal(t, 1, len(updated.Lines))
+		assert.Equal(t, uint(120), updated.Lines[0].Quantity)
+
+		w = performRequest(r, "POST", path+"/send", managerToken, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response model.SendPurchaseOrderResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Empty(t, response.EmailError)
+		sender.AssertExpectations(t)
+
+		w = performRequest(r, "PUT", path, managerToken, edit)
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		w = performRequest(r, "GET", "/purchase-orders?status=sent", token, nil)
+		var orders model.PurchaseOrdersResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &orders))
+		assert.Equal(t, 1, len(orders.PurchaseOrders))
+		assert.Equal(t, model.PurchaseOrderSent, orders.PurchaseOrders[0].Status)
+	})
+
+	db.Exec("DELETE FROM purchase_order_lines")
+	db.Exec("DELETE FROM purchase_orders")
+	db.Exec("DELETE FROM stocks")
+	db.Exec("DELETE FROM products")
+	db.Exec("DELETE FROM suppliers")
+}
+
+func TestPurchaseOrderReceiving(t *testing.T) {
+	db, token, testUser := setupTestEnvironment()
+	managerToken := useManagerService(t, 2, testUser.AccountID)
+	r := SetupRouter(db)
+
+	supplier := model.Supplier{Name: "Acme", AccountID: testUser.AccountID}
+	db.Create(&supplier)
+	washers := model.Product{Name: "Washers", SupplierID: supplier.ID, AccountID: testUser.AccountID}
+	db.Create(&washers)
+	screws := model.Product{Name: "Screws", SupplierID: supplier.ID, AccountID: testUser.AccountID}
+	db.Create(&screws)
+	db.Create(&model.ProductUnit{ProductID: washers.ID, Name: "bag", Factor: 15, AccountID: testUser.AccountID})
+
+	var order model.PurchaseOrder
+	var path string
+
+	t.Run("CreateAndSend", func(t *testing.T) {
+		w := performRequest(r, "POST", "/purchase-orders", managerToken, model.PurchaseOrder{SupplierID: supplier.ID, Lines: []model.PurchaseOrderLine{
+			{ProductID: screws.ID, Quantity: 50, UnitOfMeasure: "bag"},
+		}})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		request := model.PurchaseOrder{SupplierID: supplier.ID, Lines: []model.PurchaseOrderLine{
+			{ProductID: washers.ID, Quantity: 100, UnitOfMeasure: "bag"},
+			{ProductID: screws.ID, Quantity: 50},
+		}}
+		w = performRequest(r, "POST", "/purchase-orders", token, request)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w = performRequest(r, "POST", "/purchase-orders", managerToken, request)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &order))
+		assert.Equal(t, model.PurchaseOrderDraft, order.Status)
+		assert.Equal(t, 2, len(order.Lines))
+		path = "/purchase-orders/" + strconv.Itoa(int(order.ID))
+
+		// Drafts are not expected at the dock yet
+		_, err := utils.PostReceipt(db, model.ReceiptEvent{ReceiptID: 1, PurchaseOrderID: order.ID, LineID: order.Lines[0].ID, Quantity: 1, Location: "Dock", AccountID: testUser.AccountID})
+		assert.ErrorIs(t, err, utils.ErrPurchaseOrderNotReceivable)
+
+		w = performRequest(r, "POST", path+"/send", managerToken, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("PartialReceipt", func(t *testing.T) {
+		// Counted in the line's bags of 15
+		event := model.ReceiptEvent{ReceiptID: 1, PurchaseOrderID: order.ID, LineID: order.Lines[0].ID, Quantity: 4, Location: "Dock", AccountID: testUser.AccountID}
+		receipt, err := utils.PostReceipt(db, event)
+		assert.NoError(t, err)
+		assert.NotNil(t, receipt)
+
+		// A redelivered event is not posted twice
+		receipt, err = utils.PostReceipt(db, event)
+		assert.NoError(t, err)
+		assert.Nil(t, receipt)
+
+		var stock model.Stock
+		db.Where("product_id = ? AND location = ?", washers.ID, "Dock").First(&stock)
+		assert.Equal(t, uint(60), stock.Quantity)
+		var movement model.StockMovement
+		db.Where("stock_id = ?", stock.ID).Last(&movement)
+		assert.Equal(t, model.MovementReceipt, movement.Reason)
+
+		db.First(&order, order.ID)
+		assert.Equal(t, model.PurchaseOrderPartiallyReceived, order.Status)
+
+		position, err := utils.StockPosition(db, washers.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, 100, position)
+	})
+
+	t.Run("OverAndShortReceipts", func(t *testing.T) {
+		_, err := utils.PostReceipt(db, model.ReceiptEvent{ReceiptID: 2, PurchaseOrderID: order.ID, LineID: order.Lines[0].ID, Quantity: 45, UnitOfMeasure: "crate", Location: "Dock", AccountID: testUser.AccountID})
+		assert.ErrorIs(t, err, utils.ErrUnknownUnit)
+		_, err = utils.PostReceipt(db, model.ReceiptEvent{ReceiptID: 2, PurchaseOrderID: order.ID, LineID: order.Lines[0].ID, Quantity: 45, UnitOfMeasure: "each", Location: "Dock", AccountID: testUser.AccountID})
+		assert.NoError(t, err)
+		_, err = utils.PostReceipt(db, model.ReceiptEvent{ReceiptID: 3, PurchaseOrderID: order.ID, LineID: order.Lines[1].ID, Quantity: 30, Location: "Dock", CloseLine: true, AccountID: testUser.AccountID})
+		assert.NoError(t, err)
+
+		w := performRequest(r, "GET", path, token, nil)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &order))
+		assert.Equal(t, model.PurchaseOrderClosed, order.Status)
+		assert.NotNil(t, order.ClosedAt)
+		assert.Equal(t, uint(105), order.Lines[0].ReceivedQuantity)
+		assert.True(t, order.Lines[1].Closed)
+
+		w = performRequest(r, "GET", path+"/receipts", token, nil)
+		var response model.PurchaseOrderReceiptsResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, 3, len(response.Receipts))
+
+		w = performRequest(r, "POST", path+"/close", managerToken, nil)
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("CloseOpenOrder", func(t *testing.T) {
+		request := model.PurchaseOrder{SupplierID: supplier.ID, Lines: []model.PurchaseOrderLine{{ProductID: screws.ID, Quantity: 10}}}
+		w := performRequest(r, "POST", "/purchase-orders", managerToken, request)
+		var open model.PurchaseOrder
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &open))
+		openPath := "/purchase-orders/" + strconv.Itoa(int(open.ID))
+		performRequest(r, "POST", openPath+"/send", managerToken, nil)
+
+		position, _ := utils.StockPosition(db, screws.ID)
+		assert.Equal(t, 40, position)
+
+		w = performRequest(r, "POST", openPath+"/close", managerToken, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &open))
+		assert.Equal(t, model.PurchaseOrderClosed, open.Status)
+
+		position, _ = utils.StockPosition(db, screws.ID)
+		assert.Equal(t, 30, position)
+	})
+
+	db.Exec("DELETE FROM purchase_order_receipts")
+	db.Exec("DELETE FROM purchase_order_lines")
+	db.Exec("DELETE FROM purchase_orders")
+	db.Exec("DELETE FROM product_units")
+	db.Exec("DELETE FROM stock_movements")
+	db.Exec("DELETE FROM stocks")
+	db.Exec("DELETE FROM products")
+	db.Exec("DELETE FROM suppliers")
+}
@@ -0,0 +1,156 @@
+package tests_test
+
+import (
+	"inventory-management/internal/model"
+	"inventory-management/internal/utils"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestStockReservations(t *testing.T) {
+	db, token, testUser := setupTestEnvironment()
+	r := SetupRouter(db)
+
+	product := model.Product{Name: "Reserved Product", AccountID: testUser.AccountID}
+	db.Create(&product)
+	stock1 := model.Stock{ProductID: product.ID, Quantity: 10, AccountID: testUser.AccountID}
+	stock2 := model.Stock{ProductID: product.ID, Quantity: 10, AccountID: testUser.AccountID}
+	db.Create(&stock1)
+	db.Create(&stock2)
+
+	reload := func() (model.Stock, model.Stock) {
+		var s1, s2 model.Stock
+		db.First(&s1, stock1.ID)
+		db.First(&s2, stock2.ID)
+		return s1, s2
+	}
+
+	t.Run("ReserveAcrossStocks", func(t *testing.T) {
+		touched, err := utils.ReserveStock(db, 100, product.ID, 15)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, len(touched))
+
+		s1, s2 := reload()
+		assert.Equal(t, uint(10), s1.Quantity)
+		assert.Equal(t, uint(10), s1.ReservedQuantity)
+		assert.Equal(t, uint(5), s2.ReservedQuantity)
+		assert.Equal(t, uint(5), s2.Available())
+	})
+
+	t.Run("ReserveSameOrderTwice", func(t *testing.T) {
+		_, err := utils.ReserveStock(db, 100, product.ID, 15)
+		assert.NoError(t, err)
+
+		_, s2 := reload()
+		assert.Equal(t, uint(5), s2.ReservedQuantity)
+	})
+
+	t.Run("ReserveInsufficientStock", func(t *testing.T) {
+		_, err := utils.ReserveStock(db, 101, product.ID, 6)
+		assert.ErrorIs(t, err, utils.ErrInsufficientStock)
+	})
+
+	t.Run("CannotDeleteReservedStock", func(t *testing.T) {
+		w := performRequest(r, "DELETE", "/stocks/"+strconv.Itoa(int(stock1.ID)), token, nil)
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		// Other accounts cannot tell reserved stock from stock that does not exist
+		otherToken := createTestToken(testUser.ID, testUser.AccountID+1)
+		w = performRequest(r, "DELETE", "/stocks/"+strconv.Itoa(int(stock1.ID)), otherToken, nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		w = performRequest(r, "DELETE", "/stocks/hard/"+strconv.Itoa(int(stock1.ID)), otherToken, nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("ReleaseOnCancellation", func(t *testing.T) {
+		_, err := utils.ReserveStock(db, 102, product.ID, 5)
+		assert.NoError(t, err)
+
+		released, err := utils.ReleaseReservations(db, 102, model.ReservationReleased)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, released)
+
+		_, s2 := reload()
+		assert.Equal(t, uint(10), s2.Quantity)
+		assert.Equal(t, uint(5), s2.ReservedQuantity)
+	})
+
+	t.Run("ConsumeOnShipment", func(t *testing.T) {
+		_, err := utils.ConsumeReservations(db, 100, nil)
+		assert.NoError(t, err)
+
+		s1, s2 := reload()
+		assert.Equal(t, uint(0), s1.Quantity)
+		assert.Equal(t, uint(0), s1.ReservedQuantity)
+		assert.Equal(t, uint(5), s2.Quantity)
+		assert.Equal(t, uint(0), s2.ReservedQuantity)
+
+		var consumed int64
+		db.Model(&model.StockReservation{}).Where("order_id = ? AND status = ?", 100, model.ReservationConsumed).Count(&consumed)
+		assert.Equal(t, int64(2), consumed)
+	})
+
+	t.Run("ExpireReservations", func(t *testing.T) {
+		_, err := utils.ReserveStock(db, 103, product.ID, 3)
+		assert.NoError(t, err)
+
+		assert.NoError(t, utils.ExpireReservations(db, time.Now().Add(utils.ReservationTTL()+time.Minute)))
+
+		_, s2 := reload()
+		assert.Equal(t, uint(0), s2.ReservedQuantity)
+
+		var reservation model.StockReservation
+		db.Where("order_id = ?", 103).First(&reservation)
+		assert.Equal(t, model.ReservationExpired, reservation.Status)
+	})
+
+	t.Run("ConsumeExpiredReservation", func(t *testing.T) {
+		// Another order takes most of what order 103 no longer holds
+		_, err := utils.ReserveStock(db, 104, product.ID, 4)
+		assert.NoError(t, err)
+
+		_, err = utils.ConsumeReservations(db, 103, nil)
+		assert.NoError(t, err)
+
+		_, s2 := reload()
+		assert.Equal(t, uint(4), s2.Quantity)
+		assert.Equal(t, uint(4), s2.ReservedQuantity)
+
+		var reservation model.StockReservation
+		db.Where("order_id = ?", 103).First(&reservation)
+		assert.Equal(t, model.ReservationConsumed, reservation.Status)
+		assert.Equal(t, uint(2), reservation.ShortQuantity)
+	})
+
+	t.Run("LocksStockRows", func(t *testing.T) {
+		// SQLite leaves out FOR UPDATE, so check the locking clause reaches the stock queries
+		var locked []string
+		db.Callback().Query().Before("gorm:query").Register("test:locking", func(tx *gorm.DB) {
+			if _, ok := tx.Statement.Clauses["FOR"]; ok {
+				locked = append(locked, tx.Statement.Table)
+			}
+		})
+		defer db.Callback().Query().Remove("test:locking")
+
+		_, err := utils.ReleaseReservations(db, 104, model.ReservationReleased)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"stocks"}, locked)
+
+		locked = nil
+		_, err = utils.ReserveStock(db, 105, product.ID, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"stocks"}, locked)
+	})
+
+	// Clean up the database
+	db.Exec("DELETE FROM stock_reservations")
+	db.Exec("DELETE FROM stocks")
+	db.Exec("DELETE FROM products")
+	db.Exec("DELETE FROM users")
+	db.Exec("DELETE FROM roles")
+}
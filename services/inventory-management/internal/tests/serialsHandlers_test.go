@@ -0,0 +1,136 @@
+package tests_test
+
+import (
+	"encoding/json"
+	"inventory-management/internal/model"
+	"inventory-management/internal/utils"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSerialNumbers(t *testing.T) {
+	db, token, testUser := setupTestEnvironment()
+	r := SetupRouter(db)
+
+	product := model.Product{Name: "Serialized Product", AccountID: testUser.AccountID, IsSerialized: true}
+	db.Create(&product)
+
+	var stock, other model.Stock
+
+	t.Run("CreateRequiresSerials", func(t *testing.T) {
+		w := performRequest(r, "POST", "/stocks", token, model.Stock{ProductID: product.ID, Quantity: 2, SerialNumbers: []string{"SN-1"}})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = performRequest(r, "POST", "/stocks", token, model.Stock{ProductID: product.ID, Quantity: 3, SerialNumbers: []string{"SN-1", "SN-2", "SN-3"}})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &stock))
+
+		w = performRequest(r, "POST", "/stocks", token, model.Stock{ProductID: product.ID, Quantity: 1, SerialNumbers: []string{"SN-1"}})
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("UpdateSyncsSerials", func(t *testing.T) {
+		stockPath := "/stocks/" + strconv.Itoa(int(stock.ID))
+		w := performRequest(r, "PUT", stockPath, token, model.Stock{ProductID: product.ID, Quantity: 4})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = performRequest(r, "PUT", stockPath, token, model.Stock{ProductID: product.ID, Quantity: 3, SerialNumbers: []string{"SN-1", "SN-2", "SN-4"}})
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var removed model.SerialNumber
+		db.Where("serial = ?", "SN-3").First(&removed)
+		assert.Equal(t, model.SerialRemoved, removed.Status)
+	})
+
+	t.Run("MoveSerials", func(t *testing.T) {
+		w := performRequest(r, "POST", "/stocks", token, model.Stock{ProductID: product.ID, Quantity: 0})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &other))
+
+		w = performRequest(r, "POST", "/serials/move", token, model.MoveSerialsRequest{SerialNumbers: []string{"SN-404"}, ToStockID: other.ID})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = performRequest(r, "POST", "/serials/move", token, model.MoveSerialsRequest{SerialNumbers: []string{"SN-4"}, ToStockID: other.ID})
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var source, target model.Stock
+		db.First(&source, stock.ID)
+		db.First(&target, other.ID)
+		assert.Equal(t, uint(2), source.Quantity)
+		assert.Equal(t, uint(1), target.Quantity)
+
+		balance, _ := utils.LedgerBalance(db, other.ID)
+		assert.Equal(t, 1, balance)
+	})
+
+	t.Run("RequiresSerials", func(t *testing.T) {
+		plain := model.Product{Name: "Plain Product", AccountID: testUser.AccountID}
+		db.Create(&plain)
+		plainStock := model.Stock{ProductID: plain.ID, Quantity: 1, AccountID: testUser.AccountID}
+		db.Create(&plainStock)
+
+		// Orders of serialized products are not shipped automatically
+		serialized, err := utils.RequiresSerials(db, []model.Stock{plainStock})
+		assert.NoError(t, err)
+		assert.False(t, serialized)
+		serialized, err = utils.RequiresSerials(db, []model.Stock{plainStock, stock})
+		assert.NoError(t, err)
+		assert.True(t, serialized)
+	})
+
+	t.Run("ShipSerials", func(t *testing.T) {
+		_, err := utils.ReserveStock(db, 500, product.ID, 1)
+		assert.NoError(t, err)
+
+		// Shipments that don't name a serial for every unit keep the reservation
+		shipmentID := uint(9)
+		for want, serials := range map[error][]string{
+			utils.ErrSerialCountMismatch: nil,
+			utils.ErrSerialNotFound:      {"SN-404"},
+		} {
+			tx := db.Begin()
+			stocks, err := utils.ConsumeReservations(tx, 500, &shipmentID)
+			assert.NoError(t, err)
+			assert.ErrorIs(t, utils.ShipSerials(tx, 500, &shipmentID, stocks, serials), want)
+			tx.Rollback()
+		}
+		var reservation model.StockReservation
+		db.Where("order_id = ?", 500).First(&reservation)
+		assert.Equal(t, model.ReservationActive, reservation.Status)
+
+		tx := db.Begin()
+		stocks, err := utils.ConsumeReservations(tx, 500, &shipmentID)
+		assert.NoError(t, err)
+		assert.NoError(t, utils.ShipSerials(tx, 500, &shipmentID, stocks, []string{"SN-1"}))
+		assert.NoError(t, tx.Commit().Error)
+
+		var shipped model.SerialNumber
+		db.Where("serial = ?", "SN-1").First(&shipped)
+		assert.Equal(t, model.SerialShipped, shipped.Status)
+		assert.Equal(t, uint(500), *shipped.OrderID)
+	})
+
+	t.Run("SerialHistory", func(t *testing.T) {
+		w := performRequest(r, "GET", "/serials/SN-1/history", token, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response model.SerialHistoryResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, 2, len(response.Events))
+		assert.Equal(t, model.SerialEventReceived, response.Events[0].Event)
+		assert.Equal(t, model.SerialEventShipped, response.Events[1].Event)
+		assert.NotEmpty(t, response.ShipmentLookupError)
+
+		w = performRequest(r, "GET", "/serials/SN-404/history", token, nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	db.Exec("DELETE FROM serial_events")
+	db.Exec("DELETE FROM serial_numbers")
+	db.Exec("DELETE FROM stock_reservations")
+	db.Exec("DELETE FROM stocks")
+	db.Exec("DELETE FROM products")
+}
@@ -0,0 +1,135 @@
+package tests_test
+
+import (
+	"encoding/json"
+	"inventory-management/internal/model"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlotting(t *testing.T) {
+	db, token, testUser := setupTestEnvironment()
+	managerToken := useManagerService(t, 2, testUser.AccountID)
+	r := SetupRouter(db)
+
+	warehouse := model.Warehouse{Name: "Main", AccountID: testUser.AccountID}
+	db.Create(&warehouse)
+	zone := model.Zone{Name: "Aisles", WarehouseID: warehouse.ID, AccountID: testUser.AccountID}
+	db.Create(&zone)
+	bin := func(code string, x, y float64, golden bool) model.Bin {
+		bin := model.Bin{Code: code, ZoneID: zone.ID, X: x, Y: y, GoldenZone: golden, AccountID: testUser.AccountID}
+		db.Create(&bin)
+		return bin
+	}
+	// Dispatch is at the origin, so a bin's walking distance is x + y
+	farAway := bin("F-01", 50, 10, false)
+	midway := bin("F-02", 40, 0, false)
+	nearest := bin("G-01", 2, 3, true)
+	near := bin("G-02", 10, 0, true)
+	taken := bin("G-03", 1, 0, true)
+	bin("G-04", 60, 0, true)
+
+	product := func(name string, serialized bool) model.Product {
+		product := model.Product{Name: name, SKU: name, IsSerialized: serialized, AccountID: testUser.AccountID}
+		db.Create(&product)
+		return product
+	}
+	orderID := uint(1)
+	stockIn := func(product model.Product, bin model.Bin, picks int) model.Stock {
+		stock := model.Stock{ProductID: product.ID, Quantity: 20, BinID: &bin.ID, Location: bin.Code, AccountID: testUser.AccountID}
+		db.Create(&stock)
+		for i := 0; i < picks; i++ {
+			db.Create(&model.StockMovement{
+				StockID: stock.ID, ProductID: product.ID, BinID: &bin.ID, Delta: -1, Reason: model.MovementShipment,
+				OrderID: &orderID, CreatedAt: time.Now().AddDate(0, 0, -1), AccountID: testUser.AccountID,
+			})
+		}
+		return stock
+	}
+	fast := stockIn(product("Fast", false), farAway, 5)
+	medium := stockIn(product("Medium", false), midway, 3)
+	stockIn(product("Slow", false), taken, 0)
+	stockIn(product("Serialized", true), farAway, 9)
+
+	var recommendations model.SlottingRecommendationsResponse
+	t.Run("Generate", func(t *testing.T) {
+		w := performRequest(r, "POST", "/slotting/recommendations", token, model.GenerateSlottingRequest{WarehouseID: warehouse.ID + 100})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = performRequest(r, "POST", "/slotting/recommendations", token, model.GenerateSlottingRequest{WarehouseID: warehouse.ID})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &recommendations))
+		assert.Equal(t, 2, len(recommendations.Recommendations))
+
+		first, second := recommendations.Recommendations[0], recommendations.Recommendations[1]
+		assert.Equal(t, [3]uint{fast.ID, farAway.ID, nearest.ID}, [3]uint{first.StockID, first.FromBinID, first.ToBinID})
+		assert.Equal(t, 550.0, first.DistanceSaving)
+		assert.Equal(t, [3]uint{medium.ID, midway.ID, near.ID}, [3]uint{second.StockID, second.FromBinID, second.ToBinID})
+		assert.Equal(t, 180.0, second.DistanceSaving)
+		assert.Equal(t, 730.0, recommendations.TotalSaving)
+	})
+
+	path := func(recommendation model.SlottingRecommendation, action string) string {
+		return "/slotting/recommendations/" + strconv.Itoa(int(recommendation.ID)) + "/" + action
+	}
+
+	t.Run("Accept", func(t *testing.T) {
+		first := recommendations.Recommendations[0]
+		w := performRequest(r, "POST", path(first, "accept"), token, nil)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w = performRequest(r, "POST", path(first, "accept"), managerToken, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var accepted model.SlottingRecommendation
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &accepted))
+		assert.Equal(t, model.SlottingAccepted, accepted.Status)
+
+		var transfer model.TransferOrder
+		db.First(&transfer, *accepted.TransferID)
+		assert.Equal(t, fast.ID, transfer.FromStockID)
+		assert.Equal(t, nearest.ID, *transfer.ToBinID)
+		assert.Equal(t, uint(20), transfer.Quantity)
+		assert.Equal(t, model.TransferPending, transfer.Status)
+
+		w = performRequest(r, "POST", path(first, "accept"), managerToken, nil)
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("Dismiss", func(t *testing.T) {
+		w := performRequest(r, "POST", path(recommendations.Recommendations[1], "dismiss"), managerToken, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = performRequest(r, "GET", "/slotting/recommendations?status=dismissed", token, nil)
+		var response model.SlottingRecommendationsResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, 1, len(response.Recommendations))
+	})
+
+	t.Run("Regenerate", func(t *testing.T) {
+		// The bin awaiting the accepted transfer is no longer free, and the moving stock is held
+		w := performRequest(r, "POST", "/slotting/recommendations", token, model.GenerateSlottingRequest{WarehouseID: warehouse.ID, Days: 7})
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response model.SlottingRecommendationsResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, 1, len(response.Recommendations))
+		assert.Equal(t, medium.ID, response.Recommendations[0].StockID)
+		assert.Equal(t, near.ID, response.Recommendations[0].ToBinID)
+
+		w = performRequest(r, "GET", "/slotting/recommendations?warehouse_id="+strconv.Itoa(int(warehouse.ID)), token, nil)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, 3, len(response.Recommendations))
+	})
+
+	db.Exec("DELETE FROM slotting_recommendations")
+	db.Exec("DELETE FROM transfer_orders")
+	db.Exec("DELETE FROM stock_movements")
+	db.Exec("DELETE FROM stocks")
+	db.Exec("DELETE FROM products")
+	db.Exec("DELETE FROM bins")
+	db.Exec("DELETE FROM zones")
+	db.Exec("DELETE FROM warehouses")
+}
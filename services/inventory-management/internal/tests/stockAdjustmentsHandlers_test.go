@@ -0,0 +1,123 @@
+package tests_test
+
+import (
+	"encoding/json"
+	"inventory-management/internal/model"
+	"inventory-management/internal/utils"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStockAdjustments(t *testing.T) {
+	db, token, testUser := setupTestEnvironment()
+	r := SetupRouter(db)
+	managerToken := useManagerService(t, 2, testUser.AccountID)
+
+	product := model.Product{Name: "Adjusted Product", AccountID: testUser.AccountID}
+	db.Create(&product)
+	stock := model.Stock{ProductID: product.ID, Quantity: 20, Location: "Shelf", AccountID: testUser.AccountID}
+	db.Create(&stock)
+	cost := 5.0
+	assert.NoError(t, utils.RecordStockMovement(db, stock, 20, model.StockMovement{Reason: model.MovementReceipt, UnitCost: &cost}))
+
+	w := performRequest(r, "PUT", "/settings", managerToken, model.AccountSettings{CostingMethod: model.CostingFIFO, AdjustmentQuantityThreshold: 5, AdjustmentValueThreshold: 20})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	adjust := func(adjustment model.StockAdjustment) (int, model.StockAdjustment) {
+		adjustment.StockID = stock.ID
+		w := performRequest(r, "POST", "/stock-adjustments", token, adjustment)
+		var response model.StockAdjustment
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return w.Code, response
+	}
+	quantity := func() uint {
+		db.First(&stock, stock.ID)
+		return stock.Quantity
+	}
+
+	t.Run("Validation", func(t *testing.T) {
+		code, _ := adjust(model.StockAdjustment{Delta: -1, Reason: "lost"})
+		assert.Equal(t, http.StatusBadRequest, code)
+		code, _ = adjust(model.StockAdjustment{Delta: 0, Reason: model.AdjustmentDamage})
+		assert.Equal(t, http.StatusBadRequest, code)
+		code, _ = adjust(model.StockAdjustment{Delta: -21, Reason: model.AdjustmentShrinkage})
+		assert.Equal(t, http.StatusConflict, code)
+	})
+
+	t.Run("WithinThresholds", func(t *testing.T) {
+		code, adjustment := adjust(model.StockAdjustment{Delta: -2, Reason: model.AdjustmentDamage, Note: "dropped"})
+		assert.Equal(t, http.StatusCreated, code)
+		assert.Equal(t, model.AdjustmentApproved, adjustment.Status)
+		assert.InDelta(t, 10, adjustment.Value, 0.001)
+		assert.Equal(t, uint(18), quantity())
+
+		var movement model.StockMovement
+		db.Where("stock_id = ?", stock.ID).Order("id DESC").First(&movement)
+		assert.Equal(t, model.MovementAdjustment, movement.Reason)
+		assert.Equal(t, "damage: dropped", movement.Note)
+	})
+
+	var pending model.StockAdjustment
+	t.Run("AboveThreshold", func(t *testing.T) {
+		var code int
+		code, pending = adjust(model.StockAdjustment{Delta: 6, Reason: model.AdjustmentFound})
+		assert.Equal(t, http.StatusAccepted, code)
+		assert.Equal(t, model.AdjustmentPending, pending.Status)
+		assert.Equal(t, uint(18), quantity())
+
+		w := performRequest(r, "GET", "/stock-adjustments?status=pending", token, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response model.StockAdjustmentsResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, 1, len(response.Adjustments))
+		assert.Equal(t, pending.ID, response.Adjustments[0].ID)
+	})
+
+	t.Run("Review", func(t *testing.T) {
+		path := "/stock-adjustments/" + strconv.Itoa(int(pending.ID))
+		w := performRequest(r, "POST", path+"/approve", token, nil)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w = performRequest(r, "POST", path+"/approve", managerToken, model.ReviewAdjustmentRequest{Note: "recounted"})
+		assert.Equal(t, http.StatusOK, w.Code)
+		var approved model.StockAdjustment
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &approved))
+		assert.Equal(t, model.AdjustmentApproved, approved.Status)
+		assert.Equal(t, uint(2), *approved.ReviewedBy)
+		assert.Equal(t, uint(24), quantity())
+
+		w = performRequest(r, "POST", path+"/reject", managerToken, nil)
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		// Five units are within the quantity threshold but worth more than 20
+		code, large := adjust(model.StockAdjustment{Delta: -5, Reason: model.AdjustmentShrinkage})
+		assert.Equal(t, http.StatusAccepted, code)
+		assert.InDelta(t, 25, large.Value, 0.001)
+		w = performRequest(r, "POST", "/stock-adjustments/"+strconv.Itoa(int(large.ID))+"/reject", managerToken, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, uint(24), quantity())
+
+		// Without Kafka the approved adjustment stays unpublished and can be sent again
+		w = performRequest(r, "POST", path+"/publish", token, nil)
+		assert.Equal(t, http.StatusBadGateway, w.Code)
+		w = performRequest(r, "POST", "/stock-adjustments/"+strconv.Itoa(int(large.ID))+"/publish", token, nil)
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("StockUpdateKeepsQuantity", func(t *testing.T) {
+		w := performRequest(r, "PUT", "/stocks/"+strconv.Itoa(int(stock.ID)), token, model.Stock{ProductID: product.ID, Quantity: 50, Location: "Shelf"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, uint(24), quantity())
+	})
+
+	db.Exec("DELETE FROM stock_adjustments")
+	db.Exec("DELETE FROM account_settings")
+	db.Exec("DELETE FROM cost_consumptions")
+	db.Exec("DELETE FROM cost_layers")
+	db.Exec("DELETE FROM stock_movements")
+	db.Exec("DELETE FROM stocks")
+	db.Exec("DELETE FROM products")
+}
@@ -4,11 +4,13 @@ import (
 	"bytes"
 	"encoding/json"
 	"inventory-management/internal/model"
+	"inventory-management/internal/utils"
 	"log"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -26,7 +28,7 @@ func setupTestEnvironment() (*gorm.DB, string, *model.User) {
 		panic("failed to connect database")
 	}
 
-	db.AutoMigrate(&model.Stock{}, &model.Product{}, &model.User{}, &model.Role{})
+	db.AutoMigrate(&model.Stock{}, &model.Product{}, &model.Category{}, &model.Supplier{}, &model.User{}, &model.Role{}, &model.Warehouse{}, &model.Zone{}, &model.Bin{}, &model.StockReservation{}, &model.StockMovement{}, &model.SerialNumber{}, &model.SerialEvent{}, &model.CycleCountPlan{}, &model.CycleCountTask{}, &model.TransferOrder{}, &model.ProductUnit{}, &model.ProductBarcode{}, &model.PurchaseOrder{}, &model.PurchaseOrderLine{}, &model.PurchaseOrderReceipt{}, &model.SupplierPrice{}, &model.AccountSettings{}, &model.CostLayer{}, &model.CostConsumption{}, &model.KitComponent{}, &model.KitAssembly{}, &model.StockAdjustment{}, &model.AlertRule{}, &model.LowStockAlert{}, &model.Attachment{}, &model.StockSnapshot{}, &model.StockSnapshotLine{}, &model.SlottingRecommendation{}, &model.StockStatusChange{})
 
 	role := model.Role{
 		ID: 1,
@@ -304,3 +306,60 @@ func TestRecoverStock(t *testing.T) {
 	db.Exec("DELETE FROM users")
 	db.Exec("DELETE FROM roles")
 }
+
+func TestExpiringStocks(t *testing.T) {
+	db, token, testUser := setupTestEnvironment()
+	r := SetupRouter(db)
+
+	product := model.Product{Name: "Perishable Product", AccountID: testUser.AccountID}
+	db.Create(&product)
+
+	now := time.Now()
+	expired := now.AddDate(0, 0, -1)
+	soon := now.AddDate(0, 0, 5)
+	later := now.AddDate(0, 0, 60)
+	db.Create(&model.Stock{ProductID: product.ID, Quantity: 10, LotNumber: "LOT-EXPIRED", ExpiresAt: &expired, AccountID: testUser.AccountID})
+	db.Create(&model.Stock{ProductID: product.ID, Quantity: 10, LotNumber: "LOT-SOON", ExpiresAt: &soon, AccountID: testUser.AccountID})
+	db.Create(&model.Stock{ProductID: product.ID, Quantity: 10, LotNumber: "LOT-LATER", ExpiresAt: &later, AccountID: testUser.AccountID})
+	db.Create(&model.Stock{ProductID: product.ID, Quantity: 10, AccountID: testUser.AccountID})
+
+	t.Run("GetExpiringStocks", func(t *testing.T) {
+		w := performRequest(r, "GET", "/stocks/expiring?days=30", token, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response model.ExpiringLotsResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, 2, len(response.Lots))
+		assert.Equal(t, "LOT-EXPIRED", response.Lots[0].LotNumber)
+		assert.True(t, response.Lots[0].Expired)
+		assert.Equal(t, "LOT-SOON", response.Lots[1].LotNumber)
+		assert.Equal(t, 4, response.Lots[1].DaysUntilExpiry)
+	})
+
+	t.Run("AllocateFirstExpiredFirstOut", func(t *testing.T) {
+		touched, err := utils.ReserveStock(db, 300, product.ID, 15)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, len(touched))
+		assert.Equal(t, "LOT-SOON", touched[0].LotNumber)
+		assert.Equal(t, uint(10), touched[0].ReservedQuantity)
+		assert.Equal(t, "LOT-LATER", touched[1].LotNumber)
+		assert.Equal(t, uint(5), touched[1].ReservedQuantity)
+	})
+
+	t.Run("ExpiredLotsAreNotAllocated", func(t *testing.T) {
+		_, err := utils.ReserveStock(db, 301, product.ID, 16)
+		assert.ErrorIs(t, err, utils.ErrInsufficientStock)
+	})
+
+	t.Run("CreateStockInvalidLotDates", func(t *testing.T) {
+		w := performRequest(r, "POST", "/stocks", token, model.Stock{ProductID: product.ID, Quantity: 1, ManufacturedAt: &soon, ExpiresAt: &expired})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	// Clean up the database
+	db.Exec("DELETE FROM stock_reservations")
+	db.Exec("DELETE FROM stocks")
+	db.Exec("DELETE FROM products")
+	db.Exec("DELETE FROM users")
+	db.Exec("DELETE FROM roles")
+}
@@ -0,0 +1,124 @@
+package tests_test
+
+import (
+	"encoding/json"
+	"inventory-management/internal/model"
+	"inventory-management/internal/utils"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStockMovementLedger(t *testing.T) {
+	db, token, testUser := setupTestEnvironment()
+	r := SetupRouter(db)
+
+	product := model.Product{Name: "Ledger Product", AccountID: testUser.AccountID}
+	db.Create(&product)
+
+	var stock model.Stock
+	productQuery := "?product_id=" + strconv.Itoa(int(product.ID))
+
+	t.Run("CreateUpdateDeleteRecover", func(t *testing.T) {
+		w := performRequest(r, "POST", "/stocks", token, model.Stock{ProductID: product.ID, Quantity: 50})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &stock))
+		stockPath := "/stocks/" + strconv.Itoa(int(stock.ID))
+
+		w = performRequest(r, "PUT", stockPath, token, model.Stock{ProductID: product.ID, Quantity: 42})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		// Moving the units to another product or place would bypass the ledger
+		other := model.Product{Name: "Other Product", AccountID: testUser.AccountID}
+		db.Create(&other)
+		w = performRequest(r, "PUT", stockPath, token, model.Stock{ProductID: other.ID, Quantity: 50})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w = performRequest(r, "PUT", stockPath, token, model.Stock{ProductID: product.ID, Quantity: 50, Location: "Elsewhere"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		var unchanged model.Stock
+		db.First(&unchanged, stock.ID)
+		assert.Equal(t, product.ID, unchanged.ProductID)
+		assert.Equal(t, "", unchanged.Location)
+
+		w = performRequest(r, "POST", "/stock-adjustments", token, model.StockAdjustment{StockID: stock.ID, Delta: -8, Reason: model.AdjustmentCorrection})
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		w = performRequest(r, "DELETE", stockPath, token, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = performRequest(r, "PATCH", stockPath+"/recover", token, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("ShipmentMovement", func(t *testing.T) {
+		_, err := utils.ReserveStock(db, 200, product.ID, 2)
+		assert.NoError(t, err)
+		shipmentID := uint(9)
+		_, err = utils.ConsumeReservations(db, 200, &shipmentID)
+		assert.NoError(t, err)
+	})
+
+	t.Run("GetMovements", func(t *testing.T) {
+		w := performRequest(r, "GET", "/stock-movements"+productQuery, token, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response model.StockMovementsResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, 5, len(response.Movements))
+
+		var deltas []int
+		var reasons []model.MovementReason
+		for _, movement := range response.Movements {
+			deltas = append(deltas, movement.Delta)
+			reasons = append(reasons, movement.Reason)
+		}
+		assert.Equal(t, []int{50, -8, -42, 42, -2}, deltas)
+		assert.Equal(t, []model.MovementReason{model.MovementReceipt, model.MovementAdjustment, model.MovementDeleted, model.MovementRecovered, model.MovementShipment}, reasons)
+		assert.Equal(t, testUser.ID, *response.Movements[0].UserID)
+		assert.Equal(t, uint(200), *response.Movements[4].OrderID)
+		assert.Equal(t, uint(9), *response.Movements[4].ShipmentID)
+		assert.Equal(t, uint(40), response.Movements[4].Balance)
+	})
+
+	t.Run("GetMovementsInvalidDate", func(t *testing.T) {
+		w := performRequest(r, "GET", "/stock-movements?from=yesterday", token, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("VerifyLedger", func(t *testing.T) {
+		w := performRequest(r, "GET", "/stock-movements/verify"+productQuery, token, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response model.LedgerVerificationResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.True(t, response.Consistent)
+		assert.Equal(t, 40, response.Stocks[0].LedgerBalance)
+	})
+
+	t.Run("VerifyLedgerDetectsDrift", func(t *testing.T) {
+		db.Model(&model.Stock{}).Where("id = ?", stock.ID).Update("quantity", 39)
+
+		w := performRequest(r, "GET", "/stock-movements/verify"+productQuery, token, nil)
+		var response model.LedgerVerificationResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.False(t, response.Consistent)
+	})
+
+	t.Run("MovementsAreImmutable", func(t *testing.T) {
+		var movement model.StockMovement
+		db.Where("stock_id = ?", stock.ID).First(&movement)
+		assert.ErrorIs(t, db.Model(&movement).Update("delta", 1).Error, model.ErrImmutableMovement)
+		assert.ErrorIs(t, db.Delete(&movement).Error, model.ErrImmutableMovement)
+	})
+
+	// Clean up the database
+	db.Exec("DELETE FROM stock_adjustments")
+	db.Exec("DELETE FROM stock_movements")
+	db.Exec("DELETE FROM stock_reservations")
+	db.Exec("DELETE FROM stocks")
+	db.Exec("DELETE FROM products")
+	db.Exec("DELETE FROM users")
+	db.Exec("DELETE FROM roles")
+}
@@ -0,0 +1,159 @@
+package tests_test
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"inventory-management/internal/model"
+	"inventory-management/internal/utils"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStockSnapshots(t *testing.T) {
+	db, token, testUser := setupTestEnvironment()
+	managerToken := useManagerService(t, 2, testUser.AccountID)
+	r := SetupRouter(db)
+
+	bolts := model.Product{Name: "Bolts", SKU: "BLT", AccountID: testUser.AccountID}
+	db.Create(&bolts)
+	nuts := model.Product{Name: "Nuts", SKU: "NUT", AccountID: testUser.AccountID}
+	db.Create(&nuts)
+	bin := model.Bin{Code: "A-01", AccountID: testUser.AccountID}
+	db.Create(&bin)
+
+	shelf := model.Stock{ProductID: bolts.ID, Quantity: 12, BinID: &bin.ID, Location: "A-01", LotNumber: "L1", AccountID: testUser.AccountID}
+	db.Create(&shelf)
+	dock := model.Stock{ProductID: bolts.ID, Quantity: 4, Location: "Dock", AccountID: testUser.AccountID}
+	db.Create(&dock)
+	nutsShelf := model.Stock{ProductID: nuts.ID, Quantity: 7, BinID: &bin.ID, Location: "A-01", AccountID: testUser.AccountID}
+	db.Create(&nutsShelf)
+
+	at := func(month time.Month, day, hour int) time.Time {
+		return time.Date(2026, month, day, hour, 0, 0, 0, time.UTC)
+	}
+	for _, movement := range []model.StockMovement{
+		{StockID: shelf.ID, ProductID: bolts.ID, BinID: &bin.ID, Delta: 10, CreatedAt: at(time.January, 1, 10)},
+		{StockID: dock.ID, ProductID: bolts.ID, Delta: 4, CreatedAt: at(time.January, 1, 12)},
+		{StockID: shelf.ID, ProductID: bolts.ID, BinID: &bin.ID, Delta: -3, CreatedAt: at(time.January, 2, 9)},
+		{StockID: nutsShelf.ID, ProductID: nuts.ID, BinID: &bin.ID, Delta: 7, CreatedAt: at(time.January, 3, 8)},
+		{StockID: shelf.ID, ProductID: bolts.ID, BinID: &bin.ID, Delta: 5, CreatedAt: at(time.February, 1, 8)},
+	} {
+		movement.AccountID = testUser.AccountID
+		db.Create(&movement)
+	}
+	db.Create(&model.StockMovement{StockID: 999, ProductID: 999, Delta: 50, CreatedAt: at(time.January, 1, 8), AccountID: testUser.AccountID + 1})
+
+	asOf := func(query string) model.StockAsOfReport {
+		w := performRequest(r, "GET", "/stock-snapshots/as-of?"+query, token, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var report model.StockAsOfReport
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+		return report
+	}
+	quantities := func(report model.StockAsOfReport) []int {
+		var quantities []int
+		for _, position := range report.Positions {
+			quantities = append(quantities, position.Quantity)
+		}
+		return quantities
+	}
+
+	t.Run("AsOfFromLedger", func(t *testing.T) {
+		report := asOf("as_of=2026-01-01")
+		assert.Nil(t, report.SnapshotID)
+		assert.Equal(t, 14, report.TotalQuantity)
+		assert.Equal(t, []int{10, 4}, quantities(report))
+		assert.Equal(t, "L1", report.Positions[0].LotNumber)
+
+		report = asOf("as_of=2026-01-01T11:00:00Z&group_by=product")
+		assert.Equal(t, []model.StockPosition{{ProductID: bolts.ID, ProductName: "Bolts", SKU: "BLT", Quantity: 10}}, report.Positions)
+
+		w := performRequest(r, "GET", "/stock-snapshots/as-of?group_by=zone", token, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	var snapshot model.StockSnapshot
+	t.Run("TakeSnapshot", func(t *testing.T) {
+		body := model.TakeSnapshotRequest{TakenAt: &[]time.Time{at(time.January, 2, 0)}[0]}
+		w := performRequest(r, "POST", "/stock-snapshots", token, body)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w = performRequest(r, "POST", "/stock-snapshots", managerToken, body)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &snapshot))
+		assert.Equal(t, 2, snapshot.Lines)
+		assert.Equal(t, 14, snapshot.TotalQuantity)
+
+		// Taking it again changes nothing
+		w = performRequest(r, "POST", "/stock-snapshots", managerToken, body)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var count int64
+		db.Model(&model.StockSnapshotLine{}).Where("snapshot_id = ?", snapshot.ID).Count(&count)
+		assert.Equal(t, int64(2), count)
+	})
+
+	t.Run("AsOfFromSnapshot", func(t *testing.T) {
+		report := asOf("as_of=2026-01-31&group_by=location")
+		assert.Equal(t, snapshot.ID, *report.SnapshotID)
+		assert.Equal(t, []model.StockPosition{
+			{BinID: &bin.ID, Location: "A-01", Quantity: 14},
+			{Location: "Dock", Quantity: 4},
+		}, report.Positions)
+
+		report = asOf("product_id=" + strconv.Itoa(int(bolts.ID)) + "&bin_id=" + strconv.Itoa(int(bin.ID)))
+		assert.Equal(t, []int{12}, quantities(report))
+
+		w := performRequest(r, "GET", "/stock-snapshots/as-of?as_of=2026-01-31&format=csv", token, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		records, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+		assert.NoError(t, err)
+		assert.Equal(t, 4, len(records))
+	})
+
+	t.Run("Export", func(t *testing.T) {
+		w := performRequest(r, "GET", "/stock-snapshots?from=2026-01-01&to=2026-01-31", token, nil)
+		var response model.StockSnapshotsResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, 1, len(response.Snapshots))
+
+		w = performRequest(r, "GET", "/stock-snapshots/"+strconv.Itoa(int(snapshot.ID))+"/export", token, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		records, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+		assert.NoError(t, err)
+		assert.Equal(t, [][]string{
+			utils.SnapshotExportColumns,
+			{strconv.Itoa(int(snapshot.ID)), "2026-01-02T00:00:00Z", strconv.Itoa(int(shelf.ID)), strconv.Itoa(int(bolts.ID)), "BLT", "Bolts", "L1", strconv.Itoa(int(bin.ID)), "A-01", "10"},
+			{strconv.Itoa(int(snapshot.ID)), "2026-01-02T00:00:00Z", strconv.Itoa(int(dock.ID)), strconv.Itoa(int(bolts.ID)), "BLT", "Bolts", "", "", "Dock", "4"},
+		}, records)
+
+		w = performRequest(r, "GET", "/stock-snapshots/"+strconv.Itoa(int(snapshot.ID))+"/export", createTestToken(1, testUser.AccountID+1), nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("DailySnapshots", func(t *testing.T) {
+		// The nightly run snapshots every account, once per midnight
+		assert.NoError(t, utils.TakeDailySnapshots(db, at(time.February, 2, 0).Add(5*time.Minute)))
+		assert.NoError(t, utils.TakeDailySnapshots(db, at(time.February, 2, 0).Add(10*time.Minute)))
+
+		var snapshots []model.StockSnapshot
+		db.Where("taken_at = ?", at(time.February, 2, 0)).Order("account_id").Find(&snapshots)
+		assert.Equal(t, 2, len(snapshots))
+		assert.Equal(t, 23, snapshots[0].TotalQuantity)
+		assert.Equal(t, 50, snapshots[1].TotalQuantity)
+
+		// Reconstructing from the later snapshot matches the ledger
+		assert.Equal(t, []int{12, 4, 7}, quantities(asOf("as_of=2026-02-10")))
+	})
+
+	db.Exec("DELETE FROM stock_snapshot_lines")
+	db.Exec("DELETE FROM stock_snapshots")
+	db.Exec("DELETE FROM stock_movements")
+	db.Exec("DELETE FROM stocks")
+	db.Exec("DELETE FROM bins")
+	db.Exec("DELETE FROM products")
+}
@@ -0,0 +1,128 @@
+package tests_test
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"inventory-management/internal/model"
+	"inventory-management/internal/utils"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStockStatus(t *testing.T) {
+	db, token, testUser := setupTestEnvironment()
+	r := SetupRouter(db)
+
+	product := model.Product{Name: "Held Product", SKU: "HLD", AccountID: testUser.AccountID}
+	db.Create(&product)
+	stock := model.Stock{ProductID: product.ID, Quantity: 10, Location: "Dock", AccountID: testUser.AccountID}
+	db.Create(&stock)
+	cost := 5.0
+	assert.NoError(t, utils.RecordStockMovement(db, stock, 10, model.StockMovement{Reason: model.MovementReceipt, UnitCost: &cost}))
+
+	path := "/stocks/" + strconv.Itoa(int(stock.ID)) + "/status"
+	change := func(from, to model.InventoryStatus, quantity uint, reason model.StatusChangeReason) int {
+		w := performRequest(r, "POST", path, token, model.ChangeStockStatusRequest{FromStatus: from, ToStatus: to, Quantity: quantity, Reason: reason})
+		return w.Code
+	}
+	reload := func() model.Stock {
+		var current model.Stock
+		db.First(&current, stock.ID)
+		return current
+	}
+
+	t.Run("ParkInboundQC", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, change(model.InventoryAvailable, model.InventoryQCHold, 4, model.StatusChangeInboundQC))
+		assert.Equal(t, uint(6), reload().Available())
+
+		assert.Equal(t, http.StatusBadRequest, change(model.InventoryQCHold, model.InventoryQCHold, 1, model.StatusChangeInboundQC))
+		assert.Equal(t, http.StatusBadRequest, change(model.InventoryQCHold, "lost", 1, model.StatusChangeInboundQC))
+		assert.Equal(t, http.StatusBadRequest, change(model.InventoryQCHold, model.InventoryAvailable, 1, "because"))
+	})
+
+	t.Run("OnlyAvailableIsAllocated", func(t *testing.T) {
+		_, err := utils.ReserveStock(db, 901, product.ID, 7)
+		assert.ErrorIs(t, err, utils.ErrInsufficientStock)
+		_, err = utils.ReserveStock(db, 902, product.ID, 6)
+		assert.NoError(t, err)
+
+		// Reserved units cannot be put on hold
+		assert.Equal(t, http.StatusConflict, change(model.InventoryAvailable, model.InventoryQuarantine, 1, model.StatusChangeInvestigation))
+	})
+
+	t.Run("Inspection", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, change(model.InventoryQCHold, model.InventoryAvailable, 2, model.StatusChangeInspectionPassed))
+		assert.Equal(t, http.StatusOK, change(model.InventoryQCHold, model.InventoryDamaged, 2, model.StatusChangeInspectionFailed))
+		assert.Equal(t, http.StatusConflict, change(model.InventoryQCHold, model.InventoryAvailable, 1, model.StatusChangeInspectionPassed))
+
+		current := reload()
+		assert.Equal(t, [3]uint{0, 2, 0}, [3]uint{current.QuarantineQuantity, current.DamagedQuantity, current.QCHoldQuantity})
+		assert.Equal(t, uint(2), current.Available())
+
+		w := performRequest(r, "GET", "/stocks/status-changes?stock_id="+strconv.Itoa(int(stock.ID)), token, nil)
+		var response model.StockStatusChangesResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, 3, len(response.Changes))
+		assert.Equal(t, model.StatusChangeInspectionFailed, response.Changes[0].Reason)
+	})
+
+	t.Run("HeldReport", func(t *testing.T) {
+		w := performRequest(r, "GET", "/stocks/held", token, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var report model.HeldStockReport
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+		assert.Equal(t, 1, len(report.Lines))
+		assert.Equal(t, model.InventoryDamaged, report.Lines[0].Status)
+		assert.Equal(t, uint(2), report.TotalQuantity)
+		assert.Equal(t, 10.0, report.TotalValue)
+		assert.NotNil(t, report.Lines[0].HeldSince)
+
+		w = performRequest(r, "GET", "/stocks/held?group_by=status&format=csv", token, nil)
+		records, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+		assert.NoError(t, err)
+		assert.Equal(t, 2, len(records))
+
+		w = performRequest(r, "GET", "/stocks/held?status=available", token, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("WriteOffDamaged", func(t *testing.T) {
+		w := performRequest(r, "POST", "/stock-adjustments", token, model.StockAdjustment{
+			StockID: stock.ID, Delta: -3, Reason: model.AdjustmentDamage, FromStatus: model.InventoryDamaged,
+		})
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		w = performRequest(r, "POST", "/stock-adjustments", token, model.StockAdjustment{
+			StockID: stock.ID, Delta: -2, Reason: model.AdjustmentDamage, FromStatus: model.InventoryDamaged,
+		})
+		assert.Equal(t, http.StatusCreated, w.Code)
+		current := reload()
+		assert.Equal(t, uint(8), current.Quantity)
+		assert.Equal(t, uint(0), current.Held())
+		assert.Equal(t, uint(2), current.Available())
+	})
+
+	t.Run("ReceiveOnHold", func(t *testing.T) {
+		w := performRequest(r, "POST", "/stocks", token, model.Stock{ProductID: product.ID, Quantity: 3, QCHoldQuantity: 4, Location: "Dock"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = performRequest(r, "POST", "/stocks", token, model.Stock{ProductID: product.ID, Quantity: 3, QCHoldQuantity: 3, Location: "Dock"})
+		assert.Equal(t, http.StatusOK, w.Code)
+		var received model.Stock
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &received))
+		assert.Equal(t, uint(0), received.Available())
+	})
+
+	db.Exec("DELETE FROM stock_status_changes")
+	db.Exec("DELETE FROM stock_adjustments")
+	db.Exec("DELETE FROM stock_reservations")
+	db.Exec("DELETE FROM stock_movements")
+	db.Exec("DELETE FROM cost_consumptions")
+	db.Exec("DELETE FROM cost_layers")
+	db.Exec("DELETE FROM stocks")
+	db.Exec("DELETE FROM products")
+}
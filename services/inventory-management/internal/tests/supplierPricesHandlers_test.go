@@ -0,0 +1,172 @@
+package tests_test
+
+import (
+	"encoding/json"
+	"inventory-management/internal/model"
+	"inventory-management/internal/utils"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSupplierPricesAndScorecards(t *testing.T) {
+	db, token, testUser := setupTestEnvironment()
+	managerToken := useManagerService(t, 2, testUser.AccountID)
+	r := SetupRouter(db)
+
+	acme := model.Supplier{Name: "Acme", LeadTimeDays: 5, AccountID: testUser.AccountID}
+	db.Create(&acme)
+	other := model.Supplier{Name: "Other", LeadTimeDays: 10, AccountID: testUser.AccountID}
+	db.Create(&other)
+	product := model.Product{Name: "Hinges", SupplierID: acme.ID, AccountID: testUser.AccountID}
+	db.Create(&product)
+
+	acmePath := "/suppliers/" + strconv.Itoa(int(acme.ID))
+	from := time.Now().AddDate(0, -1, 0)
+
+	t.Run("PriceList", func(t *testing.T) {
+		price := model.SupplierPrice{ProductID: product.ID, UnitCost: 2.5, LeadTimeDays: 7, EffectiveFrom: from}
+		w := performRequest(r, "POST", acmePath+"/prices", token, price)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w = performRequest(r, "POST", acmePath+"/prices", managerToken, price)
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		// The same product and quantity break cannot have two prices at once
+		w = performRequest(r, "POST", acmePath+"/prices", managerToken, model.SupplierPrice{ProductID: product.ID, UnitCost: 3, EffectiveFrom: time.Now()})
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		ended := from.AddDate(0, 0, -1)
+		w = performRequest(r, "POST", acmePath+"/prices", managerToken, model.SupplierPrice{ProductID: product.ID, UnitCost: 3, EffectiveFrom: from, EffectiveTo: &ended, MinQuantity: 100})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = performRequest(r, "POST", acmePath+"/prices", managerToken, model.SupplierPrice{ProductID: product.ID, UnitCost: 2, MinQuantity: 100, EffectiveFrom: from})
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		w = performRequest(r, "GET", acmePath+"/prices?at="+time.Now().Format("2006-01-02"), token, nil)
+		var response model.SupplierPricesResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, 2, len(response.Prices))
+	})
+
+	t.Run("PricedPurchaseOrder", func(t *testing.T) {
+		request := model.PurchaseOrder{SupplierID: acme.ID, Lines: []model.PurchaseOrderLine{
+			{ProductID: product.ID, Quantity: 10},
+			{ProductID: product.ID, Quantity: 150},
+		}}
+		w := performRequest(r, "POST", "/purchase-orders", managerToken, request)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var order model.PurchaseOrder
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &order))
+		assert.Equal(t, 2.5, order.Lines[0].UnitCost)
+		assert.Equal(t, 2.0, order.Lines[1].UnitCost)
+
+		w = performRequest(r, "POST", "/purchase-orders/"+strconv.Itoa(int(order.ID))+"/send", managerToken, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		db.First(&order, order.ID)
+		assert.Equal(t, uint(7), order.LeadTimeDays)
+		assert.NotNil(t, order.ExpectedAt)
+	})
+
+	t.Run("Scorecards", func(t *testing.T) {
+		// A late order, received 8 days after it was sent, 10 short
+		lateSent := time.Now().AddDate(0, 0, -10)
+		lateExpected := lateSent.AddDate(0, 0, 7)
+		late := model.PurchaseOrder{SupplierID: acme.ID, Status: model.PurchaseOrderClosed, SentAt: &lateSent, ExpectedAt: &lateExpected, LeadTimeDays: 7, AccountID: testUser.AccountID,
+			Lines: []model.PurchaseOrderLine{{ProductID: product.ID, Quantity: 100, ReceivedQuantity: 90, Closed: true, AccountID: testUser.AccountID}}}
+		db.Create(&late)
+		db.Create(&model.PurchaseOrderReceipt{ReceiptID: 901, PurchaseOrderID: late.ID, LineID: late.Lines[0].ID, ProductID: product.ID, Quantity: 90, ReceivedAt: lateSent.AddDate(0, 0, 8), AccountID: testUser.AccountID})
+
+		// An early order, received complete 6 days after it was sent
+		earlySent := time.Now().AddDate(0, 0, -20)
+		early := model.PurchaseOrder{SupplierID: acme.ID, Status: model.PurchaseOrderClosed, SentAt: &earlySent, LeadTimeDays: 7, AccountID: testUser.AccountID,
+			Lines: []model.PurchaseOrderLine{{ProductID: product.ID, Quantity: 50, ReceivedQuantity: 50, Closed: true, AccountID: testUser.AccountID}}}
+		db.Create(&early)
+		db.Create(&model.PurchaseOrderReceipt{ReceiptID: 902, PurchaseOrderID: early.ID, LineID: early.Lines[0].ID, ProductID: product.ID, Quantity: 50, ReceivedAt: earlySent.AddDate(0, 0, 6), AccountID: testUser.AccountID})
+
+		w := performRequest(r, "GET", acmePath+"/scorecard", token, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var scorecard model.SupplierScorecard
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &scorecard))
+		assert.Equal(t, 2, scorecard.ClosedOrders)
+		assert.Equal(t, 1, scorecard.OnTimeOrders)
+		assert.Equal(t, 0.5, scorecard.OnTimeRate)
+		assert.InDelta(t, 140.0/150.0, scorecard.FillRate, 0.0001)
+		assert.InDelta(t, 7, scorecard.AverageLeadTimeDays, 0.01)
+		assert.InDelta(t, 0, scorecard.AverageLeadTimeVarianceDays, 0.01)
+
+		w = performRequest(r, "GET", acmePath+"/scorecard?from="+time.Now().AddDate(0, 0, -15).Format("2006-01-02"), token, nil)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &scorecard))
+		assert.Equal(t, 1, scorecard.ClosedOrders)
+		assert.Equal(t, 0.0, scorecard.OnTimeRate)
+		assert.InDelta(t, 1, scorecard.AverageLeadTimeVarianceDays, 0.01)
+
+		// Comparing suppliers for a product leaves out those without a price for it
+		w = performRequest(r, "GET", "/suppliers/scorecards?product_id="+strconv.Itoa(int(product.ID)), token, nil)
+		var response model.SupplierScorecardsResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, 1, len(response.Scorecards))
+		assert.Equal(t, acme.ID, response.Scorecards[0].SupplierID)
+		assert.Equal(t, 2.5, *response.Scorecards[0].UnitCost)
+		assert.Equal(t, uint(7), *response.Scorecards[0].ProductLeadTimeDays)
+
+		w = performRequest(r, "GET", "/suppliers/scorecards", token, nil)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, 2, len(response.Scorecards))
+	})
+
+	t.Run("ProductThresholds", func(t *testing.T) {
+		stock := model.Stock{ProductID: product.ID, Quantity: 40, Location: "A1", LowStockThreshold: 5, AccountID: testUser.AccountID}
+		db.Create(&stock)
+		productPath := "/products/" + strconv.Itoa(int(product.ID)) + "/thresholds"
+
+		w := performRequest(r, "GET", productPath, token, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var thresholds model.ProductThresholds
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &thresholds))
+		assert.Equal(t, acme.ID, thresholds.SupplierID)
+		assert.Equal(t, uint(7), thresholds.LeadTimeDays)
+		assert.Equal(t, uint(40), thresholds.OnHand)
+
+		reorderPoint, lowStock := uint(30), 12
+		update := model.ProductThresholds{ReorderPoint: &reorderPoint, LowStockThreshold: &lowStock}
+		w = performRequest(r, "PUT", productPath, token, update)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w = performRequest(r, "PUT", productPath, managerToken, update)
+		assert.Equal(t, http.StatusOK, w.Code)
+		db.First(&product, product.ID)
+		db.First(&stock, stock.ID)
+		assert.Equal(t, uint(30), product.ReorderPoint)
+		assert.Equal(t, uint(0), product.ReorderQuantity)
+		assert.Equal(t, 12, stock.LowStockThreshold)
+
+		// A safety stock is split by what each row shipped, 30 and 10 units here
+		busy := model.Stock{ProductID: product.ID, Quantity: 90, Location: "B1", AccountID: testUser.AccountID}
+		db.Create(&busy)
+		assert.NoError(t, utils.RecordStockMovement(db, busy, -30, model.StockMovement{Reason: model.MovementShipment}))
+		assert.NoError(t, utils.RecordStockMovement(db, stock, -10, model.StockMovement{Reason: model.MovementShipment}))
+
+		safetyStock := uint(9)
+		w = performRequest(r, "PUT", productPath, managerToken, model.ProductThresholds{LowStockThreshold: &lowStock, SafetyStock: &safetyStock})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w = performRequest(r, "PUT", productPath, managerToken, model.ProductThresholds{SafetyStock: &safetyStock})
+		assert.Equal(t, http.StatusOK, w.Code)
+		db.First(&stock, stock.ID)
+		db.First(&busy, busy.ID)
+		assert.Equal(t, 2, stock.LowStockThreshold)
+		assert.Equal(t, 7, busy.LowStockThreshold)
+	})
+
+	db.Exec("DELETE FROM stock_movements")
+	db.Exec("DELETE FROM stocks")
+	db.Exec("DELETE FROM purchase_order_receipts")
+	db.Exec("DELETE FROM purchase_order_lines")
+	db.Exec("DELETE FROM purchase_orders")
+	db.Exec("DELETE FROM supplier_prices")
+	db.Exec("DELETE FROM products")
+	db.Exec("DELETE FROM suppliers")
+}
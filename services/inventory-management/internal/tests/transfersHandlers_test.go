@@ -0,0 +1,185 @@
+package tests_test
+
+import (
+	"encoding/json"
+	"inventory-management/internal/model"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStockTransfers(t *testing.T) {
+	db, token, testUser := setupTestEnvironment()
+	r := SetupRouter(db)
+
+	north := model.Warehouse{Name: "North", AccountID: testUser.AccountID}
+	south := model.Warehouse{Name: "South", AccountID: testUser.AccountID}
+	db.Create(&north)
+	db.Create(&south)
+	northZone := model.Zone{Name: "Z1", WarehouseID: north.ID, AccountID: testUser.AccountID}
+	southZone := model.Zone{Name: "Z1", WarehouseID: south.ID, AccountID: testUser.AccountID}
+	db.Create(&northZone)
+	db.Create(&southZone)
+	northBin := model.Bin{Code: "N-01", ZoneID: northZone.ID, AccountID: testUser.AccountID}
+	southBin := model.Bin{Code: "S-01", ZoneID: southZone.ID, AccountID: testUser.AccountID}
+	db.Create(&northBin)
+	db.Create(&southBin)
+
+	product := model.Product{Name: "Transferred Product", AccountID: testUser.AccountID}
+	db.Create(&product)
+
+	var source model.Stock
+	w := performRequest(r, "POST", "/stocks", token, model.Stock{ProductID: product.ID, Quantity: 20, BinID: &northBin.ID})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &source))
+
+	productQuery := "/stocks?product_id=" + strconv.Itoa(int(product.ID))
+	stocksInView := func() model.StocksResponse {
+		var response model.StocksResponse
+		w := performRequest(r, "GET", productQuery, token, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response
+	}
+	onHand := func(response model.StocksResponse) int {
+		total := 0
+		for _, stock := range response.Stocks {
+			total += stock.Quantity
+		}
+		return total
+	}
+
+	var transfer model.TransferOrder
+	transferPath := func(action string) string {
+		return "/transfers/" + strconv.Itoa(int(transfer.ID)) + "/" + action
+	}
+
+	t.Run("CreateHoldsSourceQuantity", func(t *testing.T) {
+		w := performRequest(r, "POST", "/transfers", token, model.TransferOrder{FromStockID: source.ID, ToBinID: &southBin.ID, Quantity: 25})
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		w = performRequest(r, "POST", "/transfers", token, model.TransferOrder{FromStockID: source.ID, ToBinID: &southBin.ID, Quantity: 15})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &transfer))
+		assert.Equal(t, model.TransferPending, transfer.Status)
+
+		response := stocksInView()
+		assert.Equal(t, 5, response.Stocks[0].Available)
+		assert.Equal(t, 0, len(response.InTransit))
+	})
+
+	t.Run("PickAndDispatch", func(t *testing.T) {
+		w := performRequest(r, "POST", transferPath("pick"), token, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		w = performRequest(r, "POST", transferPath("dispatch"), token, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		response := stocksInView()
+		assert.Equal(t, 5, onHand(response))
+		assert.Equal(t, 1, len(response.InTransit))
+		assert.Equal(t, 15, response.InTransit[0].Quantity)
+
+		// Location filters show the transfers leaving from or going to the location
+		east := model.Warehouse{Name: "East", AccountID: testUser.AccountID}
+		db.Create(&east)
+		for query, inTransit := range map[string]int{
+			"&warehouse_id=" + strconv.Itoa(int(north.ID)): 1,
+			"&zone_id=" + strconv.Itoa(int(southZone.ID)):  1,
+			"&bin_id=" + strconv.Itoa(int(southBin.ID)):    1,
+			"&warehouse_id=" + strconv.Itoa(int(east.ID)):  0,
+		} {
+			var filtered model.StocksResponse
+			w := performRequest(r, "GET", productQuery+query, token, nil)
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &filtered))
+			assert.Equal(t, inTransit, len(filtered.InTransit), query)
+		}
+	})
+
+	t.Run("PartialReceipt", func(t *testing.T) {
+		w := performRequest(r, "POST", transferPath("receive"), token, model.ReceiveTransferRequest{Quantity: 10})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &transfer))
+		assert.Equal(t, model.TransferPartiallyReceived, transfer.Status)
+
+		response := stocksInView()
+		assert.Equal(t, 15, onHand(response))
+		assert.Equal(t, 5, response.InTransit[0].Quantity)
+
+		var destination model.Stock
+		db.First(&destination, *transfer.ToStockID)
+		assert.Equal(t, southBin.ID, *destination.BinID)
+		assert.Equal(t, uint(10), destination.Quantity)
+
+		w = performRequest(r, "POST", transferPath("receive"), token, model.ReceiveTransferRequest{Quantity: 6})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = performRequest(r, "POST", transferPath("receive"), token, model.ReceiveTransferRequest{Quantity: 5})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &transfer))
+		assert.Equal(t, model.TransferReceived, transfer.Status)
+
+		response = stocksInView()
+		assert.Equal(t, 20, onHand(response))
+		assert.Equal(t, 0, len(response.InTransit))
+
+		w = performRequest(r, "POST", transferPath("close"), token, nil)
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("CancelPendingReleasesHold", func(t *testing.T) {
+		w := performRequest(r, "POST", "/transfers", token, model.TransferOrder{FromStockID: source.ID, ToStockID: transfer.ToStockID, Quantity: 5})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &transfer))
+
+		w = performRequest(r, "DELETE", "/stocks/"+strconv.Itoa(int(source.ID)), token, nil)
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		w = performRequest(r, "POST", transferPath("cancel"), token, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var stock model.Stock
+		db.First(&stock, source.ID)
+		assert.Equal(t, uint(0), stock.ReservedQuantity)
+	})
+
+	t.Run("ShortPickRejected", func(t *testing.T) {
+		w := performRequest(r, "POST", "/transfers", token, model.TransferOrder{FromStockID: source.ID, ToBinID: &southBin.ID, Quantity: 5})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &transfer))
+
+		// The source lost units since the transfer was created
+		db.Model(&model.Stock{}).Where("id = ?", source.ID).Update("quantity", 3)
+		w = performRequest(r, "POST", transferPath("pick"), token, nil)
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		var stock model.Stock
+		db.First(&stock, source.ID)
+		assert.Equal(t, uint(3), stock.Quantity)
+		var picks int64
+		db.Model(&model.StockMovement{}).Where("stock_id = ? AND note = ?", source.ID, "Transfer order "+strconv.Itoa(int(transfer.ID))).Count(&picks)
+		assert.Equal(t, int64(0), picks)
+
+		db.Model(&model.Stock{}).Where("id = ?", source.ID).Update("quantity", 5)
+		w = performRequest(r, "POST", transferPath("cancel"), token, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("LedgerStaysConsistent", func(t *testing.T) {
+		w := performRequest(r, "GET", "/stock-movements/verify?product_id="+strconv.Itoa(int(product.ID)), token, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response model.LedgerVerificationResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.True(t, response.Consistent)
+	})
+
+	db.Exec("DELETE FROM transfer_orders")
+	db.Exec("DELETE FROM stock_movements")
+	db.Exec("DELETE FROM stocks")
+	db.Exec("DELETE FROM products")
+	db.Exec("DELETE FROM bins")
+	db.Exec("DELETE FROM zones")
+	db.Exec("DELETE FROM warehouses")
+}
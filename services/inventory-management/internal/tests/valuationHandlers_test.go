@@ -0,0 +1,146 @@
+package tests_test
+
+import (
+	"encoding/json"
+	"inventory-management/internal/model"
+	"inventory-management/internal/utils"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInventoryValuation(t *testing.T) {
+	db, token, testUser := setupTestEnvironment()
+	managerToken := useManagerService(t, 2, testUser.AccountID)
+	r := SetupRouter(db)
+	db.Exec("DELETE FROM cost_consumptions")
+	db.Exec("DELETE FROM cost_layers")
+	db.Exec("DELETE FROM stock_movements")
+
+	category := model.Category{Name: "Hardware", AccountID: testUser.AccountID}
+	db.Create(&category)
+	warehouse := model.Warehouse{Name: "Main", AccountID: testUser.AccountID}
+	db.Create(&warehouse)
+	zone := model.Zone{WarehouseID: warehouse.ID, Name: "A", AccountID: testUser.AccountID}
+	db.Create(&zone)
+	bin := model.Bin{ZoneID: zone.ID, Code: "A-01", AccountID: testUser.AccountID}
+	db.Create(&bin)
+
+	bolts := model.Product{Name: "Bolts", CategoryID: category.ID, AccountID: testUser.AccountID}
+	db.Create(&bolts)
+	nuts := model.Product{Name: "Nuts", AccountID: testUser.AccountID}
+	db.Create(&nuts)
+	boltStock := model.Stock{ProductID: bolts.ID, BinID: &bin.ID, Location: "A-01", AccountID: testUser.AccountID}
+	db.Create(&boltStock)
+	nutStock := model.Stock{ProductID: nuts.ID, Location: "Shelf", AccountID: testUser.AccountID}
+	db.Create(&nutStock)
+
+	change := func(stock *model.Stock, delta int, movement model.StockMovement) {
+		stock.Quantity = uint(int(stock.Quantity) + delta)
+		db.Model(stock).Update("quantity", stock.Quantity)
+		assert.NoError(t, utils.RecordStockMovement(db, *stock, delta, movement))
+	}
+	cost := func(c float64) *float64 { return &c }
+	orderID := uint(501)
+
+	t.Run("FIFO", func(t *testing.T) {
+		change(&boltStock, 10, model.StockMovement{Reason: model.MovementReceipt, UnitCost: cost(2)})
+		// Backdate the first receipt so it can be valued on its own
+		twoDaysAgo := time.Now().AddDate(0, 0, -2)
+		db.Model(&model.CostLayer{}).Where("product_id = ?", bolts.ID).Update("created_at", twoDaysAgo)
+		db.Model(&model.StockMovement{}).Where("product_id = ?", bolts.ID).UpdateColumn("created_at", twoDaysAgo)
+
+		change(&boltStock, 10, model.StockMovement{Reason: model.MovementReceipt, UnitCost: cost(4)})
+		change(&boltStock, -15, model.StockMovement{Reason: model.MovementShipment, OrderID: &orderID})
+
+		w := performRequest(r, "GET", "/valuation/cogs?order_id="+strconv.Itoa(int(orderID)), token, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var cogs model.COGSResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &cogs))
+		assert.Equal(t, 1, len(cogs.Orders))
+		assert.InDelta(t, 40, cogs.Orders[0].Amount, 0.001)
+
+		unitCost, err := utils.CurrentUnitCost(db, bolts.ID)
+		assert.NoError(t, err)
+		assert.InDelta(t, 4, unitCost, 0.001)
+
+		yesterday := time.Now().AddDate(0, 0, -1).Format("2006-01-02")
+		w = performRequest(r, "GET", "/valuation?as_of="+yesterday, token, nil)
+		var report model.ValuationReport
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+		assert.Equal(t, model.CostingFIFO, report.Method)
+		assert.Equal(t, 1, len(report.Lines))
+		assert.Equal(t, 10, report.Lines[0].Quantity)
+		assert.InDelta(t, 20, report.Lines[0].Value, 0.001)
+	})
+
+	t.Run("WeightedAverage", func(t *testing.T) {
+		w := performRequest(r, "PUT", "/settings", token, model.AccountSettings{CostingMethod: model.CostingAverage})
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		w = performRequest(r, "PUT", "/settings", managerToken, model.AccountSettings{CostingMethod: "lifo"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w = performRequest(r, "PUT", "/settings", managerToken, model.AccountSettings{CostingMethod: model.CostingAverage})
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		change(&nutStock, 10, model.StockMovement{Reason: model.MovementReceipt, UnitCost: cost(2)})
+		change(&nutStock, 10, model.StockMovement{Reason: model.MovementReceipt, UnitCost: cost(4)})
+		change(&nutStock, -5, model.StockMovement{Reason: model.MovementShipment, OrderID: &orderID})
+
+		// Units found later are valued at the current average cost
+		change(&nutStock, 5, model.StockMovement{Reason: model.MovementAdjustment})
+		unitCost, err := utils.CurrentUnitCost(db, nuts.ID)
+		assert.NoError(t, err)
+		assert.InDelta(t, 3, unitCost, 0.001)
+
+		w = performRequest(r, "GET", "/valuation/cogs", token, nil)
+		var cogs model.COGSResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &cogs))
+		assert.InDelta(t, 55, cogs.Total, 0.001)
+	})
+
+	t.Run("Report", func(t *testing.T) {
+		w := performRequest(r, "GET", "/valuation", token, nil)
+		var report model.ValuationReport
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+		assert.Equal(t, model.CostingAverage, report.Method)
+		assert.Equal(t, 2, len(report.Lines))
+		assert.Equal(t, 25, report.TotalQuantity)
+		assert.InDelta(t, 80, report.TotalValue, 0.001)
+
+		w = performRequest(r, "GET", "/valuation?group_by=category", token, nil)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+		assert.Equal(t, 2, len(report.Lines))
+		assert.Equal(t, "Uncategorized", report.Lines[0].Name)
+		assert.Equal(t, "Hardware", report.Lines[1].Name)
+		assert.InDelta(t, 20, report.Lines[1].Value, 0.001)
+
+		w = performRequest(r, "GET", "/valuation?group_by=warehouse", token, nil)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+		assert.Equal(t, 2, len(report.Lines))
+		assert.Equal(t, "Main", report.Lines[1].Name)
+		assert.Equal(t, 5, report.Lines[1].Quantity)
+		assert.InDelta(t, 60, report.Lines[0].Value, 0.001)
+
+		w = performRequest(r, "GET", "/valuation?group_by=product&format=csv", token, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.True(t, strings.HasPrefix(w.Body.String(), "product_id,name,quantity,unit_cost,value"))
+
+		w = performRequest(r, "GET", "/valuation?group_by=supplier", token, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	db.Exec("DELETE FROM account_settings")
+	db.Exec("DELETE FROM cost_consumptions")
+	db.Exec("DELETE FROM cost_layers")
+	db.Exec("DELETE FROM stock_movements")
+	db.Exec("DELETE FROM stocks")
+	db.Exec("DELETE FROM products")
+	db.Exec("DELETE FROM bins")
+	db.Exec("DELETE FROM zones")
+	db.Exec("DELETE FROM warehouses")
+	db.Exec("DELETE FROM categories")
+}
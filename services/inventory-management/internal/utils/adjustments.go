@@ -0,0 +1,205 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"inventory-management/internal/model"
+	"math"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrInvalidAdjustment is returned for an adjustment without a change or a known reason code
+	ErrInvalidAdjustment = errors.New("adjustment needs a non-zero delta and a reason of damage, shrinkage, found or correction")
+	// ErrAdjustmentBelowReserved is returned when an adjustment would leave less stock than is reserved or on hold
+	ErrAdjustmentBelowReserved = errors.New("adjustment would leave less stock than is reserved or on hold")
+	// ErrAdjustmentNotPending is returned when an adjustment that was already reviewed is reviewed again
+	ErrAdjustmentNotPending = errors.New("adjustment is not waiting for approval")
+	// ErrAdjustmentNeedsApproval is returned when a quantity is changed outside an adjustment
+	// by more than the account lets an adjustment change without approval
+	ErrAdjustmentNeedsApproval = errors.New("quantity change exceeds the approval threshold; request a stock adjustment instead")
+)
+
+// RequestStockAdjustment records an adjustment of a stock row, valued at the product's
+// current cost. Adjustments within the account's thresholds are applied right away;
+// larger ones stay pending until a manager reviews them.
+func RequestStockAdjustment(tx *gorm.DB, adjustment *model.StockAdjustment) error {
+	if adjustment.Delta == 0 || !slices.Contains(model.AdjustmentReasons, adjustment.Reason) {
+		return ErrInvalidAdjustment
+	}
+	if adjustment.FromStatus != "" && !slices.Contains(model.InventoryStatuses, adjustment.FromStatus) {
+		return ErrUnknownStatus
+	}
+
+	var stock model.Stock
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND account_id = ?", adjustment.StockID, adjustment.AccountID).First(&stock).Error; err != nil {
+		return err
+	}
+	if err := checkAdjustment(tx, stock, *adjustment); err != nil {
+		return err
+	}
+
+	unitCost, err := CurrentUnitCost(tx, stock.ProductID)
+	if err != nil {
+		return err
+	}
+	settings, err := LoadAccountSettings(tx, adjustment.AccountID)
+	if err != nil {
+		return err
+	}
+
+	units := uint(math.Abs(float64(adjustment.Delta)))
+	adjustment.ProductID = stock.ProductID
+	adjustment.UnitCost = unitCost
+	adjustment.Value = float64(units) * unitCost
+	adjustment.Status = model.AdjustmentApproved
+	if needsApproval(settings, units, adjustment.Value) {
+		adjustment.Status = model.AdjustmentPending
+	}
+	if err := tx.Create(adjustment).Error; err != nil {
+		return err
+	}
+
+	if adjustment.Status == model.AdjustmentPending {
+		return nil
+	}
+	return applyAdjustment(tx, stock, *adjustment)
+}
+
+// ReviewStockAdjustment approves a pending adjustment, which changes the stock, or
+// rejects it, which leaves the stock as it is
+func ReviewStockAdjustment(tx *gorm.DB, adjustment *model.StockAdjustment, approve bool, note string, managerID *uint) error {
+	if adjustment.Status != model.AdjustmentPending {
+		return ErrAdjustmentNotPending
+	}
+
+	now := time.Now()
+	adjustment.ReviewedBy = managerID
+	adjustment.ReviewedAt = &now
+	adjustment.ReviewNote = note
+	adjustment.Status = model.AdjustmentRejected
+	if approve {
+		adjustment.Status = model.AdjustmentApproved
+
+		var stock model.Stock
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&stock, adjustment.StockID).Error; err != nil {
+			return err
+		}
+		if err := checkAdjustment(tx, stock, *adjustment); err != nil {
+			return err
+		}
+		if err := applyAdjustment(tx, stock, *adjustment); err != nil {
+			return err
+		}
+	}
+
+	return tx.Model(adjustment).Updates(map[string]interface{}{
+		"status":      adjustment.Status,
+		"reviewed_by": adjustment.ReviewedBy,
+		"reviewed_at": adjustment.ReviewedAt,
+		"review_note": adjustment.ReviewNote,
+	}).Error
+}
+
+// needsApproval reports whether a change of units worth value exceeds the account's
+// adjustment thresholds
+func needsApproval(settings model.AccountSettings, units uint, value float64) bool {
+	return (settings.AdjustmentQuantityThreshold > 0 && units > settings.AdjustmentQuantityThreshold) ||
+		(settings.AdjustmentValueThreshold > 0 && value > settings.AdjustmentValueThreshold)
+}
+
+// checkAdjustment verifies that the stock row can take the adjustment: it must keep at
+// least its reserved and held quantity, or take a removal out of the held status it names,
+// and serialized stock names one serial per unit, held by the row when removing and new
+// when adding
+func checkAdjustment(tx *gorm.DB, stock model.Stock, adjustment model.StockAdjustment) error {
+	if held := stock.HeldQuantity(adjustment.FromStatus); held != nil {
+		if adjustment.Delta > 0 || int(*held)+adjustment.Delta < 0 {
+			return ErrAdjustmentBelowReserved
+		}
+	} else if int(stock.Quantity)+adjustment.Delta < int(stock.ReservedQuantity+stock.Held()) {
+		return ErrAdjustmentBelowReserved
+	}
+
+	serialized, err := isSerialized(tx, stock.ProductID)
+	if err != nil {
+		return err
+	}
+	if !serialized {
+		if len(adjustment.SerialNumbers) > 0 {
+			return fmt.Errorf("product %d is not serialized", stock.ProductID)
+		}
+		return nil
+	}
+	if len(adjustment.SerialNumbers) != int(math.Abs(float64(adjustment.Delta))) {
+		return ErrSerialCountMismatch
+	}
+
+	held, err := stockSerials(tx, stock.ID)
+	if err != nil {
+		return err
+	}
+	for _, serial := range adjustment.SerialNumbers {
+		if slices.Contains(held, serial) != (adjustment.Delta < 0) {
+			return fmt.Errorf("serial %s is not held by stock %d", serial, stock.ID)
+		}
+	}
+	return nil
+}
+
+// applyAdjustment changes the stock row's quantity and serials and records the movement
+// with the reason code and note
+func applyAdjustment(tx *gorm.DB, stock model.Stock, adjustment model.StockAdjustment) error {
+	stock.Quantity = uint(int(stock.Quantity) + adjustment.Delta)
+	if held := stock.HeldQuantity(adjustment.FromStatus); held != nil {
+		*held = uint(int(*held) + adjustment.Delta)
+	}
+	if err := tx.Model(&stock).Updates(map[string]interface{}{
+		"quantity":            stock.Quantity,
+		"quarantine_quantity": stock.QuarantineQuantity,
+		"damaged_quantity":    stock.DamagedQuantity,
+		"qc_hold_quantity":    stock.QCHoldQuantity,
+	}).Error; err != nil {
+		return err
+	}
+
+	if len(adjustment.SerialNumbers) > 0 {
+		serials, err := stockSerials(tx, stock.ID)
+		if err != nil {
+			return err
+		}
+		if adjustment.Delta > 0 {
+			serials = append(serials, adjustment.SerialNumbers...)
+		} else {
+			serials = slices.DeleteFunc(serials, func(serial string) bool {
+				return slices.Contains(adjustment.SerialNumbers, serial)
+			})
+		}
+		if err := SyncStockSerials(tx, stock, serials, adjustment.RequestedBy); err != nil {
+			return err
+		}
+	}
+
+	note := string(adjustment.Reason)
+	if adjustment.Note != "" {
+		note += ": " + adjustment.Note
+	}
+	return RecordStockMovement(tx, stock, adjustment.Delta, model.StockMovement{
+		Reason: model.MovementAdjustment,
+		UserID: adjustment.RequestedBy,
+		Note:   note,
+	})
+}
+
+// stockSerials returns the serials a stock row holds
+func stockSerials(tx *gorm.DB, stockID uint) ([]string, error) {
+	var serials []string
+	err := tx.Model(&model.SerialNumber{}).Where("stock_id = ? AND status = ?", stockID, model.SerialInStock).
+		Order("serial").Pluck("serial", &serials).Error
+	return serials, err
+}
@@ -0,0 +1,279 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"inventory-management/internal/model"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidAlertRule is returned for a rule without recipients or with an unknown mode
+var ErrInvalidAlertRule = errors.New("alert rule needs at least one user, role or department and a mode of immediate or digest")
+
+// ValidateAlertRule checks a rule before it is saved and fills in the immediate mode
+func ValidateAlertRule(rule *model.AlertRule) error {
+	if rule.Mode == "" {
+		rule.Mode = model.AlertImmediate
+	}
+	if rule.Mode != model.AlertImmediate && rule.Mode != model.AlertDigest {
+		return ErrInvalidAlertRule
+	}
+	if len(rule.UserIDs) == 0 && len(rule.RoleIDs) == 0 && len(rule.Departments) == 0 {
+		return ErrInvalidAlertRule
+	}
+	return nil
+}
+
+// NotifyLowStock runs a low stock row through the account's alert rules. Every active
+// rule that covers the row and is out of its cooldown records an alert; immediate rules
+// email their recipients right away, digest rules leave the alert for the daily digest.
+// token is used to look up recipients in user-management.
+func NotifyLowStock(db *gorm.DB, ns *NotificationService, stock model.Stock, token string) ([]model.LowStockAlert, error) {
+	var product model.Product
+	if err := db.Select("id", "name", "category_id").First(&product, stock.ProductID).Error; err != nil {
+		return nil, err
+	}
+	rules, err := matchingAlertRules(db, stock, product)
+	if err != nil {
+		return nil, err
+	}
+
+	var alerts []model.LowStockAlert
+	var users []model.DirectoryUser
+	for _, rule := range rules {
+		cooling, err := inAlertCooldown(db, rule, stock.ID, time.Now())
+		if err != nil {
+			return alerts, err
+		}
+		if cooling {
+			continue
+		}
+
+		alert := model.LowStockAlert{
+			RuleID:      rule.ID,
+			StockID:     stock.ID,
+			ProductID:   stock.ProductID,
+			ProductName: product.Name,
+			Available:   stock.Available(),
+			Threshold:   stock.LowStockThreshold,
+			Mode:        rule.Mode,
+			AccountID:   stock.AccountID,
+		}
+		if rule.Mode == model.AlertImmediate {
+			if users == nil {
+				if users, err = FetchAccountUsers(token); err != nil {
+					return alerts, err
+				}
+			}
+			alert.Recipients = AlertRecipients(rule, users)
+			sendAlert(&alert, func(email string) error {
+				return ns.SendLowStockNotification(email, []model.LowStockAlert{alert})
+			})
+		}
+		if err := db.Create(&alert).Error; err != nil {
+			return alerts, err
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts, nil
+}
+
+// SendLowStockDigests emails every digest rule's recipients one summary of the alerts
+// collected since the last digest and marks the alerts as sent
+func SendLowStockDigests(db *gorm.DB, ns *NotificationService) error {
+	var pending []model.LowStockAlert
+	if err := db.Where("mode = ? AND sent_at IS NULL AND error = ?", model.AlertDigest, "").Order("rule_id, id").Find(&pending).Error; err != nil {
+		return err
+	}
+
+	byRule := map[uint][]model.LowStockAlert{}
+	var ruleIDs []uint
+	for _, alert := range pending {
+		if _, ok := byRule[alert.RuleID]; !ok {
+			ruleIDs = append(ruleIDs, alert.RuleID)
+		}
+		byRule[alert.RuleID] = append(byRule[alert.RuleID], alert)
+	}
+
+	users := map[uint][]model.DirectoryUser{}
+	for _, ruleID := range ruleIDs {
+		alerts := byRule[ruleID]
+		var rule model.AlertRule
+		if err := db.Unscoped().First(&rule, ruleID).Error; err != nil {
+			return err
+		}
+
+		if _, ok := users[rule.AccountID]; !ok {
+			token, err := AccountToken(rule.AccountID)
+			if err != nil {
+				return err
+			}
+			if users[rule.AccountID], err = FetchAccountUsers(token); err != nil {
+				log.Printf("Error looking up recipients of alert rule %d: %v", rule.ID, err)
+				continue
+			}
+		}
+
+		digest := model.LowStockAlert{Recipients: AlertRecipients(rule, users[rule.AccountID])}
+		sendAlert(&digest, func(email string) error {
+			return ns.SendLowStockNotification(email, alerts)
+		})
+		ids := make([]uint, len(alerts))
+		for i, alert := range alerts {
+			ids[i] = alert.ID
+		}
+		if err := db.Model(&model.LowStockAlert{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+			"recipients": digest.Recipients,
+			"sent_at":    digest.SentAt,
+			"error":      digest.Error,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AlertRecipients returns the email addresses of the users a rule notifies, each once
+func AlertRecipients(rule model.AlertRule, users []model.DirectoryUser) []string {
+	recipients := []string{}
+	for _, user := range users {
+		if user.Email == "" || slices.Contains(recipients, user.Email) {
+			continue
+		}
+		if slices.Contains(rule.UserIDs, user.ID) || slices.Contains(rule.RoleIDs, user.RoleID) ||
+			(user.Department != "" && slices.Contains(rule.Departments, user.Department)) {
+			recipients = append(recipients, user.Email)
+		}
+	}
+	return recipients
+}
+
+// FetchAccountUsers lists the users of the token's account in user-management
+func FetchAccountUsers(token string) ([]model.DirectoryUser, error) {
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/users/", os.Getenv("USER_SERVICE_URL")), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// user-management answers 404 when the account has no users
+	if resp.StatusCode == http.StatusNotFound {
+		return []model.DirectoryUser{}, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch users, status code: %d", resp.StatusCode)
+	}
+
+	var response struct {
+		Users []model.DirectoryUser `json:"users"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&response)
+	return response.Users, err
+}
+
+// AccountToken signs a short-lived token for the account, used to call other services
+// from background work such as Kafka consumers and scheduled jobs where no user's
+// token is at hand
+func AccountToken(accountID uint) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"account_id": accountID,
+		"exp":        time.Now().Add(5 * time.Minute).Unix(),
+	})
+	return token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+}
+
+// matchingAlertRules returns the account's active rules that cover the stock row of the product
+func matchingAlertRules(db *gorm.DB, stock model.Stock, product model.Product) ([]model.AlertRule, error) {
+	var rules []model.AlertRule
+	if err := db.Where("account_id = ? AND is_active = ?", stock.AccountID, true).Order("id").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	parents, err := categoryParents(db, stock.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	var categories []uint
+	for id := &product.CategoryID; id != nil && *id != 0 && !slices.Contains(categories, *id); id = parents[*id] {
+		categories = append(categories, *id)
+	}
+
+	var warehouseID uint
+	if stock.BinID != nil {
+		if err := db.Model(&model.Bin{}).Select("zones.warehouse_id").Joins("JOIN zones ON zones.id = bins.zone_id").
+			Where("bins.id = ?", *stock.BinID).Scan(&warehouseID).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	var matching []model.AlertRule
+	for _, rule := range rules {
+		if len(rule.ProductIDs) > 0 && !slices.Contains(rule.ProductIDs, stock.ProductID) {
+			continue
+		}
+		if len(rule.CategoryIDs) > 0 && !slices.ContainsFunc(categories, func(id uint) bool { return slices.Contains(rule.CategoryIDs, id) }) {
+			continue
+		}
+		if len(rule.WarehouseIDs) > 0 && !slices.Contains(rule.WarehouseIDs, warehouseID) {
+			continue
+		}
+		matching = append(matching, rule)
+	}
+	return matching, nil
+}
+
+// inAlertCooldown reports whether the rule already alerted about the stock row within
+// its cooldown
+func inAlertCooldown(db *gorm.DB, rule model.AlertRule, stockID uint, now time.Time) (bool, error) {
+	if rule.CooldownMinutes == 0 {
+		return false, nil
+	}
+	var count int64
+	err := db.Model(&model.LowStockAlert{}).
+		Where("rule_id = ? AND stock_id = ? AND created_at > ?", rule.ID, stockID, now.Add(-time.Duration(rule.CooldownMinutes)*time.Minute)).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// sendAlert emails every recipient of the alert, recording when it went out or the first
+// failure. One recipient failing does not keep the others from being notified.
+func sendAlert(alert *model.LowStockAlert, send func(email string) error) {
+	if len(alert.Recipients) == 0 {
+		alert.Error = "no recipients found"
+		return
+	}
+
+	sent := false
+	for _, email := range alert.Recipients {
+		if err := send(email); err != nil {
+			log.Printf("Error sending low stock alert to %s: %v", email, err)
+			if alert.Error == "" {
+				alert.Error = err.Error()
+			}
+			continue
+		}
+		sent = true
+	}
+	if sent {
+		now := time.Now()
+		alert.SentAt = &now
+	}
+}
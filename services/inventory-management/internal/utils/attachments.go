@@ -0,0 +1,201 @@
+package utils
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"inventory-management/internal/model"
+	"inventory-management/internal/storage"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrAttachmentTooLarge = errors.New("file exceeds the attachment size limit")
+	ErrAttachmentKind     = errors.New("kind must be image, spec_sheet, sds or document")
+	ErrAttachmentType     = errors.New("file type is not allowed for this kind of attachment")
+	ErrAttachmentQuota    = errors.New("file would exceed the account's attachment quota")
+	ErrInvalidImage       = errors.New("image file is damaged or incomplete")
+)
+
+// officeTypes are the Office Open XML formats accepted as documents, by file extension.
+// Their content is a zip archive, so they are recognised by name once the content is.
+var officeTypes = map[string]string{
+	".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+}
+
+// attachmentTypes are the content types each kind of attachment accepts
+var attachmentTypes = map[model.AttachmentKind][]string{
+	model.AttachmentImage:     {"image/jpeg", "image/png", "image/gif", "image/webp"},
+	model.AttachmentSpecSheet: {"application/pdf"},
+	model.AttachmentSDS:       {"application/pdf"},
+	model.AttachmentDocument: {"application/pdf", "text/plain", "text/csv",
+		officeTypes[".docx"], officeTypes[".xlsx"], officeTypes[".pptx"]},
+}
+
+var safeExtension = regexp.MustCompile(`^\.[a-z0-9]{1,8}$`)
+
+// MaxAttachmentSize is the largest file that can be attached, ATTACHMENT_MAX_BYTES or
+// 10 MiB by default
+func MaxAttachmentSize() int64 {
+	return bytesFromEnv("ATTACHMENT_MAX_BYTES", 10<<20)
+}
+
+// DefaultAttachmentQuota is the storage an account's attachments may use unless its
+// settings say otherwise, ATTACHMENT_QUOTA_BYTES or 1 GiB by default
+func DefaultAttachmentQuota() int64 {
+	return bytesFromEnv("ATTACHMENT_QUOTA_BYTES", 1<<30)
+}
+
+// AttachmentContentType detects the content type of a file from its content and checks
+// that the kind of attachment accepts it. Without a kind, images are attached as images
+// and everything else as documents.
+func AttachmentContentType(kind *model.AttachmentKind, fileName string, data []byte) (string, error) {
+	contentType, _, err := mime.ParseMediaType(http.DetectContentType(data))
+	if err != nil {
+		return "", ErrAttachmentType
+	}
+	ext := strings.ToLower(filepath.Ext(fileName))
+	switch {
+	case contentType == "text/plain" && ext == ".csv":
+		contentType = "text/csv"
+	case contentType == "application/zip" && officeTypes[ext] != "":
+		contentType = officeTypes[ext]
+	}
+
+	if *kind == "" {
+		*kind = model.AttachmentDocument
+		if strings.HasPrefix(contentType, "image/") {
+			*kind = model.AttachmentImage
+		}
+	}
+	allowed, ok := attachmentTypes[*kind]
+	if !ok {
+		return "", ErrAttachmentKind
+	}
+	if !slices.Contains(allowed, contentType) {
+		return "", ErrAttachmentType
+	}
+	return contentType, nil
+}
+
+// SaveAttachment checks a file against the size limit, its kind's content types and the
+// account's quota, stores it with a thumbnail for images and records the attachment
+func SaveAttachment(ctx context.Context, db *gorm.DB, store storage.Storage, attachment *model.Attachment, data []byte) error {
+	if int64(len(data)) > MaxAttachmentSize() {
+		return ErrAttachmentTooLarge
+	}
+	attachment.FileName = filepath.Base(filepath.Clean("/" + strings.ReplaceAll(attachment.FileName, "\\", "/")))
+	contentType, err := AttachmentContentType(&attachment.Kind, attachment.FileName, data)
+	if err != nil {
+		return err
+	}
+	attachment.ContentType = contentType
+	attachment.Size = int64(len(data))
+
+	var thumbnail []byte
+	if attachment.Kind == model.AttachmentImage {
+		if thumbnail, err = MakeThumbnail(data); err != nil {
+			return ErrInvalidImage
+		}
+	}
+
+	usage, err := GetAttachmentUsage(db, attachment.AccountID)
+	if err != nil {
+		return err
+	}
+	if usage.UsedBytes+attachment.Size+int64(len(thumbnail)) > usage.QuotaBytes {
+		return ErrAttachmentQuota
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return err
+	}
+	ext := strings.ToLower(filepath.Ext(attachment.FileName))
+	if !safeExtension.MatchString(ext) {
+		ext = ""
+	}
+	base := fmt.Sprintf("%d/products/%d/%s", attachment.AccountID, attachment.ProductID, hex.EncodeToString(id))
+	attachment.StorageKey = base + ext
+	if err := store.Put(ctx, attachment.StorageKey, data, contentType); err != nil {
+		return err
+	}
+	if thumbnail != nil {
+		attachment.ThumbnailKey = base + "-thumb.jpg"
+		attachment.ThumbnailSize = int64(len(thumbnail))
+		if err := store.Put(ctx, attachment.ThumbnailKey, thumbnail, "image/jpeg"); err != nil {
+			deleteStoredObjects(ctx, store, attachment.StorageKey)
+			return err
+		}
+	}
+
+	if err := db.Create(attachment).Error; err != nil {
+		deleteStoredObjects(ctx, store, attachment.StorageKey, attachment.ThumbnailKey)
+		return err
+	}
+	return nil
+}
+
+// DeleteAttachment removes an attachment's file and thumbnail from storage and then the
+// attachment itself, so a failed deletion can be retried
+func DeleteAttachment(ctx context.Context, db *gorm.DB, store storage.Storage, attachment model.Attachment) error {
+	for _, key := range []string{attachment.StorageKey, attachment.ThumbnailKey} {
+		if key == "" {
+			continue
+		}
+		if err := store.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+	return db.Delete(&attachment).Error
+}
+
+// GetAttachmentUsage adds up the storage an account's attachments and thumbnails use
+func GetAttachmentUsage(db *gorm.DB, accountID uint) (model.AttachmentUsage, error) {
+	var usage model.AttachmentUsage
+	if err := db.Model(&model.Attachment{}).Where("account_id = ?", accountID).
+		Select("COUNT(*) AS count, COALESCE(SUM(size + thumbnail_size), 0) AS used_bytes").
+		Scan(&usage).Error; err != nil {
+		return usage, err
+	}
+
+	settings, err := LoadAccountSettings(db, accountID)
+	if err != nil {
+		return usage, err
+	}
+	usage.QuotaBytes = settings.AttachmentQuotaBytes
+	return usage, nil
+}
+
+// deleteStoredObjects cleans up objects stored for an attachment that could not be saved
+func deleteStoredObjects(ctx context.Context, store storage.Storage, keys ...string) {
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		if err := store.Delete(ctx, key); err != nil {
+			log.Printf("Failed to delete stored object %s: %v", key, err)
+		}
+	}
+}
+
+func bytesFromEnv(name string, fallback int64) int64 {
+	if value, err := strconv.ParseInt(os.Getenv(name), 10, 64); err == nil && value > 0 {
+		return value
+	}
+	return fallback
+}
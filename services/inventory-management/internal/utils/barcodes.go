@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"errors"
+	"inventory-management/internal/model"
+	"strings"
+)
+
+// ErrInvalidBarcode is returned when a code does not fit its symbology or fails its check digit
+var ErrInvalidBarcode = errors.New("barcode is not valid for its symbology")
+
+// gtinLengths are the digit counts of each GTIN symbology
+var gtinLengths = map[model.BarcodeSymbology]int{
+	model.BarcodeEAN13:  13,
+	model.BarcodeUPCA:   12,
+	model.BarcodeGTIN14: 14,
+}
+
+// BarcodeLookupKey validates code against its symbology and returns the key it is looked
+// up by. GTIN codes are zero-padded to 14 digits, so the UPC-A 036000291452 and the
+// EAN-13 0036000291452 resolve to the same product.
+func BarcodeLookupKey(symbology model.BarcodeSymbology, code string) (string, error) {
+	if symbology == model.BarcodeCode128 {
+		if code == "" || len(code) > 48 {
+			return "", ErrInvalidBarcode
+		}
+		for _, r := range code {
+			if r < 32 || r > 126 {
+				return "", ErrInvalidBarcode
+			}
+		}
+		return code, nil
+	}
+
+	length, ok := gtinLengths[symbology]
+	if !ok || len(code) != length || !validGTIN(code) {
+		return "", ErrInvalidBarcode
+	}
+	return strings.Repeat("0", 14-length) + code, nil
+}
+
+// ScanLookupKeys returns the keys a raw scan may be stored under. Scanners do not report
+// the symbology, so a numeric scan that is a valid GTIN is tried both as GTIN-14 and as is.
+func ScanLookupKeys(scan string) []string {
+	keys := []string{scan}
+	if len(scan) >= 12 && len(scan) <= 14 && validGTIN(scan) {
+		if key := strings.Repeat("0", 14-len(scan)) + scan; key != scan {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// validGTIN checks that code is all digits and ends in the GS1 mod-10 check digit
+func validGTIN(code string) bool {
+	sum := 0
+	for i := len(code) - 1; i >= 0; i-- {
+		digit := int(code[i] - '0')
+		if digit < 0 || digit > 9 {
+			return false
+		}
+		if i == len(code)-1 {
+			continue
+		}
+		// Weights alternate 3, 1, 3, ... starting next to the check digit
+		if (len(code)-1-i)%2 == 1 {
+			digit *= 3
+		}
+		sum += digit
+	}
+	return len(code) > 1 && (10-sum%10)%10 == int(code[len(code)-1]-'0')
+}
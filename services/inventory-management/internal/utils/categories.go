@@ -0,0 +1,110 @@
+package utils
+
+import (
+	"errors"
+	"inventory-management/internal/model"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrParentCategoryNotFound is returned when a parent category does not exist in the account
+	ErrParentCategoryNotFound = errors.New("parent category not found")
+	// ErrCategoryCycle is returned when a category would become its own ancestor
+	ErrCategoryCycle = errors.New("category cannot be moved below itself or one of its subcategories")
+)
+
+// ValidateCategoryParent checks that parentID exists in the account and that making it
+// the parent of categoryID does not create a cycle. A nil parent makes a root category.
+func ValidateCategoryParent(db *gorm.DB, accountID, categoryID uint, parentID *uint) error {
+	if parentID == nil {
+		return nil
+	}
+
+	parents, err := categoryParents(db, accountID)
+	if err != nil {
+		return err
+	}
+	if _, ok := parents[*parentID]; !ok {
+		return ErrParentCategoryNotFound
+	}
+
+	// Walk up from the new parent; reaching the category itself means it would be its own ancestor
+	for id, steps := parentID, 0; id != nil && steps <= len(parents); steps++ {
+		if categoryID != 0 && *id == categoryID {
+			return ErrCategoryCycle
+		}
+		id = parents[*id]
+	}
+	return nil
+}
+
+// CategoryDescendantIDs returns rootID and the IDs of all categories below it
+func CategoryDescendantIDs(db *gorm.DB, accountID, rootID uint) ([]uint, error) {
+	parents, err := categoryParents(db, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	children := make(map[uint][]uint)
+	for id, parentID := range parents {
+		if parentID != nil {
+			children[*parentID] = append(children[*parentID], id)
+		}
+	}
+
+	ids := []uint{rootID}
+	for i := 0; i < len(ids); i++ {
+		ids = append(ids, children[ids[i]]...)
+	}
+	return ids, nil
+}
+
+// BuildCategoryTree nests categories under their parents. Categories whose parent is not
+// in the list, e.g. because it was deleted, are returned as roots.
+func BuildCategoryTree(categories []model.Category) []model.CategoryNode {
+	present := make(map[uint]bool, len(categories))
+	children := make(map[uint][]model.Category)
+	for _, category := range categories {
+		present[category.ID] = true
+	}
+
+	var roots []model.Category
+	for _, category := range categories {
+		if category.ParentID != nil && present[*category.ParentID] && *category.ParentID != category.ID {
+			children[*category.ParentID] = append(children[*category.ParentID], category)
+		} else {
+			roots = append(roots, category)
+		}
+	}
+
+	var build func([]model.Category) []model.CategoryNode
+	build = func(categories []model.Category) []model.CategoryNode {
+		nodes := make([]model.CategoryNode, 0, len(categories))
+		for _, category := range categories {
+			nodes = append(nodes, model.CategoryNode{
+				ID:          category.ID,
+				Name:        category.Name,
+				Description: category.Description,
+				ParentID:    category.ParentID,
+				Children:    build(children[category.ID]),
+			})
+		}
+		return nodes
+	}
+	return build(roots)
+}
+
+// categoryParents maps every category of the account to its parent ID
+func categoryParents(db *gorm.DB, accountID uint) (map[uint]*uint, error) {
+	var categories []model.Category
+	if err := db.Select("id", "parent_id").Where("account_id = ?", accountID).Find(&categories).Error; err != nil {
+		return nil, err
+	}
+
+	parents := make(map[uint]*uint, len(categories))
+	for _, category := range categories {
+		parents[category.ID] = category.ParentID
+	}
+	return parents, nil
+}
@@ -0,0 +1,204 @@
+package utils
+
+import (
+	"inventory-management/internal/model"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Products making up the first 80% of the value sold are class A and the next 15% class
+// B; the rest, including products that sold nothing, are class C
+const (
+	abcShareA = 0.80
+	abcShareB = 0.95
+)
+
+// Products whose weekly demand varies by up to half its average are class X and up to
+// once its average class Y; more erratic products, and those that sold nothing, are Z
+const (
+	xyzVariationX = 0.5
+	xyzVariationY = 1.0
+)
+
+// ClassifyProducts ranks every product of the account by the value of the orders shipped
+// in the account's classification window (ABC) and by how much its weekly demand varied
+// over the window (XYZ), and stores the classes on the products
+func ClassifyProducts(db *gorm.DB, accountID uint, now time.Time) (model.ClassificationSummary, error) {
+	settings, err := LoadAccountSettings(db, accountID)
+	if err != nil {
+		return model.ClassificationSummary{}, err
+	}
+	from := now.AddDate(0, 0, -settings.ClassificationWindowDays)
+	summary := model.ClassificationSummary{From: from, To: now, ClassifiedAt: &now, Classes: []model.ClassCount{}}
+
+	var products []model.Product
+	if err := db.Select("id", "price").Where("account_id = ?", accountID).Find(&products).Error; err != nil {
+		return summary, err
+	}
+
+	// Demand is what was shipped for orders, bucketed by week of the window
+	weeks := (settings.ClassificationWindowDays + 6) / 7
+	demand := map[uint][]float64{}
+	var shipments []model.StockMovement
+	if err := db.Select("product_id", "delta", "created_at").
+		Where("account_id = ? AND reason = ? AND order_id IS NOT NULL AND created_at > ? AND created_at <= ?", accountID, model.MovementShipment, from, now).
+		Find(&shipments).Error; err != nil {
+		return summary, err
+	}
+	for _, shipment := range shipments {
+		if demand[shipment.ProductID] == nil {
+			demand[shipment.ProductID] = make([]float64, weeks)
+		}
+		week := min(int(shipment.CreatedAt.Sub(from)/(7*24*time.Hour)), weeks-1)
+		demand[shipment.ProductID][week] -= float64(shipment.Delta)
+	}
+
+	type productClass struct {
+		ID    uint
+		Value float64
+		ABC   string
+		XYZ   string
+	}
+	classes := make([]productClass, len(products))
+	total := 0.0
+	for i, product := range products {
+		sold := 0.0
+		for _, quantity := range demand[product.ID] {
+			sold += quantity
+		}
+		classes[i] = productClass{ID: product.ID, Value: sold * product.Price, XYZ: xyzClass(demand[product.ID])}
+		total += classes[i].Value
+	}
+
+	sort.SliceStable(classes, func(i, j int) bool {
+		return classes[i].Value > classes[j].Value || (classes[i].Value == classes[j].Value && classes[i].ID < classes[j].ID)
+	})
+	cumulative := 0.0
+	for i := range classes {
+		// A product belongs to the class its value starts in, so the one crossing 80% is still A
+		share := 1.0
+		if total > 0 {
+			share = cumulative / total
+		}
+		switch {
+		case classes[i].Value > 0 && share < abcShareA:
+			classes[i].ABC = "A"
+		case classes[i].Value > 0 && share < abcShareB:
+			classes[i].ABC = "B"
+		default:
+			classes[i].ABC = "C"
+		}
+		cumulative += classes[i].Value
+	}
+
+	groups := map[[2]string][]uint{}
+	counts := map[[2]string]*model.ClassCount{}
+	for _, class := range classes {
+		key := [2]string{class.ABC, class.XYZ}
+		groups[key] = append(groups[key], class.ID)
+		if counts[key] == nil {
+			counts[key] = &model.ClassCount{ABCClass: class.ABC, XYZClass: class.XYZ}
+		}
+		counts[key].Products++
+		counts[key].Value += class.Value
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for key, ids := range groups {
+			for start := 0; start < len(ids); start += exportBatchSize {
+				batch := ids[start:min(start+exportBatchSize, len(ids))]
+				if err := tx.Model(&model.Product{}).Where("id IN ?", batch).UpdateColumns(map[string]interface{}{
+					"abc_class":     key[0],
+					"xyz_class":     key[1],
+					"classified_at": now,
+				}).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return summary, err
+	}
+
+	for _, count := range counts {
+		summary.Classes = append(summary.Classes, *count)
+	}
+	sortClassCounts(summary.Classes)
+	return summary, nil
+}
+
+// ClassifyAllProducts reclassifies the products of every account
+func ClassifyAllProducts(db *gorm.DB, now time.Time) error {
+	var accountIDs []uint
+	if err := db.Model(&model.Product{}).Distinct("account_id").Pluck("account_id", &accountIDs).Error; err != nil {
+		return err
+	}
+
+	var firstErr error
+	for _, accountID := range accountIDs {
+		if _, err := ClassifyProducts(db, accountID, now); err != nil {
+			log.Printf("Error classifying products of account %d: %v", accountID, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// ClassificationCounts counts the account's products by their stored ABC and XYZ class
+func ClassificationCounts(db *gorm.DB, accountID uint) ([]model.ClassCount, *time.Time, error) {
+	counts := []model.ClassCount{}
+	if err := db.Model(&model.Product{}).Where("account_id = ?", accountID).
+		Select("abc_class, xyz_class, COUNT(*) AS products").Group("abc_class, xyz_class").
+		Scan(&counts).Error; err != nil {
+		return nil, nil, err
+	}
+	sortClassCounts(counts)
+
+	var latest model.Product
+	err := db.Select("classified_at").Where("account_id = ? AND classified_at IS NOT NULL", accountID).
+		Order("classified_at DESC").Limit(1).Find(&latest).Error
+	return counts, latest.ClassifiedAt, err
+}
+
+// xyzClass classifies weekly demand by its coefficient of variation
+func xyzClass(weekly []float64) string {
+	if len(weekly) == 0 {
+		return "Z"
+	}
+	mean := 0.0
+	for _, quantity := range weekly {
+		mean += quantity
+	}
+	mean /= float64(len(weekly))
+	if mean == 0 {
+		return "Z"
+	}
+
+	variance := 0.0
+	for _, quantity := range weekly {
+		variance += (quantity - mean) * (quantity - mean)
+	}
+	variation := math.Sqrt(variance/float64(len(weekly))) / mean
+	switch {
+	case variation <= xyzVariationX:
+		return "X"
+	case variation <= xyzVariationY:
+		return "Y"
+	default:
+		return "Z"
+	}
+}
+
+func sortClassCounts(counts []model.ClassCount) {
+	sort.Slice(counts, func(i, j int) bool {
+		return counts[i].ABCClass < counts[j].ABCClass || (counts[i].ABCClass == counts[j].ABCClass && counts[i].XYZClass < counts[j].XYZClass)
+	})
+}
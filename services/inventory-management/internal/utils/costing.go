@@ -0,0 +1,178 @@
+package utils
+
+import (
+	"errors"
+	"inventory-management/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AccountCostingMethod returns the costing method an account configured, FIFO by default
+func AccountCostingMethod(db *gorm.DB, accountID uint) (model.CostingMethod, error) {
+	settings, err := LoadAccountSettings(db, accountID)
+	return settings.CostingMethod, err
+}
+
+// CurrentUnitCost returns the cost of one unit of a product in stock: the value of its
+// remaining cost layers over their quantity, or the cost of the last layer when nothing
+// is left in stock
+func CurrentUnitCost(db *gorm.DB, productID uint) (float64, error) {
+	quantity, value, err := costPool(db, productID)
+	if err != nil {
+		return 0, err
+	}
+	if quantity > 0 {
+		return value / float64(quantity), nil
+	}
+
+	var last model.CostLayer
+	err = db.Where("product_id = ?", productID).Order("id DESC").First(&last).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
+	}
+	return last.UnitCost, err
+}
+
+// RecordOpeningCostLayers gives products that are in stock but have no cost history an
+// opening layer at zero cost, so that their later shipments consume a layer. Finance can
+// correct the opening cost by adjusting the stock at the right cost.
+func RecordOpeningCostLayers(db *gorm.DB) error {
+	var stocks []model.Stock
+	if err := db.Where("quantity > 0 AND product_id NOT IN (?)", db.Model(&model.CostLayer{}).Select("product_id")).
+		Find(&stocks).Error; err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, stock := range stocks {
+			zero := 0.0
+			if _, err := recordCost(tx, stock, int(stock.Quantity), model.StockMovement{Reason: model.MovementOpeningBalance, UnitCost: &zero}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// recordCost adds a cost layer for an increase of stock and consumes layers for a
+// decrease, returning the value added or consumed. Transfers move stock between
+// locations without changing its cost.
+func recordCost(tx *gorm.DB, stock model.Stock, delta int, movement model.StockMovement) (float64, error) {
+	if delta == 0 || movement.Reason == model.MovementTransfer {
+		return 0, nil
+	}
+
+	if delta > 0 {
+		unitCost := 0.0
+		if movement.UnitCost != nil {
+			unitCost = *movement.UnitCost
+		} else {
+			var err error
+			if unitCost, err = CurrentUnitCost(tx, stock.ProductID); err != nil {
+				return 0, err
+			}
+		}
+		return float64(delta) * unitCost, tx.Create(&model.CostLayer{
+			ProductID:  stock.ProductID,
+			StockID:    stock.ID,
+			MovementID: movement.ID,
+			Reason:     movement.Reason,
+			Quantity:   uint(delta),
+			Remaining:  uint(delta),
+			UnitCost:   unitCost,
+			AccountID:  stock.AccountID,
+		}).Error
+	}
+
+	return consumeCost(tx, stock, uint(-delta), movement)
+}
+
+// consumeCost takes quantity out of the product's cost layers, oldest first, and returns
+// the value consumed. Under FIFO each layer is consumed at its own cost; under weighted
+// average every unit is consumed at the average cost of the remaining layers.
+func consumeCost(tx *gorm.DB, stock model.Stock, quantity uint, movement model.StockMovement) (float64, error) {
+	method, err := AccountCostingMethod(tx, stock.AccountID)
+	if err != nil {
+		return 0, err
+	}
+	averageCost, err := CurrentUnitCost(tx, stock.ProductID)
+	if err != nil {
+		return 0, err
+	}
+
+	consumption := model.CostConsumption{
+		ProductID:  stock.ProductID,
+		StockID:    stock.ID,
+		MovementID: movement.ID,
+		Reason:     movement.Reason,
+		OrderID:    movement.OrderID,
+		ShipmentID: movement.ShipmentID,
+		AccountID:  stock.AccountID,
+	}
+
+	var layers []model.CostLayer
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("product_id = ? AND remaining > 0", stock.ProductID).Order("created_at, id").Find(&layers).Error; err != nil {
+		return 0, err
+	}
+
+	consumed := 0.0
+	for _, layer := range layers {
+		if quantity == 0 {
+			break
+		}
+		taken := min(layer.Remaining, quantity)
+		if err := tx.Model(&layer).Update("remaining", layer.Remaining-taken).Error; err != nil {
+			return 0, err
+		}
+
+		unitCost := layer.UnitCost
+		if method == model.CostingAverage {
+			unitCost = averageCost
+		}
+		record := consumption
+		record.LayerID = &layer.ID
+		record.Quantity = taken
+		record.UnitCost = unitCost
+		record.Amount = float64(taken) * unitCost
+		if err := tx.Create(&record).Error; err != nil {
+			return 0, err
+		}
+		consumed += record.Amount
+		quantity -= taken
+	}
+
+	// Stock that predates cost tracking has no layer; it leaves at the last known cost
+	if quantity > 0 {
+		consumption.Quantity = quantity
+		consumption.UnitCost = averageCost
+		consumption.Amount = float64(quantity) * averageCost
+		consumed += consumption.Amount
+		if err := tx.Create(&consumption).Error; err != nil {
+			return 0, err
+		}
+	}
+	return consumed, nil
+}
+
+// costPool returns the quantity and value of a product's remaining cost layers. The value
+// is what entered minus what was consumed, which holds for both costing methods.
+func costPool(db *gorm.DB, productID uint) (int, float64, error) {
+	var layers struct {
+		Remaining int
+		Value     float64
+	}
+	if err := db.Model(&model.CostLayer{}).Where("product_id = ?", productID).
+		Select("COALESCE(SUM(remaining), 0) AS remaining, COALESCE(SUM(quantity * unit_cost), 0) AS value").
+		Scan(&layers).Error; err != nil {
+		return 0, 0, err
+	}
+
+	var consumed float64
+	if err := db.Model(&model.CostConsumption{}).Where("product_id = ? AND layer_id IS NOT NULL", productID).
+		Select("COALESCE(SUM(amount), 0)").Scan(&consumed).Error; err != nil {
+		return 0, 0, err
+	}
+	return layers.Remaining, layers.Value - consumed, nil
+}
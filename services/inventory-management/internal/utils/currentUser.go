@@ -0,0 +1,14 @@
+package utils
+
+import "github.com/gin-gonic/gin"
+
+// CurrentUserID returns the ID of the authenticated user, or nil when the token
+// carried no subject.
+func CurrentUserID(c *gin.Context) *uint {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		return nil
+	}
+	id := userID.(uint)
+	return &id
+}
@@ -0,0 +1,191 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"inventory-management/internal/model"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrNothingToCount is returned when a cycle-count plan selects no stock rows
+	ErrNothingToCount = errors.New("no stock matches the cycle count plan")
+	// ErrCountBelowReserved is returned when a count would leave less stock than is reserved or on hold
+	ErrCountBelowReserved = errors.New("counted quantity is lower than the reserved and held quantity")
+	// ErrStaleCount is returned when the stock changed in a way the count can no longer be applied to
+	ErrStaleCount = errors.New("stock changed since it was counted, recount required")
+)
+
+// GenerateCycleCountTasks creates a pending count task for every stock row selected by
+// the plan. Stock rows that already have an open task in another plan are skipped so
+// the same shelf is not counted twice at once.
+func GenerateCycleCountTasks(tx *gorm.DB, plan model.CycleCountPlan) ([]model.CycleCountTask, error) {
+	openTasks := tx.Model(&model.CycleCountTask{}).Select("stock_id").
+		Where("account_id = ? AND status IN ?", plan.AccountID,
+			[]model.CycleCountTaskStatus{model.CycleCountTaskPending, model.CycleCountTaskPendingApproval})
+	query := tx.Where("account_id = ? AND id NOT IN (?)", plan.AccountID, openTasks)
+
+	switch plan.Type {
+	case model.CycleCountByBin:
+		query = query.Where("bin_id IN ?", plan.BinIDs).Order("bin_id, id")
+	case model.CycleCountByABCClass:
+		query = query.Where("product_id IN (?)", tx.Model(&model.Product{}).Select("id").
+			Where("account_id = ? AND abc_class = ?", plan.AccountID, plan.ABCClass)).Order("bin_id, id")
+	case model.CycleCountRandom:
+		query = query.Order("RANDOM()").Limit(plan.SampleSize)
+	default:
+		return nil, fmt.Errorf("unknown cycle count plan type %q", plan.Type)
+	}
+
+	var stocks []model.Stock
+	if err := query.Find(&stocks).Error; err != nil {
+		return nil, err
+	}
+	if len(stocks) == 0 {
+		return nil, ErrNothingToCount
+	}
+
+	tasks := make([]model.CycleCountTask, 0, len(stocks))
+	for _, stock := range stocks {
+		tasks = append(tasks, model.CycleCountTask{
+			PlanID:    plan.ID,
+			StockID:   stock.ID,
+			ProductID: stock.ProductID,
+			BinID:     stock.BinID,
+			Status:    model.CycleCountTaskPending,
+			AccountID: plan.AccountID,
+		})
+	}
+	if err := tx.Create(&tasks).Error; err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// WithinTolerance reports whether a variance is small enough to be applied without approval
+func WithinTolerance(systemQuantity uint, variance int, tolerancePercent float64) bool {
+	if variance == 0 {
+		return true
+	}
+	if systemQuantity == 0 {
+		return false
+	}
+	return math.Abs(float64(variance))/float64(systemQuantity)*100 <= tolerancePercent
+}
+
+// SubmitCycleCount records a count against the current on-hand quantity. Variances within
+// the plan's tolerance are applied straight away; larger ones wait for a manager.
+func SubmitCycleCount(tx *gorm.DB, task *model.CycleCountTask, plan model.CycleCountPlan, counted uint, serials []string, userID *uint) error {
+	var stock model.Stock
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&stock, task.StockID).Error; err != nil {
+		return err
+	}
+
+	var product model.Product
+	if err := tx.First(&product, stock.ProductID).Error; err != nil {
+		return err
+	}
+	if product.IsSerialized && len(serials) != int(counted) {
+		return ErrSerialCountMismatch
+	}
+
+	now := time.Now()
+	task.SystemQuantity = stock.Quantity
+	task.CountedQuantity = &counted
+	task.SerialNumbers = serials
+	task.Variance = int(counted) - int(stock.Quantity)
+	task.CountedBy = userID
+	task.CountedAt = &now
+	task.Status = model.CycleCountTaskPendingApproval
+
+	if WithinTolerance(task.SystemQuantity, task.Variance, plan.TolerancePercent) {
+		if err := ApplyCycleCount(tx, task, userID); err != nil {
+			return err
+		}
+		task.Status = model.CycleCountTaskCompleted
+	}
+
+	if err := tx.Save(task).Error; err != nil {
+		return err
+	}
+	return completePlanIfDone(tx, plan.ID)
+}
+
+// ReviewCycleCount approves or rejects a count that was beyond tolerance. Only approved
+// counts adjust the stock.
+func ReviewCycleCount(tx *gorm.DB, task *model.CycleCountTask, approve bool, note string, userID *uint) error {
+	if approve {
+		if err := ApplyCycleCount(tx, task, userID); err != nil {
+			return err
+		}
+		task.Status = model.CycleCountTaskApproved
+	} else {
+		task.Status = model.CycleCountTaskRejected
+	}
+
+	now := time.Now()
+	task.ReviewedBy = userID
+	task.ReviewedAt = &now
+	if note != "" {
+		task.Note = note
+	}
+	if err := tx.Save(task).Error; err != nil {
+		return err
+	}
+	return completePlanIfDone(tx, task.PlanID)
+}
+
+// ApplyCycleCount adjusts the stock row by the task's variance and records it in the
+// movement ledger. Serialized stock also takes over the counted serial numbers.
+func ApplyCycleCount(tx *gorm.DB, task *model.CycleCountTask, userID *uint) error {
+	var stock model.Stock
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&stock, task.StockID).Error; err != nil {
+		return err
+	}
+
+	quantity := int(stock.Quantity) + task.Variance
+	if quantity < int(stock.ReservedQuantity+stock.Held()) {
+		return ErrCountBelowReserved
+	}
+	stock.Quantity = uint(quantity)
+
+	var product model.Product
+	if err := tx.First(&product, stock.ProductID).Error; err != nil {
+		return err
+	}
+	if product.IsSerialized && len(task.SerialNumbers) != quantity {
+		return ErrStaleCount
+	}
+
+	if err := tx.Model(&stock).Update("quantity", stock.Quantity).Error; err != nil {
+		return err
+	}
+	if product.IsSerialized {
+		if err := SyncStockSerials(tx, stock, task.SerialNumbers, userID); err != nil {
+			return err
+		}
+	}
+	return RecordStockMovement(tx, stock, task.Variance, model.StockMovement{
+		Reason: model.MovementCycleCount,
+		UserID: userID,
+		Note:   fmt.Sprintf("Cycle count task %d", task.ID),
+	})
+}
+
+// completePlanIfDone closes a plan once none of its tasks is waiting for a count or a review
+func completePlanIfDone(tx *gorm.DB, planID uint) error {
+	var open int64
+	if err := tx.Model(&model.CycleCountTask{}).Where("plan_id = ? AND status IN ?", planID,
+		[]model.CycleCountTaskStatus{model.CycleCountTaskPending, model.CycleCountTaskPendingApproval}).
+		Count(&open).Error; err != nil {
+		return err
+	}
+	if open > 0 {
+		return nil
+	}
+	return tx.Model(&model.CycleCountPlan{}).Where("id = ?", planID).Update("status", model.CycleCountPlanCompleted).Error
+}
@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"fmt"
+	"inventory-management/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// exportBatchSize is the number of records loaded at a time while streaming an export
+const exportBatchSize = 500
+
+// ExportRecords streams the account's records of entity to emit in ImportColumns order,
+// loading them in batches so large catalogs are never held in memory at once
+func ExportRecords(db *gorm.DB, entity string, accountID uint, emit func([]interface{}) error) error {
+	query := db.Where("account_id = ?", accountID).Order("id")
+
+	switch entity {
+	case "categories":
+		// Parents are exported by name, which stays valid when the file is imported elsewhere
+		var names []model.Category
+		if err := db.Select("id", "name").Where("account_id = ?", accountID).Find(&names).Error; err != nil {
+			return err
+		}
+		parentNames := make(map[uint]string, len(names))
+		for _, category := range names {
+			parentNames[category.ID] = category.Name
+		}
+
+		var categories []model.Category
+		return query.FindInBatches(&categories, exportBatchSize, func(tx *gorm.DB, batch int) error {
+			for _, category := range categories {
+				parent := ""
+				if category.ParentID != nil {
+					parent = parentNames[*category.ParentID]
+				}
+				if err := emit([]interface{}{category.Name, category.Description, parent}); err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+	case "suppliers":
+		var suppliers []model.Supplier
+		return query.FindInBatches(&suppliers, exportBatchSize, func(tx *gorm.DB, batch int) error {
+			for _, supplier := range suppliers {
+				if err := emit([]interface{}{supplier.Name, supplier.Description, supplier.Email, supplier.Contact, supplier.LeadTimeDays}); err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+	case "products":
+		var products []model.Product
+		return query.Preload("Category").Preload("Supplier").FindInBatches(&products, exportBatchSize, func(tx *gorm.DB, batch int) error {
+			for _, product := range products {
+				if err := emit([]interface{}{
+					product.Name, product.SKU, product.Description, product.Price,
+					product.Category.Name, product.CategoryID, product.Supplier.Name, product.SupplierID,
+					product.BaseUnit, product.IsSerialized, product.ABCClass, product.ReorderPoint, product.ReorderQuantity,
+				}); err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+	case "stocks":
+		var stocks []model.Stock
+		return query.Preload("Product").FindInBatches(&stocks, exportBatchSize, func(tx *gorm.DB, batch int) error {
+			for _, stock := range stocks {
+				if err := emit([]interface{}{
+					stock.Product.SKU, stock.ProductID, stock.Location, stock.BinID, stock.LotNumber,
+					dateValue(stock.ManufacturedAt), dateValue(stock.ExpiresAt), stock.Quantity, stock.LowStockThreshold,
+				}); err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+	default:
+		return fmt.Errorf("unknown export entity %q", entity)
+	}
+}
+
+// dateValue formats an optional timestamp for export, leaving missing dates empty
+func dateValue(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.Format(time.RFC3339)
+}
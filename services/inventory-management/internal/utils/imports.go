@@ -0,0 +1,442 @@
+package utils
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"inventory-management/internal/model"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ImportColumns lists the columns of every entity that can be imported and exported.
+// Exports write exactly these columns, so an export can be imported into another account.
+var ImportColumns = map[string][]string{
+	"categories": {"name", "description", "parent"},
+	"suppliers":  {"name", "description", "email", "contact", "lead_time_days"},
+	"products":   {"name", "sku", "description", "price", "category", "category_id", "supplier", "supplier_id", "base_unit", "is_serialized", "abc_class", "reorder_point", "reorder_quantity"},
+	"stocks":     {"sku", "product_id", "location", "bin_id", "lot_number", "manufactured_at", "expires_at", "quantity", "low_stock_threshold"},
+}
+
+// ImportRow is one record of an import file keyed by column name. Empty values count as
+// not given: they keep the current value of an updated record.
+type ImportRow map[string]string
+
+// RowReader streams the records of an import file
+type RowReader interface {
+	// Next returns the next record, or io.EOF after the last one
+	Next() (ImportRow, error)
+}
+
+// NewRowReader returns a reader for a CSV file with a header row or a JSON array of objects
+func NewRowReader(r io.Reader, format string) (RowReader, error) {
+	switch format {
+	case "csv":
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = -1
+		reader.TrimLeadingSpace = true
+		header, err := reader.Read()
+		if err != nil {
+			return nil, fmt.Errorf("reading CSV header: %w", err)
+		}
+		for i := range header {
+			header[i] = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(header[i], "\ufeff")))
+		}
+		return &csvRowReader{reader: reader, header: header}, nil
+	case "json":
+		decoder := json.NewDecoder(r)
+		if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+			return nil, errors.New("JSON import must be an array of objects")
+		}
+		return &jsonRowReader{decoder: decoder}, nil
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+type csvRowReader struct {
+	reader *csv.Reader
+	header []string
+}
+
+func (r *csvRowReader) Next() (ImportRow, error) {
+	record, err := r.reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	row := make(ImportRow, len(record))
+	for i, value := range record {
+		if i < len(r.header) {
+			row[r.header[i]] = strings.TrimSpace(value)
+		}
+	}
+	return row, nil
+}
+
+type jsonRowReader struct {
+	decoder *json.Decoder
+}
+
+func (r *jsonRowReader) Next() (ImportRow, error) {
+	if !r.decoder.More() {
+		return nil, io.EOF
+	}
+	var object map[string]json.RawMessage
+	if err := r.decoder.Decode(&object); err != nil {
+		return nil, err
+	}
+
+	row := make(ImportRow, len(object))
+	for key, raw := range object {
+		var text string
+		switch {
+		case string(raw) == "null":
+		case json.Unmarshal(raw, &text) == nil:
+		default:
+			text = string(raw)
+		}
+		row[strings.ToLower(key)] = strings.TrimSpace(text)
+	}
+	return row, nil
+}
+
+// ImportRecord validates one row and creates or updates the matching record of the
+// account. Categories and suppliers are matched by name, products by SKU or, without a
+// SKU, by name, and stock rows by product, location and lot. It returns whether a record
+// was created and the key the row was matched by.
+func ImportRecord(tx *gorm.DB, entity string, accountID uint, userID *uint, row ImportRow) (bool, string, error) {
+	switch entity {
+	case "categories":
+		return importCategory(tx, accountID, row)
+	case "suppliers":
+		return importSupplier(tx, accountID, row)
+	case "products":
+		return importProduct(tx, accountID, row)
+	case "stocks":
+		return importStock(tx, accountID, userID, row)
+	default:
+		return false, "", fmt.Errorf("unknown import entity %q", entity)
+	}
+}
+
+func importCategory(tx *gorm.DB, accountID uint, row ImportRow) (bool, string, error) {
+	if row["name"] == "" {
+		return false, "", errors.New("name is required")
+	}
+
+	var category model.Category
+	found, err := findExisting(tx.Where("name = ? AND account_id = ?", row["name"], accountID), &category)
+	if err != nil {
+		return false, row["name"], err
+	}
+
+	category.Name = row["name"]
+	setString(row, "description", &category.Description)
+	parentID, err := resolveReference(tx, &model.Category{}, accountID, row["parent"], "")
+	if err != nil {
+		return false, category.Name, fmt.Errorf("parent: %w", err)
+	}
+	if parentID != nil {
+		if err := ValidateCategoryParent(tx, accountID, category.ID, parentID); err != nil {
+			return false, category.Name, err
+		}
+		category.ParentID = parentID
+	}
+	category.AccountID = accountID
+	return !found, category.Name, tx.Save(&category).Error
+}
+
+func importSupplier(tx *gorm.DB, accountID uint, row ImportRow) (bool, string, error) {
+	if row["name"] == "" {
+		return false, "", errors.New("name is required")
+	}
+
+	var supplier model.Supplier
+	found, err := findExisting(tx.Where("name = ? AND account_id = ?", row["name"], accountID), &supplier)
+	if err != nil {
+		return false, row["name"], err
+	}
+
+	supplier.Name = row["name"]
+	setString(row, "description", &supplier.Description)
+	setString(row, "email", &supplier.Email)
+	setString(row, "contact", &supplier.Contact)
+	if err := setUint(row, "lead_time_days", &supplier.LeadTimeDays); err != nil {
+		return false, supplier.Name, err
+	}
+	supplier.AccountID = accountID
+	return !found, supplier.Name, tx.Save(&supplier).Error
+}
+
+func importProduct(tx *gorm.DB, accountID uint, row ImportRow) (bool, string, error) {
+	key, query := row["sku"], tx.Where("sku = ? AND account_id = ?", row["sku"], accountID)
+	if key == "" {
+		key, query = row["name"], tx.Where("name = ? AND account_id = ?", row["name"], accountID)
+	}
+	if key == "" {
+		return false, "", errors.New("sku or name is required")
+	}
+
+	var product model.Product
+	found, err := findExisting(query, &product)
+	if err != nil {
+		return false, key, err
+	}
+	if !found {
+		if row["name"] == "" {
+			return false, key, errors.New("name is required for new products")
+		}
+		if row["sku"] != "" {
+			var deleted int64
+			tx.Unscoped().Model(&model.Product{}).Where("sku = ? AND account_id = ?", row["sku"], accountID).Count(&deleted)
+			if deleted > 0 {
+				return false, key, errors.New("SKU belongs to a deleted product")
+			}
+		}
+	}
+
+	setString(row, "name", &product.Name)
+	setString(row, "sku", &product.SKU)
+	setString(row, "description", &product.Description)
+	setString(row, "base_unit", &product.BaseUnit)
+	setString(row, "abc_class", &product.ABCClass)
+	if err := setFloat(row, "price", &product.Price); err != nil {
+		return false, key, err
+	}
+	if err := setBool(row, "is_serialized", &product.IsSerialized); err != nil {
+		return false, key, err
+	}
+	if err := setUint(row, "reorder_point", &product.ReorderPoint); err != nil {
+		return false, key, err
+	}
+	if err := setUint(row, "reorder_quantity", &product.ReorderQuantity); err != nil {
+		return false, key, err
+	}
+
+	categoryID, err := resolveReference(tx, &model.Category{}, accountID, row["category"], row["category_id"])
+	if err != nil {
+		return false, key, fmt.Errorf("category: %w", err)
+	}
+	if categoryID != nil {
+		product.CategoryID = *categoryID
+	}
+	supplierID, err := resolveReference(tx, &model.Supplier{}, accountID, row["supplier"], row["supplier_id"])
+	if err != nil {
+		return false, key, fmt.Errorf("supplier: %w", err)
+	}
+	if supplierID != nil {
+		product.SupplierID = *supplierID
+	}
+
+	product.AccountID = accountID
+	return !found, key, tx.Omit("Category", "Supplier", "PreferredSupplier", "Stocks", "Units", "Barcodes").Save(&product).Error
+}
+
+func importStock(tx *gorm.DB, accountID uint, userID *uint, row ImportRow) (bool, string, error) {
+	var product model.Product
+	var err error
+	switch {
+	case row["sku"] != "":
+		err = tx.Where("sku = ? AND account_id = ?", row["sku"], accountID).First(&product).Error
+	case row["product_id"] != "":
+		err = tx.Where("id = ? AND account_id = ?", row["product_id"], accountID).First(&product).Error
+	default:
+		return false, "", errors.New("sku or product_id is required")
+	}
+	key := row["sku"]
+	if key == "" {
+		key = row["product_id"]
+	}
+	if err != nil {
+		return false, key, errors.New("product not found")
+	}
+	if product.IsSerialized {
+		return false, key, errors.New("serialized stock must be received with its serial numbers")
+	}
+
+	stock := model.Stock{ProductID: product.ID, AccountID: accountID}
+	if err := setOptionalUint(row, "bin_id", &stock.BinID); err != nil {
+		return false, key, err
+	}
+	stock.Location = row["location"]
+	if stock.BinID != nil {
+		var bin model.Bin
+		if err := tx.Preload("Zone.Warehouse").Where("id = ? AND account_id = ?", *stock.BinID, accountID).First(&bin).Error; err != nil {
+			return false, key, errors.New("bin not found")
+		}
+		stock.Location = bin.Label()
+	}
+	stock.LotNumber = row["lot_number"]
+
+	query := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("product_id = ? AND location = ? AND lot_number = ? AND account_id = ?", product.ID, stock.Location, stock.LotNumber, accountID)
+	found, err := findExisting(query, &stock)
+	if err != nil {
+		return false, key, err
+	}
+
+	if err := setTime(row, "manufactured_at", &stock.ManufacturedAt); err != nil {
+		return false, key, err
+	}
+	if err := setTime(row, "expires_at", &stock.ExpiresAt); err != nil {
+		return false, key, err
+	}
+	if stock.ManufacturedAt != nil && stock.ExpiresAt != nil && !stock.ExpiresAt.After(*stock.ManufacturedAt) {
+		return false, key, errors.New("expiry date must be after manufacture date")
+	}
+
+	previous := stock.Quantity
+	if err := setUint(row, "quantity", &stock.Quantity); err != nil {
+		return false, key, err
+	}
+	if stock.Quantity < stock.ReservedQuantity+stock.Held() {
+		return false, key, ErrInsufficientStock
+	}
+	if found && stock.Quantity != previous {
+		// Changes to counted stock are held to the thresholds of stock adjustments
+		units := uint(math.Abs(float64(int(stock.Quantity) - int(previous))))
+		unitCost, err := CurrentUnitCost(tx, product.ID)
+		if err != nil {
+			return false, key, err
+		}
+		settings, err := LoadAccountSettings(tx, accountID)
+		if err != nil {
+			return false, key, err
+		}
+		if needsApproval(settings, units, float64(units)*unitCost) {
+			return false, key, ErrAdjustmentNeedsApproval
+		}
+	}
+	if err := setInt(row, "low_stock_threshold", &stock.LowStockThreshold); err != nil {
+		return false, key, err
+	}
+
+	if err := tx.Omit("Product", "Bin").Save(&stock).Error; err != nil {
+		return false, key, err
+	}
+	return !found, key, RecordStockMovement(tx, stock, int(stock.Quantity)-int(previous), model.StockMovement{
+		Reason: model.MovementImport,
+		UserID: userID,
+	})
+}
+
+// findExisting loads the first record matching query and reports whether there was one
+func findExisting(query *gorm.DB, dest interface{}) (bool, error) {
+	err := query.First(dest).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// resolveReference finds a category or supplier of the account by name or, without a
+// name, by ID. It returns nil when the row gives neither.
+func resolveReference(tx *gorm.DB, table interface{}, accountID uint, name, id string) (*uint, error) {
+	query := tx.Model(table).Where("account_id = ?", accountID)
+	switch {
+	case name != "":
+		query = query.Where("name = ?", name)
+	case id != "":
+		query = query.Where("id = ?", id)
+	default:
+		return nil, nil
+	}
+
+	var ids []uint
+	if err := query.Limit(1).Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, errors.New("not found")
+	}
+	return &ids[0], nil
+}
+
+func setString(row ImportRow, column string, dest *string) {
+	if value := row[column]; value != "" {
+		*dest = value
+	}
+}
+
+func setFloat(row ImportRow, column string, dest *float64) error {
+	if row[column] == "" {
+		return nil
+	}
+	value, err := strconv.ParseFloat(row[column], 64)
+	if err != nil {
+		return fmt.Errorf("%s must be a number", column)
+	}
+	*dest = value
+	return nil
+}
+
+func setBool(row ImportRow, column string, dest *bool) error {
+	if row[column] == "" {
+		return nil
+	}
+	value, err := strconv.ParseBool(row[column])
+	if err != nil {
+		return fmt.Errorf("%s must be true or false", column)
+	}
+	*dest = value
+	return nil
+}
+
+func setUint(row ImportRow, column string, dest *uint) error {
+	if row[column] == "" {
+		return nil
+	}
+	value, err := strconv.ParseUint(row[column], 10, 0)
+	if err != nil {
+		return fmt.Errorf("%s must be a non-negative whole number", column)
+	}
+	*dest = uint(value)
+	return nil
+}
+
+func setOptionalUint(row ImportRow, column string, dest **uint) error {
+	if row[column] == "" {
+		return nil
+	}
+	var value uint
+	if err := setUint(row, column, &value); err != nil {
+		return err
+	}
+	*dest = &value
+	return nil
+}
+
+func setInt(row ImportRow, column string, dest *int) error {
+	if row[column] == "" {
+		return nil
+	}
+	value, err := strconv.Atoi(row[column])
+	if err != nil {
+		return fmt.Errorf("%s must be a whole number", column)
+	}
+	*dest = value
+	return nil
+}
+
+// setTime accepts RFC 3339 timestamps and plain YYYY-MM-DD dates
+func setTime(row ImportRow, column string, dest **time.Time) error {
+	if row[column] == "" {
+		return nil
+	}
+	value, err := time.Parse(time.RFC3339, row[column])
+	if err != nil {
+		value, err = time.Parse(time.DateOnly, row[column])
+	}
+	if err != nil {
+		return fmt.Errorf("%s must be a date (YYYY-MM-DD or RFC 3339)", column)
+	}
+	*dest = &value
+	return nil
+}
@@ -0,0 +1,191 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"inventory-management/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrNestedKit is returned when a kit would contain another kit or become a component itself
+	ErrNestedKit = errors.New("kits cannot be nested")
+	// ErrSerializedKit is returned when a kit or one of its components is serialized
+	ErrSerializedKit = errors.New("serialized products cannot be kits or kit components")
+	// ErrNotAKit is returned when a product without components is assembled
+	ErrNotAKit = errors.New("product has no kit components")
+	// ErrKitAssemblyNotOpen is returned when a completed or cancelled work order is changed
+	ErrKitAssemblyNotOpen = errors.New("kit assembly is not open")
+)
+
+// KitComponents returns the bill of materials of a product, empty when it is not a kit
+func KitComponents(db *gorm.DB, kitID uint) ([]model.KitComponent, error) {
+	var components []model.KitComponent
+	err := db.Where("kit_id = ?", kitID).Order("id").Find(&components).Error
+	return components, err
+}
+
+// ValidateKitComponents checks a bill of materials before it replaces the kit's: every
+// component is another product of the account, listed once with a positive quantity, and
+// neither the kit nor its components are kits of their own or serialized
+func ValidateKitComponents(db *gorm.DB, kit model.Product, components []model.KitComponent) error {
+	if len(components) == 0 {
+		return nil
+	}
+	if kit.IsSerialized {
+		return ErrSerializedKit
+	}
+
+	var usedIn int64
+	if err := db.Model(&model.KitComponent{}).Where("component_id = ?", kit.ID).Count(&usedIn).Error; err != nil {
+		return err
+	}
+	if usedIn > 0 {
+		return ErrNestedKit
+	}
+
+	seen := map[uint]bool{}
+	for _, component := range components {
+		if component.Quantity == 0 || component.ComponentID == kit.ID || seen[component.ComponentID] {
+			return fmt.Errorf("invalid component %d: components must be other products, listed once with a positive quantity", component.ComponentID)
+		}
+		seen[component.ComponentID] = true
+
+		var product model.Product
+		if err := db.Where("id = ? AND account_id = ?", component.ComponentID, kit.AccountID).First(&product).Error; err != nil {
+			return fmt.Errorf("component %d not found", component.ComponentID)
+		}
+		if product.IsSerialized {
+			return ErrSerializedKit
+		}
+
+		var nested int64
+		if err := db.Model(&model.KitComponent{}).Where("kit_id = ?", product.ID).Count(&nested).Error; err != nil {
+			return err
+		}
+		if nested > 0 {
+			return ErrNestedKit
+		}
+	}
+	return nil
+}
+
+// KitAvailability returns how many kits are assembled and available, and how many more
+// can be sold from the components on hand: the component that runs out first decides
+func KitAvailability(db *gorm.DB, kitID uint, components []model.KitComponent) (assembled, fromComponents uint, err error) {
+	if assembled, err = availableQuantity(db, kitID); err != nil {
+		return 0, 0, err
+	}
+
+	for i, component := range components {
+		available, err := availableQuantity(db, component.ComponentID)
+		if err != nil {
+			return 0, 0, err
+		}
+		kits := available / component.Quantity
+		if i == 0 || kits < fromComponents {
+			fromComponents = kits
+		}
+	}
+	return assembled, fromComponents, nil
+}
+
+// CompleteKitAssembly builds the kits of an open work order: it takes the components out
+// of stock first-expired-first-out and adds the kits at the work order's bin or location,
+// valued at the cost of the components consumed. Nothing is taken unless every component
+// is available.
+func CompleteKitAssembly(tx *gorm.DB, assembly *model.KitAssembly, userID *uint) error {
+	if assembly.Status != model.KitAssemblyOpen {
+		return ErrKitAssemblyNotOpen
+	}
+	components, err := KitComponents(tx, assembly.KitID)
+	if err != nil {
+		return err
+	}
+	if len(components) == 0 {
+		return ErrNotAKit
+	}
+
+	componentStocks := make([][]model.Stock, len(components))
+	for i, component := range components {
+		stocks, available, err := allocatableStocks(tx, component.ComponentID)
+		if err != nil {
+			return err
+		}
+		if available < component.Quantity*assembly.Quantity {
+			return ErrInsufficientStock
+		}
+		componentStocks[i] = stocks
+	}
+
+	movement := model.StockMovement{
+		Reason: model.MovementAssembly,
+		UserID: userID,
+		Note:   fmt.Sprintf("kit assembly %d", assembly.ID),
+	}
+	cost := 0.0
+	for i, component := range components {
+		remaining := component.Quantity * assembly.Quantity
+		for _, stock := range componentStocks[i] {
+			if remaining == 0 {
+				break
+			}
+			take := min(stock.Available(), remaining)
+			stock.Quantity -= take
+			if err := tx.Model(&stock).Update("quantity", stock.Quantity).Error; err != nil {
+				return err
+			}
+			consumed, err := RecordCostedMovement(tx, stock, -int(take), movement)
+			if err != nil {
+				return err
+			}
+			cost += consumed
+			remaining -= take
+		}
+	}
+
+	kits, err := destinationStock(tx, model.Stock{
+		ProductID: assembly.KitID,
+		Location:  assembly.Location,
+		BinID:     assembly.BinID,
+		AccountID: assembly.AccountID,
+	})
+	if err != nil {
+		return err
+	}
+	kits.Quantity += assembly.Quantity
+	if err := tx.Model(&kits).Update("quantity", kits.Quantity).Error; err != nil {
+		return err
+	}
+	unitCost := cost / float64(assembly.Quantity)
+	movement.UnitCost = &unitCost
+	if err := RecordStockMovement(tx, kits, int(assembly.Quantity), movement); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	assembly.Status = model.KitAssemblyCompleted
+	assembly.UnitCost = unitCost
+	assembly.StockID = &kits.ID
+	assembly.CompletedBy = userID
+	assembly.CompletedAt = &now
+	return tx.Model(assembly).Updates(map[string]interface{}{
+		"status":       assembly.Status,
+		"unit_cost":    assembly.UnitCost,
+		"stock_id":     assembly.StockID,
+		"completed_by": assembly.CompletedBy,
+		"completed_at": assembly.CompletedAt,
+	}).Error
+}
+
+// availableQuantity adds up the available, unexpired quantity of a product
+func availableQuantity(db *gorm.DB, productID uint) (uint, error) {
+	var available uint
+	err := db.Model(&model.Stock{}).
+		Where("product_id = ? AND "+availableQuantitySQL+" > 0", productID).
+		Where("expires_at IS NULL OR expires_at > ?", time.Now()).
+		Select("COALESCE(SUM(" + availableQuantitySQL + "), 0)").Scan(&available).Error
+	return available, err
+}
@@ -2,7 +2,10 @@ package utils
 
 import (
 	"fmt"
+	"html"
+	"inventory-management/internal/model"
 	"log"
+	"strings"
 )
 
 // NotificationService handles sending notifications
@@ -23,6 +26,32 @@ func (ns *NotificationService) sendNotification(to, subject, body string) error
 	return ns.emailSender.SendEmail(to, subject, body)
 }
 
-func (ns *NotificationService) SendLowStockNotification(email string) error {
-	return ns.sendNotification(email, "Low Stock Alert", "Attention: The stock for some items is running low.")
+// SendLowStockNotification tells a recipient which stock rows are running low, one
+// alert at a time or several in a daily digest
+func (ns *NotificationService) SendLowStockNotification(email string, alerts []model.LowStockAlert) error {
+	subject := "Low Stock Alert"
+	if len(alerts) > 1 {
+		subject = fmt.Sprintf("Low Stock Digest: %d items", len(alerts))
+	}
+	var body strings.Builder
+	body.WriteString("<p>Attention: The stock for the following items is running low.</p><ul>")
+	for _, alert := range alerts {
+		fmt.Fprintf(&body, "<li>%s (product %d, stock %d): %d available, threshold %d</li>",
+			html.EscapeString(alert.ProductName), alert.ProductID, alert.StockID, alert.Available, alert.Threshold)
+	}
+	body.WriteString("</ul>")
+	return ns.sendNotification(email, subject, body.String())
+}
+
+func (ns *NotificationService) SendPurchaseOrder(email string, order model.PurchaseOrder) error {
+	var body strings.Builder
+	fmt.Fprintf(&body, "<p>Purchase order %d</p><ul>", order.ID)
+	for _, line := range order.Lines {
+		fmt.Fprintf(&body, "<li>Product %d: %d units</li>", line.ProductID, line.Quantity)
+	}
+	body.WriteString("</ul>")
+	if order.ExpectedAt != nil {
+		fmt.Fprintf(&body, "<p>Expected delivery: %s</p>", order.ExpectedAt.Format("2006-01-02"))
+	}
+	return ns.sendNotification(email, fmt.Sprintf("Purchase Order %d", order.ID), body.String())
 }
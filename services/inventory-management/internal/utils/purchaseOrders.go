@@ -0,0 +1,214 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"inventory-management/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrPurchaseOrderNotReceivable is returned when stock is received against a draft or closed purchase order
+	ErrPurchaseOrderNotReceivable = errors.New("purchase order is not open for receiving")
+	// ErrReceiptLocationRequired is returned when a receipt names neither a bin nor a location
+	ErrReceiptLocationRequired = errors.New("receipt needs a bin or a location")
+)
+
+// PostReceipt adds stock received against a purchase order line to inventory, records it
+// on the line and moves the order to partially received or closed. The received quantity
+// is converted from the receipt's unit, or the line's, into the product's base unit.
+// Receipts that were already posted are ignored so redelivered events do not count twice.
+func PostReceipt(tx *gorm.DB, event model.ReceiptEvent) (*model.PurchaseOrderReceipt, error) {
+	var posted int64
+	if err := tx.Model(&model.PurchaseOrderReceipt{}).
+		Where("receipt_id = ? AND account_id = ?", event.ReceiptID, event.AccountID).Count(&posted).Error; err != nil {
+		return nil, err
+	}
+	if posted > 0 {
+		return nil, nil
+	}
+
+	var order model.PurchaseOrder
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND account_id = ?", event.PurchaseOrderID, event.AccountID).First(&order).Error; err != nil {
+		return nil, err
+	}
+	if order.Status != model.PurchaseOrderSent && order.Status != model.PurchaseOrderPartiallyReceived {
+		return nil, ErrPurchaseOrderNotReceivable
+	}
+
+	var line model.PurchaseOrderLine
+	if err := tx.Where("id = ? AND purchase_order_id = ?", event.LineID, order.ID).First(&line).Error; err != nil {
+		return nil, err
+	}
+	if event.ProductID != 0 && event.ProductID != line.ProductID {
+		return nil, fmt.Errorf("receipt product %d does not match line product %d", event.ProductID, line.ProductID)
+	}
+
+	unit := event.UnitOfMeasure
+	if unit == "" {
+		unit = line.UnitOfMeasure
+	}
+	quantity, err := ToBaseQuantity(tx, line.ProductID, unit, event.Quantity)
+	if err != nil {
+		return nil, err
+	}
+
+	stock, err := receiptDestination(tx, line, event)
+	if err != nil {
+		return nil, err
+	}
+
+	serialized, err := isSerialized(tx, line.ProductID)
+	if err != nil {
+		return nil, err
+	}
+	if serialized && len(event.SerialNumbers) != int(quantity) {
+		return nil, ErrSerialCountMismatch
+	}
+
+	stock.Quantity += quantity
+	if err := tx.Model(&stock).Update("quantity", stock.Quantity).Error; err != nil {
+		return nil, err
+	}
+	if err := RecordStockMovement(tx, stock, int(quantity), model.StockMovement{
+		Reason:   model.MovementReceipt,
+		UserID:   event.ReceivedBy,
+		Note:     fmt.Sprintf("purchase order %d line %d", order.ID, line.ID),
+		UnitCost: &line.UnitCost,
+	}); err != nil {
+		return nil, err
+	}
+	if serialized {
+		for _, serial := range event.SerialNumbers {
+			if err := receiveSerial(tx, stock, serial, event.ReceivedBy); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	line.ReceivedQuantity += quantity
+	line.Closed = line.Closed || event.CloseLine || line.ReceivedQuantity >= line.Quantity
+	if err := tx.Model(&line).Updates(map[string]interface{}{
+		"received_quantity": line.ReceivedQuantity,
+		"closed":            line.Closed,
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	receivedAt := event.ReceivedAt
+	if receivedAt.IsZero() {
+		receivedAt = time.Now()
+	}
+	receipt := model.PurchaseOrderReceipt{
+		ReceiptID:       event.ReceiptID,
+		PurchaseOrderID: order.ID,
+		LineID:          line.ID,
+		ProductID:       line.ProductID,
+		StockID:         stock.ID,
+		Quantity:        quantity,
+		ReceivedAt:      receivedAt,
+		ReceivedBy:      event.ReceivedBy,
+		AccountID:       order.AccountID,
+	}
+	if err := tx.Create(&receipt).Error; err != nil {
+		return nil, err
+	}
+
+	return &receipt, refreshPurchaseOrderStatus(tx, &order)
+}
+
+// ClosePurchaseOrder closes every line of a sent or partially received order, e.g. when
+// the supplier cannot deliver the rest, so the missing quantity is no longer on order
+func ClosePurchaseOrder(tx *gorm.DB, order *model.PurchaseOrder) error {
+	if order.Status != model.PurchaseOrderSent && order.Status != model.PurchaseOrderPartiallyReceived {
+		return ErrPurchaseOrderNotReceivable
+	}
+	if err := tx.Model(&model.PurchaseOrderLine{}).Where("purchase_order_id = ?", order.ID).
+		Update("closed", true).Error; err != nil {
+		return err
+	}
+	return refreshPurchaseOrderStatus(tx, order)
+}
+
+// refreshPurchaseOrderStatus derives the order status from its lines: closed once no line
+// expects more stock, partially received once anything has arrived
+func refreshPurchaseOrderStatus(tx *gorm.DB, order *model.PurchaseOrder) error {
+	var lines []model.PurchaseOrderLine
+	if err := tx.Where("purchase_order_id = ?", order.ID).Find(&lines).Error; err != nil {
+		return err
+	}
+
+	open, received := false, false
+	for _, line := range lines {
+		if line.Outstanding() > 0 {
+			open = true
+		}
+		if line.ReceivedQuantity > 0 {
+			received = true
+		}
+	}
+
+	updates := map[string]interface{}{}
+	switch {
+	case !open:
+		now := time.Now()
+		order.Status = model.PurchaseOrderClosed
+		order.ClosedAt = &now
+		updates["closed_at"] = now
+	case received:
+		order.Status = model.PurchaseOrderPartiallyReceived
+	default:
+		return nil
+	}
+	updates["status"] = order.Status
+	return tx.Model(order).Updates(updates).Error
+}
+
+// receiptDestination finds the stock row of the received product at the receipt's bin or
+// location and lot, creating it when the product was not stored there before
+func receiptDestination(tx *gorm.DB, line model.PurchaseOrderLine, event model.ReceiptEvent) (model.Stock, error) {
+	return destinationStock(tx, model.Stock{
+		ProductID: line.ProductID,
+		LotNumber: event.LotNumber,
+		ExpiresAt: event.ExpiresAt,
+		Location:  event.Location,
+		BinID:     event.BinID,
+		AccountID: line.AccountID,
+	})
+}
+
+// destinationStock finds the stock row of a product at the template's bin or location,
+// lot and expiry date, creating it from the template when there is none yet
+func destinationStock(tx *gorm.DB, stock model.Stock) (model.Stock, error) {
+	query := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("product_id = ? AND lot_number = ? AND account_id = ?", stock.ProductID, stock.LotNumber, stock.AccountID)
+	switch {
+	case stock.BinID != nil:
+		var bin model.Bin
+		if err := tx.Preload("Zone.Warehouse").Where("id = ? AND account_id = ?", *stock.BinID, stock.AccountID).First(&bin).Error; err != nil {
+			return stock, err
+		}
+		stock.BinID = &bin.ID
+		stock.Location = bin.Label()
+		query = query.Where("bin_id = ?", bin.ID)
+	case stock.Location != "":
+		query = query.Where("bin_id IS NULL AND location = ?", stock.Location)
+	default:
+		return stock, ErrReceiptLocationRequired
+	}
+	if stock.ExpiresAt == nil {
+		query = query.Where("expires_at IS NULL")
+	} else {
+		query = query.Where("expires_at = ?", *stock.ExpiresAt)
+	}
+
+	found, err := findExisting(query, &stock)
+	if err != nil || found {
+		return stock, err
+	}
+	return stock, tx.Omit("Product", "Bin").Create(&stock).Error
+}
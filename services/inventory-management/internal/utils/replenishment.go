@@ -0,0 +1,141 @@
+package utils
+
+import (
+	"errors"
+	"inventory-management/internal/model"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrNoSupplier is returned when a product needs replenishment but has no supplier to order from
+var ErrNoSupplier = errors.New("product has no preferred supplier")
+
+// ReplenishProduct proposes the product's reorder quantity on a draft purchase order when
+// its stock position has fallen to the reorder point. It returns the draft that was
+// created or extended, or nil when no replenishment is needed.
+func ReplenishProduct(db *gorm.DB, productID uint) (*model.PurchaseOrder, error) {
+	var order *model.PurchaseOrder
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var product model.Product
+		if err := tx.First(&product, productID).Error; err != nil {
+			return err
+		}
+		var err error
+		order, err = proposeReplenishment(tx, product)
+		return err
+	})
+	return order, err
+}
+
+// GenerateReplenishment checks every product with a reorder quantity, of one account or
+// of all accounts when accountID is nil, and returns the draft purchase orders that
+// received new quantity. Products that fail are logged and skipped.
+func GenerateReplenishment(db *gorm.DB, accountID *uint) ([]model.PurchaseOrder, error) {
+	query := db.Model(&model.Product{}).Where("reorder_quantity > 0")
+	if accountID != nil {
+		query = query.Where("account_id = ?", *accountID)
+	}
+	var productIDs []uint
+	if err := query.Order("id").Pluck("id", &productIDs).Error; err != nil {
+		return nil, err
+	}
+
+	seen := map[uint]bool{}
+	orderIDs := []uint{}
+	for _, productID := range productIDs {
+		order, err := ReplenishProduct(db, productID)
+		if err != nil {
+			log.Printf("Failed to replenish product %d: %v", productID, err)
+			continue
+		}
+		if order != nil && !seen[order.ID] {
+			seen[order.ID] = true
+			orderIDs = append(orderIDs, order.ID)
+		}
+	}
+
+	orders := []model.PurchaseOrder{}
+	if len(orderIDs) == 0 {
+		return orders, nil
+	}
+	err := db.Preload("Lines").Preload("Supplier").Where("id IN ?", orderIDs).Order("id").Find(&orders).Error
+	return orders, err
+}
+
+// StockPosition returns the available quantity of a product plus the quantity still
+// expected on open purchase orders, i.e. ordered but not yet received on open lines
+func StockPosition(tx *gorm.DB, productID uint) (int, error) {
+	var available int
+	if err := tx.Model(&model.Stock{}).Where("product_id = ? AND "+availableQuantitySQL+" > 0", productID).
+		Select("COALESCE(SUM(" + availableQuantitySQL + "), 0)").Scan(&available).Error; err != nil {
+		return 0, err
+	}
+
+	var onOrder int
+	if err := tx.Model(&model.PurchaseOrderLine{}).
+		Joins("JOIN purchase_orders ON purchase_orders.id = purchase_order_lines.purchase_order_id").
+		Where("purchase_order_lines.product_id = ? AND purchase_orders.status IN ?", productID, model.OpenPurchaseOrderStatuses).
+		Where("purchase_order_lines.closed = ? AND purchase_order_lines.received_quantity < purchase_order_lines.quantity", false).
+		Select("COALESCE(SUM(purchase_order_lines.quantity - purchase_order_lines.received_quantity), 0)").Scan(&onOrder).Error; err != nil {
+		return 0, err
+	}
+	return available + onOrder, nil
+}
+
+// proposeReplenishment orders whole multiples of the reorder quantity until the stock
+// position is above the reorder point. Proposals for the same supplier are collected on
+// one draft order until a manager sends it.
+func proposeReplenishment(tx *gorm.DB, product model.Product) (*model.PurchaseOrder, error) {
+	if product.ReorderQuantity == 0 {
+		return nil, nil
+	}
+
+	position, err := StockPosition(tx, product.ID)
+	if err != nil {
+		return nil, err
+	}
+	if position > int(product.ReorderPoint) {
+		return nil, nil
+	}
+
+	supplierID := product.ReplenishmentSupplierID()
+	if supplierID == 0 {
+		return nil, ErrNoSupplier
+	}
+
+	quantity := product.ReorderQuantity
+	for position+int(quantity) <= int(product.ReorderPoint) {
+		quantity += product.ReorderQuantity
+	}
+
+	order := model.PurchaseOrder{
+		SupplierID:    supplierID,
+		Status:        model.PurchaseOrderDraft,
+		AutoGenerated: true,
+		AccountID:     product.AccountID,
+	}
+	if err := tx.Where(order).FirstOrCreate(&order).Error; err != nil {
+		return nil, err
+	}
+
+	line := model.PurchaseOrderLine{PurchaseOrderID: order.ID, ProductID: product.ID, AccountID: product.AccountID}
+	if err := tx.Where(line).FirstOrInit(&line).Error; err != nil {
+		return nil, err
+	}
+	line.Quantity += quantity
+	price, err := EffectiveSupplierPrice(tx, supplierID, product.ID, line.Quantity, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if price != nil {
+		line.UnitCost = price.UnitCost
+	}
+	if err := tx.Save(&line).Error; err != nil {
+		return nil, err
+	}
+
+	log.Printf("Proposed %d units of product %d on draft purchase order %d", quantity, product.ID, order.ID)
+	return &order, nil
+}
@@ -0,0 +1,268 @@
+package utils
+
+import (
+	"errors"
+	"inventory-management/internal/model"
+	"log"
+	"os"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInsufficientStock is returned when a product does not have enough available quantity
+var ErrInsufficientStock = errors.New("insufficient stock")
+
+const defaultReservationTTL = 72 * time.Hour
+
+// ReservationTTL returns how long a reservation is held before it expires.
+// It can be overridden with the RESERVATION_TTL environment variable, e.g. "48h".
+func ReservationTTL() time.Duration {
+	if ttl, err := time.ParseDuration(os.Getenv("RESERVATION_TTL")); err == nil && ttl > 0 {
+		return ttl
+	}
+	return defaultReservationTTL
+}
+
+// ReserveStock reserves quantity of a product for an order, spreading it across the
+// product's stock rows first-expired-first-out. Expired lots and quantity on hold are
+// never allocated and stock without an expiry date is used last. Kits are reserved
+// from assembled kit stock first and the rest from their components, all or nothing.
+// It returns the stock rows that were touched. Reserving an order that already holds
+// reservations is a no-op.
+func ReserveStock(tx *gorm.DB, orderID, productID, quantity uint) ([]model.Stock, error) {
+	var existing int64
+	if err := tx.Model(&model.StockReservation{}).Where("order_id = ?", orderID).Count(&existing).Error; err != nil {
+		return nil, err
+	}
+	if existing > 0 {
+		log.Printf("Order %d already holds reservations, skipping\n", orderID)
+		return nil, nil
+	}
+
+	components, err := KitComponents(tx, productID)
+	if err != nil {
+		return nil, err
+	}
+	if len(components) > 0 {
+		return reserveKit(tx, orderID, productID, components, quantity)
+	}
+
+	stocks, available, err := allocatableStocks(tx, productID)
+	if err != nil {
+		return nil, err
+	}
+	if available < quantity {
+		return nil, ErrInsufficientStock
+	}
+	return reserveFrom(tx, orderID, stocks, quantity)
+}
+
+// reserveKit reserves kits that are already assembled and the components of the rest.
+// Every component is checked before anything is reserved.
+func reserveKit(tx *gorm.DB, orderID, kitID uint, components []model.KitComponent, quantity uint) ([]model.Stock, error) {
+	kits, assembled, err := allocatableStocks(tx, kitID)
+	if err != nil {
+		return nil, err
+	}
+	fromKits := min(assembled, quantity)
+	toAssemble := quantity - fromKits
+
+	componentStocks := make([][]model.Stock, len(components))
+	for i, component := range components {
+		stocks, available, err := allocatableStocks(tx, component.ComponentID)
+		if err != nil {
+			return nil, err
+		}
+		if available < component.Quantity*toAssemble {
+			return nil, ErrInsufficientStock
+		}
+		componentStocks[i] = stocks
+	}
+
+	touched, err := reserveFrom(tx, orderID, kits, fromKits)
+	if err != nil {
+		return nil, err
+	}
+	for i, component := range components {
+		reserved, err := reserveFrom(tx, orderID, componentStocks[i], component.Quantity*toAssemble)
+		if err != nil {
+			return nil, err
+		}
+		touched = append(touched, reserved...)
+	}
+	return touched, nil
+}
+
+// allocatableStocks locks the stock rows of a product that have available, unexpired
+// quantity, in the order they should be allocated, and returns their total available
+func allocatableStocks(tx *gorm.DB, productID uint) ([]model.Stock, uint, error) {
+	var stocks []model.Stock
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("product_id = ? AND "+availableQuantitySQL+" > 0", productID).
+		Where("expires_at IS NULL OR expires_at > ?", time.Now()).
+		Order("expires_at IS NULL, expires_at, id").Find(&stocks).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var available uint
+	for _, stock := range stocks {
+		available += stock.Available()
+	}
+	return stocks, available, nil
+}
+
+// reserveFrom reserves quantity for an order from stock rows that hold enough of it
+func reserveFrom(tx *gorm.DB, orderID uint, stocks []model.Stock, quantity uint) ([]model.Stock, error) {
+	expiresAt := time.Now().Add(ReservationTTL())
+	remaining := quantity
+	var touched []model.Stock
+	for _, stock := range stocks {
+		if remaining == 0 {
+			break
+		}
+
+		take := stock.Available()
+		if take > remaining {
+			take = remaining
+		}
+
+		stock.ReservedQuantity += take
+		if err := tx.Model(&stock).Update("reserved_quantity", stock.ReservedQuantity).Error; err != nil {
+			return nil, err
+		}
+
+		reservation := model.StockReservation{
+			OrderID:   orderID,
+			StockID:   stock.ID,
+			ProductID: stock.ProductID,
+			Quantity:  take,
+			Status:    model.ReservationActive,
+			ExpiresAt: expiresAt,
+			AccountID: stock.AccountID,
+		}
+		if err := tx.Create(&reservation).Error; err != nil {
+			return nil, err
+		}
+
+		touched = append(touched, stock)
+		remaining -= take
+	}
+
+	return touched, nil
+}
+
+// ConsumeReservations turns the reservations of a shipped order into an actual decrement
+// of on-hand quantity and records it in the movement ledger. Reservations that expired
+// before the shipment went out are still deducted from on-hand, since the goods
+// physically left the building, but only as far as the stock is still available: the
+// rest may have been reserved for other orders or put on hold since. What could not be
+// deducted is kept on the reservation as its short quantity.
+func ConsumeReservations(tx *gorm.DB, orderID uint, shipmentID *uint) ([]model.Stock, error) {
+	var reservations []model.StockReservation
+	if err := tx.Where("order_id = ? AND status IN ?", orderID, []model.ReservationStatus{model.ReservationActive, model.ReservationExpired}).
+		Find(&reservations).Error; err != nil {
+		return nil, err
+	}
+
+	var touched []model.Stock
+	for _, reservation := range reservations {
+		var stock model.Stock
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&stock, reservation.StockID).Error; err != nil {
+			return nil, err
+		}
+
+		var shipped uint
+		if reservation.Status == model.ReservationActive {
+			stock.ReservedQuantity -= min(stock.ReservedQuantity, reservation.Quantity)
+			shipped = min(stock.Quantity, reservation.Quantity)
+		} else {
+			shipped = min(stock.Available(), reservation.Quantity)
+		}
+		short := reservation.Quantity - shipped
+		if short > 0 {
+			log.Printf("Reservation %d for order %d expired before shipment, %d units short\n", reservation.ID, orderID, short)
+		}
+		stock.Quantity -= shipped
+
+		if err := tx.Model(&stock).Updates(map[string]interface{}{
+			"quantity":          stock.Quantity,
+			"reserved_quantity": stock.ReservedQuantity,
+		}).Error; err != nil {
+			return nil, err
+		}
+
+		if err := RecordStockMovement(tx, stock, -int(shipped), model.StockMovement{
+			Reason:     model.MovementShipment,
+			OrderID:    &orderID,
+			ShipmentID: shipmentID,
+		}); err != nil {
+			return nil, err
+		}
+
+		if err := tx.Model(&reservation).Updates(map[string]interface{}{
+			"status":         model.ReservationConsumed,
+			"short_quantity": short,
+		}).Error; err != nil {
+			return nil, err
+		}
+
+		touched = append(touched, stock)
+	}
+
+	return touched, nil
+}
+
+// ReleaseReservations returns the quantity held by an order's active reservations to
+// available stock and marks them with the given status (released or expired).
+func ReleaseReservations(tx *gorm.DB, orderID uint, status model.ReservationStatus) (int, error) {
+	var reservations []model.StockReservation
+	if err := tx.Where("order_id = ? AND status = ?", orderID, model.ReservationActive).Find(&reservations).Error; err != nil {
+		return 0, err
+	}
+
+	for _, reservation := range reservations {
+		var stock model.Stock
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&stock, reservation.StockID).Error; err != nil {
+			return 0, err
+		}
+
+		stock.ReservedQuantity -= min(stock.ReservedQuantity, reservation.Quantity)
+		if err := tx.Model(&stock).Update("reserved_quantity", stock.ReservedQuantity).Error; err != nil {
+			return 0, err
+		}
+
+		if err := tx.Model(&reservation).Update("status", status).Error; err != nil {
+			return 0, err
+		}
+	}
+
+	return len(reservations), nil
+}
+
+// ExpireReservations releases every active reservation whose expiry has passed. All
+// reservations of an order share the same expiry, so orders are released as a whole.
+func ExpireReservations(db *gorm.DB, now time.Time) error {
+	var orderIDs []uint
+	if err := db.Model(&model.StockReservation{}).
+		Where("status = ? AND expires_at < ?", model.ReservationActive, now).
+		Distinct().Pluck("order_id", &orderIDs).Error; err != nil {
+		return err
+	}
+
+	for _, orderID := range orderIDs {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			released, err := ReleaseReservations(tx, orderID, model.ReservationExpired)
+			if err == nil && released > 0 {
+				log.Printf("Expired %d reservations for order %d\n", released, orderID)
+			}
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
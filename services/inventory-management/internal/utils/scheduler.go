@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"log"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"gorm.io/gorm"
+)
+
+// Scheduler handles scheduled tasks
+type Scheduler struct {
+	DB *gorm.DB
+}
+
+// NewScheduler creates a new Scheduler instance
+func NewScheduler(db *gorm.DB) *Scheduler {
+	return &Scheduler{
+		DB: db,
+	}
+}
+
+// StartReservationExpiryScheduler releases expired stock reservations every minute
+func (s *Scheduler) StartReservationExpiryScheduler() {
+	c := cron.New()
+	c.AddFunc("@every 1m", func() {
+		if err := ExpireReservations(s.DB, time.Now()); err != nil {
+			log.Printf("Error expiring reservations: %v", err)
+		}
+	})
+	c.Start()
+}
+
+// StartReplenishmentScheduler turns low stock positions into draft purchase orders every hour
+func (s *Scheduler) StartReplenishmentScheduler() {
+	c := cron.New()
+	c.AddFunc("@hourly", func() {
+		orders, err := GenerateReplenishment(s.DB, nil)
+		if err != nil {
+			log.Printf("Error generating replenishment orders: %v", err)
+			return
+		}
+		log.Printf("Replenishment run updated %d draft purchase orders", len(orders))
+	})
+	c.Start()
+}
+
+// StartLowStockDigestScheduler sends the daily digest of low stock alerts every morning
+func (s *Scheduler) StartLowStockDigestScheduler(ns *NotificationService) {
+	c := cron.New()
+	c.AddFunc("0 7 * * *", func() {
+		if err := SendLowStockDigests(s.DB, ns); err != nil {
+			log.Printf("Error sending low stock digests: %v", err)
+		}
+	})
+	c.Start()
+}
+
+// StartStockSnapshotScheduler records every account's stock on hand as of midnight each night
+func (s *Scheduler) StartStockSnapshotScheduler() {
+	c := cron.New()
+	c.AddFunc("5 0 * * *", func() {
+		if err := TakeDailySnapshots(s.DB, time.Now()); err != nil {
+			log.Printf("Error taking daily stock snapshots: %v", err)
+		}
+	})
+	c.Start()
+}
+
+// StartClassificationScheduler recomputes the ABC/XYZ classes of every account's products each night
+func (s *Scheduler) StartClassificationScheduler() {
+	c := cron.New()
+	c.AddFunc("30 2 * * *", func() {
+		if err := ClassifyAllProducts(s.DB, time.Now()); err != nil {
+			log.Printf("Error classifying products: %v", err)
+		}
+	})
+	c.Start()
+}
@@ -0,0 +1,206 @@
+package utils
+
+import (
+	"inventory-management/internal/model"
+	"slices"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// ProductSearch narrows a product search. Query is matched against the product name,
+// SKU, description and supplier name; the other fields filter the matches.
+type ProductSearch struct {
+	Query       string
+	CategoryID  *uint // Includes the category's subcategories
+	SupplierID  *uint
+	StockStatus model.StockStatus
+	Limit       int
+	Offset      int
+}
+
+// searchDocument is the weighted full-text document of a product, kept in sync with
+// the expression index CreateSearchIndexes builds
+const searchDocument = `setweight(to_tsvector('simple', coalesce(products.name, '')), 'A') || ` +
+	`setweight(to_tsvector('simple', coalesce(products.sku, '')), 'A') || ` +
+	`setweight(to_tsvector('simple', coalesce(products.description, '')), 'C')`
+
+// stockStatusExpression classifies a product by its available quantity
+const stockStatusExpression = `CASE WHEN COALESCE(levels.available, 0) = 0 THEN 'out_of_stock' ` +
+	`WHEN COALESCE(levels.available, 0) <= products.reorder_point THEN 'low_stock' ELSE 'in_stock' END`
+
+// CreateSearchIndexes builds the PostgreSQL indexes product search relies on: a GIN index
+// over the full-text document of products and supplier names, and trigram indexes for
+// typo-tolerant name and SKU matching
+func CreateSearchIndexes(db *gorm.DB) error {
+	if db.Dialector.Name() != "postgres" {
+		return nil
+	}
+	for _, statement := range []string{
+		`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+		`CREATE INDEX IF NOT EXISTS idx_products_search ON products USING GIN ((` + searchDocument + `))`,
+		`CREATE INDEX IF NOT EXISTS idx_products_name_trgm ON products USING GIN (lower(name) gin_trgm_ops)`,
+		`CREATE INDEX IF NOT EXISTS idx_products_sku_trgm ON products USING GIN (lower(sku) gin_trgm_ops)`,
+		`CREATE INDEX IF NOT EXISTS idx_suppliers_search ON suppliers USING GIN (to_tsvector('simple', coalesce(name, '')))`,
+	} {
+		if err := db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SearchProducts finds the account's products matching the search, best matches first,
+// with facet counts by category, supplier and stock status. On PostgreSQL every word of
+// the query matches as a prefix and names and SKUs also match with typos; other databases
+// fall back to substring matching.
+func SearchProducts(db *gorm.DB, accountID uint, search ProductSearch) (model.ProductSearchResponse, error) {
+	response := model.ProductSearchResponse{Results: []model.ProductSearchHit{}}
+
+	var categoryIDs []uint
+	if search.CategoryID != nil {
+		var err error
+		if categoryIDs, err = CategoryDescendantIDs(db, accountID, *search.CategoryID); err != nil {
+			return response, err
+		}
+	}
+
+	terms := searchTerms(search.Query)
+	postgres := db.Dialector.Name() == "postgres"
+	// matches builds the matching products, leaving out the filter a facet is counted by
+	matches := func(except string) *gorm.DB {
+		levels := db.Model(&model.Stock{}).
+			Select("product_id, SUM(CASE WHEN " + availableQuantitySQL + " > 0 THEN " + availableQuantitySQL + " ELSE 0 END) AS available").
+			Group("product_id")
+		query := db.Table("products").
+			Joins("LEFT JOIN (?) AS levels ON levels.product_id = products.id", levels).
+			Joins("LEFT JOIN suppliers ON suppliers.id = products.supplier_id").
+			Where("products.account_id = ? AND products.deleted_at IS NULL", accountID)
+		if len(terms) > 0 {
+			if postgres {
+				query = query.Where("("+searchDocument+") @@ to_tsquery('simple', ?) OR "+
+					"products.supplier_id IN (SELECT id FROM suppliers WHERE account_id = ? AND to_tsvector('simple', coalesce(name, '')) @@ to_tsquery('simple', ?)) OR "+
+					"? <% lower(products.name) OR ? <% lower(products.sku)",
+					prefixQuery(terms), accountID, prefixQuery(terms), strings.Join(terms, " "), strings.Join(terms, " "))
+			} else {
+				for _, term := range terms {
+					like := "%" + term + "%"
+					query = query.Where("LOWER(products.name) LIKE ? OR LOWER(products.sku) LIKE ? OR LOWER(products.description) LIKE ? OR LOWER(COALESCE(suppliers.name, '')) LIKE ?",
+						like, like, like, like)
+				}
+			}
+		}
+		if search.CategoryID != nil && except != "category" {
+			query = query.Where("products.category_id IN ?", categoryIDs)
+		}
+		if search.SupplierID != nil && except != "supplier" {
+			query = query.Where("products.supplier_id = ?", *search.SupplierID)
+		}
+		if search.StockStatus != "" && except != "stock_status" {
+			query = query.Where(stockStatusExpression+" = ?", search.StockStatus)
+		}
+		return query
+	}
+
+	if err := matches("").Count(&response.Total).Error; err != nil {
+		return response, err
+	}
+
+	rank, rankArgs := searchRank(terms, postgres)
+	var hits []struct {
+		ID          uint
+		Rank        float64
+		Available   uint
+		StockStatus model.StockStatus
+	}
+	if err := matches("").
+		Select("products.id, "+rank+" AS rank, COALESCE(levels.available, 0) AS available, "+stockStatusExpression+" AS stock_status", rankArgs...).
+		Order("rank DESC, products.name, products.id").Limit(search.Limit).Offset(search.Offset).
+		Scan(&hits).Error; err != nil {
+		return response, err
+	}
+
+	ids := make([]uint, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ID
+	}
+	var products []model.Product
+	if err := db.Preload("Category").Preload("Supplier").Where("id IN ?", ids).Find(&products).Error; err != nil {
+		return response, err
+	}
+	for _, hit := range hits {
+		i := slices.IndexFunc(products, func(product model.Product) bool { return product.ID == hit.ID })
+		if i < 0 {
+			continue
+		}
+		response.Results = append(response.Results, model.ProductSearchHit{
+			Product:     products[i],
+			Rank:        hit.Rank,
+			Available:   hit.Available,
+			StockStatus: hit.StockStatus,
+		})
+	}
+
+	response.Facets.Categories = []model.SearchFacet{}
+	if err := matches("category").Joins("LEFT JOIN categories ON categories.id = products.category_id").
+		Select("products.category_id AS id, COALESCE(categories.name, '') AS name, COUNT(*) AS count").
+		Group("products.category_id, categories.name").Order("count DESC, name").
+		Scan(&response.Facets.Categories).Error; err != nil {
+		return response, err
+	}
+	response.Facets.Suppliers = []model.SearchFacet{}
+	if err := matches("supplier").
+		Select("products.supplier_id AS id, COALESCE(suppliers.name, '') AS name, COUNT(*) AS count").
+		Group("products.supplier_id, suppliers.name").Order("count DESC, name").
+		Scan(&response.Facets.Suppliers).Error; err != nil {
+		return response, err
+	}
+	response.Facets.StockStatus = []model.StockStatusFacet{}
+	if err := matches("stock_status").
+		Select(stockStatusExpression + " AS status, COUNT(*) AS count").
+		Group(stockStatusExpression).Order("count DESC, status").
+		Scan(&response.Facets.StockStatus).Error; err != nil {
+		return response, err
+	}
+	return response, nil
+}
+
+// searchTerms splits a query into lower-case words of letters and digits
+func searchTerms(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// prefixQuery builds a tsquery matching documents with every term as a word prefix
+func prefixQuery(terms []string) string {
+	prefixes := make([]string, len(terms))
+	for i, term := range terms {
+		prefixes[i] = term + ":*"
+	}
+	return strings.Join(prefixes, " & ")
+}
+
+// searchRank returns the relevance expression of a search. PostgreSQL ranks the full-text
+// match and adds how closely the name resembles the query; the fallback scores where the
+// terms were found, names and SKUs above descriptions and supplier names.
+func searchRank(terms []string, postgres bool) (string, []interface{}) {
+	if len(terms) == 0 {
+		return "0", nil
+	}
+	if postgres {
+		return "ts_rank(" + searchDocument + ", to_tsquery('simple', ?)) + word_similarity(?, lower(products.name))",
+			[]interface{}{prefixQuery(terms), strings.Join(terms, " ")}
+	}
+
+	var parts []string
+	var args []interface{}
+	for _, term := range terms {
+		parts = append(parts, "CASE WHEN LOWER(products.name) LIKE ? THEN 3 WHEN LOWER(products.name) LIKE ? OR LOWER(products.sku) LIKE ? THEN 2 "+
+			"WHEN LOWER(products.description) LIKE ? THEN 1 ELSE 0.5 END")
+		like := "%" + term + "%"
+		args = append(args, term+"%", like, like, like)
+	}
+	return "(" + strings.Join(parts, " + ") + ")", args
+}
@@ -0,0 +1,265 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"inventory-management/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrSerialCountMismatch is returned when the number of serials does not match the quantity
+	ErrSerialCountMismatch = errors.New("number of serial numbers must match the quantity")
+	// ErrDuplicateSerial is returned when a serial is listed twice or is already in stock
+	ErrDuplicateSerial = errors.New("serial number is already in stock")
+	// ErrSerialNotFound is returned when a serial is not in stock where it is expected
+	ErrSerialNotFound = errors.New("serial number not found in stock")
+)
+
+// SyncStockSerials makes the in-stock serials of a stock row equal to serials. New serials
+// are received into the row and missing ones are removed. The stock must already hold its
+// new quantity, which has to match the number of serials.
+func SyncStockSerials(tx *gorm.DB, stock model.Stock, serials []string, userID *uint) error {
+	if len(serials) != int(stock.Quantity) {
+		return ErrSerialCountMismatch
+	}
+
+	wanted := make(map[string]bool, len(serials))
+	for _, serial := range serials {
+		if serial == "" || wanted[serial] {
+			return fmt.Errorf("%w: %q", ErrDuplicateSerial, serial)
+		}
+		wanted[serial] = true
+	}
+
+	var current []model.SerialNumber
+	if err := tx.Where("stock_id = ? AND status = ?", stock.ID, model.SerialInStock).Find(&current).Error; err != nil {
+		return err
+	}
+
+	held := make(map[string]bool, len(current))
+	for _, serialNumber := range current {
+		held[serialNumber.Serial] = true
+		if wanted[serialNumber.Serial] {
+			continue
+		}
+		if err := changeSerial(tx, serialNumber, model.SerialRemoved, model.SerialEvent{
+			Event:       model.SerialEventRemoved,
+			FromStockID: &stock.ID,
+			UserID:      userID,
+		}); err != nil {
+			return err
+		}
+	}
+
+	for _, serial := range serials {
+		if held[serial] {
+			continue
+		}
+		if err := receiveSerial(tx, stock, serial, userID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// receiveSerial puts a serial into a stock row. Serials that left the building before,
+// e.g. a returned unit, are brought back into stock rather than duplicated.
+func receiveSerial(tx *gorm.DB, stock model.Stock, serial string, userID *uint) error {
+	var serialNumber model.SerialNumber
+	err := tx.Where("serial = ? AND product_id = ? AND account_id = ?", serial, stock.ProductID, stock.AccountID).
+		First(&serialNumber).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		serialNumber = model.SerialNumber{
+			Serial:    serial,
+			ProductID: stock.ProductID,
+			AccountID: stock.AccountID,
+		}
+	case err != nil:
+		return err
+	case serialNumber.Status == model.SerialInStock:
+		return fmt.Errorf("%w: %q", ErrDuplicateSerial, serial)
+	}
+
+	serialNumber.StockID = &stock.ID
+	return changeSerial(tx, serialNumber, model.SerialInStock, model.SerialEvent{
+		Event:     model.SerialEventReceived,
+		ToStockID: &stock.ID,
+		UserID:    userID,
+	})
+}
+
+// RemoveStockSerials takes every serial still held by a stock row out of stock, e.g. when
+// the row is permanently deleted
+func RemoveStockSerials(tx *gorm.DB, stockID uint, userID *uint) error {
+	var serialNumbers []model.SerialNumber
+	if err := tx.Where("stock_id = ? AND status = ?", stockID, model.SerialInStock).Find(&serialNumbers).Error; err != nil {
+		return err
+	}
+
+	for _, serialNumber := range serialNumbers {
+		if err := changeSerial(tx, serialNumber, model.SerialRemoved, model.SerialEvent{
+			Event:       model.SerialEventRemoved,
+			FromStockID: &stockID,
+			UserID:      userID,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MoveSerials moves in-stock serials of the target's product into the target stock row.
+// The quantity of every source row and of the target is adjusted and recorded in the
+// movement ledger. Reserved units cannot be moved away from their row.
+func MoveSerials(tx *gorm.DB, serials []string, target model.Stock, userID *uint) error {
+	var serialNumbers []model.SerialNumber
+	if err := tx.Where("serial IN ? AND product_id = ? AND account_id = ? AND status = ?",
+		serials, target.ProductID, target.AccountID, model.SerialInStock).Find(&serialNumbers).Error; err != nil {
+		return err
+	}
+	if len(serialNumbers) != len(serials) {
+		return ErrSerialNotFound
+	}
+
+	moved := make(map[uint]uint)
+	for _, serialNumber := range serialNumbers {
+		if serialNumber.StockID == nil || *serialNumber.StockID == target.ID {
+			continue
+		}
+		from := *serialNumber.StockID
+		serialNumber.StockID = &target.ID
+		if err := changeSerial(tx, serialNumber, model.SerialInStock, model.SerialEvent{
+			Event:       model.SerialEventMoved,
+			FromStockID: &from,
+			ToStockID:   &target.ID,
+			UserID:      userID,
+		}); err != nil {
+			return err
+		}
+		moved[from]++
+	}
+
+	var total uint
+	for stockID, count := range moved {
+		var source model.Stock
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&source, stockID).Error; err != nil {
+			return err
+		}
+		if source.Available() < count {
+			return ErrInsufficientStock
+		}
+
+		source.Quantity -= count
+		if err := tx.Model(&source).Update("quantity", source.Quantity).Error; err != nil {
+			return err
+		}
+		if err := RecordStockMovement(tx, source, -int(count), model.StockMovement{
+			Reason: model.MovementTransfer,
+			UserID: userID,
+		}); err != nil {
+			return err
+		}
+		total += count
+	}
+
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&target, target.ID).Error; err != nil {
+		return err
+	}
+	target.Quantity += total
+	if err := tx.Model(&target).Update("quantity", target.Quantity).Error; err != nil {
+		return err
+	}
+	return RecordStockMovement(tx, target, int(total), model.StockMovement{
+		Reason: model.MovementTransfer,
+		UserID: userID,
+	})
+}
+
+// RequiresSerials reports whether any of the stock rows reserved for an order holds a
+// serialized product. Such an order can only ship with a serial number for every unit.
+func RequiresSerials(tx *gorm.DB, stocks []model.Stock) (bool, error) {
+	for _, stock := range stocks {
+		serialized, err := isSerialized(tx, stock.ProductID)
+		if err != nil || serialized {
+			return serialized, err
+		}
+	}
+	return false, nil
+}
+
+// ShipSerials marks the serials that left on a shipment as shipped. Every unit of a
+// serialized product has to be named by a serial held by the stock rows the order was
+// shipped from; otherwise the shipment is rejected so the caller can roll back the
+// consumed reservations until the dock sends the right serials.
+func ShipSerials(tx *gorm.DB, orderID uint, shipmentID *uint, stocks []model.Stock, serials []string) error {
+	if len(stocks) == 0 {
+		return nil
+	}
+
+	var serializedProducts []uint
+	stockIDs := make([]uint, 0, len(stocks))
+	shippedPerProduct := make(map[uint]int)
+	for _, stock := range stocks {
+		stockIDs = append(stockIDs, stock.ID)
+	}
+	if err := tx.Model(&model.Product{}).Where("is_serialized = ? AND id IN (?)", true,
+		tx.Model(&model.Stock{}).Select("product_id").Where("id IN ?", stockIDs)).
+		Pluck("id", &serializedProducts).Error; err != nil {
+		return err
+	}
+
+	for _, serial := range serials {
+		var serialNumber model.SerialNumber
+		if err := tx.Where("serial = ? AND stock_id IN ? AND status = ?", serial, stockIDs, model.SerialInStock).
+			First(&serialNumber).Error; err != nil {
+			return fmt.Errorf("%w: %s", ErrSerialNotFound, serial)
+		}
+
+		serialNumber.OrderID = &orderID
+		serialNumber.ShipmentID = shipmentID
+		if err := changeSerial(tx, serialNumber, model.SerialShipped, model.SerialEvent{
+			Event:       model.SerialEventShipped,
+			FromStockID: serialNumber.StockID,
+			OrderID:     &orderID,
+			ShipmentID:  shipmentID,
+		}); err != nil {
+			return err
+		}
+		shippedPerProduct[serialNumber.ProductID]++
+	}
+
+	for _, productID := range serializedProducts {
+		// Consumed reservations include units that left short of an expired reservation
+		var shipped int
+		if err := tx.Model(&model.StockReservation{}).
+			Where("order_id = ? AND product_id = ? AND status = ?", orderID, productID, model.ReservationConsumed).
+			Select("COALESCE(SUM(quantity), 0)").Scan(&shipped).Error; err != nil {
+			return err
+		}
+		if shippedPerProduct[productID] != shipped {
+			return fmt.Errorf("%w: OrderID %d shipped %d units of ProductID %d with %d serial numbers",
+				ErrSerialCountMismatch, orderID, shipped, productID, shippedPerProduct[productID])
+		}
+	}
+
+	return nil
+}
+
+// changeSerial saves a serial with its new status and appends the matching history event
+func changeSerial(tx *gorm.DB, serialNumber model.SerialNumber, status model.SerialStatus, event model.SerialEvent) error {
+	serialNumber.Status = status
+	if err := tx.Save(&serialNumber).Error; err != nil {
+		return err
+	}
+
+	event.SerialNumberID = serialNumber.ID
+	event.Serial = serialNumber.Serial
+	event.ProductID = serialNumber.ProductID
+	event.AccountID = serialNumber.AccountID
+	return tx.Create(&event).Error
+}
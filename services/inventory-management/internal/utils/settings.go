@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"inventory-management/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// LoadAccountSettings loads an account's settings, filling in the defaults for an account
+// that has not saved any
+func LoadAccountSettings(db *gorm.DB, accountID uint) (model.AccountSettings, error) {
+	settings := model.AccountSettings{AccountID: accountID}
+	if err := db.Where(model.AccountSettings{AccountID: accountID}).FirstOrInit(&settings).Error; err != nil {
+		return settings, err
+	}
+	if settings.CostingMethod == "" {
+		settings.CostingMethod = model.CostingFIFO
+	}
+	if settings.ClassificationWindowDays == 0 {
+		settings.ClassificationWindowDays = 90
+	}
+	if settings.AttachmentQuotaBytes == 0 {
+		settings.AttachmentQuotaBytes = DefaultAttachmentQuota()
+	}
+	return settings, nil
+}
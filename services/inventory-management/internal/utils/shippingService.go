@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"inventory-management/internal/model"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+// FetchSerialShipments asks the shipping-receiving service which shipments carried a
+// serial number, forwarding the caller's token
+func FetchSerialShipments(token, serial string) ([]model.SerialShipment, error) {
+	shippingServiceURL := os.Getenv("SHIPPING_SERVICE_URL")
+	if shippingServiceURL == "" {
+		return nil, fmt.Errorf("SHIPPING_SERVICE_URL is not set")
+	}
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/shipping-receiving/serials/%s", shippingServiceURL, url.PathEscape(serial)), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch shipments, status code: %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Shipments []model.SerialShipment `json:"shipments"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	return body.Shipments, nil
+}
@@ -0,0 +1,207 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"inventory-management/internal/model"
+	"math"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrRecommendationReviewed is returned when a slotting recommendation was already accepted or dismissed
+	ErrRecommendationReviewed = errors.New("slotting recommendation has already been reviewed")
+	// ErrStaleRecommendation is returned when the stock or the target bin changed since the recommendation was made
+	ErrStaleRecommendation = errors.New("stock or target bin changed since the recommendation was made")
+)
+
+// RecommendSlotting replaces the warehouse's pending slotting recommendations with new
+// ones. Stock rows are ranked by how often they were picked for orders between from and
+// to, and the busiest rows outside the golden zone are offered the free golden-zone bins
+// closest to dispatch, as long as that shortens the walk. Serialized stock is left out
+// since its transfers have to name the serials moved.
+func RecommendSlotting(tx *gorm.DB, warehouse model.Warehouse, from, to time.Time) ([]model.SlottingRecommendation, error) {
+	if err := tx.Where("warehouse_id = ? AND status = ?", warehouse.ID, model.SlottingPending).
+		Delete(&model.SlottingRecommendation{}).Error; err != nil {
+		return nil, err
+	}
+
+	var bins []model.Bin
+	if err := tx.Where("account_id = ? AND zone_id IN (?)", warehouse.AccountID,
+		tx.Model(&model.Zone{}).Select("id").Where("warehouse_id = ?", warehouse.ID)).
+		Find(&bins).Error; err != nil {
+		return nil, err
+	}
+	binsByID := map[uint]model.Bin{}
+	binIDs := make([]uint, 0, len(bins))
+	for _, bin := range bins {
+		binsByID[bin.ID] = bin
+		binIDs = append(binIDs, bin.ID)
+	}
+
+	var stocks []model.Stock
+	if err := tx.Where("account_id = ? AND bin_id IN ? AND quantity > 0", warehouse.AccountID, binIDs).
+		Find(&stocks).Error; err != nil {
+		return nil, err
+	}
+
+	// Bins holding stock or awaiting a transfer are not free to move into
+	occupied := map[uint]bool{}
+	for _, stock := range stocks {
+		occupied[*stock.BinID] = true
+	}
+	var incoming []uint
+	if err := tx.Model(&model.TransferOrder{}).
+		Where("account_id = ? AND to_bin_id IN ? AND status IN ?", warehouse.AccountID, binIDs, model.OpenTransferStatuses).
+		Pluck("to_bin_id", &incoming).Error; err != nil {
+		return nil, err
+	}
+	for _, binID := range incoming {
+		occupied[binID] = true
+	}
+
+	var free []model.Bin
+	for _, bin := range bins {
+		if bin.GoldenZone && !occupied[bin.ID] {
+			free = append(free, bin)
+		}
+	}
+	sort.SliceStable(free, func(i, j int) bool {
+		a, b := dispatchDistance(warehouse, free[i]), dispatchDistance(warehouse, free[j])
+		return a < b || (a == b && free[i].ID < free[j].ID)
+	})
+
+	type stockPicks struct {
+		StockID uint
+		Picks   int
+	}
+	var counted []stockPicks
+	if err := tx.Model(&model.StockMovement{}).Select("stock_id, COUNT(*) AS picks").
+		Where("account_id = ? AND reason = ? AND order_id IS NOT NULL AND bin_id IN ? AND created_at > ? AND created_at <= ?",
+			warehouse.AccountID, model.MovementShipment, binIDs, from, to).
+		Group("stock_id").Scan(&counted).Error; err != nil {
+		return nil, err
+	}
+	picks := map[uint]int{}
+	for _, row := range counted {
+		picks[row.StockID] = row.Picks
+	}
+
+	var serialized []uint
+	if err := tx.Model(&model.Product{}).Where("account_id = ? AND is_serialized = ?", warehouse.AccountID, true).
+		Pluck("id", &serialized).Error; err != nil {
+		return nil, err
+	}
+	skip := map[uint]bool{}
+	for _, productID := range serialized {
+		skip[productID] = true
+	}
+
+	sort.SliceStable(stocks, func(i, j int) bool {
+		a, b := picks[stocks[i].ID], picks[stocks[j].ID]
+		return a > b || (a == b && stocks[i].ID < stocks[j].ID)
+	})
+	recommendations := []model.SlottingRecommendation{}
+	for _, stock := range stocks {
+		if len(free) == 0 || picks[stock.ID] == 0 {
+			break
+		}
+		current := binsByID[*stock.BinID]
+		if current.GoldenZone || skip[stock.ProductID] || stock.Available() == 0 {
+			continue
+		}
+		fromDistance, toDistance := dispatchDistance(warehouse, current), dispatchDistance(warehouse, free[0])
+		if toDistance >= fromDistance {
+			continue
+		}
+
+		// Every pick is a walk from dispatch to the bin and back
+		recommendations = append(recommendations, model.SlottingRecommendation{
+			WarehouseID:    warehouse.ID,
+			StockID:        stock.ID,
+			ProductID:      stock.ProductID,
+			FromBinID:      current.ID,
+			ToBinID:        free[0].ID,
+			Quantity:       stock.Available(),
+			Picks:          picks[stock.ID],
+			FromDistance:   fromDistance,
+			ToDistance:     toDistance,
+			DistanceSaving: 2 * float64(picks[stock.ID]) * (fromDistance - toDistance),
+			Status:         model.SlottingPending,
+			AccountID:      warehouse.AccountID,
+		})
+		free = free[1:]
+	}
+
+	if len(recommendations) > 0 {
+		if err := tx.Create(&recommendations).Error; err != nil {
+			return nil, err
+		}
+	}
+	return recommendations, nil
+}
+
+// AcceptSlottingRecommendation turns a pending recommendation into a transfer of the
+// stock row's available quantity to the recommended bin
+func AcceptSlottingRecommendation(tx *gorm.DB, recommendation *model.SlottingRecommendation, userID *uint) error {
+	if recommendation.Status != model.SlottingPending {
+		return ErrRecommendationReviewed
+	}
+
+	var stock model.Stock
+	if err := tx.First(&stock, recommendation.StockID).Error; err != nil {
+		return err
+	}
+	if stock.BinID == nil || *stock.BinID != recommendation.FromBinID {
+		return ErrStaleRecommendation
+	}
+	var taken int64
+	if err := tx.Model(&model.Stock{}).Where("bin_id = ? AND quantity > 0", recommendation.ToBinID).Count(&taken).Error; err != nil {
+		return err
+	}
+	if taken > 0 {
+		return ErrStaleRecommendation
+	}
+
+	transfer := model.TransferOrder{
+		FromStockID: stock.ID,
+		ToBinID:     &recommendation.ToBinID,
+		Quantity:    stock.Available(),
+		Note:        fmt.Sprintf("Re-slotting recommendation %d", recommendation.ID),
+		CreatedBy:   userID,
+		AccountID:   recommendation.AccountID,
+	}
+	if err := CreateTransfer(tx, &transfer); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	recommendation.Status = model.SlottingAccepted
+	recommendation.TransferID = &transfer.ID
+	recommendation.Quantity = transfer.Quantity
+	recommendation.ReviewedBy = userID
+	recommendation.ReviewedAt = &now
+	return tx.Save(recommendation).Error
+}
+
+// DismissSlottingRecommendation closes a pending recommendation without moving stock
+func DismissSlottingRecommendation(tx *gorm.DB, recommendation *model.SlottingRecommendation, userID *uint) error {
+	if recommendation.Status != model.SlottingPending {
+		return ErrRecommendationReviewed
+	}
+
+	now := time.Now()
+	recommendation.Status = model.SlottingDismissed
+	recommendation.ReviewedBy = userID
+	recommendation.ReviewedAt = &now
+	return tx.Save(recommendation).Error
+}
+
+// dispatchDistance is the walking distance between the warehouse's dispatch area and a
+// bin. Warehouses are walked along aisles, so it is measured along the floor plan's axes.
+func dispatchDistance(warehouse model.Warehouse, bin model.Bin) float64 {
+	return math.Abs(bin.X-warehouse.DispatchX) + math.Abs(bin.Y-warehouse.DispatchY)
+}
@@ -0,0 +1,297 @@
+package utils
+
+import (
+	"errors"
+	"inventory-management/internal/model"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// StockGroups are the ways stock on hand at a point in time can be broken down
+var StockGroups = []string{"stock", "product", "location"}
+
+// SnapshotExportColumns are the columns of an exported stock snapshot
+var SnapshotExportColumns = []string{"snapshot_id", "taken_at", "stock_id", "product_id", "sku", "product_name", "lot_number", "bin_id", "location", "quantity"}
+
+// StockFilter narrows stock on hand to a product, a bin or a location
+type StockFilter struct {
+	ProductID *uint
+	BinID     *uint
+	Location  string
+}
+
+// stockBalance is the reconstructed quantity of a stock row and the bin it was in
+type stockBalance struct {
+	StockID   uint
+	ProductID uint
+	BinID     *uint
+	Quantity  int
+}
+
+// StockAsOf reconstructs an account's stock on hand at a point in time by stock row,
+// product or location. It starts from the latest snapshot taken at or before asOf and
+// replays the stock movements recorded after it, or the whole ledger without a snapshot.
+func StockAsOf(db *gorm.DB, accountID uint, asOf time.Time, groupBy string, filter StockFilter) (model.StockAsOfReport, error) {
+	report := model.StockAsOfReport{AsOf: asOf, GroupBy: groupBy, Positions: []model.StockPosition{}}
+	balances, snapshot, err := balancesAsOf(db, accountID, asOf)
+	if err != nil {
+		return report, err
+	}
+	if snapshot != nil {
+		report.SnapshotID = &snapshot.ID
+	}
+
+	stockIDs := make([]uint, 0, len(balances))
+	productIDs := make([]uint, 0, len(balances))
+	for _, balance := range balances {
+		stockIDs = append(stockIDs, balance.StockID)
+		productIDs = append(productIDs, balance.ProductID)
+	}
+	stocks := map[uint]model.Stock{}
+	var stockRows []model.Stock
+	if err := db.Unscoped().Select("id", "location", "lot_number").Where("id IN ?", stockIDs).Find(&stockRows).Error; err != nil {
+		return report, err
+	}
+	for _, stock := range stockRows {
+		stocks[stock.ID] = stock
+	}
+	products := map[uint]model.Product{}
+	var productRows []model.Product
+	if err := db.Unscoped().Select("id", "name", "sku").Where("id IN ?", productIDs).Find(&productRows).Error; err != nil {
+		return report, err
+	}
+	for _, product := range productRows {
+		products[product.ID] = product
+	}
+
+	positions := map[string]*model.StockPosition{}
+	for _, balance := range balances {
+		stock := stocks[balance.StockID]
+		if filter.ProductID != nil && balance.ProductID != *filter.ProductID {
+			continue
+		}
+		if filter.BinID != nil && (balance.BinID == nil || *balance.BinID != *filter.BinID) {
+			continue
+		}
+		if filter.Location != "" && stock.Location != filter.Location {
+			continue
+		}
+
+		var key string
+		position := model.StockPosition{}
+		switch groupBy {
+		case "stock":
+			key = strconv.FormatUint(uint64(balance.StockID), 10)
+			product := products[balance.ProductID]
+			position = model.StockPosition{
+				StockID: balance.StockID, ProductID: balance.ProductID, ProductName: product.Name, SKU: product.SKU,
+				LotNumber: stock.LotNumber, BinID: balance.BinID, Location: stock.Location,
+			}
+		case "product":
+			key = strconv.FormatUint(uint64(balance.ProductID), 10)
+			product := products[balance.ProductID]
+			position = model.StockPosition{ProductID: balance.ProductID, ProductName: product.Name, SKU: product.SKU}
+		case "location":
+			// Rows in a bin are grouped by bin, others by their free-text location
+			key = "location:" + stock.Location
+			if balance.BinID != nil {
+				key = "bin:" + strconv.FormatUint(uint64(*balance.BinID), 10)
+			}
+			position = model.StockPosition{BinID: balance.BinID, Location: stock.Location}
+		}
+		if positions[key] == nil {
+			positions[key] = &position
+		}
+		positions[key].Quantity += balance.Quantity
+	}
+
+	for _, position := range positions {
+		report.Positions = append(report.Positions, *position)
+		report.TotalQuantity += position.Quantity
+	}
+	sort.Slice(report.Positions, func(i, j int) bool {
+		a, b := report.Positions[i], report.Positions[j]
+		if groupBy == "location" {
+			return a.Location < b.Location || (a.Location == b.Location && binOrder(a.BinID) < binOrder(b.BinID))
+		}
+		return a.ProductID < b.ProductID || (a.ProductID == b.ProductID && a.StockID < b.StockID)
+	})
+	return report, nil
+}
+
+// TakeStockSnapshot records the account's stock on hand at a point in time. Taking a
+// snapshot that already exists returns it unchanged, so a missed or repeated scheduled
+// run can safely be retried; the bool reports whether a new snapshot was recorded.
+func TakeStockSnapshot(db *gorm.DB, accountID uint, at time.Time) (model.StockSnapshot, bool, error) {
+	snapshot := model.StockSnapshot{TakenAt: at, AccountID: accountID}
+	err := db.Where("account_id = ? AND taken_at = ?", accountID, at).First(&snapshot).Error
+	if err == nil {
+		return snapshot, false, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return snapshot, false, err
+	}
+
+	balances, _, err := balancesAsOf(db, accountID, at)
+	if err != nil {
+		return snapshot, false, err
+	}
+	var lines []model.StockSnapshotLine
+	for _, balance := range balances {
+		lines = append(lines, model.StockSnapshotLine{
+			StockID:   balance.StockID,
+			ProductID: balance.ProductID,
+			BinID:     balance.BinID,
+			Quantity:  balance.Quantity,
+			AccountID: accountID,
+		})
+		snapshot.TotalQuantity += balance.Quantity
+	}
+	sort.Slice(lines, func(i, j int) bool { return lines[i].StockID < lines[j].StockID })
+	snapshot.Lines = len(lines)
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&snapshot)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		if len(lines) == 0 {
+			return nil
+		}
+		for i := range lines {
+			lines[i].SnapshotID = snapshot.ID
+		}
+		return tx.CreateInBatches(lines, exportBatchSize).Error
+	})
+	if err != nil || snapshot.ID != 0 {
+		return snapshot, err == nil, err
+	}
+
+	// Another run recorded the same snapshot in the meantime
+	err = db.Where("account_id = ? AND taken_at = ?", accountID, at).First(&snapshot).Error
+	return snapshot, false, err
+}
+
+// TakeDailySnapshots records every account's stock on hand as of the last midnight
+func TakeDailySnapshots(db *gorm.DB, now time.Time) error {
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	var accountIDs []uint
+	if err := db.Model(&model.StockMovement{}).Where("created_at <= ?", midnight).
+		Distinct("account_id").Pluck("account_id", &accountIDs).Error; err != nil {
+		return err
+	}
+
+	var firstErr error
+	for _, accountID := range accountIDs {
+		if _, _, err := TakeStockSnapshot(db, accountID, midnight); err != nil {
+			log.Printf("Error taking stock snapshot of account %d: %v", accountID, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// ExportStockSnapshot streams the lines of a snapshot to emit in SnapshotExportColumns
+// order, loading them in batches
+func ExportStockSnapshot(db *gorm.DB, snapshot model.StockSnapshot, emit func([]interface{}) error) error {
+	type exportLine struct {
+		StockID     uint
+		ProductID   uint
+		SKU         string
+		ProductName string
+		LotNumber   string
+		BinID       *uint
+		Location    string
+		Quantity    int
+	}
+
+	for offset := 0; ; offset += exportBatchSize {
+		var lines []exportLine
+		if err := db.Table("stock_snapshot_lines").
+			Select("stock_snapshot_lines.stock_id, stock_snapshot_lines.product_id, products.sku, products.name AS product_name, "+
+				"stocks.lot_number, stock_snapshot_lines.bin_id, stocks.location, stock_snapshot_lines.quantity").
+			Joins("LEFT JOIN products ON products.id = stock_snapshot_lines.product_id").
+			Joins("LEFT JOIN stocks ON stocks.id = stock_snapshot_lines.stock_id").
+			Where("stock_snapshot_lines.snapshot_id = ?", snapshot.ID).
+			Order("stock_snapshot_lines.stock_id").Limit(exportBatchSize).Offset(offset).
+			Scan(&lines).Error; err != nil {
+			return err
+		}
+		for _, line := range lines {
+			if err := emit([]interface{}{
+				snapshot.ID, snapshot.TakenAt.Format(time.RFC3339), line.StockID, line.ProductID, line.SKU, line.ProductName,
+				line.LotNumber, line.BinID, line.Location, line.Quantity,
+			}); err != nil {
+				return err
+			}
+		}
+		if len(lines) < exportBatchSize {
+			return nil
+		}
+	}
+}
+
+// balancesAsOf reconstructs the quantity of every stock row of the account that held
+// stock at asOf, from the latest snapshot at or before asOf and the movements after it
+func balancesAsOf(db *gorm.DB, accountID uint, asOf time.Time) (map[uint]*stockBalance, *model.StockSnapshot, error) {
+	balances := map[uint]*stockBalance{}
+	movements := db.Model(&model.StockMovement{}).Where("account_id = ? AND created_at <= ?", accountID, asOf)
+
+	var snapshot *model.StockSnapshot
+	var latest model.StockSnapshot
+	err := db.Where("account_id = ? AND taken_at <= ?", accountID, asOf).Order("taken_at DESC").First(&latest).Error
+	switch {
+	case err == nil:
+		snapshot = &latest
+		var lines []model.StockSnapshotLine
+		if err := db.Where("snapshot_id = ?", latest.ID).Find(&lines).Error; err != nil {
+			return nil, nil, err
+		}
+		for _, line := range lines {
+			balances[line.StockID] = &stockBalance{StockID: line.StockID, ProductID: line.ProductID, BinID: line.BinID, Quantity: line.Quantity}
+		}
+		movements = movements.Where("created_at > ?", latest.TakenAt)
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, nil, err
+	}
+
+	var batch []model.StockMovement
+	err = movements.Select("id", "stock_id", "product_id", "bin_id", "delta").
+		FindInBatches(&batch, exportBatchSize, func(tx *gorm.DB, _ int) error {
+			for _, movement := range batch {
+				balance := balances[movement.StockID]
+				if balance == nil {
+					balance = &stockBalance{StockID: movement.StockID, ProductID: movement.ProductID}
+					balances[movement.StockID] = balance
+				}
+				balance.Quantity += movement.Delta
+				balance.BinID = movement.BinID
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for stockID, balance := range balances {
+		if balance.Quantity == 0 {
+			delete(balances, stockID)
+		}
+	}
+	return balances, snapshot, nil
+}
+
+// binOrder sorts positions without a bin first
+func binOrder(binID *uint) uint {
+	if binID == nil {
+		return 0
+	}
+	return *binID
+}
@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"inventory-management/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// RecordStockMovement appends a ledger entry for a change of stock.Quantity and books its
+// cost. The stock must already hold its new quantity; the movement template carries the
+// reason, the source order, shipment or user and, for receipts, the unit cost.
+func RecordStockMovement(tx *gorm.DB, stock model.Stock, delta int, movement model.StockMovement) error {
+	_, err := RecordCostedMovement(tx, stock, delta, movement)
+	return err
+}
+
+// RecordCostedMovement records a stock movement like RecordStockMovement and returns the
+// cost it booked: the value consumed by a decrease or added by an increase
+func RecordCostedMovement(tx *gorm.DB, stock model.Stock, delta int, movement model.StockMovement) (float64, error) {
+	if delta == 0 {
+		return 0, nil
+	}
+
+	movement.ID = 0
+	movement.StockID = stock.ID
+	movement.ProductID = stock.ProductID
+	movement.BinID = stock.BinID
+	movement.Delta = delta
+	movement.Balance = stock.Quantity
+	movement.AccountID = stock.AccountID
+	if err := tx.Create(&movement).Error; err != nil {
+		return 0, err
+	}
+	return recordCost(tx, stock, delta, movement)
+}
+
+// LedgerBalance replays the movements of a stock row and returns the resulting quantity
+func LedgerBalance(db *gorm.DB, stockID uint) (int, error) {
+	var balance int
+	err := db.Model(&model.StockMovement{}).Where("stock_id = ?", stockID).
+		Select("COALESCE(SUM(delta), 0)").Scan(&balance).Error
+	return balance, err
+}
+
+// RecordOpeningBalances writes an opening balance movement for every stock row that has
+// quantity but no ledger history yet, so that replaying the ledger matches the stock
+// rows that existed before the ledger was introduced.
+func RecordOpeningBalances(db *gorm.DB) error {
+	var stocks []model.Stock
+	if err := db.Where("quantity > 0 AND id NOT IN (?)", db.Model(&model.StockMovement{}).Select("stock_id")).
+		Find(&stocks).Error; err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, stock := range stocks {
+			if err := RecordStockMovement(tx, stock, int(stock.Quantity), model.StockMovement{Reason: model.MovementOpeningBalance}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,161 @@
+package utils
+
+import (
+	"errors"
+	"inventory-management/internal/model"
+	"sort"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrUnknownStatus is returned for a stock status that is not one of model.InventoryStatuses
+	ErrUnknownStatus = errors.New("status must be available, quarantine, damaged or qc_hold")
+	// ErrInsufficientStatusQuantity is returned when a status change moves more than the source status holds
+	ErrInsufficientStatusQuantity = errors.New("not enough quantity in the source status")
+)
+
+// HeldStockGroups are the ways held stock can be broken down
+var HeldStockGroups = []string{"stock", "product", "status"}
+
+// HeldStockColumns are the columns of an exported held stock report
+var HeldStockColumns = []string{"stock_id", "product_id", "sku", "product_name", "lot_number", "bin_id", "location", "status", "quantity", "value", "held_since"}
+
+// availableQuantitySQL is the SQL counterpart of model.Stock.Available, meaningful where it is positive
+const availableQuantitySQL = "quantity - reserved_quantity - quarantine_quantity - damaged_quantity - qc_hold_quantity"
+
+// ChangeStockStatus moves quantity of a stock row between statuses and records the change.
+// Only unreserved available units can be put on hold.
+func ChangeStockStatus(tx *gorm.DB, change *model.StockStatusChange) error {
+	var stock model.Stock
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&stock, change.StockID).Error; err != nil {
+		return err
+	}
+
+	if from := stock.HeldQuantity(change.FromStatus); from != nil {
+		if *from < change.Quantity {
+			return ErrInsufficientStatusQuantity
+		}
+		*from -= change.Quantity
+	} else if stock.Available() < change.Quantity {
+		return ErrInsufficientStatusQuantity
+	}
+	if to := stock.HeldQuantity(change.ToStatus); to != nil {
+		*to += change.Quantity
+	}
+
+	if err := tx.Model(&stock).Updates(map[string]interface{}{
+		"quarantine_quantity": stock.QuarantineQuantity,
+		"damaged_quantity":    stock.DamagedQuantity,
+		"qc_hold_quantity":    stock.QCHoldQuantity,
+	}).Error; err != nil {
+		return err
+	}
+
+	change.ProductID = stock.ProductID
+	change.AccountID = stock.AccountID
+	return tx.Create(change).Error
+}
+
+// HeldStockReport lists the account's quantity on hold by stock row, product or status,
+// valued at the products' current cost. Rows can be narrowed to a product or a status.
+func HeldStockReport(db *gorm.DB, accountID uint, groupBy string, productID *uint, status model.InventoryStatus) (model.HeldStockReport, error) {
+	report := model.HeldStockReport{GroupBy: groupBy, Lines: []model.HeldStock{}}
+
+	query := db.Preload("Product").Where("account_id = ? AND quarantine_quantity + damaged_quantity + qc_hold_quantity > 0", accountID)
+	if productID != nil {
+		query = query.Where("product_id = ?", *productID)
+	}
+	var stocks []model.Stock
+	if err := query.Order("id").Find(&stocks).Error; err != nil {
+		return report, err
+	}
+
+	// When each row last had quantity moved into each status
+	stockIDs := make([]uint, 0, len(stocks))
+	for _, stock := range stocks {
+		stockIDs = append(stockIDs, stock.ID)
+	}
+	var changes []model.StockStatusChange
+	if err := db.Select("stock_id", "to_status", "created_at").Where("stock_id IN ?", stockIDs).
+		Order("created_at").Find(&changes).Error; err != nil {
+		return report, err
+	}
+	since := map[string]time.Time{}
+	for _, change := range changes {
+		since[strconv.FormatUint(uint64(change.StockID), 10)+":"+string(change.ToStatus)] = change.CreatedAt
+	}
+
+	costs := map[uint]float64{}
+	lines := map[string]*model.HeldStock{}
+	for _, stock := range stocks {
+		if _, ok := costs[stock.ProductID]; !ok {
+			cost, err := CurrentUnitCost(db, stock.ProductID)
+			if err != nil {
+				return report, err
+			}
+			costs[stock.ProductID] = cost
+		}
+
+		for _, held := range model.InventoryStatuses[1:] {
+			quantity := *stock.HeldQuantity(held)
+			if quantity == 0 || (status != "" && held != status) {
+				continue
+			}
+
+			var key string
+			line := model.HeldStock{Status: held}
+			switch groupBy {
+			case "stock":
+				key = strconv.FormatUint(uint64(stock.ID), 10) + ":" + string(held)
+				line = model.HeldStock{
+					StockID: stock.ID, ProductID: stock.ProductID, ProductName: stock.Product.Name, SKU: stock.Product.SKU,
+					LotNumber: stock.LotNumber, BinID: stock.BinID, Location: stock.Location, Status: held,
+				}
+				if at, ok := since[key]; ok {
+					line.HeldSince = &at
+				}
+			case "product":
+				key = strconv.FormatUint(uint64(stock.ProductID), 10) + ":" + string(held)
+				line = model.HeldStock{ProductID: stock.ProductID, ProductName: stock.Product.Name, SKU: stock.Product.SKU, Status: held}
+			case "status":
+				key = string(held)
+			}
+			if lines[key] == nil {
+				lines[key] = &line
+			}
+			lines[key].Quantity += quantity
+			lines[key].Value += float64(quantity) * costs[stock.ProductID]
+		}
+	}
+
+	for _, line := range lines {
+		report.Lines = append(report.Lines, *line)
+		report.TotalQuantity += line.Quantity
+		report.TotalValue += line.Value
+	}
+	sort.Slice(report.Lines, func(i, j int) bool {
+		a, b := report.Lines[i], report.Lines[j]
+		if a.ProductID != b.ProductID {
+			return a.ProductID < b.ProductID
+		}
+		if a.StockID != b.StockID {
+			return a.StockID < b.StockID
+		}
+		return statusOrder(a.Status) < statusOrder(b.Status)
+	})
+	return report, nil
+}
+
+// statusOrder sorts statuses in the order they are declared
+func statusOrder(status model.InventoryStatus) int {
+	for i, candidate := range model.InventoryStatuses {
+		if candidate == status {
+			return i
+		}
+	}
+	return len(model.InventoryStatuses)
+}
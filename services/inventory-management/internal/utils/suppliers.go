@@ -0,0 +1,205 @@
+package utils
+
+import (
+	"errors"
+	"inventory-management/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrOverlappingPrice is returned when a price list entry overlaps another one for the
+	// same supplier, product and quantity break
+	ErrOverlappingPrice = errors.New("price overlaps an existing entry for the same product and quantity break")
+	// ErrInvalidPricePeriod is returned when a price list entry ends before it starts
+	ErrInvalidPricePeriod = errors.New("effective_to must be after effective_from")
+)
+
+// ValidateSupplierPrice checks that a price list entry has a valid period and does not
+// overlap another entry of the same supplier, product and quantity break
+func ValidateSupplierPrice(db *gorm.DB, price model.SupplierPrice) error {
+	if price.EffectiveTo != nil && !price.EffectiveTo.After(price.EffectiveFrom) {
+		return ErrInvalidPricePeriod
+	}
+
+	query := db.Model(&model.SupplierPrice{}).
+		Where("supplier_id = ? AND product_id = ? AND min_quantity = ? AND id <> ?",
+			price.SupplierID, price.ProductID, price.MinQuantity, price.ID).
+		Where("effective_to IS NULL OR effective_to > ?", price.EffectiveFrom)
+	if price.EffectiveTo != nil {
+		query = query.Where("effective_from < ?", *price.EffectiveTo)
+	}
+	var overlapping int64
+	if err := query.Count(&overlapping).Error; err != nil {
+		return err
+	}
+	if overlapping > 0 {
+		return ErrOverlappingPrice
+	}
+	return nil
+}
+
+// EffectiveSupplierPrice returns the price list entry of a supplier for a product that is
+// in effect at the given time for the ordered quantity, or nil when there is none
+func EffectiveSupplierPrice(db *gorm.DB, supplierID, productID, quantity uint, at time.Time) (*model.SupplierPrice, error) {
+	var price model.SupplierPrice
+	err := db.Where("supplier_id = ? AND product_id = ? AND min_quantity <= ?", supplierID, productID, quantity).
+		Where("effective_from <= ? AND (effective_to IS NULL OR effective_to > ?)", at, at).
+		Order("min_quantity DESC, effective_from DESC").First(&price).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &price, nil
+}
+
+// QuotedLeadTime returns the lead time the supplier quoted for a purchase order: the
+// longest lead time of its lines, falling back to the supplier's default lead time for
+// products without a price list entry
+func QuotedLeadTime(db *gorm.DB, order model.PurchaseOrder, at time.Time) (uint, error) {
+	var supplier model.Supplier
+	if err := db.Unscoped().First(&supplier, order.SupplierID).Error; err != nil {
+		return 0, err
+	}
+
+	var leadTime uint
+	for _, line := range order.Lines {
+		lineLeadTime, err := supplierLeadTime(db, supplier, line.ProductID, line.Quantity, at)
+		if err != nil {
+			return 0, err
+		}
+		if lineLeadTime > leadTime {
+			leadTime = lineLeadTime
+		}
+	}
+	return leadTime, nil
+}
+
+// ProductLeadTime returns the lead time quoted for reordering a product from the supplier
+// it is replenished from, or zero when the product has no supplier
+func ProductLeadTime(db *gorm.DB, product model.Product, at time.Time) (uint, error) {
+	supplierID := product.ReplenishmentSupplierID()
+	if supplierID == 0 {
+		return 0, nil
+	}
+	var supplier model.Supplier
+	if err := db.Unscoped().First(&supplier, supplierID).Error; err != nil {
+		return 0, err
+	}
+	return supplierLeadTime(db, supplier, product.ID, max(product.ReorderQuantity, 1), at)
+}
+
+// supplierLeadTime returns the lead time of the supplier's price list entry for the
+// product and quantity, or the supplier's default lead time without one
+func supplierLeadTime(db *gorm.DB, supplier model.Supplier, productID, quantity uint, at time.Time) (uint, error) {
+	price, err := EffectiveSupplierPrice(db, supplier.ID, productID, quantity, at)
+	if err != nil {
+		return 0, err
+	}
+	if price != nil && price.LeadTimeDays > 0 {
+		return price.LeadTimeDays, nil
+	}
+	return supplier.LeadTimeDays, nil
+}
+
+// SupplierScorecards scores the deliveries of an account's suppliers, or of one supplier
+// when supplierID is set, on the purchase orders sent between from and to
+func SupplierScorecards(db *gorm.DB, accountID uint, supplierID *uint, from, to *time.Time) ([]model.SupplierScorecard, error) {
+	supplierQuery := db.Where("account_id = ?", accountID)
+	if supplierID != nil {
+		supplierQuery = supplierQuery.Where("id = ?", *supplierID)
+	}
+	var suppliers []model.Supplier
+	if err := supplierQuery.Order("id").Find(&suppliers).Error; err != nil {
+		return nil, err
+	}
+
+	scorecards := make([]model.SupplierScorecard, 0, len(suppliers))
+	for _, supplier := range suppliers {
+		scorecard, err := supplierScorecard(db, supplier, from, to)
+		if err != nil {
+			return nil, err
+		}
+		scorecards = append(scorecards, scorecard)
+	}
+	return scorecards, nil
+}
+
+func supplierScorecard(db *gorm.DB, supplier model.Supplier, from, to *time.Time) (model.SupplierScorecard, error) {
+	scorecard := model.SupplierScorecard{
+		SupplierID:         supplier.ID,
+		SupplierName:       supplier.Name,
+		QuotedLeadTimeDays: supplier.LeadTimeDays,
+	}
+
+	query := db.Preload("Lines").
+		Where("supplier_id = ? AND account_id = ? AND sent_at IS NOT NULL AND status IN ?", supplier.ID, supplier.AccountID,
+			[]model.PurchaseOrderStatus{model.PurchaseOrderPartiallyReceived, model.PurchaseOrderClosed})
+	if from != nil {
+		query = query.Where("sent_at >= ?", *from)
+	}
+	if to != nil {
+		query = query.Where("sent_at <= ?", *to)
+	}
+	var orders []model.PurchaseOrder
+	if err := query.Find(&orders).Error; err != nil {
+		return scorecard, err
+	}
+
+	var leadTime, variance float64
+	for _, order := range orders {
+		var first, last model.PurchaseOrderReceipt
+		if err := db.Where("purchase_order_id = ?", order.ID).Order("received_at").First(&first).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			return scorecard, err
+		}
+		if err := db.Where("purchase_order_id = ?", order.ID).Order("received_at DESC").First(&last).Error; err != nil {
+			return scorecard, err
+		}
+
+		scorecard.ReceivedOrders++
+		days := first.ReceivedAt.Sub(*order.SentAt).Hours() / 24
+		leadTime += days
+		variance += days - float64(order.LeadTimeDays)
+
+		if order.Status != model.PurchaseOrderClosed {
+			continue
+		}
+		scorecard.ClosedOrders++
+		for _, line := range order.Lines {
+			scorecard.OrderedQuantity += line.Quantity
+			scorecard.ReceivedQuantity += min(line.ReceivedQuantity, line.Quantity)
+		}
+		// Orders sent without an expected date are due after the quoted lead time
+		due := order.SentAt.AddDate(0, 0, int(order.LeadTimeDays))
+		if order.ExpectedAt != nil {
+			due = *order.ExpectedAt
+		}
+		if !last.ReceivedAt.After(endOfDay(due)) {
+			scorecard.OnTimeOrders++
+		}
+	}
+
+	if scorecard.ReceivedOrders > 0 {
+		scorecard.AverageLeadTimeDays = leadTime / float64(scorecard.ReceivedOrders)
+		scorecard.AverageLeadTimeVarianceDays = variance / float64(scorecard.ReceivedOrders)
+	}
+	if scorecard.ClosedOrders > 0 {
+		scorecard.OnTimeRate = float64(scorecard.OnTimeOrders) / float64(scorecard.ClosedOrders)
+	}
+	if scorecard.OrderedQuantity > 0 {
+		scorecard.FillRate = float64(scorecard.ReceivedQuantity) / float64(scorecard.OrderedQuantity)
+	}
+	return scorecard, nil
+}
+
+// endOfDay returns the last instant of the day of t, so deliveries on the expected date count as on time
+func endOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day+1, 0, 0, 0, 0, t.Location()).Add(-time.Nanosecond)
+}
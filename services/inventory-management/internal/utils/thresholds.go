@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"inventory-management/internal/model"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// safetyStockWindow is how far back shipments are counted to split a safety stock
+const safetyStockWindow = 90 * 24 * time.Hour
+
+// SplitSafetyStock sets the low-stock threshold of each of a product's stock rows to its
+// share of the product-wide safety stock, in proportion to what the row shipped over the
+// last 90 days, or evenly when nothing shipped. Shares are rounded so the thresholds add
+// up to the safety stock.
+func SplitSafetyStock(tx *gorm.DB, product model.Product, safetyStock uint, now time.Time) error {
+	var stocks []model.Stock
+	if err := tx.Where("product_id = ? AND account_id = ?", product.ID, product.AccountID).
+		Order("id").Find(&stocks).Error; err != nil {
+		return err
+	}
+	if len(stocks) == 0 {
+		return nil
+	}
+
+	type stockDemand struct {
+		StockID uint
+		Shipped int
+	}
+	var shipped []stockDemand
+	if err := tx.Model(&model.StockMovement{}).Select("stock_id, -SUM(delta) AS shipped").
+		Where("product_id = ? AND account_id = ? AND reason = ? AND created_at > ?",
+			product.ID, product.AccountID, model.MovementShipment, now.Add(-safetyStockWindow)).
+		Group("stock_id").Scan(&shipped).Error; err != nil {
+		return err
+	}
+	demand := map[uint]int{}
+	for _, row := range shipped {
+		demand[row.StockID] = row.Shipped
+	}
+
+	weights := make([]int, len(stocks))
+	total := 0
+	for i, stock := range stocks {
+		weights[i] = max(demand[stock.ID], 0)
+		total += weights[i]
+	}
+	if total == 0 {
+		for i := range weights {
+			weights[i] = 1
+		}
+		total = len(stocks)
+	}
+
+	// Largest remainder: floor every share, then hand out what is left to the rows that
+	// lost the most to rounding
+	thresholds := make([]int, len(stocks))
+	remainders := make([]int, len(stocks))
+	assigned := 0
+	for i, weight := range weights {
+		share := int(safetyStock) * weight
+		thresholds[i] = share / total
+		remainders[i] = share % total
+		assigned += thresholds[i]
+	}
+	order := make([]int, len(stocks))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return remainders[order[a]] > remainders[order[b]]
+	})
+	for _, i := range order[:int(safetyStock)-assigned] {
+		thresholds[i]++
+	}
+
+	for i, stock := range stocks {
+		if err := tx.Model(&stock).Update("low_stock_threshold", thresholds[i]).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif" // Register the decoders thumbnails are made from
+	"image/jpeg"
+	_ "image/png"
+)
+
+// ThumbnailSize is the longest side of a thumbnail in pixels
+const ThumbnailSize = 256
+
+// maxThumbnailPixels bounds the images decoded for a thumbnail, so a small file that
+// decompresses into a huge bitmap cannot exhaust memory
+const maxThumbnailPixels = 40_000_000
+
+// MakeThumbnail scales a JPEG, PNG or GIF image down to fit ThumbnailSize and encodes it
+// as JPEG, transparent areas on white. It returns no thumbnail for other formats and for
+// images too large to decode, and an error for image data that cannot be decoded.
+func MakeThumbnail(data []byte) ([]byte, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if errors.Is(err, image.ErrFormat) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if format == "" || config.Width*config.Height > maxThumbnailPixels {
+		return nil, nil
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width == 0 || height == 0 {
+		return nil, errors.New("image is empty")
+	}
+	scale := min(1, float64(ThumbnailSize)/float64(max(width, height)))
+	thumbWidth, thumbHeight := max(1, int(float64(width)*scale)), max(1, int(float64(height)*scale))
+
+	// Flatten onto white so every source format is averaged the same way
+	flat := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(flat, flat.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), src, bounds.Min, draw.Over)
+
+	// Each thumbnail pixel is the average of the source pixels it covers
+	thumb := image.NewRGBA(image.Rect(0, 0, thumbWidth, thumbHeight))
+	for y := 0; y < thumbHeight; y++ {
+		y0, y1 := y*height/thumbHeight, max((y+1)*height/thumbHeight, y*height/thumbHeight+1)
+		for x := 0; x < thumbWidth; x++ {
+			x0, x1 := x*width/thumbWidth, max((x+1)*width/thumbWidth, x*width/thumbWidth+1)
+			var r, g, b, n int
+			for sy := y0; sy < y1; sy++ {
+				row := flat.Pix[sy*flat.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					r += int(row[sx*4])
+					g += int(row[sx*4+1])
+					b += int(row[sx*4+2])
+					n++
+				}
+			}
+			thumb.SetRGBA(x, y, color.RGBA{R: uint8(r / n), G: uint8(g / n), B: uint8(b / n), A: 255})
+		}
+	}
+
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, thumb, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, err
+	}
+	return encoded.Bytes(), nil
+}
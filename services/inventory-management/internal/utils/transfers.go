@@ -0,0 +1,309 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"inventory-management/internal/model"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrInvalidTransferState is returned when a transfer action does not fit its current status
+	ErrInvalidTransferState = errors.New("transfer is not in a state that allows this action")
+	// ErrReceiveExceedsTransfer is returned when more is received than is still in transit
+	ErrReceiveExceedsTransfer = errors.New("received quantity exceeds the quantity in transit")
+)
+
+// CreateTransfer holds the transfer quantity at the source stock row so it cannot be
+// allocated to orders while it waits to be picked. Serialized products must name the
+// serials that are transferred.
+func CreateTransfer(tx *gorm.DB, transfer *model.TransferOrder) error {
+	var source model.Stock
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&source, transfer.FromStockID).Error; err != nil {
+		return err
+	}
+	if source.Available() < transfer.Quantity {
+		return ErrInsufficientStock
+	}
+
+	serialized, err := isSerialized(tx, source.ProductID)
+	if err != nil {
+		return err
+	}
+	if serialized {
+		if len(transfer.SerialNumbers) != int(transfer.Quantity) {
+			return ErrSerialCountMismatch
+		}
+		var held int64
+		if err := tx.Model(&model.SerialNumber{}).Where("serial IN ? AND stock_id = ? AND status = ?",
+			transfer.SerialNumbers, source.ID, model.SerialInStock).Count(&held).Error; err != nil {
+			return err
+		}
+		if held != int64(len(transfer.SerialNumbers)) {
+			return ErrSerialNotFound
+		}
+	} else {
+		transfer.SerialNumbers = nil
+	}
+
+	source.ReservedQuantity += transfer.Quantity
+	if err := tx.Model(&source).Update("reserved_quantity", source.ReservedQuantity).Error; err != nil {
+		return err
+	}
+
+	transfer.ProductID = source.ProductID
+	transfer.Status = model.TransferPending
+	return tx.Create(transfer).Error
+}
+
+// PickTransfer takes the transfer quantity out of the source stock row. From here on it
+// is counted as in transit rather than as part of any stock row. A source row that no
+// longer holds the whole quantity cannot be picked short.
+func PickTransfer(tx *gorm.DB, transfer *model.TransferOrder, userID *uint) error {
+	if transfer.Status != model.TransferPending {
+		return ErrInvalidTransferState
+	}
+
+	var source model.Stock
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&source, transfer.FromStockID).Error; err != nil {
+		return err
+	}
+	if source.Quantity < transfer.Quantity {
+		return ErrInsufficientStock
+	}
+	source.Quantity -= transfer.Quantity
+	source.ReservedQuantity -= min(source.ReservedQuantity, transfer.Quantity)
+	if err := tx.Model(&source).Updates(map[string]interface{}{
+		"quantity":          source.Quantity,
+		"reserved_quantity": source.ReservedQuantity,
+	}).Error; err != nil {
+		return err
+	}
+	if err := RecordStockMovement(tx, source, -int(transfer.Quantity), transferMovement(transfer, userID)); err != nil {
+		return err
+	}
+
+	if err := moveTransferSerials(tx, transfer, transfer.SerialNumbers, model.SerialInStock, model.SerialInTransit,
+		nil, model.SerialEvent{FromStockID: &source.ID, UserID: userID}); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	transfer.Status = model.TransferPicked
+	transfer.PickedAt = &now
+	return tx.Save(transfer).Error
+}
+
+// DispatchTransfer marks a picked transfer as having left the source location
+func DispatchTransfer(tx *gorm.DB, transfer *model.TransferOrder) error {
+	if transfer.Status != model.TransferPicked {
+		return ErrInvalidTransferState
+	}
+
+	now := time.Now()
+	transfer.Status = model.TransferInTransit
+	transfer.DispatchedAt = &now
+	return tx.Save(transfer).Error
+}
+
+// ReceiveTransfer books quantity that arrived into the destination stock row, creating
+// the row with the source's lot details when the transfer targets a bin. Partial
+// receipts leave the rest in transit.
+func ReceiveTransfer(tx *gorm.DB, transfer *model.TransferOrder, quantity uint, serials []string, userID *uint) error {
+	if transfer.InTransit() == 0 {
+		return ErrInvalidTransferState
+	}
+	if quantity == 0 || quantity > transfer.InTransit() {
+		return ErrReceiveExceedsTransfer
+	}
+	if transfer.SerialNumbers != nil && len(serials) != int(quantity) {
+		return ErrSerialCountMismatch
+	}
+
+	destination, err := transferDestination(tx, transfer)
+	if err != nil {
+		return err
+	}
+	destination.Quantity += quantity
+	if err := tx.Model(&destination).Update("quantity", destination.Quantity).Error; err != nil {
+		return err
+	}
+	if err := RecordStockMovement(tx, destination, int(quantity), transferMovement(transfer, userID)); err != nil {
+		return err
+	}
+
+	if transfer.SerialNumbers != nil {
+		if err := moveTransferSerials(tx, transfer, serials, model.SerialInTransit, model.SerialInStock,
+			&destination.ID, model.SerialEvent{ToStockID: &destination.ID, UserID: userID}); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	transfer.ToStockID = &destination.ID
+	transfer.ReceivedQuantity += quantity
+	transfer.ReceivedAt = &now
+	transfer.Status = model.TransferPartiallyReceived
+	if transfer.ReceivedQuantity == transfer.Quantity {
+		transfer.Status = model.TransferReceived
+	}
+	return tx.Save(transfer).Error
+}
+
+// CancelTransfer releases the hold of a pending transfer, or returns a picked transfer
+// that has not left the building to its source stock row
+func CancelTransfer(tx *gorm.DB, transfer *model.TransferOrder, userID *uint) error {
+	var source model.Stock
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&source, transfer.FromStockID).Error; err != nil {
+		return err
+	}
+
+	switch transfer.Status {
+	case model.TransferPending:
+		source.ReservedQuantity -= min(source.ReservedQuantity, transfer.Quantity)
+		if err := tx.Model(&source).Update("reserved_quantity", source.ReservedQuantity).Error; err != nil {
+			return err
+		}
+	case model.TransferPicked:
+		source.Quantity += transfer.Quantity
+		if err := tx.Model(&source).Update("quantity", source.Quantity).Error; err != nil {
+			return err
+		}
+		if err := RecordStockMovement(tx, source, int(transfer.Quantity), transferMovement(transfer, userID)); err != nil {
+			return err
+		}
+		if err := moveTransferSerials(tx, transfer, transfer.SerialNumbers, model.SerialInTransit, model.SerialInStock,
+			&source.ID, model.SerialEvent{ToStockID: &source.ID, UserID: userID}); err != nil {
+			return err
+		}
+	default:
+		return ErrInvalidTransferState
+	}
+
+	transfer.Status = model.TransferCancelled
+	return tx.Save(transfer).Error
+}
+
+// CloseTransfer ends a transfer whose remaining quantity will not arrive. The missing
+// units already left the source when they were picked, so only serials still in transit
+// are written off.
+func CloseTransfer(tx *gorm.DB, transfer *model.TransferOrder, userID *uint) error {
+	if transfer.Status != model.TransferInTransit && transfer.Status != model.TransferPartiallyReceived {
+		return ErrInvalidTransferState
+	}
+
+	if err := moveTransferSerials(tx, transfer, transfer.SerialNumbers, model.SerialInTransit, model.SerialRemoved,
+		nil, model.SerialEvent{Event: model.SerialEventRemoved, UserID: userID}); err != nil {
+		return err
+	}
+
+	if short := transfer.Quantity - transfer.ReceivedQuantity; short > 0 {
+		transfer.Note = strings.TrimSpace(fmt.Sprintf("%s Closed %d units short.", transfer.Note, short))
+	}
+	transfer.Status = model.TransferClosed
+	return tx.Save(transfer).Error
+}
+
+// transferDestination locks the stock row a transfer is received into. Transfers to a
+// bin reuse a row of the same product and lot in that bin or create one.
+func transferDestination(tx *gorm.DB, transfer *model.TransferOrder) (model.Stock, error) {
+	var destination model.Stock
+	if transfer.ToStockID != nil {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&destination, *transfer.ToStockID).Error
+		return destination, err
+	}
+
+	var source model.Stock
+	if err := tx.Unscoped().First(&source, transfer.FromStockID).Error; err != nil {
+		return destination, err
+	}
+
+	query := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("product_id = ? AND bin_id = ? AND lot_number = ? AND account_id = ?",
+			source.ProductID, *transfer.ToBinID, source.LotNumber, source.AccountID)
+	if source.ExpiresAt == nil {
+		query = query.Where("expires_at IS NULL")
+	} else {
+		query = query.Where("expires_at = ?", *source.ExpiresAt)
+	}
+	err := query.First(&destination).Error
+	if err == nil || !errors.Is(err, gorm.ErrRecordNotFound) {
+		return destination, err
+	}
+
+	var bin model.Bin
+	if err := tx.Preload("Zone.Warehouse").First(&bin, *transfer.ToBinID).Error; err != nil {
+		return destination, err
+	}
+	destination = model.Stock{
+		ProductID:         source.ProductID,
+		LotNumber:         source.LotNumber,
+		ManufacturedAt:    source.ManufacturedAt,
+		ExpiresAt:         source.ExpiresAt,
+		Location:          bin.Label(),
+		BinID:             &bin.ID,
+		AccountID:         source.AccountID,
+		LowStockThreshold: source.LowStockThreshold,
+	}
+	return destination, tx.Create(&destination).Error
+}
+
+// moveTransferSerials changes the status of a transfer's serials and records the move.
+// A nil stockID keeps the serial pointing at its last stock row.
+func moveTransferSerials(tx *gorm.DB, transfer *model.TransferOrder, serials []string, from, to model.SerialStatus, stockID *uint, event model.SerialEvent) error {
+	if len(serials) == 0 {
+		return nil
+	}
+
+	allowed := make(map[string]bool, len(transfer.SerialNumbers))
+	for _, serial := range transfer.SerialNumbers {
+		allowed[serial] = true
+	}
+
+	var serialNumbers []model.SerialNumber
+	if err := tx.Where("serial IN ? AND product_id = ? AND account_id = ? AND status = ?",
+		serials, transfer.ProductID, transfer.AccountID, from).Find(&serialNumbers).Error; err != nil {
+		return err
+	}
+	for _, serialNumber := range serialNumbers {
+		if !allowed[serialNumber.Serial] {
+			return ErrSerialNotFound
+		}
+	}
+	if len(serialNumbers) != len(serials) && to != model.SerialRemoved {
+		return ErrSerialNotFound
+	}
+
+	if event.Event == "" {
+		event.Event = model.SerialEventMoved
+	}
+	for _, serialNumber := range serialNumbers {
+		if stockID != nil {
+			serialNumber.StockID = stockID
+		}
+		if err := changeSerial(tx, serialNumber, to, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func transferMovement(transfer *model.TransferOrder, userID *uint) model.StockMovement {
+	return model.StockMovement{
+		Reason: model.MovementTransfer,
+		UserID: userID,
+		Note:   fmt.Sprintf("Transfer order %d", transfer.ID),
+	}
+}
+
+func isSerialized(tx *gorm.DB, productID uint) (bool, error) {
+	var product model.Product
+	if err := tx.Unscoped().Select("is_serialized").First(&product, productID).Error; err != nil {
+		return false, err
+	}
+	return product.IsSerialized, nil
+}
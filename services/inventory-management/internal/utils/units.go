@@ -0,0 +1,25 @@
+package utils
+
+import (
+	"errors"
+	"inventory-management/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// ErrUnknownUnit is returned when a quantity is given in a unit the product does not define
+var ErrUnknownUnit = errors.New("unknown unit of measure")
+
+// ToBaseQuantity converts a quantity counted in unit into the product's base unit
+func ToBaseQuantity(db *gorm.DB, productID uint, unit string, quantity uint) (uint, error) {
+	var product model.Product
+	if err := db.Preload("Units").First(&product, productID).Error; err != nil {
+		return 0, err
+	}
+
+	factor, ok := product.UnitFactor(unit)
+	if !ok {
+		return 0, ErrUnknownUnit
+	}
+	return quantity * factor, nil
+}
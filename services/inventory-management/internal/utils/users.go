@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"inventory-management/internal/model"
+	"net/http"
+	"os"
+)
+
+// FetchUser retrieves a user from the user-management service, forwarding the caller's token
+func FetchUser(token string, userID uint) (model.User, error) {
+	var user model.User
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/users/%d", os.Getenv("USER_SERVICE_URL"), userID), nil)
+	if err != nil {
+		return user, err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return user, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return user, fmt.Errorf("failed to fetch user details, status code: %d", resp.StatusCode)
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(&user)
+	return user, err
+}
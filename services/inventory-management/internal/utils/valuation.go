@@ -0,0 +1,249 @@
+package utils
+
+import (
+	"fmt"
+	"inventory-management/internal/model"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ValuationGroups are the ways a valuation report can be broken down
+var ValuationGroups = []string{"product", "category", "warehouse"}
+
+// productValue is the on-hand quantity and value of a product from its cost history
+type productValue struct {
+	ProductID uint
+	Quantity  int
+	Value     float64
+}
+
+// Valuation reports the on-hand quantity and value of an account's stock as of a point in
+// time, by product, category or warehouse. Values come from the cost layers and
+// consumptions recorded up to asOf. Warehouses share a product's value in proportion to
+// the quantity the stock ledger places there, since costs are pooled per product.
+func Valuation(db *gorm.DB, accountID uint, asOf time.Time, groupBy string) ([]model.ValuationLine, error) {
+	values, err := productValues(db, accountID, asOf)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []model.ValuationLine
+	switch groupBy {
+	case "product":
+		lines, err = valuationByProduct(db, values)
+	case "category":
+		lines, err = valuationByCategory(db, values)
+	case "warehouse":
+		lines, err = valuationByWarehouse(db, accountID, asOf, values)
+	default:
+		return nil, fmt.Errorf("unknown valuation group %q", groupBy)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(lines, func(i, j int) bool { return lines[i].ID < lines[j].ID })
+	return lines, nil
+}
+
+// OrderCOGS returns the cost of the goods shipped per order, optionally for one order or
+// for the shipments in a period
+func OrderCOGS(db *gorm.DB, accountID uint, orderID *uint, from, to *time.Time) ([]model.OrderCOGS, error) {
+	query := db.Model(&model.CostConsumption{}).
+		Where("account_id = ? AND reason = ? AND order_id IS NOT NULL", accountID, model.MovementShipment)
+	if orderID != nil {
+		query = query.Where("order_id = ?", *orderID)
+	}
+	if from != nil {
+		query = query.Where("created_at >= ?", *from)
+	}
+	if to != nil {
+		query = query.Where("created_at <= ?", *to)
+	}
+
+	var consumptions []model.CostConsumption
+	if err := query.Order("order_id, created_at").Find(&consumptions).Error; err != nil {
+		return nil, err
+	}
+
+	orders := []model.OrderCOGS{}
+	for _, consumption := range consumptions {
+		if len(orders) == 0 || orders[len(orders)-1].OrderID != *consumption.OrderID {
+			orders = append(orders, model.OrderCOGS{OrderID: *consumption.OrderID})
+		}
+		order := &orders[len(orders)-1]
+		order.Quantity += consumption.Quantity
+		order.Amount += consumption.Amount
+		order.ShippedAt = consumption.CreatedAt
+	}
+	return orders, nil
+}
+
+// productValues replays the cost layers and consumptions of an account up to asOf
+func productValues(db *gorm.DB, accountID uint, asOf time.Time) (map[uint]*productValue, error) {
+	var layers []productValue
+	if err := db.Model(&model.CostLayer{}).Where("account_id = ? AND created_at <= ?", accountID, asOf).
+		Select("product_id, SUM(quantity) AS quantity, SUM(quantity * unit_cost) AS value").
+		Group("product_id").Scan(&layers).Error; err != nil {
+		return nil, err
+	}
+
+	var consumed []productValue
+	if err := db.Model(&model.CostConsumption{}).
+		Where("account_id = ? AND created_at <= ? AND layer_id IS NOT NULL", accountID, asOf).
+		Select("product_id, SUM(quantity) AS quantity, SUM(amount) AS value").
+		Group("product_id").Scan(&consumed).Error; err != nil {
+		return nil, err
+	}
+
+	values := make(map[uint]*productValue, len(layers))
+	for i := range layers {
+		values[layers[i].ProductID] = &layers[i]
+	}
+	for _, consumption := range consumed {
+		if value, ok := values[consumption.ProductID]; ok {
+			value.Quantity -= consumption.Quantity
+			value.Value -= consumption.Value
+		}
+	}
+	for productID, value := range values {
+		if value.Quantity <= 0 {
+			delete(values, productID)
+		}
+	}
+	return values, nil
+}
+
+func valuationByProduct(db *gorm.DB, values map[uint]*productValue) ([]model.ValuationLine, error) {
+	products, err := valuedProducts(db, values)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := make([]model.ValuationLine, 0, len(values))
+	for productID, value := range values {
+		lines = append(lines, model.ValuationLine{
+			ID:       productID,
+			Name:     products[productID].Name,
+			Quantity: value.Quantity,
+			Value:    value.Value,
+			UnitCost: value.Value / float64(value.Quantity),
+		})
+	}
+	return lines, nil
+}
+
+func valuationByCategory(db *gorm.DB, values map[uint]*productValue) ([]model.ValuationLine, error) {
+	products, err := valuedProducts(db, values)
+	if err != nil {
+		return nil, err
+	}
+
+	byCategory := map[uint]*model.ValuationLine{}
+	for productID, value := range values {
+		categoryID := products[productID].CategoryID
+		line, ok := byCategory[categoryID]
+		if !ok {
+			line = &model.ValuationLine{ID: categoryID, Name: "Uncategorized"}
+			byCategory[categoryID] = line
+		}
+		line.Quantity += value.Quantity
+		line.Value += value.Value
+	}
+
+	var categories []model.Category
+	if err := db.Unscoped().Where("id IN ?", mapKeys(byCategory)).Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	for _, category := range categories {
+		byCategory[category.ID].Name = category.Name
+	}
+	return mapLines(byCategory), nil
+}
+
+func valuationByWarehouse(db *gorm.DB, accountID uint, asOf time.Time, values map[uint]*productValue) ([]model.ValuationLine, error) {
+	var placed []struct {
+		ProductID   uint
+		WarehouseID *uint
+		Quantity    int
+	}
+	if err := db.Table("stock_movements").
+		Joins("LEFT JOIN bins ON bins.id = stock_movements.bin_id").
+		Joins("LEFT JOIN zones ON zones.id = bins.zone_id").
+		Where("stock_movements.account_id = ? AND stock_movements.created_at <= ?", accountID, asOf).
+		Select("stock_movements.product_id, zones.warehouse_id, SUM(stock_movements.delta) AS quantity").
+		Group("stock_movements.product_id, zones.warehouse_id").Scan(&placed).Error; err != nil {
+		return nil, err
+	}
+
+	onHand := map[uint]int{}
+	for _, row := range placed {
+		if row.Quantity > 0 {
+			onHand[row.ProductID] += row.Quantity
+		}
+	}
+
+	byWarehouse := map[uint]*model.ValuationLine{}
+	for _, row := range placed {
+		value, ok := values[row.ProductID]
+		if !ok || row.Quantity <= 0 {
+			continue
+		}
+		var warehouseID uint
+		if row.WarehouseID != nil {
+			warehouseID = *row.WarehouseID
+		}
+		line, ok := byWarehouse[warehouseID]
+		if !ok {
+			line = &model.ValuationLine{ID: warehouseID, Name: "Unassigned"}
+			byWarehouse[warehouseID] = line
+		}
+		share := float64(row.Quantity) / float64(onHand[row.ProductID])
+		line.Quantity += row.Quantity
+		line.Value += value.Value * share
+	}
+
+	var warehouses []model.Warehouse
+	if err := db.Unscoped().Where("id IN ?", mapKeys(byWarehouse)).Find(&warehouses).Error; err != nil {
+		return nil, err
+	}
+	for _, warehouse := range warehouses {
+		byWarehouse[warehouse.ID].Name = warehouse.Name
+	}
+	return mapLines(byWarehouse), nil
+}
+
+// valuedProducts loads the products of a valuation, including deleted ones that still had
+// stock at the time
+func valuedProducts(db *gorm.DB, values map[uint]*productValue) (map[uint]model.Product, error) {
+	var products []model.Product
+	if err := db.Unscoped().Where("id IN ?", mapKeys(values)).Find(&products).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]model.Product, len(products))
+	for _, product := range products {
+		byID[product.ID] = product
+	}
+	return byID, nil
+}
+
+func mapKeys[V any](m map[uint]V) []uint {
+	keys := make([]uint, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func mapLines(m map[uint]*model.ValuationLine) []model.ValuationLine {
+	lines := make([]model.ValuationLine, 0, len(m))
+	for _, line := range m {
+		if line.Quantity > 0 {
+			line.UnitCost = line.Value / float64(line.Quantity)
+		}
+		lines = append(lines, *line)
+	}
+	return lines
+}
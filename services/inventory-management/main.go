@@ -7,7 +7,9 @@ import (
 	"inventory-management/internal/cache"
 	"inventory-management/internal/initializers"
 	"inventory-management/internal/kafka"
+	"inventory-management/internal/storage"
 	"inventory-management/internal/utils"
+	"log"
 	"os"
 
 	"github.com/gin-gonic/gin"
@@ -42,13 +44,29 @@ func main() {
 		fmt.Println(e)
 	}
 
-	go kafka.ConsumerOrderEvents()
+	ns := utils.NewNotificationService(&utils.DefaultEmailSender{})
+
+	store, err := storage.NewFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to set up attachment storage: %v", err)
+	}
+
+	go kafka.ConsumerOrderEvents(ns)
+	go kafka.ConsumerShippingStatus()
+	go kafka.ConsumerReceipts()
+
+	scheduler := utils.NewScheduler(initializers.DB)
+	scheduler.StartReservationExpiryScheduler()
+	scheduler.StartReplenishmentScheduler()
+	scheduler.StartLowStockDigestScheduler(ns)
+	scheduler.StartStockSnapshotScheduler()
+	scheduler.StartClassificationScheduler()
 
 	r := gin.Default()
 
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
-	routes.Routers(r, initializers.DB, &utils.NotificationService{})
+	routes.Routers(r, initializers.DB, ns, store)
 
 	r.Run()
 
@@ -131,11 +131,12 @@ func CreateOrder(db *gorm.DB, ns *utils.NotificationService) gin.HandlerFunc {
 
 		// Create the order with status "Pending"
 		order := model.Order{
-			AccountID:  accountID.(uint),
-			CustomerID: orderRequest.CustomerID,
-			Quantity:   orderRequest.Quantity,
-			ProductID:  orderRequest.ProductID,
-			Status:     "Pending",
+			AccountID:     accountID.(uint),
+			CustomerID:    orderRequest.CustomerID,
+			Quantity:      orderRequest.Quantity,
+			ProductID:     orderRequest.ProductID,
+			Status:        "Pending",
+			UnitOfMeasure: orderRequest.UnitOfMeasure,
 		}
 
 		// Save the new order to the database
@@ -153,7 +154,7 @@ func CreateOrder(db *gorm.DB, ns *utils.NotificationService) gin.HandlerFunc {
 		}
 
 		// Publish Kafka Event
-		kafka.PublishOrderEvent(order.ID, order.ProductID, order.Quantity, "create")
+		kafka.PublishOrderEvent(order.ID, order.ProductID, order.Quantity, order.UnitOfMeasure, "create")
 
 		// Respond with success message
 		c.JSON(http.StatusOK, model.SuccessResponse{Message: "Order created successfully", Order: order})
@@ -366,7 +367,7 @@ func CancelOrder(db *gorm.DB, ns *utils.NotificationService) gin.HandlerFunc {
 		// }(order.CustomerEmail, order.ID)
 
 		// Publish order cancellation event to Kafka
-		kafka.PublishOrderEvent(order.ID, order.ProductID, order.Quantity, "cancelled")
+		kafka.PublishOrderEvent(order.ID, order.ProductID, order.Quantity, order.UnitOfMeasure, "cancelled")
 
 		// Respond with success message
 		c.JSON(http.StatusOK, model.SuccessResponse{Message: "Order cancelled successfully"})
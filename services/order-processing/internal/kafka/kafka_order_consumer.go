@@ -117,7 +117,7 @@ func ConsumerInventoryStatus() {
 			} else {
 				log.Printf("Order status updated successfully for OrderID: %d\n", event.OrderID)
 				if event.Action == "Ready for Shipping" {
-					PublishOrderEvent(order.ID, order.ProductID, order.Quantity, "ship")
+					PublishOrderEvent(order.ID, order.ProductID, order.Quantity, order.UnitOfMeasure, "ship")
 				}
 			}
 		} else {
@@ -10,13 +10,14 @@ import (
 )
 
 // PublishOrderEvent publishes an order event to the Kafka topic.
-func PublishOrderEvent(orderID uint, productID uint, quantity uint, action string) {
+func PublishOrderEvent(orderID uint, productID uint, quantity uint, unitOfMeasure string, action string) {
 	// Create an order event struct
 	event := model.OrderEvent{
-		OrderID:   orderID,
-		ProductID: productID,
-		Quantity:  quantity,
-		Action:    action,
+		OrderID:       orderID,
+		ProductID:     productID,
+		Quantity:      quantity,
+		UnitOfMeasure: unitOfMeasure,
+		Action:        action,
 	}
 
 	// Marshal the order event into JSON
@@ -8,17 +8,18 @@ import (
 
 // Order represents an order in the system.
 type Order struct {
-	ID           uint       `gorm:"primarykey" json:"id"`
-	CreatedAt    time.Time  `json:"created_at"`
-	UpdatedAt    time.Time  `json:"updated_at"`
-	DeletedAt    *time.Time `json:"deleted_at" swaggertype:"string" example:"2023-01-01T00:00:00Z"`
-	AccountID    uint       `gorm:"index" json:"account_id"`
-	ProductID    uint       `json:"product_id"`
-	Quantity     uint       `json:"quantity"`
-	CustomerID   uint       `json:"customer_id"`
-	Status       string     `json:"status"`
-	Version      int        `json:"version"`
-	ShippingDate time.Time  `json:"shipping_date"`
+	ID            uint       `gorm:"primarykey" json:"id"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	DeletedAt     *time.Time `json:"deleted_at" swaggertype:"string" example:"2023-01-01T00:00:00Z"`
+	AccountID     uint       `gorm:"index" json:"account_id"`
+	ProductID     uint       `json:"product_id"`
+	Quantity      uint       `json:"quantity"`
+	UnitOfMeasure string     `json:"unit_of_measure"`
+	CustomerID    uint       `json:"customer_id"`
+	Status        string     `json:"status"`
+	Version       int        `json:"version"`
+	ShippingDate  time.Time  `json:"shipping_date"`
 }
 
 // OrderStatusUpdateRequest represents the payload to update the status of an order.
@@ -28,10 +29,11 @@ type OrderStatusUpdateRequest struct {
 
 // OrderEvent represents an order event for Kafka.
 type OrderEvent struct {
-	OrderID   uint   `json:"order_id"`
-	ProductID uint   `json:"product_id"`
-	Quantity  uint   `json:"quantity"`
-	Action    string `json:"action"`
+	OrderID       uint   `json:"order_id"`
+	ProductID     uint   `json:"product_id"`
+	Quantity      uint   `json:"quantity"`
+	UnitOfMeasure string `json:"unit_of_measure,omitempty"`
+	Action        string `json:"action"`
 }
 
 // InventoryStatusEvent represents an inventory status event.
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"net/http"
+	"reporting-analytics/internal/model"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetStockAdjustments godoc
+// @Summary Get stock adjustments
+// @Description Retrieve the approved stock adjustments reported by inventory, newest first
+// @Produce json
+// @Param reason query string false "damage, shrinkage, found or correction"
+// @Param product_id query int false "Product ID"
+// @Param from query string false "Adjusted from (YYYY-MM-DD)"
+// @Param to query string false "Adjusted until (YYYY-MM-DD, inclusive)"
+// @Success 200 {array} model.StockAdjustmentReport
+// @Failure 400 {object} gin.H{"error": "Invalid date, expected YYYY-MM-DD"}
+// @Failure 401 {object} gin.H{"error": "Account ID not found"}
+// @Failure 500 {object} gin.H{"error": "Failed to retrieve stock adjustments"}
+// @Router /reports/adjustments [get]
+func GetStockAdjustments(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Account ID not found"})
+			return
+		}
+
+		query, ok := adjustmentQuery(c, db, accountID)
+		if !ok {
+			return
+		}
+
+		var adjustments []model.StockAdjustmentReport
+		if err := query.Order("adjusted_at DESC, id DESC").Find(&adjustments).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve stock adjustments"})
+			return
+		}
+
+		c.JSON(http.StatusOK, adjustments)
+	}
+}
+
+// GetStockAdjustmentSummary godoc
+// @Summary Summarize stock adjustments by reason
+// @Description Total the approved stock adjustments per reason code: how many there were and the units and value
+// @Description added and removed, e.g. to see what damage and shrinkage cost over a period
+// @Produce json
+// @Param reason query string false "damage, shrinkage, found or correction"
+// @Param product_id query int false "Product ID"
+// @Param from query string false "Adjusted from (YYYY-MM-DD)"
+// @Param to query string false "Adjusted until (YYYY-MM-DD, inclusive)"
+// @Success 200 {array} model.AdjustmentSummary
+// @Failure 400 {object} gin.H{"error": "Invalid date, expected YYYY-MM-DD"}
+// @Failure 401 {object} gin.H{"error": "Account ID not found"}
+// @Failure 500 {object} gin.H{"error": "Failed to summarize stock adjustments"}
+// @Router /reports/adjustments/summary [get]
+func GetStockAdjustmentSummary(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Account ID not found"})
+			return
+		}
+
+		query, ok := adjustmentQuery(c, db, accountID)
+		if !ok {
+			return
+		}
+
+		summary := []model.AdjustmentSummary{}
+		err := query.Model(&model.StockAdjustmentReport{}).
+			Select(`reason, COUNT(*) AS count,
+				COALESCE(SUM(CASE WHEN delta > 0 THEN delta ELSE 0 END), 0) AS units_added,
+				COALESCE(SUM(CASE WHEN delta < 0 THEN -delta ELSE 0 END), 0) AS units_removed,
+				COALESCE(SUM(CASE WHEN delta > 0 THEN value ELSE 0 END), 0) AS value_added,
+				COALESCE(SUM(CASE WHEN delta < 0 THEN value ELSE 0 END), 0) AS value_removed`).
+			Group("reason").Order("reason").Scan(&summary).Error
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to summarize stock adjustments"})
+			return
+		}
+
+		c.JSON(http.StatusOK, summary)
+	}
+}
+
+// adjustmentQuery scopes stock adjustments to the account and the request's filters
+func adjustmentQuery(c *gin.Context, db *gorm.DB, accountID interface{}) (*gorm.DB, bool) {
+	query := db.Where("account_id = ?", accountID)
+	if reason := c.Query("reason"); reason != "" {
+		query = query.Where("reason = ?", reason)
+	}
+	if productID := c.Query("product_id"); productID != "" {
+		query = query.Where("product_id = ?", productID)
+	}
+
+	for param, condition := range map[string]string{"from": "adjusted_at >= ?", "to": "adjusted_at < ?"} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		day, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date, expected YYYY-MM-DD"})
+			return nil, false
+		}
+		if param == "to" {
+			day = day.AddDate(0, 0, 1)
+		}
+		query = query.Where(condition, day)
+	}
+	return query, true
+}
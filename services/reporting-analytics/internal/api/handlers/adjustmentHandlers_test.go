@@ -0,0 +1,71 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reporting-analytics/internal/model"
+	"reporting-analytics/internal/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStockAdjustmentReports(t *testing.T) {
+	db, err := setupTestDB()
+	assert.NoError(t, err)
+	defer os.Remove("test_reporting.db")
+
+	yesterday := time.Now().AddDate(0, 0, -1)
+	lastMonth := time.Now().AddDate(0, -1, 0)
+	for _, report := range []model.StockAdjustmentReport{
+		{AdjustmentID: 1, ProductID: 7, Delta: -2, Reason: "damage", Value: 10, AdjustedAt: yesterday, AccountID: 1},
+		{AdjustmentID: 2, ProductID: 7, Delta: -3, Reason: "damage", Value: 15, AdjustedAt: lastMonth, AccountID: 1},
+		{AdjustmentID: 3, ProductID: 8, Delta: 4, Reason: "found", Value: 8, AdjustedAt: yesterday, AccountID: 1},
+		{AdjustmentID: 1, ProductID: 9, Delta: -9, Reason: "damage", Value: 90, AdjustedAt: yesterday, AccountID: 2},
+	} {
+		assert.NoError(t, utils.SaveStockAdjustment(db, &report))
+	}
+	// Inventory reposting an adjustment does not count it twice
+	repost := model.StockAdjustmentReport{AdjustmentID: 1, ProductID: 7, Delta: -2, Reason: "damage", Value: 10, AdjustedAt: yesterday, AccountID: 1}
+	assert.NoError(t, utils.SaveStockAdjustment(db, &repost))
+
+	r := setupRouter(db)
+	get := func(path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", path, nil)
+		req.Header.Set("Authorization", "Bearer "+createTestToken(1, 1))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("List", func(t *testing.T) {
+		w := get("/reports/adjustments?reason=damage")
+		assert.Equal(t, http.StatusOK, w.Code)
+		var adjustments []model.StockAdjustmentReport
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &adjustments))
+		assert.Equal(t, 2, len(adjustments))
+		assert.Equal(t, uint(1), adjustments[0].AdjustmentID)
+
+		w = get("/reports/adjustments?from=yesterday")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Summary", func(t *testing.T) {
+		w := get("/reports/adjustments/summary?from=" + yesterday.Format("2006-01-02"))
+		assert.Equal(t, http.StatusOK, w.Code)
+		var summary []model.AdjustmentSummary
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &summary))
+		assert.Equal(t, []model.AdjustmentSummary{
+			{Reason: "damage", Count: 1, UnitsRemoved: 2, ValueRemoved: 10},
+			{Reason: "found", Count: 1, UnitsAdded: 4, ValueAdded: 8},
+		}, summary)
+	})
+
+	db.Exec("DELETE FROM stock_adjustment_reports")
+	db.Exec("DELETE FROM accounts")
+	db.Exec("DELETE FROM users")
+	db.Exec("DELETE FROM roles")
+}
@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"reporting-analytics/internal/model"
+	"reporting-analytics/internal/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetDemandForecast godoc
+// @Summary Forecast product demand
+// @Description Forecast a product's demand from its sales history with a moving average or exponential smoothing with
+// @Description seasonality, and recommend the safety stock and reorder point for the supplier lead time and target
+// @Description service level. The lead time is taken from inventory unless lead_time_days is given
+// @Produce json
+// @Param product_id path int true "Product ID"
+// @Param method query string false "moving_average or exponential_smoothing (default)"
+// @Param period_days query int false "Days per period, default 1"
+// @Param periods query int false "Periods of history, default 180"
+// @Param horizon query int false "Periods to forecast, default 30"
+// @Param window query int false "Moving average window in periods, default 28"
+// @Param season_length query int false "Periods per season, default 7"
+// @Param alpha query number false "Level smoothing, default 0.3"
+// @Param beta query number false "Trend smoothing, default 0.05"
+// @Param gamma query number false "Seasonal smoothing, default 0.2"
+// @Param service_level query number false "Target service level, default 0.95"
+// @Param lead_time_days query int false "Lead time override"
+// @Success 200 {object} model.DemandForecast
+// @Failure 400 {object} gin.H{"error": "Invalid forecast options"}
+// @Failure 401 {object} gin.H{"error": "Account ID not found"}
+// @Failure 500 {object} gin.H{"error": "Failed to forecast demand"}
+// @Router /reports/forecasts/{product_id} [get]
+func GetDemandForecast(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Account ID not found"})
+			return
+		}
+
+		forecast, ok := demandForecast(c, db, accountID.(uint))
+		if !ok {
+			return
+		}
+
+		c.JSON(http.StatusOK, forecast)
+	}
+}
+
+// ApplyDemandForecast godoc
+// @Summary Apply a forecast recommendation to inventory
+// @Description Forecast a product's demand as GET /reports/forecasts/{product_id} does and set the recommended
+// @Description reorder point on the product. The product-wide safety stock is split across its stock rows as their
+// @Description low-stock thresholds, by each row's share of the product's shipments over the last 90 days (evenly
+// @Description when nothing shipped), so the thresholds add up to the safety stock. Requires manager permission in
+// @Description inventory
+// @Produce json
+// @Param product_id path int true "Product ID"
+// @Success 200 {object} model.StockRecommendation
+// @Failure 400 {object} gin.H{"error": "Invalid forecast options"}
+// @Failure 401 {object} gin.H{"error": "Account ID not found"}
+// @Failure 403 {object} gin.H{"error": "Manager permission required"}
+// @Failure 404 {object} gin.H{"error": "Product not found"}
+// @Failure 502 {object} gin.H{"error": "Failed to apply recommendation"}
+// @Router /reports/forecasts/{product_id}/apply [post]
+func ApplyDemandForecast(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Account ID not found"})
+			return
+		}
+
+		forecast, ok := demandForecast(c, db, accountID.(uint))
+		if !ok {
+			return
+		}
+
+		_, err := utils.UpdateProductThresholds(bearerToken(c), forecast.ProductID, model.ProductThresholds{
+			ReorderPoint: &forecast.ReorderPoint,
+			SafetyStock:  &forecast.SafetyStock,
+		})
+		if !inventoryRequestSucceeded(c, err, "Failed to apply recommendation") {
+			return
+		}
+
+		recommendation := model.StockRecommendation{
+			ProductID:     forecast.ProductID,
+			Method:        forecast.Method,
+			AverageDemand: forecast.AverageDemand,
+			DemandStdDev:  forecast.DemandStdDev,
+			LeadTimeDays:  forecast.LeadTimeDays,
+			ServiceLevel:  forecast.ServiceLevel,
+			SafetyStock:   forecast.SafetyStock,
+			ReorderPoint:  forecast.ReorderPoint,
+			AccountID:     accountID.(uint),
+		}
+		if userID, ok := c.Get("user_id"); ok {
+			appliedBy := userID.(uint)
+			recommendation.AppliedBy = &appliedBy
+		}
+		if err := db.Create(&recommendation).Error; err != nil {
+			log.Printf("Applied recommendation for product %d but failed to record it: %v", forecast.ProductID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record recommendation"})
+			return
+		}
+
+		c.JSON(http.StatusOK, recommendation)
+	}
+}
+
+// GetStockRecommendations godoc
+// @Summary Get applied stock recommendations
+// @Description Retrieve the forecast recommendations applied to inventory, newest first
+// @Produce json
+// @Param product_id query int false "Product ID"
+// @Success 200 {array} model.StockRecommendation
+// @Failure 401 {object} gin.H{"error": "Account ID not found"}
+// @Failure 500 {object} gin.H{"error": "Failed to retrieve stock recommendations"}
+// @Router /reports/stock-recommendations [get]
+func GetStockRecommendations(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Account ID not found"})
+			return
+		}
+
+		query := db.Where("account_id = ?", accountID)
+		if productID := c.Query("product_id"); productID != "" {
+			query = query.Where("product_id = ?", productID)
+		}
+
+		var recommendations []model.StockRecommendation
+		if err := query.Order("created_at DESC, id DESC").Find(&recommendations).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve stock recommendations"})
+			return
+		}
+
+		c.JSON(http.StatusOK, recommendations)
+	}
+}
+
+// demandForecast forecasts the demand of the product in the path with the options in the
+// query, fetching the lead time from inventory when it is not given. It writes the error
+// response and returns false when the forecast cannot be made.
+func demandForecast(c *gin.Context, db *gorm.DB, accountID uint) (model.DemandForecast, bool) {
+	productID, err := strconv.ParseUint(c.Param("product_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return model.DemandForecast{}, false
+	}
+
+	options, err := forecastOptions(c)
+	if err == nil {
+		err = options.Validate()
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return model.DemandForecast{}, false
+	}
+
+	if c.Query("lead_time_days") == "" {
+		thresholds, err := utils.FetchProductThresholds(bearerToken(c), uint(productID))
+		if !inventoryRequestSucceeded(c, err, "Failed to retrieve lead time") {
+			return model.DemandForecast{}, false
+		}
+		options.LeadTimeDays = thresholds.LeadTimeDays
+	}
+
+	forecast, err := utils.ForecastDemand(db, accountID, uint(productID), options, time.Now())
+	if err != nil {
+		log.Printf("Failed to forecast demand of product %d: %v", productID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to forecast demand"})
+		return model.DemandForecast{}, false
+	}
+	return forecast, true
+}
+
+// forecastOptions reads the forecast options from the query, keeping the defaults for
+// those that are not given
+func forecastOptions(c *gin.Context) (utils.ForecastOptions, error) {
+	options := utils.DefaultForecastOptions()
+	options.Method = c.DefaultQuery("method", options.Method)
+
+	ints := map[string]*int{
+		"period_days":   &options.PeriodDays,
+		"periods":       &options.Periods,
+		"horizon":       &options.Horizon,
+		"window":        &options.Window,
+		"season_length": &options.SeasonLength,
+	}
+	for name, target := range ints {
+		if value := c.Query(name); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil {
+				return options, errors.New("invalid " + name)
+			}
+			*target = parsed
+		}
+	}
+
+	floats := map[string]*float64{
+		"alpha":         &options.Alpha,
+		"beta":          &options.Beta,
+		"gamma":         &options.Gamma,
+		"service_level": &options.ServiceLevel,
+	}
+	for name, target := range floats {
+		if value := c.Query(name); value != "" {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return options, errors.New("invalid " + name)
+			}
+			*target = parsed
+		}
+	}
+
+	if value := c.Query("lead_time_days"); value != "" {
+		leadTime, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return options, errors.New("invalid lead_time_days")
+		}
+		options.LeadTimeDays = uint(leadTime)
+	}
+	return options, nil
+}
+
+// inventoryRequestSucceeded writes the response for a failed call to the inventory
+// service and returns false, or returns true when err is nil
+func inventoryRequestSucceeded(c *gin.Context, err error, message string) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, utils.ErrProductNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+	case errors.Is(err, utils.ErrManagerRequired):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Manager permission required"})
+	default:
+		log.Printf("%s: %v", message, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": message})
+	}
+	return false
+}
+
+func bearerToken(c *gin.Context) string {
+	return strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+}
@@ -0,0 +1,118 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reporting-analytics/internal/model"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDemandForecast(t *testing.T) {
+	db, err := setupTestDB()
+	assert.NoError(t, err)
+	defer os.Remove("test_reporting.db")
+
+	managerToken := createTestToken(2, 1)
+
+	// Inventory quotes a 7 day lead time and only lets the manager change thresholds
+	var applied model.ProductThresholds
+	inventory := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PUT" {
+			if r.Header.Get("Authorization") != "Bearer "+managerToken {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			json.NewDecoder(r.Body).Decode(&applied)
+		}
+		json.NewEncoder(w).Encode(model.ProductThresholds{ProductID: 7, LeadTimeDays: 7})
+	}))
+	defer inventory.Close()
+	os.Setenv("INVENTORY_SERVICE_URL", inventory.URL)
+
+	// Eight weeks of sales of 10 a day, and 30 on the last two days of each week
+	start := time.Now().Truncate(24*time.Hour).AddDate(0, 0, -55)
+	for day := 0; day < 56; day++ {
+		quantity := uint(10)
+		if day%7 >= 5 {
+			quantity = 30
+		}
+		db.Create(&model.SalesReport{ProductID: 7, Quantity: quantity, Timestamp: start.AddDate(0, 0, day).Add(12 * time.Hour), AccountID: 1})
+	}
+
+	r := setupRouter(db)
+	forecast := func(token, method, path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	token := createTestToken(1, 1)
+
+	t.Run("ExponentialSmoothing", func(t *testing.T) {
+		w := forecast(token, "GET", "/reports/forecasts/7?periods=56&horizon=14")
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response model.DemandForecast
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, 7, response.SeasonLength)
+		assert.Equal(t, uint(7), response.LeadTimeDays)
+		assert.Equal(t, 56, len(response.History))
+		assert.Equal(t, 14, len(response.Forecast))
+		assert.InDelta(t, 10, response.Forecast[0].Quantity, 0.01)
+		assert.InDelta(t, 30, response.Forecast[5].Quantity, 0.01)
+
+		// The pattern repeats exactly, so no safety stock is needed beyond a week of demand
+		assert.InDelta(t, 0, response.DemandStdDev, 0.01)
+		assert.Equal(t, uint(0), response.SafetyStock)
+		assert.InDelta(t, 110, response.ReorderPoint, 1)
+	})
+
+	t.Run("MovingAverage", func(t *testing.T) {
+		w := forecast(token, "GET", "/reports/forecasts/7?method=moving_average&periods=56&window=7&lead_time_days=14&service_level=0.99")
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response model.DemandForecast
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, uint(14), response.LeadTimeDays)
+		assert.InDelta(t, 110.0/7, response.AverageDemand, 0.01)
+		assert.Greater(t, response.SafetyStock, uint(0))
+		assert.InDelta(t, 220+float64(response.SafetyStock), response.ReorderPoint, 1)
+
+		w = forecast(token, "GET", "/reports/forecasts/7?method=naive")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w = forecast(token, "GET", "/reports/forecasts/7?service_level=1")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("ApplyRecommendation", func(t *testing.T) {
+		w := forecast(token, "POST", "/reports/forecasts/7/apply?periods=56")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w = forecast(managerToken, "POST", "/reports/forecasts/7/apply?periods=56")
+		assert.Equal(t, http.StatusOK, w.Code)
+		var recommendation model.StockRecommendation
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &recommendation))
+		assert.Equal(t, recommendation.ReorderPoint, *applied.ReorderPoint)
+		assert.Equal(t, recommendation.SafetyStock, *applied.SafetyStock)
+		assert.Nil(t, applied.LowStockThreshold)
+		assert.Equal(t, uint(2), *recommendation.AppliedBy)
+
+		w = forecast(token, "GET", "/reports/stock-recommendations?product_id=7")
+		assert.Equal(t, http.StatusOK, w.Code)
+		var recommendations []model.StockRecommendation
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &recommendations))
+		assert.Equal(t, 1, len(recommendations))
+		assert.True(t, strings.HasPrefix(recommendations[0].Method, "exponential"))
+	})
+
+	db.Exec("DELETE FROM stock_recommendations")
+	db.Exec("DELETE FROM sales_reports")
+	db.Exec("DELETE FROM accounts")
+	db.Exec("DELETE FROM users")
+	db.Exec("DELETE FROM roles")
+}
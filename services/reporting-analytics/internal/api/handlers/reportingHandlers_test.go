@@ -29,7 +29,7 @@ func setupTestDB() (*gorm.DB, error) {
 		return nil, err
 	}
 
-	db.AutoMigrate(&model.SalesReport{}, &model.InventoryLevel{}, &model.ShippingStatus{}, &model.UserActivity{}, &model.User{}, &model.Role{}, &model.Account{})
+	db.AutoMigrate(&model.SalesReport{}, &model.InventoryLevel{}, &model.ShippingStatus{}, &model.UserActivity{}, &model.StockRecommendation{}, &model.StockAdjustmentReport{}, &model.User{}, &model.Role{}, &model.Account{})
 
 	// Create test data
 	role := model.Role{
@@ -19,4 +19,9 @@ func Routers(r *gin.Engine, db *gorm.DB) {
 	report.GET("/inventory", handlers.GetInventoryLevels(db))
 	report.GET("/shipping", handlers.GetShippingStatuses(db))
 	report.GET("/user-activity", handlers.GetUserActivities(db))
+	report.GET("/adjustments", handlers.GetStockAdjustments(db))
+	report.GET("/adjustments/summary", handlers.GetStockAdjustmentSummary(db))
+	report.GET("/forecasts/:product_id", handlers.GetDemandForecast(db))
+	report.POST("/forecasts/:product_id/apply", handlers.ApplyDemandForecast(db))
+	report.GET("/stock-recommendations", handlers.GetStockRecommendations(db))
 }
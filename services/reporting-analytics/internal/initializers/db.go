@@ -25,5 +25,5 @@ func ConnectToDB() {
 		panic("Failed to connect to db")
 	}
 
-	DB.AutoMigrate(&model.SalesReport{}, &model.InventoryLevel{}, &model.ShippingStatus{}, &model.UserActivity{})
+	DB.AutoMigrate(&model.SalesReport{}, &model.InventoryLevel{}, &model.ShippingStatus{}, &model.UserActivity{}, &model.StockRecommendation{}, &model.StockAdjustmentReport{})
 }
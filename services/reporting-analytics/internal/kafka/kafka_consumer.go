@@ -7,6 +7,7 @@ import (
 	"os"
 	"reporting-analytics/internal/initializers"
 	"reporting-analytics/internal/model"
+	"reporting-analytics/internal/utils"
 	"time"
 
 	"github.com/segmentio/kafka-go"
@@ -183,3 +184,36 @@ func saveUserActivity(userActivity *model.UserActivity) error {
 	}
 	return nil
 }
+
+// ConsumerStockAdjustments consumes approved stock adjustments from inventory-management
+func ConsumerStockAdjustments() {
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:  []string{os.Getenv("KAFKA_BROKERS")},
+		Topic:    os.Getenv("STOCK_ADJUSTMENT_TOPIC"),
+		GroupID:  "reporting-analytics-group",
+		MinBytes: 10e3, // 10KB
+		MaxBytes: 10e6, // 10MB
+	})
+
+	for {
+		m, err := r.ReadMessage(context.Background())
+		if err != nil {
+			log.Printf("Error reading message: %v\n", err)
+			continue
+		}
+		log.Printf("Received message: %s\n", string(m.Value))
+
+		var adjustment model.StockAdjustmentReport
+		if err := json.Unmarshal(m.Value, &adjustment); err != nil {
+			log.Printf("Failed to unmarshal message: %v\n", err)
+			continue
+		}
+
+		if err := utils.SaveStockAdjustment(initializers.DB, &adjustment); err != nil {
+			log.Printf("Failed to save stock adjustment: %v\n", err)
+			continue
+		}
+
+		log.Printf("Stock adjustment recorded successfully: %v\n", adjustment)
+	}
+}
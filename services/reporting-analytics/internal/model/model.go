@@ -53,3 +53,85 @@ type UserActivity struct {
 	AccountID uint           `gorm:"index"`
 	Action    string         `json:"action"`
 }
+
+// ForecastPoint is the demand of a product in one period, observed or forecast
+type ForecastPoint struct {
+	PeriodStart time.Time `json:"period_start"`
+	Quantity    float64   `json:"quantity"`
+}
+
+// DemandForecast is the forecast demand of a product with the reorder point and safety
+// stock recommended to cover the supplier lead time at the target service level
+type DemandForecast struct {
+	ProductID     uint            `json:"product_id"`
+	Method        string          `json:"method"`
+	PeriodDays    int             `json:"period_days"`
+	SeasonLength  int             `json:"season_length"` // Zero when no seasonality was fitted
+	History       []ForecastPoint `json:"history"`
+	Forecast      []ForecastPoint `json:"forecast"`
+	AverageDemand float64         `json:"average_demand"` // Forecast demand per period
+	DemandStdDev  float64         `json:"demand_std_dev"` // Error of the one-period-ahead forecast
+	LeadTimeDays  uint            `json:"lead_time_days"`
+	ServiceLevel  float64         `json:"service_level"`
+	SafetyStock   uint            `json:"safety_stock"`
+	ReorderPoint  uint            `json:"reorder_point"`
+}
+
+// StockRecommendation records a forecast recommendation a manager applied to inventory
+type StockRecommendation struct {
+	ID            uint      `gorm:"primarykey" json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	ProductID     uint      `gorm:"index" json:"product_id"`
+	Method        string    `json:"method"`
+	AverageDemand float64   `json:"average_demand"`
+	DemandStdDev  float64   `json:"demand_std_dev"`
+	LeadTimeDays  uint      `json:"lead_time_days"`
+	ServiceLevel  float64   `json:"service_level"`
+	SafetyStock   uint      `json:"safety_stock"`
+	ReorderPoint  uint      `json:"reorder_point"`
+	AppliedBy     *uint     `json:"applied_by"`
+	AccountID     uint      `gorm:"index"`
+}
+
+// ProductThresholds are the stock thresholds of a product in inventory-management
+type ProductThresholds struct {
+	ProductID         uint  `json:"product_id"`
+	SupplierID        uint  `json:"supplier_id"`
+	LeadTimeDays      uint  `json:"lead_time_days"`
+	OnHand            uint  `json:"on_hand"`
+	ReorderPoint      *uint `json:"reorder_point,omitempty"`
+	ReorderQuantity   *uint `json:"reorder_quantity,omitempty"`
+	LowStockThreshold *int  `json:"low_stock_threshold,omitempty"`
+	// SafetyStock is split by inventory across the product's stock rows as their low-stock
+	// thresholds, by each row's share of recent shipments
+	SafetyStock *uint `json:"safety_stock,omitempty"`
+}
+
+// StockAdjustmentReport is an approved stock adjustment reported by inventory-management.
+// Inventory may report an adjustment again, so it is stored once per adjustment ID.
+type StockAdjustmentReport struct {
+	ID           uint      `gorm:"primarykey" json:"id"`
+	CreatedAt    time.Time `json:"created_at"`
+	AdjustmentID uint      `gorm:"uniqueIndex:idx_adjustment_account" json:"adjustment_id"`
+	StockID      uint      `json:"stock_id"`
+	ProductID    uint      `gorm:"index" json:"product_id"`
+	Delta        int       `json:"delta"`
+	Reason       string    `gorm:"index" json:"reason"`
+	Note         string    `json:"note"`
+	UnitCost     float64   `json:"unit_cost"`
+	Value        float64   `json:"value"`
+	RequestedBy  *uint     `json:"requested_by"`
+	ApprovedBy   *uint     `json:"approved_by"`
+	AdjustedAt   time.Time `gorm:"index" json:"adjusted_at"`
+	AccountID    uint      `gorm:"uniqueIndex:idx_adjustment_account" json:"account_id"`
+}
+
+// AdjustmentSummary totals the approved adjustments of one reason code
+type AdjustmentSummary struct {
+	Reason       string  `json:"reason"`
+	Count        int64   `json:"count"`
+	UnitsAdded   int64   `json:"units_added"`
+	UnitsRemoved int64   `json:"units_removed"`
+	ValueAdded   float64 `json:"value_added"`
+	ValueRemoved float64 `json:"value_removed"`
+}
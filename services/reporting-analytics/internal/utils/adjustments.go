@@ -0,0 +1,13 @@
+package utils
+
+import (
+	"reporting-analytics/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// SaveStockAdjustment stores a reported stock adjustment unless the account already has it
+func SaveStockAdjustment(db *gorm.DB, report *model.StockAdjustmentReport) error {
+	return db.Where("adjustment_id = ? AND account_id = ?", report.AdjustmentID, report.AccountID).
+		FirstOrCreate(report).Error
+}
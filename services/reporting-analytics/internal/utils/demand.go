@@ -0,0 +1,137 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"reporting-analytics/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// ForecastMovingAverage forecasts the mean demand of the last periods
+	ForecastMovingAverage = "moving_average"
+	// ForecastExponentialSmoothing forecasts with Holt-Winters smoothing of level, trend and season
+	ForecastExponentialSmoothing = "exponential_smoothing"
+)
+
+// ForecastOptions configure a demand forecast and the recommendation derived from it
+type ForecastOptions struct {
+	Method       string
+	PeriodDays   int // Length of a period, e.g. 1 for daily or 7 for weekly demand
+	Periods      int // Periods of sales history to forecast from
+	Horizon      int // Periods to forecast
+	Window       int // Periods averaged by the moving average
+	SeasonLength int // Periods in a season for exponential smoothing
+	Alpha        float64
+	Beta         float64
+	Gamma        float64
+	ServiceLevel float64 // Target probability of not running out during a lead time
+	LeadTimeDays uint
+}
+
+// DefaultForecastOptions returns daily exponential smoothing over 180 days with weekly
+// seasonality and a 95% service level
+func DefaultForecastOptions() ForecastOptions {
+	return ForecastOptions{
+		Method:       ForecastExponentialSmoothing,
+		PeriodDays:   1,
+		Periods:      180,
+		Horizon:      30,
+		Window:       28,
+		SeasonLength: 7,
+		Alpha:        0.3,
+		Beta:         0.05,
+		Gamma:        0.2,
+		ServiceLevel: 0.95,
+	}
+}
+
+// Validate checks that the options describe a forecast that can be computed
+func (o ForecastOptions) Validate() error {
+	switch {
+	case o.Method != ForecastMovingAverage && o.Method != ForecastExponentialSmoothing:
+		return fmt.Errorf("method must be %s or %s", ForecastMovingAverage, ForecastExponentialSmoothing)
+	case o.PeriodDays < 1 || o.Periods < 1 || o.Horizon < 1 || o.Window < 1 || o.SeasonLength < 0:
+		return fmt.Errorf("period_days, periods, horizon and window must be positive")
+	case o.Alpha <= 0 || o.Alpha > 1 || o.Beta < 0 || o.Beta > 1 || o.Gamma < 0 || o.Gamma > 1:
+		return fmt.Errorf("alpha must be in (0, 1], beta and gamma in [0, 1]")
+	case o.ServiceLevel < 0.5 || o.ServiceLevel >= 1:
+		return fmt.Errorf("service_level must be at least 0.5 and below 1")
+	}
+	return nil
+}
+
+// ForecastDemand forecasts a product's demand from its sales reports in the periods up to
+// now and recommends the safety stock and reorder point that cover the lead time
+func ForecastDemand(db *gorm.DB, accountID, productID uint, options ForecastOptions, now time.Time) (model.DemandForecast, error) {
+	forecast := model.DemandForecast{
+		ProductID:    productID,
+		Method:       options.Method,
+		PeriodDays:   options.PeriodDays,
+		LeadTimeDays: options.LeadTimeDays,
+		ServiceLevel: options.ServiceLevel,
+	}
+
+	periodLength := time.Duration(options.PeriodDays) * 24 * time.Hour
+	end := now.Truncate(24 * time.Hour).Add(24 * time.Hour)
+	start := end.Add(-time.Duration(options.Periods) * periodLength)
+	history, err := demandHistory(db, accountID, productID, start, end, periodLength, options.Periods)
+	if err != nil {
+		return forecast, err
+	}
+
+	leadPeriods := float64(options.LeadTimeDays) / float64(options.PeriodDays)
+	horizon := max(options.Horizon, int(math.Ceil(leadPeriods)))
+
+	var fitted, predicted []float64
+	if options.Method == ForecastMovingAverage {
+		fitted, predicted = MovingAverage(history, options.Window, horizon)
+	} else {
+		var seasonal bool
+		fitted, predicted, seasonal = HoltWinters(history, options.SeasonLength, options.Alpha, options.Beta, options.Gamma, horizon)
+		if seasonal {
+			forecast.SeasonLength = options.SeasonLength
+		}
+	}
+
+	for i, quantity := range history {
+		forecast.History = append(forecast.History, model.ForecastPoint{PeriodStart: start.Add(time.Duration(i) * periodLength), Quantity: quantity})
+	}
+	for h, quantity := range predicted[:options.Horizon] {
+		forecast.Forecast = append(forecast.Forecast, model.ForecastPoint{PeriodStart: end.Add(time.Duration(h) * periodLength), Quantity: quantity})
+		forecast.AverageDemand += quantity / float64(options.Horizon)
+	}
+
+	forecast.DemandStdDev = ForecastError(history, fitted)
+	safetyStock := ServiceFactor(options.ServiceLevel) * forecast.DemandStdDev * math.Sqrt(leadPeriods)
+	forecast.SafetyStock = wholeUnits(safetyStock)
+	forecast.ReorderPoint = wholeUnits(LeadTimeDemand(predicted, leadPeriods) + safetyStock)
+	return forecast, nil
+}
+
+// wholeUnits rounds a quantity up to whole units, ignoring floating point noise so that
+// e.g. 110.0000000001 stays 110
+func wholeUnits(quantity float64) uint {
+	return uint(math.Max(math.Ceil(math.Round(quantity*1e6)/1e6), 0))
+}
+
+// demandHistory adds up the quantity sold of a product per period, with zero for periods
+// without sales
+func demandHistory(db *gorm.DB, accountID, productID uint, start, end time.Time, periodLength time.Duration, periods int) ([]float64, error) {
+	var reports []model.SalesReport
+	if err := db.Where("account_id = ? AND product_id = ? AND timestamp >= ? AND timestamp < ?", accountID, productID, start, end).
+		Find(&reports).Error; err != nil {
+		return nil, err
+	}
+
+	history := make([]float64, periods)
+	for _, report := range reports {
+		period := int(report.Timestamp.Sub(start) / periodLength)
+		if period >= 0 && period < periods {
+			history[period] += float64(report.Quantity)
+		}
+	}
+	return history, nil
+}
@@ -0,0 +1,137 @@
+package utils
+
+import (
+	"math"
+)
+
+// MovingAverage forecasts every future period as the mean of the last window periods.
+// fitted holds the one-period-ahead forecast of each observed period, NaN until a full
+// window has been observed.
+func MovingAverage(history []float64, window, horizon int) (fitted, forecast []float64) {
+	if window < 1 {
+		window = 1
+	}
+	fitted = make([]float64, len(history))
+	for t := range history {
+		fitted[t] = math.NaN()
+		if t >= window {
+			fitted[t] = mean(history[t-window : t])
+		}
+	}
+
+	next := mean(history[len(history)-min(window, len(history)):])
+	forecast = make([]float64, horizon)
+	for h := range forecast {
+		forecast[h] = next
+	}
+	return fitted, forecast
+}
+
+// HoltWinters forecasts with additive triple exponential smoothing: alpha smooths the
+// level, beta the trend and gamma the seasonal pattern of seasonLength periods. Without
+// two full seasons of history the seasonal component is left out, which reduces it to
+// Holt's linear smoothing. It reports whether seasonality was fitted.
+func HoltWinters(history []float64, seasonLength int, alpha, beta, gamma float64, horizon int) (fitted, forecast []float64, seasonal bool) {
+	fitted = make([]float64, len(history))
+	forecast = make([]float64, horizon)
+	if len(history) == 0 {
+		return fitted, forecast, false
+	}
+
+	seasonal = seasonLength > 1 && len(history) >= 2*seasonLength
+	m := 1
+	if seasonal {
+		m = seasonLength
+	}
+
+	// Initialize from the first season: its mean as the level, the change to the second
+	// season as the trend and each period's deviation from the mean as its seasonal index
+	level := mean(history[:m])
+	trend := 0.0
+	season := make([]float64, len(history)+horizon)
+	if seasonal {
+		trend = (mean(history[m:2*m]) - level) / float64(m)
+		for i := 0; i < m; i++ {
+			season[i] = history[i] - level
+		}
+	}
+	for t := 0; t < m; t++ {
+		fitted[t] = math.NaN()
+	}
+
+	for t := m; t < len(history); t++ {
+		fitted[t] = level + trend + season[t-m]
+		previous := level
+		level = alpha*(history[t]-season[t-m]) + (1-alpha)*(level+trend)
+		trend = beta*(level-previous) + (1-beta)*trend
+		if seasonal {
+			season[t] = gamma*(history[t]-level) + (1-gamma)*season[t-m]
+		}
+	}
+
+	n := len(history)
+	for h := 1; h <= horizon; h++ {
+		value := level + float64(h)*trend
+		if seasonal {
+			value += season[n-m+(h-1)%m]
+		}
+		forecast[h-1] = math.Max(value, 0)
+	}
+	return fitted, forecast, seasonal
+}
+
+// ForecastError returns the root mean squared error of the one-period-ahead forecasts,
+// or the standard deviation of the history when nothing was forecast yet
+func ForecastError(history, fitted []float64) float64 {
+	squared, count := 0.0, 0
+	for t, value := range fitted {
+		if math.IsNaN(value) {
+			continue
+		}
+		squared += (history[t] - value) * (history[t] - value)
+		count++
+	}
+	if count > 0 {
+		return math.Sqrt(squared / float64(count))
+	}
+
+	if len(history) < 2 {
+		return 0
+	}
+	average := mean(history)
+	for _, value := range history {
+		squared += (value - average) * (value - average)
+	}
+	return math.Sqrt(squared / float64(len(history)-1))
+}
+
+// ServiceFactor returns the number of standard deviations of demand that safety stock must
+// cover for the probability of not running out during a lead time to be serviceLevel
+func ServiceFactor(serviceLevel float64) float64 {
+	return math.Sqrt2 * math.Erfinv(2*serviceLevel-1)
+}
+
+// LeadTimeDemand adds up the forecast over a lead time of leadPeriods periods, counting
+// the last period partially when the lead time ends inside it
+func LeadTimeDemand(forecast []float64, leadPeriods float64) float64 {
+	demand := 0.0
+	for h, value := range forecast {
+		remaining := leadPeriods - float64(h)
+		if remaining <= 0 {
+			break
+		}
+		demand += value * math.Min(remaining, 1)
+	}
+	return demand
+}
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, value := range values {
+		sum += value
+	}
+	return sum / float64(len(values))
+}
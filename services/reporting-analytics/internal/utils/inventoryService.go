@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"reporting-analytics/internal/model"
+)
+
+var (
+	// ErrProductNotFound is returned when inventory does not know the product
+	ErrProductNotFound = errors.New("product not found")
+	// ErrManagerRequired is returned when inventory refuses a change that needs manager permission
+	ErrManagerRequired = errors.New("manager permission required")
+)
+
+// FetchProductThresholds loads the stock thresholds and lead time of a product from the
+// inventory service, forwarding the caller's token
+func FetchProductThresholds(token string, productID uint) (*model.ProductThresholds, error) {
+	return productThresholdsRequest(token, "GET", productID, nil)
+}
+
+// UpdateProductThresholds sets the stock thresholds of a product in the inventory service,
+// which requires the caller to be a manager
+func UpdateProductThresholds(token string, productID uint, thresholds model.ProductThresholds) (*model.ProductThresholds, error) {
+	return productThresholdsRequest(token, "PUT", productID, &thresholds)
+}
+
+func productThresholdsRequest(token, method string, productID uint, body *model.ProductThresholds) (*model.ProductThresholds, error) {
+	inventoryServiceURL := os.Getenv("INVENTORY_SERVICE_URL")
+	if inventoryServiceURL == "" {
+		return nil, fmt.Errorf("INVENTORY_SERVICE_URL is not set")
+	}
+
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			return nil, err
+		}
+	}
+	req, err := http.NewRequest(method, fmt.Sprintf("%s/products/%d/thresholds", inventoryServiceURL, productID), &payload)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, ErrProductNotFound
+	case http.StatusForbidden:
+		return nil, ErrManagerRequired
+	default:
+		return nil, fmt.Errorf("product thresholds request failed, status code: %d", resp.StatusCode)
+	}
+
+	var thresholds model.ProductThresholds
+	if err := json.NewDecoder(resp.Body).Decode(&thresholds); err != nil {
+		return nil, err
+	}
+	return &thresholds, nil
+}
@@ -35,6 +35,7 @@ func main() {
 	go kafka.ConsumerInventoryLevel()
 	go kafka.ConsumerShippingStatus()
 	go kafka.ConsumerUserActivity()
+	go kafka.ConsumerStockAdjustments()
 
 	r := gin.Default()
 
@@ -0,0 +1,281 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"shipping-receiving/internal/kafka"
+	"shipping-receiving/internal/model"
+	"shipping-receiving/internal/utils"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CreateReceipt godoc
+// @Summary Receive stock against a purchase order line
+// @Description Record goods arriving at the dock for a line of a sent purchase order. Receipts that take the line
+// @Description above its ordered quantity are flagged "over"; a final receipt that leaves it short is flagged "under".
+// @Description The quantity is counted in unit_of_measure, or the line's unit when empty, and compared with the
+// @Description order in the product's base unit. It is sent to inventory through RECEIPT_EVENT_TOPIC and marked
+// @Description posted once inventory reports on RECEIPT_STATUS_TOPIC that it was added to stock
+// @Tags Receipts
+// @Accept json
+// @Produce json
+// @Param Receipt body model.Receipt true "Receipt"
+// @Success 201 {object} model.ReceiptResponse
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Failure 502 {object} model.ErrorResponse
+// @Router /shipping-receiving/receipts [post]
+func CreateReceipt(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		var receipt model.Receipt
+		if err := c.ShouldBindJSON(&receipt); err != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid request data"})
+			return
+		}
+		if receipt.Quantity == 0 {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Quantity must be positive"})
+			return
+		}
+		if receipt.BinID == nil && receipt.Location == "" {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "A bin or location is required"})
+			return
+		}
+
+		token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		order, err := utils.FetchPurchaseOrder(token, receipt.PurchaseOrderID)
+		if errors.Is(err, utils.ErrPurchaseOrderNotFound) {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Purchase order not found"})
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to fetch purchase order %d: %v", receipt.PurchaseOrderID, err)
+			c.JSON(http.StatusBadGateway, model.ErrorResponse{Error: "Failed to fetch purchase order"})
+			return
+		}
+		if order.Status != "sent" && order.Status != "partially_received" {
+			c.JSON(http.StatusConflict, model.ErrorResponse{Error: "Purchase order is not open for receiving"})
+			return
+		}
+
+		var line *model.PurchaseOrderLine
+		for i := range order.Lines {
+			if order.Lines[i].ID == receipt.LineID {
+				line = &order.Lines[i]
+			}
+		}
+		if line == nil {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Purchase order line not found"})
+			return
+		}
+		if line.Closed {
+			c.JSON(http.StatusConflict, model.ErrorResponse{Error: "Purchase order line is closed"})
+			return
+		}
+
+		if receipt.UnitOfMeasure == "" {
+			receipt.UnitOfMeasure = line.UnitOfMeasure
+		}
+		factor := uint(1)
+		if receipt.UnitOfMeasure != "" {
+			product, err := utils.FetchProduct(token, line.ProductID)
+			if err != nil {
+				log.Printf("Failed to fetch product %d: %v", line.ProductID, err)
+				c.JSON(http.StatusBadGateway, model.ErrorResponse{Error: "Failed to fetch product"})
+				return
+			}
+			var ok bool
+			if factor, ok = product.UnitFactor(receipt.UnitOfMeasure); !ok {
+				c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Unknown unit of measure"})
+				return
+			}
+		}
+
+		receipt.ID = 0
+		receipt.BaseQuantity = receipt.Quantity * factor
+		receipt.ProductID = line.ProductID
+		receipt.OrderedQuantity = line.Quantity
+		receipt.ReceivedBy = nil
+		if userID, ok := c.Get("user_id"); ok {
+			id := userID.(uint)
+			receipt.ReceivedBy = &id
+		}
+		receipt.Published = false
+		receipt.Posted = false
+		receipt.PostError = ""
+		receipt.AccountID = accountID.(uint)
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			receivingLine := model.ReceivingLine{PurchaseOrderID: receipt.PurchaseOrderID, LineID: receipt.LineID, AccountID: receipt.AccountID}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&receivingLine).Error; err != nil {
+				return err
+			}
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where(receivingLine).First(&receivingLine).Error; err != nil {
+				return err
+			}
+
+			var previous uint
+			if err := tx.Model(&model.Receipt{}).
+				Where("purchase_order_id = ? AND line_id = ? AND account_id = ?", receipt.PurchaseOrderID, receipt.LineID, receipt.AccountID).
+				Select("COALESCE(SUM(base_quantity), 0)").Scan(&previous).Error; err != nil {
+				return err
+			}
+			flagDiscrepancy(&receipt, previous)
+			return tx.Create(&receipt).Error
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to record receipt"})
+			return
+		}
+
+		message := "Receipt recorded successfully"
+		if err := postReceipt(db, &receipt); err != nil {
+			log.Printf("Failed to publish receipt %d: %v", receipt.ID, err)
+			message = "Receipt recorded but not yet sent to inventory"
+		}
+
+		c.JSON(http.StatusCreated, model.ReceiptResponse{Message: message, Receipt: receipt})
+	}
+}
+
+// GetReceipts godoc
+// @Summary Get receipts
+// @Description Get the receipts recorded at the dock, e.g. the over and under receipts of a purchase order
+// @Tags Receipts
+// @Produce json
+// @Param purchase_order_id query int false "Purchase order ID"
+// @Param discrepancy query string false "Discrepancy (over, under)"
+// @Param posted query bool false "Whether inventory added the receipt to stock"
+// @Param failed query bool false "Only receipts inventory could not post"
+// @Success 200 {object} model.ReceiptsResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /shipping-receiving/receipts [get]
+func GetReceipts(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		query := db.Where("account_id = ?", accountID)
+		if purchaseOrderID := c.Query("purchase_order_id"); purchaseOrderID != "" {
+			query = query.Where("purchase_order_id = ?", purchaseOrderID)
+		}
+		if discrepancy := c.Query("discrepancy"); discrepancy != "" {
+			query = query.Where("discrepancy = ?", discrepancy)
+		}
+		if posted := c.Query("posted"); posted != "" {
+			query = query.Where("posted = ?", posted == "true")
+		}
+		if c.Query("failed") == "true" {
+			query = query.Where("post_error <> ?", "")
+		}
+
+		var receipts []model.Receipt
+		if err := query.Order("id").Find(&receipts).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, model.ReceiptsResponse{Message: "Receipts retrieved successfully", Receipts: receipts})
+	}
+}
+
+// RepostReceipt godoc
+// @Summary Post a receipt to inventory again
+// @Description Send a receipt to inventory again when it could not be sent when it was recorded, or when
+// @Description inventory could not post it, e.g. after the purchase order or product was corrected
+// @Tags Receipts
+// @Produce json
+// @Param id path int true "Receipt ID"
+// @Success 200 {object} model.ReceiptResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Failure 502 {object} model.ErrorResponse
+// @Router /shipping-receiving/receipts/{id}/post [post]
+func RepostReceipt(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		var receipt model.Receipt
+		if err := db.Where("id = ? AND account_id = ?", c.Param("id"), accountID).First(&receipt).Error; err != nil {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Receipt not found"})
+			return
+		}
+		if receipt.Posted {
+			c.JSON(http.StatusConflict, model.ErrorResponse{Error: "Receipt was already posted"})
+			return
+		}
+
+		if err := postReceipt(db, &receipt); err != nil {
+			log.Printf("Failed to publish receipt %d: %v", receipt.ID, err)
+			c.JSON(http.StatusBadGateway, model.ErrorResponse{Error: "Failed to post receipt to inventory"})
+			return
+		}
+
+		c.JSON(http.StatusOK, model.ReceiptResponse{Message: "Receipt posted successfully", Receipt: receipt})
+	}
+}
+
+// flagDiscrepancy compares the line's total received quantity, including this receipt,
+// with the ordered quantity. Short receipts are only flagged once the line is final.
+func flagDiscrepancy(receipt *model.Receipt, previous uint) {
+	receipt.ReceivedTotal = previous + receipt.BaseQuantity
+	receipt.Variance = int(receipt.ReceivedTotal) - int(receipt.OrderedQuantity)
+	receipt.Discrepancy = ""
+	switch {
+	case receipt.Variance > 0:
+		receipt.Discrepancy = model.DiscrepancyOver
+	case receipt.Variance < 0 && receipt.Final:
+		receipt.Discrepancy = model.DiscrepancyUnder
+	default:
+		receipt.Variance = 0
+	}
+}
+
+// postReceipt publishes the receipt for inventory and marks it as sent. Inventory reports
+// back whether it was posted.
+func postReceipt(db *gorm.DB, receipt *model.Receipt) error {
+	if err := kafka.PublishReceipt(model.ReceiptEvent{
+		ReceiptID:       receipt.ID,
+		PurchaseOrderID: receipt.PurchaseOrderID,
+		LineID:          receipt.LineID,
+		ProductID:       receipt.ProductID,
+		Quantity:        receipt.Quantity,
+		UnitOfMeasure:   receipt.UnitOfMeasure,
+		BinID:           receipt.BinID,
+		Location:        receipt.Location,
+		LotNumber:       receipt.LotNumber,
+		ExpiresAt:       receipt.ExpiresAt,
+		SerialNumbers:   receipt.SerialNumbers,
+		CloseLine:       receipt.Final || receipt.ReceivedTotal >= receipt.OrderedQuantity,
+		ReceivedBy:      receipt.ReceivedBy,
+		ReceivedAt:      receipt.CreatedAt,
+		AccountID:       receipt.AccountID,
+	}); err != nil {
+		return err
+	}
+
+	receipt.Published = true
+	receipt.PostError = ""
+	return db.Model(receipt).Updates(map[string]interface{}{
+		"published":  true,
+		"post_error": "",
+	}).Error
+}
@@ -63,7 +63,7 @@ func setupTestDB() (*gorm.DB, error) {
 		return nil, err
 	}
 
-	db.AutoMigrate(&model.Shipping{}, &model.User{}, &model.Role{}, &model.Account{}, &model.Department{})
+	db.AutoMigrate(&model.Shipping{}, &model.ShippingSerial{}, &model.Receipt{}, &model.ReceivingLine{}, &model.User{}, &model.Role{}, &model.Account{}, &model.Department{})
 	// Create a role and user for testing
 	role := model.Role{
 		ID: 1,
@@ -140,6 +140,55 @@ func TestCreateShipping(t *testing.T) {
 	})
 }
 
+func TestCreateShippingSerials(t *testing.T) {
+	db, err := setupTestDB()
+	assert.NoError(t, err)
+	defer os.Remove("test_shipping.db")
+
+	inventory := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch req.URL.Query().Get("id") {
+		case "3":
+			json.NewEncoder(w).Encode(model.Product{ID: 3, IsSerialized: true})
+		case "4":
+			json.NewEncoder(w).Encode(model.Product{ID: 4})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer inventory.Close()
+	os.Setenv("INVENTORY_SERVICE_URL", inventory.URL)
+	defer os.Unsetenv("INVENTORY_SERVICE_URL")
+
+	r := SetupRouter(db)
+	token := createTestToken(1, 1)
+
+	ship := func(shipping model.Shipping) int {
+		jsonValue, _ := json.Marshal(shipping)
+		req, _ := http.NewRequest("POST", "/shipping-receiving", bytes.NewBuffer(jsonValue))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusBadRequest, ship(model.Shipping{OrderID: 1, ProductID: 3, Quantity: 2, SerialNumbers: []string{"SN-1"}}))
+	assert.Equal(t, http.StatusBadRequest, ship(model.Shipping{OrderID: 1, ProductID: 3, Quantity: 1}))
+	assert.Equal(t, http.StatusBadRequest, ship(model.Shipping{OrderID: 1, ProductID: 4, Quantity: 1, SerialNumbers: []string{"SN-1"}}))
+	assert.Equal(t, http.StatusBadRequest, ship(model.Shipping{OrderID: 1, SerialNumbers: []string{"SN-1"}}))
+	assert.Equal(t, http.StatusNotFound, ship(model.Shipping{OrderID: 1, ProductID: 5, Quantity: 1}))
+
+	var count int64
+	db.Model(&model.Shipping{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+
+	db.Exec("DELETE FROM users")
+	db.Exec("DELETE FROM accounts")
+	db.Exec("DELETE FROM roles")
+	db.Exec("DELETE FROM departments")
+}
+
 func TestGetShippings(t *testing.T) {
 	db, err := setupTestDB()
 	assert.NoError(t, err)
@@ -392,3 +441,175 @@ func TestDeliverShipping(t *testing.T) {
 	db.Exec("DELETE FROM roles")
 	db.Exec("DELETE FROM departments")
 }
+
+func TestGetShipmentsBySerial(t *testing.T) {
+	db, err := setupTestDB()
+	assert.NoError(t, err)
+	defer os.Remove("test_shipping.db")
+
+	shipping := model.Shipping{
+		ReceiverID: 1,
+		OrderID:    7,
+		Status:     "Shipped",
+		AccountID:  1,
+	}
+	db.Create(&shipping)
+	db.Create(&model.ShippingSerial{ShippingID: shipping.ID, OrderID: 7, Serial: "SN-100", AccountID: 1})
+	db.Create(&model.ShippingSerial{ShippingID: shipping.ID, OrderID: 7, Serial: "SN-100", AccountID: 2})
+
+	r := SetupRouter(db)
+
+	t.Run("GetShipmentsBySerialSuccess", func(t *testing.T) {
+		token := createTestToken(1, 1)
+
+		req, _ := http.NewRequest("GET", "/shipping-receiving/serials/SN-100", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response model.SerialShipmentsResponse
+		err = json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(response.Shipments))
+		assert.Equal(t, shipping.ID, response.Shipments[0].ShippingID)
+		assert.Equal(t, uint(7), response.Shipments[0].OrderID)
+	})
+	db.Exec("DELETE FROM shipping_serials")
+	db.Exec("DELETE FROM shippings")
+	db.Exec("DELETE FROM users")
+	db.Exec("DELETE FROM accounts")
+	db.Exec("DELETE FROM roles")
+	db.Exec("DELETE FROM departments")
+}
+
+func TestReceivePurchaseOrder(t *testing.T) {
+	db, err := setupTestDB()
+	assert.NoError(t, err)
+	defer os.Remove("test_shipping.db")
+
+	inventory := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch req.URL.Path {
+		case "/purchase-orders/7":
+			json.NewEncoder(w).Encode(model.PurchaseOrder{ID: 7, Status: "sent", Lines: []model.PurchaseOrderLine{
+				{ID: 70, ProductID: 3, Quantity: 100},
+				{ID: 71, ProductID: 4, Quantity: 50},
+				{ID: 72, ProductID: 5, Quantity: 20, UnitOfMeasure: "case"},
+			}})
+		case "/products":
+			json.NewEncoder(w).Encode(model.Product{ID: 5, BaseUnit: "each", Units: []model.ProductUnit{{Name: "case", Factor: 12}}})
+		case "/purchase-orders/8":
+			json.NewEncoder(w).Encode(model.PurchaseOrder{ID: 8, Status: "draft"})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer inventory.Close()
+	os.Setenv("INVENTORY_SERVICE_URL", inventory.URL)
+	defer os.Unsetenv("INVENTORY_SERVICE_URL")
+
+	r := SetupRouter(db)
+	token := createTestToken(1, 1)
+
+	receive := func(receipt model.Receipt) (*httptest.ResponseRecorder, model.ReceiptResponse) {
+		jsonValue, _ := json.Marshal(receipt)
+		req, _ := http.NewRequest("POST", "/shipping-receiving/receipts", bytes.NewBuffer(jsonValue))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var response model.ReceiptResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return w, response
+	}
+
+	t.Run("Rejected", func(t *testing.T) {
+		w, _ := receive(model.Receipt{PurchaseOrderID: 7, LineID: 70, Quantity: 5})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w, _ = receive(model.Receipt{PurchaseOrderID: 8, LineID: 80, Quantity: 5, Location: "Dock"})
+		assert.Equal(t, http.StatusConflict, w.Code)
+		w, _ = receive(model.Receipt{PurchaseOrderID: 9, LineID: 90, Quantity: 5, Location: "Dock"})
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		w, _ = receive(model.Receipt{PurchaseOrderID: 7, LineID: 99, Quantity: 5, Location: "Dock"})
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("OverAndUnder", func(t *testing.T) {
+		w, response := receive(model.Receipt{PurchaseOrderID: 7, LineID: 70, Quantity: 60, Location: "Dock"})
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, uint(3), response.Receipt.ProductID)
+		assert.Empty(t, response.Receipt.Discrepancy)
+		// Without a broker the receipt is kept for a later post
+		assert.False(t, response.Receipt.Posted)
+
+		_, response = receive(model.Receipt{PurchaseOrderID: 7, LineID: 70, Quantity: 45, Location: "Dock"})
+		assert.Equal(t, model.DiscrepancyOver, response.Receipt.Discrepancy)
+		assert.Equal(t, uint(105), response.Receipt.ReceivedTotal)
+		assert.Equal(t, 5, response.Receipt.Variance)
+
+		_, response = receive(model.Receipt{PurchaseOrderID: 7, LineID: 71, Quantity: 30, Location: "Dock", Final: true})
+		assert.Equal(t, model.DiscrepancyUnder, response.Receipt.Discrepancy)
+		assert.Equal(t, -20, response.Receipt.Variance)
+
+		// Two cases of 12 against 20 ordered units
+		_, response = receive(model.Receipt{PurchaseOrderID: 7, LineID: 72, Quantity: 2, Location: "Dock"})
+		assert.Equal(t, "case", response.Receipt.UnitOfMeasure)
+		assert.Equal(t, uint(24), response.Receipt.BaseQuantity)
+		assert.Equal(t, 4, response.Receipt.Variance)
+		w, _ = receive(model.Receipt{PurchaseOrderID: 7, LineID: 72, Quantity: 2, UnitOfMeasure: "pallet", Location: "Dock"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		req, _ := http.NewRequest("GET", "/shipping-receiving/receipts?purchase_order_id=7&discrepancy=over", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var receipts model.ReceiptsResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &receipts))
+		assert.Equal(t, 2, len(receipts.Receipts))
+
+		// Receipts against a line are serialized on one row per line
+		var lines int64
+		db.Model(&model.ReceivingLine{}).Count(&lines)
+		assert.Equal(t, int64(3), lines)
+	})
+
+	t.Run("PostingStatus", func(t *testing.T) {
+		var receipt model.Receipt
+		db.Where("line_id = ?", 71).First(&receipt)
+		get := func(path string) model.ReceiptsResponse {
+			req, _ := http.NewRequest("GET", path, nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			var receipts model.ReceiptsResponse
+			json.Unmarshal(w.Body.Bytes(), &receipts)
+			return receipts
+		}
+
+		// Inventory could not post it, so it stays open for a repost
+		assert.NoError(t, utils.ApplyReceiptStatus(db, model.ReceiptStatusEvent{ReceiptID: receipt.ID, Error: "unknown unit of measure", AccountID: 1}))
+		failed := get("/shipping-receiving/receipts?failed=true")
+		assert.Equal(t, 1, len(failed.Receipts))
+		assert.False(t, failed.Receipts[0].Posted)
+		assert.Equal(t, "unknown unit of measure", failed.Receipts[0].PostError)
+
+		assert.NoError(t, utils.ApplyReceiptStatus(db, model.ReceiptStatusEvent{ReceiptID: receipt.ID, Posted: true, AccountID: 1}))
+		assert.Equal(t, 0, len(get("/shipping-receiving/receipts?failed=true").Receipts))
+
+		req, _ := http.NewRequest("POST", "/shipping-receiving/receipts/"+strconv.Itoa(int(receipt.ID))+"/post", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	db.Exec("DELETE FROM receipts")
+	db.Exec("DELETE FROM receiving_lines")
+	db.Exec("DELETE FROM users")
+	db.Exec("DELETE FROM accounts")
+	db.Exec("DELETE FROM roles")
+	db.Exec("DELETE FROM departments")
+}
@@ -3,12 +3,16 @@ package handlers
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"os"
+	"shipping-receiving/internal/kafka"
 	"shipping-receiving/internal/model"
 	"shipping-receiving/internal/utils"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -17,14 +21,17 @@ import (
 
 // CreateShipping godoc
 // @Summary Create a new Shipping
-// @Description Create a new Shipping
+// @Description Create a new Shipping. A shipment of a serialized product has to list one serial number per unit
+// @Description shipped; inventory only releases the order's reserved stock once the serials match
 // @Tags Shippings
 // @Accept json
 // @Produce json
 // @Param Shipping body model.Shipping true "Shipping"
 // @Success 200 {object} model.SuccessResponse
 // @Failure 400 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
 // @Failure 500 {object} model.ErrorResponse
+// @Failure 502 {object} model.ErrorResponse
 // @Router /shippings [post]
 func CreateShipping(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -39,17 +46,45 @@ func CreateShipping(db *gorm.DB) gin.HandlerFunc {
 			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid request data"})
 			return
 		}
+		if len(shipping.SerialNumbers) > 0 && (shipping.ProductID == 0 || shipping.Quantity == 0) {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Serial numbers require a product_id and quantity"})
+			return
+		}
+		if shipping.ProductID != 0 {
+			token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+			product, err := utils.FetchProduct(token, shipping.ProductID)
+			if errors.Is(err, utils.ErrProductNotFound) {
+				c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Product not found"})
+				return
+			}
+			if err != nil {
+				log.Printf("Failed to fetch product %d: %v", shipping.ProductID, err)
+				c.JSON(http.StatusBadGateway, model.ErrorResponse{Error: "Failed to fetch product"})
+				return
+			}
+			if product.IsSerialized && uint(len(shipping.SerialNumbers)) != shipping.Quantity {
+				c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Serialized products ship with one serial number per unit"})
+				return
+			}
+			if !product.IsSerialized && len(shipping.SerialNumbers) > 0 {
+				c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Product is not serialized"})
+				return
+			}
+		}
 
 		shipping.AccountID = accountID.(uint)
 
-		if result := db.Create(&shipping); result.Error != nil {
-			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: result.Error.Error()})
+		if err := utils.RecordShipping(db, &shipping); err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
 			return
 		}
 		if err := updateOrderStatusAndShippingDate(shipping.OrderID, "Shipped", shipping.ShippingDate); err != nil {
 			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: fmt.Sprintf("Failed to update order status: %v", err)})
 			return
 		}
+		if err := kafka.PublishShippingStatus(shipping.OrderID, shipping.ID, "Shipped", shipping.SerialNumbers); err != nil {
+			log.Printf("Failed to publish shipping status: %v", err)
+		}
 
 		c.JSON(http.StatusOK, model.SuccessResponse{Message: "Shipping created successfully", Data: shipping})
 	}
@@ -341,3 +376,40 @@ func DeliverShipping(db *gorm.DB, ns *utils.NotificationService) gin.HandlerFunc
 		c.JSON(http.StatusOK, model.SuccessResponse{Message: "Shipping delivered successfully"})
 	}
 }
+
+// GetShipmentsBySerial godoc
+// @Summary Get shipments by serial number
+// @Description Retrieve every shipment that carried the given serial number
+// @Tags Shippings
+// @Produce json
+// @Param serial path string true "Serial number"
+// @Success 200 {object} model.SerialShipmentsResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /shipping-receiving/serials/{serial} [get]
+func GetShipmentsBySerial(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		serial := c.Param("serial")
+		var shipments []model.SerialShipment
+		if result := db.Model(&model.ShippingSerial{}).
+			Select("shipping_serials.shipping_id, shipping_serials.order_id, shippings.status, shippings.shipping_date, shipping_serials.created_at AS recorded_at").
+			Joins("JOIN shippings ON shippings.id = shipping_serials.shipping_id").
+			Where("shipping_serials.serial = ? AND shipping_serials.account_id = ?", serial, accountID).
+			Order("shipping_serials.created_at").
+			Scan(&shipments); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: result.Error.Error()})
+			return
+		}
+
+		if shipments == nil {
+			shipments = []model.SerialShipment{}
+		}
+
+		c.JSON(http.StatusOK, model.SerialShipmentsResponse{Message: "Shipments retrieved successfully", Serial: serial, Shipments: shipments})
+	}
+}
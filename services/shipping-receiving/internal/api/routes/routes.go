@@ -21,4 +21,8 @@ func Routers(r *gin.Engine, db *gorm.DB, ns *utils.NotificationService) {
 	shippings.DELETE("/hard/:id", handlers.HardDeleteShipping(db))
 	shippings.PATCH("/:id/recover", handlers.RecoverShipping(db))
 	shippings.POST("/:id/deliver", handlers.DeliverShipping(db, ns))
+	shippings.GET("/serials/:serial", handlers.GetShipmentsBySerial(db))
+	shippings.POST("/receipts", handlers.CreateReceipt(db))
+	shippings.GET("/receipts", handlers.GetReceipts(db))
+	shippings.POST("/receipts/:id/post", handlers.RepostReceipt(db))
 }
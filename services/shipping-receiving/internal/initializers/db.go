@@ -26,5 +26,5 @@ func ConnectToDB() {
 		panic("Failed to connect to db")
 	}
 
-	DB.AutoMigrate(&model.Shipping{})
+	DB.AutoMigrate(&model.Shipping{}, &model.ShippingSerial{}, &model.Receipt{}, &model.ReceivingLine{})
 }
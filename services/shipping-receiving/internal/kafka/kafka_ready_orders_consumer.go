@@ -10,6 +10,7 @@ import (
 	"os"
 	"shipping-receiving/internal/initializers"
 	"shipping-receiving/internal/model"
+	"shipping-receiving/internal/utils"
 	"strconv"
 	"time"
 
@@ -32,12 +33,7 @@ func ConsumerShippingEvents() {
 			continue
 		}
 
-		var event struct {
-			OrderID   uint   `json:"order_id"`
-			ProductID uint   `json:"product_id"`
-			Quantity  uint   `json:"quantity"`
-			Action    string `json:"action"`
-		}
+		var event model.OrderEvent
 		if err := json.Unmarshal(m.Value, &event); err != nil {
 			log.Printf("failed to unmarshal shipping event: %v", err)
 			continue
@@ -48,9 +44,15 @@ func ConsumerShippingEvents() {
 			shipping.OrderID = event.OrderID
 			shipping.Status = "Shipped"
 			shipping.ShippingDate = time.Now()
+			shipping.ProductID = event.ProductID
+			shipping.Quantity = event.Quantity
+			// Orders of serialized products never get here: inventory holds them as awaiting
+			// serials until a shipment is created with their serial numbers
 
-			if err := initializers.DB.Create(&shipping).Error; err != nil {
+			if err := utils.RecordShipping(initializers.DB, &shipping); err != nil {
 				log.Printf("failed to create shipping record: %v", err)
+			} else if err := PublishShippingStatus(shipping.OrderID, shipping.ID, shipping.Status, shipping.SerialNumbers); err != nil {
+				log.Printf("failed to publish shipping status: %v", err)
 			}
 
 			// Update the order status via HTTP request
@@ -0,0 +1,45 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"shipping-receiving/internal/model"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// PublishReceipt publishes stock received against a purchase order line to
+// RECEIPT_EVENT_TOPIC. Inventory adds the quantity to stock and updates the order.
+func PublishReceipt(event model.ReceiptEvent) error {
+	brokers := os.Getenv("KAFKA_BROKERS")
+	topic := os.Getenv("RECEIPT_EVENT_TOPIC")
+
+	if brokers == "" || topic == "" {
+		return fmt.Errorf("KAFKA_BROKERS or RECEIPT_EVENT_TOPIC environment variable not set")
+	}
+
+	writer := kafka.Writer{
+		Addr:     kafka.TCP(brokers),
+		Topic:    topic,
+		Balancer: &kafka.LeastBytes{},
+	}
+	defer writer.Close()
+
+	messageBytes, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal receipt: %w", err)
+	}
+
+	if err := writer.WriteMessages(context.Background(), kafka.Message{
+		Key:   []byte(fmt.Sprintf("purchase_order_%d", event.PurchaseOrderID)),
+		Value: messageBytes,
+	}); err != nil {
+		return fmt.Errorf("failed to write receipt to kafka: %w", err)
+	}
+
+	log.Printf("Published receipt: %s\n", string(messageBytes))
+	return nil
+}
@@ -0,0 +1,46 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"shipping-receiving/internal/initializers"
+	"shipping-receiving/internal/model"
+	"shipping-receiving/internal/utils"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// ConsumerReceiptStatus reads from RECEIPT_STATUS_TOPIC whether inventory posted the
+// receipts the dock published
+func ConsumerReceiptStatus() {
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:  []string{os.Getenv("KAFKA_BROKERS")},
+		Topic:    os.Getenv("RECEIPT_STATUS_TOPIC"),
+		GroupID:  "shipping-management-group",
+		MinBytes: 10e3, // 10KB
+		MaxBytes: 10e6, // 10MB
+	})
+
+	for {
+		m, err := r.ReadMessage(context.Background())
+		if err != nil {
+			log.Printf("could not read message: %v", err)
+			continue
+		}
+
+		var event model.ReceiptStatusEvent
+		if err := json.Unmarshal(m.Value, &event); err != nil {
+			log.Printf("failed to unmarshal receipt status: %v", err)
+			continue
+		}
+
+		if !event.Posted {
+			log.Printf("inventory could not post receipt %d: %s", event.ReceiptID, event.Error)
+		}
+		if err := utils.ApplyReceiptStatus(initializers.DB, event); err != nil {
+			log.Printf("failed to record status of receipt %d: %v", event.ReceiptID, err)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"shipping-receiving/internal/model"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// PublishShippingStatus publishes a shipping status change for an order to SHIPPING_STATUS_TOPIC.
+// Inventory uses the "Shipped" status to consume the stock reserved for the order and to
+// mark the listed serial numbers as shipped.
+func PublishShippingStatus(orderID, shippingID uint, action string, serialNumbers []string) error {
+	brokers := os.Getenv("KAFKA_BROKERS")
+	topic := os.Getenv("SHIPPING_STATUS_TOPIC")
+
+	if brokers == "" || topic == "" {
+		return fmt.Errorf("KAFKA_BROKERS or SHIPPING_STATUS_TOPIC environment variable not set")
+	}
+
+	writer := kafka.Writer{
+		Addr:     kafka.TCP(brokers),
+		Topic:    topic,
+		Balancer: &kafka.LeastBytes{},
+	}
+	defer writer.Close()
+
+	messageBytes, err := json.Marshal(model.ShippingStatusEvent{
+		OrderID:       orderID,
+		ShippingID:    shippingID,
+		Action:        action,
+		SerialNumbers: serialNumbers,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal shipping status: %w", err)
+	}
+
+	if err := writer.WriteMessages(context.Background(), kafka.Message{
+		Key:   []byte("order_id"),
+		Value: messageBytes,
+	}); err != nil {
+		return fmt.Errorf("failed to write shipping status to kafka: %w", err)
+	}
+
+	log.Printf("Published shipping status: %s\n", string(messageBytes))
+	return nil
+}
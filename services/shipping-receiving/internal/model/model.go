@@ -16,6 +16,36 @@ type Shipping struct {
 	Status       string         `json:"status"`
 	AccountID    uint           `json:"account_id"`
 	ShippingDate time.Time      `json:"shipping_date"`
+	// ProductID and Quantity are what left with the shipment, when known
+	ProductID uint `json:"product_id,omitempty"`
+	Quantity  uint `json:"quantity,omitempty"`
+	// SerialNumbers lists the serialized units that left with the shipment, one per unit
+	SerialNumbers []string `gorm:"-" json:"serial_numbers,omitempty"`
+}
+
+// ShippingSerial records a serial number that went out on a shipment
+type ShippingSerial struct {
+	ID         uint      `gorm:"primarykey" json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	ShippingID uint      `gorm:"index" json:"shipping_id"`
+	OrderID    uint      `gorm:"index" json:"order_id"`
+	Serial     string    `gorm:"index" json:"serial"`
+	AccountID  uint      `gorm:"index" json:"account_id"`
+}
+
+// SerialShipment is a shipment that carried a given serial number
+type SerialShipment struct {
+	ShippingID   uint      `json:"shipping_id"`
+	OrderID      uint      `json:"order_id"`
+	Status       string    `json:"status"`
+	ShippingDate time.Time `json:"shipping_date"`
+	RecordedAt   time.Time `json:"recorded_at"`
+}
+
+type SerialShipmentsResponse struct {
+	Message   string           `json:"message"`
+	Serial    string           `json:"serial"`
+	Shipments []SerialShipment `json:"shipments"`
 }
 
 type OrderEvent struct {
@@ -25,6 +55,144 @@ type OrderEvent struct {
 	Action    string `json:"action"` // can be "create", "cancel", "ship"
 }
 
+// ShippingStatusEvent is published to SHIPPING_STATUS_TOPIC whenever a shipment changes status
+type ShippingStatusEvent struct {
+	OrderID       uint     `json:"order_id"`
+	ShippingID    uint     `json:"shipping_id"`
+	Action        string   `json:"action"`
+	SerialNumbers []string `json:"serial_numbers,omitempty"`
+}
+
+// Receipt records stock the dock received against a purchase order line. Discrepancy is
+// "over" when more than the ordered quantity has arrived and "under" when a line was
+// finished short; Variance is the difference to the ordered quantity in either case.
+// Quantity is counted in UnitOfMeasure, the other quantities in the product's base unit.
+type Receipt struct {
+	ID              uint       `gorm:"primarykey" json:"id"`
+	CreatedAt       time.Time  `json:"created_at"`
+	PurchaseOrderID uint       `gorm:"index" json:"purchase_order_id"`
+	LineID          uint       `gorm:"index" json:"line_id"`
+	ProductID       uint       `json:"product_id"`
+	Quantity        uint       `json:"quantity"`
+	UnitOfMeasure   string     `json:"unit_of_measure,omitempty"` // The line's unit when empty
+	BaseQuantity    uint       `json:"base_quantity"`
+	OrderedQuantity uint       `json:"ordered_quantity"`
+	ReceivedTotal   uint       `json:"received_total"`
+	Final           bool       `json:"final"`
+	Discrepancy     string     `gorm:"index" json:"discrepancy,omitempty"`
+	Variance        int        `json:"variance"`
+	BinID           *uint      `json:"bin_id,omitempty"`
+	Location        string     `json:"location,omitempty"`
+	LotNumber       string     `json:"lot_number,omitempty"`
+	ExpiresAt       *time.Time `json:"expires_at,omitempty"`
+	SerialNumbers   []string   `gorm:"serializer:json" json:"serial_numbers,omitempty"`
+	Note            string     `json:"note,omitempty"`
+	ReceivedBy      *uint      `json:"received_by,omitempty"`
+	Published       bool       `json:"published"`            // Sent to inventory
+	Posted          bool       `json:"posted"`               // Added to stock by inventory
+	PostError       string     `json:"post_error,omitempty"` // Why inventory could not post it
+	AccountID       uint       `gorm:"index" json:"account_id"`
+}
+
+// ReceivingLine stands for a purchase order line at the dock. Receipts lock it while they
+// add up the line's earlier receipts, so concurrent receipts see each other.
+type ReceivingLine struct {
+	ID              uint `gorm:"primarykey"`
+	PurchaseOrderID uint `gorm:"uniqueIndex:idx_receiving_line"`
+	LineID          uint `gorm:"uniqueIndex:idx_receiving_line"`
+	AccountID       uint `gorm:"uniqueIndex:idx_receiving_line"`
+}
+
+const (
+	DiscrepancyOver  = "over"
+	DiscrepancyUnder = "under"
+)
+
+// PurchaseOrder is the part of an inventory purchase order the dock receives against
+type PurchaseOrder struct {
+	ID         uint                `json:"id"`
+	SupplierID uint                `json:"supplier_id"`
+	Status     string              `json:"status"`
+	ExpectedAt *time.Time          `json:"expected_at,omitempty"`
+	Lines      []PurchaseOrderLine `json:"lines"`
+}
+
+// PurchaseOrderLine orders Quantity in the product's base unit, delivered in UnitOfMeasure
+type PurchaseOrderLine struct {
+	ID            uint   `json:"id"`
+	ProductID     uint   `json:"product_id"`
+	Quantity      uint   `json:"quantity"`
+	UnitOfMeasure string `json:"unit_of_measure,omitempty"`
+	Closed        bool   `json:"closed"`
+}
+
+// Product is the part of an inventory product the dock needs to know about
+type Product struct {
+	ID           uint          `json:"id"`
+	IsSerialized bool          `json:"is_serialized"`
+	BaseUnit     string        `json:"base_unit"`
+	Units        []ProductUnit `json:"units"`
+}
+
+// ProductUnit is a pack size of a product, e.g. a case of 12 base units
+type ProductUnit struct {
+	Name   string `json:"name"`
+	Factor uint   `json:"factor"`
+}
+
+// UnitFactor returns how many base units one unit holds. An empty unit or the base unit
+// itself has factor 1.
+func (p Product) UnitFactor(unit string) (uint, bool) {
+	if unit == "" || unit == p.BaseUnit {
+		return 1, true
+	}
+	for _, productUnit := range p.Units {
+		if productUnit.Name == unit {
+			return productUnit.Factor, true
+		}
+	}
+	return 0, false
+}
+
+// ReceiptEvent is published to RECEIPT_EVENT_TOPIC so inventory posts the received stock
+type ReceiptEvent struct {
+	ReceiptID       uint       `json:"receipt_id"`
+	PurchaseOrderID uint       `json:"purchase_order_id"`
+	LineID          uint       `json:"line_id"`
+	ProductID       uint       `json:"product_id"`
+	Quantity        uint       `json:"quantity"`
+	UnitOfMeasure   string     `json:"unit_of_measure,omitempty"`
+	BinID           *uint      `json:"bin_id,omitempty"`
+	Location        string     `json:"location,omitempty"`
+	LotNumber       string     `json:"lot_number,omitempty"`
+	ExpiresAt       *time.Time `json:"expires_at,omitempty"`
+	SerialNumbers   []string   `json:"serial_numbers,omitempty"`
+	CloseLine       bool       `json:"close_line"`
+	ReceivedBy      *uint      `json:"received_by,omitempty"`
+	ReceivedAt      time.Time  `json:"received_at"`
+	AccountID       uint       `json:"account_id"`
+}
+
+// ReceiptStatusEvent is read from RECEIPT_STATUS_TOPIC once inventory posted a receipt to
+// stock or failed to
+type ReceiptStatusEvent struct {
+	ReceiptID       uint   `json:"receipt_id"`
+	PurchaseOrderID uint   `json:"purchase_order_id"`
+	Posted          bool   `json:"posted"`
+	Error           string `json:"error,omitempty"`
+	AccountID       uint   `json:"account_id"`
+}
+
+type ReceiptResponse struct {
+	Message string  `json:"message"`
+	Receipt Receipt `json:"receipt"`
+}
+
+type ReceiptsResponse struct {
+	Message  string    `json:"message"`
+	Receipts []Receipt `json:"receipts"`
+}
+
 type ErrorResponse struct {
 	Error string `json:"message"`
 }
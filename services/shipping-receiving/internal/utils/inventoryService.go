@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"shipping-receiving/internal/model"
+)
+
+// ErrPurchaseOrderNotFound is returned when inventory does not know the purchase order
+var ErrPurchaseOrderNotFound = errors.New("purchase order not found")
+
+// FetchPurchaseOrder loads a purchase order with its lines from the inventory service,
+// forwarding the caller's token so the order is looked up in the caller's account
+func FetchPurchaseOrder(token string, purchaseOrderID uint) (*model.PurchaseOrder, error) {
+	inventoryServiceURL := os.Getenv("INVENTORY_SERVICE_URL")
+	if inventoryServiceURL == "" {
+		return nil, fmt.Errorf("INVENTORY_SERVICE_URL is not set")
+	}
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/purchase-orders/%d", inventoryServiceURL, purchaseOrderID), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrPurchaseOrderNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch purchase order, status code: %d", resp.StatusCode)
+	}
+
+	var order model.PurchaseOrder
+	if err := json.NewDecoder(resp.Body).Decode(&order); err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// ErrProductNotFound is returned when inventory does not know the product
+var ErrProductNotFound = errors.New("product not found")
+
+// FetchProduct loads a product from the inventory service in the caller's account
+func FetchProduct(token string, productID uint) (*model.Product, error) {
+	inventoryServiceURL := os.Getenv("INVENTORY_SERVICE_URL")
+	if inventoryServiceURL == "" {
+		return nil, fmt.Errorf("INVENTORY_SERVICE_URL is not set")
+	}
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/products?id=%d", inventoryServiceURL, productID), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrProductNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch product, status code: %d", resp.StatusCode)
+	}
+
+	var product model.Product
+	if err := json.NewDecoder(resp.Body).Decode(&product); err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
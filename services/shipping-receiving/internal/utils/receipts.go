@@ -0,0 +1,18 @@
+package utils
+
+import (
+	"shipping-receiving/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// ApplyReceiptStatus records whether inventory posted a receipt to stock. A failed receipt
+// stays unposted with the error so it can be reposted.
+func ApplyReceiptStatus(db *gorm.DB, event model.ReceiptStatusEvent) error {
+	return db.Model(&model.Receipt{}).
+		Where("id = ? AND account_id = ? AND posted = ?", event.ReceiptID, event.AccountID, false).
+		Updates(map[string]interface{}{
+			"posted":     event.Posted,
+			"post_error": event.Error,
+		}).Error
+}
@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"shipping-receiving/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// RecordShipping creates a shipment together with the serial numbers that left with it
+func RecordShipping(db *gorm.DB, shipping *model.Shipping) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(shipping).Error; err != nil {
+			return err
+		}
+		for _, serial := range shipping.SerialNumbers {
+			if err := tx.Create(&model.ShippingSerial{
+				ShippingID: shipping.ID,
+				OrderID:    shipping.OrderID,
+				Serial:     serial,
+				AccountID:  shipping.AccountID,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
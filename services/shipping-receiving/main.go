@@ -21,6 +21,7 @@ func init() {
 
 func main() {
 	go kafka.ConsumerShippingEvents()
+	go kafka.ConsumerReceiptStatus()
 
 	r := gin.Default()
 
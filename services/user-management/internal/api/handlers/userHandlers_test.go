@@ -10,6 +10,7 @@ import (
 	"strconv"
 	"testing"
 	"user-management/internal/api/handlers"
+	"user-management/internal/api/routes"
 	"user-management/internal/model"
 	"user-management/internal/utils"
 
@@ -544,3 +545,140 @@ func TestRecoverUser(t *testing.T) {
 		db.Exec("DELETE FROM roles")
 	})
 }
+
+func TestGetUsers(t *testing.T) {
+	os.Setenv("JWT_SECRET", "test_secret")
+
+	db := SetupTestDB(t)
+	defer db.Exec("DELETE FROM users")
+	defer db.Exec("DELETE FROM roles")
+	defer db.Exec("DELETE FROM departments")
+
+	department := model.Department{Name: "Purchasing", AccountID: 1}
+	db.Create(&department)
+
+	role := model.Role{
+		Role:         "Buyer",
+		DepartmentID: department.ID,
+		AccountID:    1,
+	}
+	db.Create(&role)
+
+	testUser := model.User{
+		PersonalID: "12345",
+		Name:       "Test User",
+		Email:      "user@example.com",
+		Age:        25,
+		BirthDate:  "1999-01-01",
+		RoleID:     role.ID,
+		Phone:      "1234567890",
+		AccountID:  1,
+		Permission: model.PermissionManager,
+	}
+	db.Create(&testUser)
+
+	r := SetupRouter()
+	r.GET("/users/", func(c *gin.Context) {
+		c.Set("account_id", uint(1))
+		handlers.GetUsers(db)(c)
+	})
+
+	// Inventory alert rules pick recipients by role_id, so it must survive next to the role name
+	t.Run("GetUsersRoleAndDepartment", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/users/", nil)
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response struct {
+			Users []struct {
+				ID         uint   `json:"id"`
+				RoleID     uint   `json:"role_id"`
+				Role       string `json:"role"`
+				Department string `json:"department"`
+			} `json:"users"`
+		}
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(response.Users))
+		assert.Equal(t, testUser.ID, response.Users[0].ID)
+		assert.Equal(t, role.ID, response.Users[0].RoleID)
+		assert.Equal(t, "Buyer", response.Users[0].Role)
+		assert.Equal(t, "Purchasing", response.Users[0].Department)
+	})
+}
+
+func TestGetUser(t *testing.T) {
+	os.Setenv("JWT_SECRET", "test_secret")
+
+	db := SetupTestDB(t)
+	defer db.Exec("DELETE FROM users")
+	defer db.Exec("DELETE FROM roles")
+
+	role := model.Role{Role: "Supervisor", AccountID: 1}
+	db.Create(&role)
+
+	password, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
+	testUser := model.User{
+		PersonalID: "12345",
+		Name:       "Test User",
+		Email:      "user@example.com",
+		Age:        25,
+		BirthDate:  "1999-01-01",
+		RoleID:     role.ID,
+		Phone:      "1234567890",
+		Password:   string(password),
+		AccountID:  1,
+		Permission: model.PermissionManager,
+	}
+	db.Create(&testUser)
+
+	// Inventory checks manager permission through this route
+	t.Run("GetUserRouteRegistered", func(t *testing.T) {
+		router := gin.New()
+		routes.Routers(router, db)
+
+		var registered bool
+		for _, route := range router.Routes() {
+			registered = registered || (route.Method == "GET" && route.Path == "/users/:id")
+		}
+		assert.True(t, registered)
+	})
+
+	r := SetupRouter()
+	r.GET("/users/:id", func(c *gin.Context) {
+		c.Set("account_id", uint(1))
+		handlers.GetUser(db)(c)
+	})
+
+	t.Run("GetUserSuccess", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/users/"+strconv.Itoa(int(testUser.ID)), nil)
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response model.User
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, testUser.ID, response.ID)
+		assert.Equal(t, model.PermissionManager, response.Permission)
+		assert.Empty(t, response.Password)
+	})
+
+	t.Run("GetUserOtherAccount", func(t *testing.T) {
+		db.Model(&testUser).Update("account_id", 2)
+
+		req, _ := http.NewRequest("GET", "/users/"+strconv.Itoa(int(testUser.ID)), nil)
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		var response map[string]interface{}
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, "User not found", response["error"])
+	})
+}
@@ -330,7 +330,7 @@ func GetUsers(db *gorm.DB) gin.HandlerFunc {
 
 		var users []struct {
 			model.User
-			RoleID     string `gorm:"column:role_id"`
+			RoleName   string `gorm:"column:role"`
 			Permission string `gorm:"column:permission"`
 			IsActive   bool   `gorm:"column:is_active"`
 			Department string `gorm:"column:department"`
@@ -368,7 +368,7 @@ func GetUsers(db *gorm.DB) gin.HandlerFunc {
 		for _, user := range users {
 			userResponses = append(userResponses, model.UserResponse{
 				User:       user.User,
-				Role:       user.RoleID,
+				Role:       user.RoleName,
 				Permission: user.Permission,
 				IsActive:   user.IsActive,
 				Department: user.Department,
@@ -382,6 +382,37 @@ func GetUsers(db *gorm.DB) gin.HandlerFunc {
 	}
 }
 
+// GetUser godoc
+// @Summary Get a user
+// @Description Retrieve a user of the caller's account by ID, without the password. Other services read the
+// @Description user's permission from it
+// @Tags users
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} model.User
+// @Failure 404 {object} model.ErrorResponse
+// @Router /users/{id} [get]
+func GetUser(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID, exists := c.Get("account_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Account ID not found"})
+			return
+		}
+
+		var user model.User
+		if result := db.Where("id = ? AND account_id = ?", c.Param("id"), accountID).First(&user); result.Error != nil {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{
+				Error: "User not found",
+			})
+			return
+		}
+		user.Password = ""
+
+		c.JSON(http.StatusOK, user)
+	}
+}
+
 // SoftDeleteUser godoc
 // @Summary Soft delete a user
 // @Description Soft delete a user by ID
@@ -21,6 +21,7 @@ func Routers(r *gin.Engine, db *gorm.DB) {
 	users.Use(middleware.RequireAuth(db))
 
 	users.GET("/", handlers.GetUsers(db))
+	users.GET("/:id", handlers.GetUser(db))
 	users.PUT("/:id", handlers.UpdateUser(db))
 	users.PATCH("/recover/:id", handlers.RecoverUser(db))
 	users.DELETE("/:id", handlers.SoftDeleteUser(db))